@@ -0,0 +1,320 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//<<================= Import ADIF files from other loggers =================>>
+
+//The LoTW lexer in genadif.go only knows the handful of fields LoTW sends.
+//This is a general reader for ADIF 3.1 .adi files as written by N1MM,
+//Log4OM, WSJT-X and the like.  The field names are case insensitive and
+//data specifiers may carry a type (e.g. <FREQ:6:N>14.074).  The ADIF spec
+//is at https://adif.org/312/ADIF_312.htm
+
+// adifRecord maps the upper case ADIF field name to its data
+type adifRecord map[string]string
+
+// importResult is one record that was skipped or rejected on import
+type importResult struct {
+	Record int //position of the record in the file, starting at 1
+	Call   string
+	Time   string
+	Reason string
+}
+
+// importSummary is the outcome of importing one file
+type importSummary struct {
+	File     string
+	Added    []LogsRow
+	Skipped  []importResult
+	Rejected []importResult
+	DryRun   bool //checked only, nothing was logged
+}
+
+// QSOs of the same call, band and mode within this window are dupes
+const importDupeWindow = 2 * time.Minute
+
+var errADIFHeader = errors.New("ADIF header is missing <EOH>")
+
+// parses an .adi file into its records.  A file that starts with anything
+// other than "<" has a header that runs to <EOH>.
+func parseADIF(input string) ([]adifRecord, error) {
+	records := []adifRecord{}
+	pos := 0
+	if !strings.HasPrefix(strings.TrimSpace(input), "<") {
+		n := strings.Index(strings.ToUpper(input), "<EOH>")
+		if n == -1 {
+			return records, errADIFHeader
+		}
+		pos = n + len("<EOH>")
+	}
+	rec := adifRecord{}
+	for {
+		n := strings.Index(input[pos:], "<")
+		if n == -1 {
+			break
+		}
+		pos += n + 1
+		m := strings.Index(input[pos:], ">")
+		if m == -1 {
+			return records, fmt.Errorf("field specifier at offset %d is not closed", pos-1)
+		}
+		spec := input[pos : pos+m]
+		pos += m + 1
+		parts := strings.Split(spec, ":")
+		name := strings.ToUpper(strings.TrimSpace(parts[0]))
+		if len(parts) == 1 {
+			switch name {
+			case "EOR":
+				if len(rec) > 0 {
+					records = append(records, rec)
+				}
+				rec = adifRecord{}
+			case "EOH":
+				//header fields of a file that started with a field
+				rec = adifRecord{}
+			}
+			continue
+		}
+		l, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+		if err != nil || l < 0 {
+			return records, fmt.Errorf("bad data length in <%s>", spec)
+		}
+		if pos+l > len(input) {
+			return records, fmt.Errorf("data of <%s> runs past the end of the file", spec)
+		}
+		rec[name] = input[pos : pos+l]
+		pos += l
+	}
+	return records, nil
+}
+
+// maps the standard ADIF fields of a record to a log row
+func adifToLog(r adifRecord) (*LogsRow, error) {
+	l := &LogsRow{}
+	l.Call = strings.ToUpper(strings.TrimSpace(r["CALL"]))
+	if l.Call == "" {
+		return l, fmt.Errorf("no call sign")
+	}
+	if !validCall(l.Call) {
+		return l, fmt.Errorf("bad call sign %s", l.Call)
+	}
+	t, err := adifTime(r["QSO_DATE"], r["TIME_ON"])
+	if err != nil {
+		return l, err
+	}
+	l.Time = t
+
+	l.Freq, l.FreqRx = adifFreq(r["FREQ"]), adifFreq(r["FREQ_RX"])
+	l.Band, err = importBand(r["BAND"], l.Freq)
+	if err != nil {
+		return l, err
+	}
+	l.Mode = adifMode(r["MODE"], r["SUBMODE"], l.Band)
+	if l.Mode == "" {
+		return l, fmt.Errorf("no mode")
+	}
+
+	l.Sent = r["RST_SENT"]
+	l.Rcvd = r["RST_RCVD"]
+	l.Name = r["NAME"]
+	l.Country = r["COUNTRY"]
+	l.DXCC, _ = strconv.Atoi(r["DXCC"])
+	l.CQZone, _ = strconv.Atoi(r["CQZ"])
+	l.ITUZone, _ = strconv.Atoi(r["ITUZ"])
+	l.Continent = strings.ToUpper(r["CONT"])
+	l.State = r["STATE"]
+	//CNTY is the state and the county, NJ,Middlesex
+	if i := strings.Index(r["CNTY"], ","); i >= 0 {
+		l.County = r["CNTY"][i+1:]
+	} else {
+		l.County = r["CNTY"]
+	}
+	l.Grid = r["GRIDSQUARE"]
+	//DISTANCE is in km and may have decimals
+	if d, err := strconv.ParseFloat(r["DISTANCE"], 64); err == nil {
+		l.Distance = int(math.Round(d))
+	}
+	l.Comment = r["COMMENT"]
+	if l.Comment == "" {
+		l.Comment = r["NOTES"]
+	}
+	if adifYes(r["LOTW_QSL_SENT"]) {
+		l.Lotwsent = "YES"
+	}
+	if adifYes(r["LOTW_QSL_RCVD"]) {
+		l.Lotwrcvd = "YES"
+	}
+	if adifYes(r["EQSL_QSL_SENT"]) {
+		l.Eqslsent = "YES"
+	}
+	if adifYes(r["EQSL_QSL_RCVD"]) {
+		l.Eqslrcvd = "YES"
+	}
+	switch strings.ToUpper(strings.TrimSpace(r["QSL_SENT"])) {
+	case "Y":
+		l.QSLsent = "YES"
+	case "Q":
+		l.QSLsent = cardQueued
+	}
+	if adifYes(r["QSL_RCVD"]) {
+		l.QSLrcvd = "YES"
+	}
+	l.QSLsentVia = viaRoute(r["QSL_SENT_VIA"])
+	l.QSLrcvdVia = viaRoute(r["QSL_RCVD_VIA"])
+	if c := r["CONTEST_ID"]; c != "" {
+		l.Contest = "Yes"
+		l.ContestName = c
+		l.ExchSent = r["STX_STRING"]
+		if l.ExchSent == "" {
+			l.ExchSent = r["STX"]
+		}
+		l.ExchRcvd = r["SRX_STRING"]
+		if l.ExchRcvd == "" {
+			l.ExchRcvd = r["SRX"]
+		}
+	}
+	//written by genFullADIFFile
+	sent := []*string{&l.Field1Sent, &l.Field2Sent, &l.Field3Sent, &l.Field4Sent, &l.Field5Sent}
+	rcvd := []*string{&l.Field1Rcvd, &l.Field2Rcvd, &l.Field3Rcvd, &l.Field4Rcvd, &l.Field5Rcvd}
+	for i := range sent {
+		*sent[i] = r[fmt.Sprintf("APP_STATIONMASTER_FIELD%d_SENT", i+1)]
+		*rcvd[i] = r[fmt.Sprintf("APP_STATIONMASTER_FIELD%d_RCVD", i+1)]
+		if len(*sent[i]) > 10 || len(*rcvd[i]) > 10 {
+			return l, fmt.Errorf("field too long for the log")
+		}
+	}
+	if len(l.Sent) > 10 || len(l.Rcvd) > 10 || len(l.Band) > 10 ||
+		len(l.Mode) > 20 || len(l.ContestName) > 50 ||
+		len(l.ExchSent) > 10 || len(l.ExchRcvd) > 10 {
+		return l, fmt.Errorf("field too long for the log")
+	}
+	return l, nil
+}
+
+// ADIF dates are YYYYMMDD and times are HHMM or HHMMSS, all in UTC
+func adifTime(d, t string) (time.Time, error) {
+	d = strings.TrimSpace(d)
+	t = strings.TrimSpace(t)
+	if len(t) == 4 {
+		t += "00"
+	}
+	qsoTime, err := time.Parse("20060102150405", d+t)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("bad QSO date %q or time %q", d, t)
+	}
+	if qsoTime.After(time.Now().UTC().Add(24 * time.Hour)) {
+		return time.Time{}, fmt.Errorf("QSO date %s is in the future", d)
+	}
+	return qsoTime, nil
+}
+
+// the band of a QSO read from an ADIF or a CSV file, the band given or else
+// the band of the frequency (in MHz, blank for none).  The band has to be
+// an ADIF one and the frequency on it.
+func importBand(band, freq string) (string, error) {
+	band = strings.ToLower(strings.TrimSpace(band))
+	onBand := mhzToBand(freq)
+	if band == "" {
+		band = onBand
+	}
+	if band == "" {
+		if freq != "" {
+			return "", fmt.Errorf("frequency %s is not on a band", freq)
+		}
+		return "", fmt.Errorf("no band or frequency")
+	}
+	if !isBand(band) {
+		return "", fmt.Errorf("%s is not a band", band)
+	}
+	if freq != "" && onBand != band {
+		return "", fmt.Errorf("frequency %s is not on %s", freq, band)
+	}
+	return band, nil
+}
+
+// the log keeps the sideband (USB, LSB) and the digital submodes (FT4)
+// where ADIF files use MODE SSB and MODE MFSK with a SUBMODE
+func adifMode(mode, submode, band string) string {
+	mode = strings.ToUpper(strings.TrimSpace(mode))
+	submode = strings.ToUpper(strings.TrimSpace(submode))
+	switch mode {
+	case "SSB":
+		if submode == "USB" || submode == "LSB" {
+			return submode
+		}
+		switch band {
+		case "160m", "80m", "40m":
+			return "LSB"
+		}
+		return "USB"
+	case "MFSK":
+		if submode != "" {
+			return submode
+		}
+	}
+	return mode
+}
+
+// the frequencies are kept to the Hz, the same as the radio and the VFO
+// write them
+func adifFreq(f string) string {
+	mhz, err := strconv.ParseFloat(strings.TrimSpace(f), 64)
+	if err != nil || mhz <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("%.6f", mhz)
+}
+
+func adifYes(s string) bool {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	return s == "Y" || s == "V"
+}
+
+// imports the records of an ADIF file, skipping the dupes and rejecting
+// the records that can not be logged
+func (app *application) importADIF(fileName, input string) (*importSummary, error) {
+	sum := &importSummary{File: fileName}
+	records, err := parseADIF(input)
+	if err != nil {
+		return sum, err
+	}
+	for i, r := range records {
+		res := importResult{Record: i + 1, Call: r["CALL"],
+			Time: strings.TrimSpace(r["QSO_DATE"] + " " + r["TIME_ON"])}
+		l, err := adifToLog(r)
+		if err != nil {
+			res.Reason = err.Error()
+			sum.Rejected = append(sum.Rejected, res)
+			continue
+		}
+		dupe, err := app.logsModel.findDupe(l, importDupeWindow)
+		if err != nil {
+			return sum, err
+		}
+		if dupe {
+			res.Reason = "already in the log"
+			sum.Skipped = append(sum.Skipped, res)
+			continue
+		}
+		if l.Country == "" {
+			l.Country = app.entityOf(*l)
+		}
+		err = app.fillLocation(l)
+		if err != nil {
+			return sum, err
+		}
+		l.Id, err = app.logsModel.importLog(l, sourceImport)
+		if err != nil {
+			return sum, err
+		}
+		sum.Added = append(sum.Added, *l)
+	}
+	return sum, nil
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+const adifImportData = `Log4OM test export
+<ADIF_VER:5>3.1.0 <PROGRAMID:6>Log4OM
+<EOH>
+<CALL:5>AA7BQ <QSO_DATE:8>20230415 <TIME_ON:6>141500 <BAND:3>20M <MODE:3>SSB
+<FREQ:6:N>14.250 <RST_SENT:2>59 <RST_RCVD:2>57 <NAME:4>Fred <COUNTRY:13>United States
+<LOTW_QSL_RCVD:1>Y <EOR>
+<call:5>DL1AB <qso_date:8>20230415 <time_on:4>1420 <freq:6>14.074 <mode:3>FT8
+<comment:8>-12 dB r <eor>
+<CALL:6>G4ABC. <QSO_DATE:8>20230415 <TIME_ON:4>1430 <BAND:3>40m <MODE:2>CW <EOR>
+<CALL:5>W1AW1 <QSO_DATE:8>2023041 <TIME_ON:4>1430 <BAND:3>40m <MODE:2>CW <EOR>
+<CALL:4>K1XX <QSO_DATE:8>20230415 <TIME_ON:4>1500 <BAND:3>40m <MODE:3>SSB
+<CONTEST_ID:7>ARRL-DX <STX_STRING:2>NJ <SRX:3>100 <EOR>
+<CALL:5>AA7BQ <QSO_DATE:8>20230415 <TIME_ON:4>1416 <BAND:3>20m <MODE:3>SSB
+<SUBMODE:3>USB <EOR>
+`
+
+func TestParseADIF(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		records int
+		wantErr bool
+	}{
+		{"header", adifImportData, 6, false},
+		{"no header", "<CALL:4>N2VY<BAND:3>20m<EOR><CALL:4>K1XX<EOR>", 2, false},
+		{"field header", "<ADIF_VER:5>3.1.0<EOH><CALL:4>N2VY<EOR>", 1, false},
+		{"missing eoh", "exported by hand\n<CALL:4>N2VY<EOR>", 0, true},
+		{"bad length", "<CALL:x>N2VY<EOR>", 0, true},
+		{"too long", "<CALL:10>N2VY<EOR>", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, err := parseADIF(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("want error %v, got %v", tt.wantErr, err)
+			}
+			if len(records) != tt.records {
+				t.Errorf("want %d records, got %d", tt.records, len(records))
+			}
+		})
+	}
+	records, _ := parseADIF(adifImportData)
+	if records[0]["FREQ"] != "14.250" || records[1]["COMMENT"] != "-12 dB r" {
+		t.Errorf("did not get the field data, got %v", records[:2])
+	}
+}
+
+func TestAdifToLog(t *testing.T) {
+	tests := []struct {
+		name    string
+		rec     adifRecord
+		band    string
+		mode    string
+		freq    string
+		freqRx  string
+		wantErr bool
+	}{
+		{"ssb on 40", adifRecord{"CALL": "n2vy", "QSO_DATE": "20230101",
+			"TIME_ON": "0100", "BAND": "40M", "MODE": "SSB"}, "40m", "LSB", "", "", false},
+		{"ft4 from freq", adifRecord{"CALL": "N2VY", "QSO_DATE": "20230101",
+			"TIME_ON": "010203", "FREQ": "21.140", "MODE": "MFSK", "SUBMODE": "FT4"},
+			"15m", "FT4", "21.140000", "", false},
+		{"split", adifRecord{"CALL": "N2VY", "QSO_DATE": "20230101",
+			"TIME_ON": "0100", "FREQ": "14.0255", "FREQ_RX": "14.027", "MODE": "CW"},
+			"20m", "CW", "14.025500", "14.027000", false},
+		{"no call", adifRecord{"QSO_DATE": "20230101", "TIME_ON": "0100",
+			"BAND": "40M", "MODE": "CW"}, "", "", "", "", true},
+		{"no band", adifRecord{"CALL": "N2VY", "QSO_DATE": "20230101",
+			"TIME_ON": "0100", "MODE": "CW"}, "", "", "", "", true},
+		{"2m from freq", adifRecord{"CALL": "N2VY", "QSO_DATE": "20230101",
+			"TIME_ON": "0100", "FREQ": "144.174", "MODE": "FT8"}, "2m", "FT8", "144.174000", "", false},
+		{"not a band", adifRecord{"CALL": "N2VY", "QSO_DATE": "20230101",
+			"TIME_ON": "0100", "BAND": "11M", "MODE": "CW"}, "", "", "", "", true},
+		{"freq off band", adifRecord{"CALL": "N2VY", "QSO_DATE": "20230101",
+			"TIME_ON": "0100", "BAND": "40M", "FREQ": "14.025", "MODE": "CW"}, "", "", "", "", true},
+		{"future", adifRecord{"CALL": "N2VY", "QSO_DATE": "29990101",
+			"TIME_ON": "0100", "BAND": "40M", "MODE": "CW"}, "", "", "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, err := adifToLog(tt.rec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("want error %v, got %v", tt.wantErr, err)
+			}
+			if tt.wantErr {
+				return
+			}
+			if l.Band != tt.band || l.Mode != tt.mode {
+				t.Errorf("want %s %s, got %s %s", tt.band, tt.mode, l.Band, l.Mode)
+			}
+			if l.Freq != tt.freq || l.FreqRx != tt.freqRx {
+				t.Errorf("want freq %q rx %q, got %q %q", tt.freq, tt.freqRx, l.Freq, l.FreqRx)
+			}
+		})
+	}
+}
+
+func TestImportADIF(t *testing.T) {
+	app := newTestSQLiteApp(t)
+
+	sum, err := app.importADIF("log4om.adi", adifImportData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sum.Added) != 3 || len(sum.Skipped) != 1 || len(sum.Rejected) != 2 {
+		t.Fatalf("want 3 added, 1 skipped, 2 rejected, got %d %d %d",
+			len(sum.Added), len(sum.Skipped), len(sum.Rejected))
+	}
+	l, err := app.logsModel.getLogByID(sum.Added[0].Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := time.Date(2023, 4, 15, 14, 15, 0, 0, time.UTC)
+	if !l.Time.Equal(want) || l.Mode != "USB" || l.Lotwrcvd != "YES" {
+		t.Errorf("want USB at %v confirmed, got %s at %v %s", want, l.Mode, l.Time, l.Lotwrcvd)
+	}
+	if sum.Added[2].ContestName != "ARRL-DX" || sum.Added[2].ExchRcvd != "100" {
+		t.Errorf("want ARRL-DX exchange 100, got %s %s",
+			sum.Added[2].ContestName, sum.Added[2].ExchRcvd)
+	}
+
+	//a second import of the same file only has dupes
+	sum, err = app.importADIF("log4om.adi", adifImportData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sum.Added) != 0 || len(sum.Skipped) != 4 {
+		t.Errorf("want 0 added and 4 skipped, got %d %d", len(sum.Added), len(sum.Skipped))
+	}
+}
+
+func TestADIFImportFile(t *testing.T) {
+	app := newTestSQLiteApp(t)
+
+	var b bytes.Buffer
+	mw := multipart.NewWriter(&b)
+	fw, err := mw.CreateFormFile("adiffile", "wsjtx_log.adi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fw.Write([]byte(adifImportData))
+	mw.Close()
+
+	rr := httptest.NewRecorder()
+	r, err := http.NewRequest(http.MethodPost, "/adif-import-file", &b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	app.adifImportFile(rr, r)
+	rs := rr.Result()
+	defer rs.Body.Close()
+	bod, err := io.ReadAll(rs.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rs.StatusCode != http.StatusOK {
+		t.Errorf("expected %d got %d", http.StatusOK, rs.StatusCode)
+	}
+	if !bytes.Contains(bod, []byte("3 added, 1 skipped, 2 rejected")) {
+		t.Errorf("expected the import summary in the body, did not get")
+	}
+}
+
+func TestAdifLocation(t *testing.T) {
+	l, err := adifToLog(adifRecord{"CALL": "W1AW", "QSO_DATE": "20230101",
+		"TIME_ON": "0100", "BAND": "40M", "MODE": "CW", "DXCC": "291", "CQZ": "5",
+		"ITUZ": "8", "CONT": "na", "STATE": "CT", "CNTY": "CT,Hartford",
+		"GRIDSQUARE": "FN31pr", "DISTANCE": "171.6"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.DXCC != 291 || l.CQZone != 5 || l.ITUZone != 8 || l.Continent != "NA" ||
+		l.State != "CT" || l.County != "Hartford" || l.Grid != "FN31pr" || l.Distance != 172 {
+		t.Errorf("did not get the location, got %d %d %d %s %s %s %s %d", l.DXCC, l.CQZone,
+			l.ITUZone, l.Continent, l.State, l.County, l.Grid, l.Distance)
+	}
+}
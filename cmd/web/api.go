@@ -0,0 +1,484 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//<<======================== JSON API, version 1 ========================>>
+
+//The API is for scripts that work with the station.  It is built on the
+//same models as the web pages and answers in JSON, errors included:
+//
+//	GET    /api/v1/qsos          search, the logFilter fields and page
+//	POST   /api/v1/qsos          log a QSO, Time defaults to now
+//	GET    /api/v1/qsos/{id}     one QSO
+//	PUT    /api/v1/qsos/{id}     change the fields that are sent
+//	DELETE /api/v1/qsos/{id}     move a QSO to the trash
+//	GET    /api/v1/lookup/{call} QRZ data, from the local cache if there
+//	GET    /api/v1/defaults      the logger and contest settings
+//	PUT    /api/v1/defaults      change some of them
+//	GET    /api/v1/station       call, band, mode and frequency
+//	GET    /api/v1/spots         the DX spots for the current band
+//	GET    /api/v1/map           GeoJSON of the QSOs, the logFilter fields
+//	                             and mapcolor (band or confirmed)
+
+const apiPrefix = "/api/v1/"
+
+// how many QSOs a page of API search results has unless size says otherwise
+const apiPageSize = 100
+
+// the defaults the API can read and change
+var apiDefaults = []string{"band", "mode", "split", "contest", "contestname",
+	"contestdate", "contesttime", "sent", "exch", "fieldCount",
+	"field1Name", "field2Name", "field3Name", "field4Name", "field5Name",
+	"field1Data", "field2Data", "field3Data", "field4Data", "field5Data",
+	"profile"}
+
+type apiQSOPage struct {
+	QSOs  []LogsRow
+	Total int
+	Page  int
+	Pages int
+}
+
+type apiStation struct {
+	Call    string
+	Profile string
+	Band    string
+	Mode    string
+	Freq    string
+	FreqRx  string
+}
+
+type apiError struct {
+	Error  string
+	Fields map[string][]string `json:",omitempty"`
+}
+
+func (app *application) apiRoutes(mux *http.ServeMux) {
+	mux.HandleFunc(apiPrefix+"qsos", app.apiQSOs)
+	mux.HandleFunc(apiPrefix+"qsos/", app.apiQSO)
+	mux.HandleFunc(apiPrefix+"lookup/", app.apiLookup)
+	mux.HandleFunc(apiPrefix+"defaults", app.apiDefaults)
+	mux.HandleFunc(apiPrefix+"station", app.apiStation)
+	mux.HandleFunc(apiPrefix+"spots", app.apiSpots)
+	mux.HandleFunc(apiPrefix+"map", app.apiMap)
+	mux.HandleFunc(apiPrefix, func(w http.ResponseWriter, r *http.Request) {
+		app.apiError(w, http.StatusNotFound, "no such API endpoint")
+	})
+}
+
+func (app *application) apiQSOs(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		app.apiSearch(w, r)
+	case http.MethodPost:
+		app.apiCreateQSO(w, r)
+	default:
+		app.apiMethodNotAllowed(w, http.MethodGet, http.MethodPost)
+	}
+}
+
+func (app *application) apiSearch(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	f := newForm(q)
+	lf := f.logFilter()
+	page := apiInt(f, "page", 1)
+	size := apiInt(f, "size", apiPageSize)
+	if size > 1000 {
+		f.Errors.add("size", "can not be more than 1000")
+	}
+	if !f.valid() {
+		app.apiInvalid(w, f.Errors)
+		return
+	}
+	rows, total, err := app.logsModel.searchLogs(lf, (page-1)*size, size)
+	if err != nil {
+		app.apiServerError(w, err)
+		return
+	}
+	app.apiJSON(w, http.StatusOK, &apiQSOPage{
+		QSOs:  rows,
+		Total: total,
+		Page:  page,
+		Pages: (total + size - 1) / size,
+	})
+}
+
+// an optional positive integer from the query string
+func apiInt(f *formData, field string, def int) int {
+	v := f.Get(field)
+	if v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 {
+		f.Errors.add(field, "must be a whole number of 1 or more")
+		return def
+	}
+	return n
+}
+
+func (app *application) apiCreateQSO(w http.ResponseWriter, r *http.Request) {
+	l := &LogsRow{}
+	if !app.apiDecode(w, r, l) {
+		return
+	}
+	l.Id = 0
+	if l.Time.IsZero() {
+		l.Time = time.Now().UTC()
+	}
+	if errs := checkQSO(l); len(errs) > 0 {
+		app.apiInvalid(w, errs)
+		return
+	}
+	if l.Country == "" {
+		l.Country = app.entityOf(*l)
+	}
+	err := app.fillLocation(l)
+	if err != nil {
+		app.apiServerError(w, err)
+		return
+	}
+	id, err := app.logsModel.importLog(l, sourceAPI)
+	if err != nil {
+		app.apiServerError(w, err)
+		return
+	}
+	app.logged(id)
+	l, err = app.logsModel.getLogByID(id)
+	if err != nil {
+		app.apiServerError(w, err)
+		return
+	}
+	w.Header().Set("Location", fmt.Sprintf("%sqsos/%d", apiPrefix, id))
+	app.apiJSON(w, http.StatusCreated, l)
+}
+
+func (app *application) apiQSO(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, apiPrefix+"qsos/"))
+	if err != nil || id < 1 {
+		app.apiError(w, http.StatusNotFound, "no such QSO")
+		return
+	}
+	switch r.Method {
+	case http.MethodGet, http.MethodPut:
+	case http.MethodDelete:
+		n, err := app.logsModel.deleteLogs([]int{id}, sourceAPI)
+		if err != nil {
+			app.apiServerError(w, err)
+			return
+		}
+		if n == 0 {
+			app.apiError(w, http.StatusNotFound, "no such QSO")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	default:
+		app.apiMethodNotAllowed(w, http.MethodGet, http.MethodPut, http.MethodDelete)
+		return
+	}
+	l, err := app.logsModel.getLogByID(id)
+	if err != nil {
+		if errors.Is(err, errNoRecord) {
+			app.apiError(w, http.StatusNotFound, "no such QSO")
+			return
+		}
+		app.apiServerError(w, err)
+		return
+	}
+	if r.Method == http.MethodGet {
+		app.apiJSON(w, http.StatusOK, l)
+		return
+	}
+	//the fields that are not sent keep their values
+	if !app.apiDecode(w, r, l) {
+		return
+	}
+	l.Id = id
+	if errs := checkQSO(l); len(errs) > 0 {
+		app.apiInvalid(w, errs)
+		return
+	}
+	err = app.logsModel.replaceLog(l, sourceAPI)
+	if err != nil {
+		app.apiServerError(w, err)
+		return
+	}
+	l, err = app.logsModel.getLogByID(id)
+	if err != nil {
+		app.apiServerError(w, err)
+		return
+	}
+	app.apiJSON(w, http.StatusOK, l)
+}
+
+// checks a QSO sent to the API the way the log forms are checked
+func checkQSO(l *LogsRow) formErrors {
+	l.Call = strings.ToUpper(strings.TrimSpace(l.Call))
+	l.Band = strings.ToLower(strings.TrimSpace(l.Band))
+	l.Mode = strings.ToUpper(strings.TrimSpace(l.Mode))
+	f := newForm(url.Values{
+		"Call": {l.Call}, "Band": {l.Band}, "Mode": {l.Mode},
+		"Sent": {l.Sent}, "Rcvd": {l.Rcvd}, "Freq": {l.Freq}, "FreqRx": {l.FreqRx},
+	})
+	f.required("Call", "Band", "Mode")
+	f.maxLength("Call", 20)
+	f.maxLength("Band", 10)
+	f.maxLength("Mode", 20)
+	f.maxLength("Sent", 10)
+	f.maxLength("Rcvd", 10)
+	f.isFreq("Freq")
+	f.isFreq("FreqRx")
+	if l.Call != "" && !validCall(l.Call) {
+		f.Errors.add("Call", "this is not a valid call sign")
+	}
+	if l.Band != "" && !isBand(l.Band) {
+		f.Errors.add("Band", "this is not a band, e.g. 20m")
+	}
+	//the receive frequency can be on another band, e.g. satellites
+	if b := mhzToBand(l.Freq); b != "" && isBand(l.Band) && b != l.Band {
+		f.Errors.add("Freq", fmt.Sprintf("this frequency is not on %s", l.Band))
+	}
+	return f.Errors
+}
+
+// the QRZ data for a call sign.  A call that is not in the local cache is
+// looked up on QRZ.com and cached.
+func (app *application) apiLookup(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		app.apiMethodNotAllowed(w, http.MethodGet)
+		return
+	}
+	call := strings.ToUpper(strings.TrimPrefix(r.URL.Path, apiPrefix+"lookup/"))
+	if !validCall(call) {
+		app.apiError(w, http.StatusBadRequest, "this is not a valid call sign")
+		return
+	}
+	c, err := app.qrzModel.getQRZ(call)
+	if err == nil {
+		app.apiJSON(w, http.StatusOK, c)
+		return
+	}
+	if !errors.Is(err, errNoRecord) {
+		app.apiServerError(w, err)
+		return
+	}
+	q, err := app.getHamInfo(call)
+	if err != nil {
+		app.apiError(w, http.StatusBadGateway, fmt.Sprintf("QRZ lookup failed: %v", err))
+		return
+	}
+	if q.Callsign.Call == "" {
+		app.apiError(w, http.StatusNotFound, "QRZ does not know this call sign")
+		return
+	}
+	err = app.qrzModel.insertQRZ(&q.Callsign)
+	if err != nil {
+		app.apiServerError(w, err)
+		return
+	}
+	app.apiJSON(w, http.StatusOK, &q.Callsign)
+}
+
+func (app *application) apiDefaults(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		v := map[string]string{}
+		if !app.apiDecode(w, r, &v) {
+			return
+		}
+		errs := formErrors{}
+		for k := range v {
+			if !isAPIDefault(k) {
+				errs.add(k, "this default can not be changed through the API")
+			}
+		}
+		if p, ok := v["profile"]; ok {
+			id, err := strconv.Atoi(p)
+			if err == nil {
+				_, err = app.profileModel.getProfile(id)
+			}
+			if err != nil {
+				errs.add("profile", "there is no such profile")
+			}
+		}
+		if len(errs) > 0 {
+			app.apiInvalid(w, errs)
+			return
+		}
+		for k, val := range v {
+			err := app.otherModel.updateDefault(k, val)
+			if err != nil {
+				app.apiServerError(w, err)
+				return
+			}
+		}
+		if _, ok := v["profile"]; ok {
+			err := app.switchProfile()
+			if err != nil {
+				app.apiServerError(w, err)
+				return
+			}
+		}
+	default:
+		app.apiMethodNotAllowed(w, http.MethodGet, http.MethodPut)
+		return
+	}
+	d := map[string]string{}
+	for _, k := range apiDefaults {
+		v, err := app.otherModel.getDefault(k)
+		if err != nil && !errors.Is(err, errNoRecord) {
+			app.apiServerError(w, err)
+			return
+		}
+		d[k] = v
+	}
+	app.apiJSON(w, http.StatusOK, d)
+}
+
+func isAPIDefault(k string) bool {
+	for _, d := range apiDefaults {
+		if d == k {
+			return true
+		}
+	}
+	return false
+}
+
+func (app *application) apiStation(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		app.apiMethodNotAllowed(w, http.MethodGet)
+		return
+	}
+	s := &apiStation{Call: app.stationCall()}
+	p, err := app.activeProfile()
+	if err != nil && !errors.Is(err, errNoProfile) {
+		app.apiServerError(w, err)
+		return
+	}
+	if p != nil {
+		s.Profile = p.Name
+	}
+	s.Band, err = app.otherModel.getDefault("band")
+	if err != nil && !errors.Is(err, errNoRecord) {
+		app.apiServerError(w, err)
+		return
+	}
+	s.Mode, err = app.otherModel.getDefault("mode")
+	if err != nil && !errors.Is(err, errNoRecord) {
+		app.apiServerError(w, err)
+		return
+	}
+	s.Freq, s.FreqRx, err = app.currentFreq(s.Band)
+	if err != nil {
+		app.apiServerError(w, err)
+		return
+	}
+	app.apiJSON(w, http.StatusOK, s)
+}
+
+func (app *application) apiSpots(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		app.apiMethodNotAllowed(w, http.MethodGet)
+		return
+	}
+	band, err := app.otherModel.getDefault("band")
+	if err != nil {
+		app.apiServerError(w, err)
+		return
+	}
+	if !app.spiderUp() {
+		app.apiError(w, http.StatusServiceUnavailable, "not connected to the DX spider")
+		return
+	}
+	dx, err := app.getSpider(band, dxLines)
+	if err != nil {
+		err = app.spiderError(err)
+		if err == nil {
+			dx, err = app.getSpider(band, dxLines)
+		}
+		if err != nil {
+			app.apiError(w, http.StatusBadGateway, fmt.Sprintf("DX spider: %v", err))
+			return
+		}
+	}
+	app.apiJSON(w, http.StatusOK, dx)
+}
+
+// the QSOs the filter selects as GeoJSON points for a map
+func (app *application) apiMap(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		app.apiMethodNotAllowed(w, http.MethodGet)
+		return
+	}
+	f := newForm(r.URL.Query())
+	lf := f.logFilter()
+	by := f.mapColor()
+	if !f.valid() {
+		app.apiInvalid(w, f.Errors)
+		return
+	}
+	points, _, err := app.mapPoints(lf)
+	if err != nil {
+		app.apiServerError(w, err)
+		return
+	}
+	app.apiJSON(w, http.StatusOK, geoJSON(points, by))
+}
+
+//<+++++++++++++++++++++++++  API helpers  ++++++++++++++++++++++++++>
+
+func (app *application) apiJSON(w http.ResponseWriter, status int, v interface{}) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		app.apiServerError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(b)
+}
+
+func (app *application) apiError(w http.ResponseWriter, status int, msg string) {
+	app.apiJSON(w, status, &apiError{Error: msg})
+}
+
+func (app *application) apiInvalid(w http.ResponseWriter, errs formErrors) {
+	app.apiJSON(w, http.StatusUnprocessableEntity,
+		&apiError{Error: "invalid data", Fields: errs})
+}
+
+func (app *application) apiServerError(w http.ResponseWriter, err error) {
+	trace := fmt.Sprintf("%s\n%s", err.Error(), debug.Stack())
+	app.errorLog.Output(2, trace)
+	app.apiError(w, http.StatusInternalServerError,
+		http.StatusText(http.StatusInternalServerError))
+}
+
+func (app *application) apiMethodNotAllowed(w http.ResponseWriter, methods ...string) {
+	w.Header().Set("Allow", strings.Join(methods, ", "))
+	app.apiError(w, http.StatusMethodNotAllowed, "method not allowed")
+}
+
+// decodes the JSON body into v, on failure the response has been written
+func (app *application) apiDecode(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20))
+	dec.DisallowUnknownFields()
+	err := dec.Decode(v)
+	if err != nil {
+		app.apiError(w, http.StatusBadRequest, fmt.Sprintf("bad JSON: %v", err))
+		return false
+	}
+	return true
+}
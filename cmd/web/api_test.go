@@ -0,0 +1,164 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// sends a request to the API through the routes and decodes the answer
+// into v when v is not nil
+func apiRequest(t *testing.T, app *application, method, path, body string, v interface{}) int {
+	t.Helper()
+	rr := httptest.NewRecorder()
+	r, err := http.NewRequest(method, path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	app.routes().ServeHTTP(rr, r)
+	if ct := rr.Header().Get("Content-Type"); rr.Code != http.StatusNoContent && ct != "application/json" {
+		t.Errorf("want a JSON answer, got %q", ct)
+	}
+	if v != nil {
+		err = json.Unmarshal(rr.Body.Bytes(), v)
+		if err != nil {
+			t.Fatalf("bad JSON %q: %v", rr.Body.String(), err)
+		}
+	}
+	return rr.Code
+}
+
+func TestAPIQSOs(t *testing.T) {
+	app := newTestSQLiteApp(t)
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		body     string
+		wantCode int
+		wantCall string
+	}{
+		{"create", "POST", "/api/v1/qsos", `{"Call":"ja1abc","Band":"20M","Mode":"cw",
+			"Sent":"599","Rcvd":"599","Freq":"14.025000","Time":"2023-05-01T12:00:00Z"}`, 201, "JA1ABC"},
+		{"create now", "POST", "/api/v1/qsos", `{"Call":"VK3XYZ","Band":"15m","Mode":"SSB"}`,
+			201, "VK3XYZ"},
+		{"missing fields", "POST", "/api/v1/qsos", `{"Call":"VK3XYZ"}`, 422, ""},
+		{"bad call and freq", "POST", "/api/v1/qsos", `{"Call":"V","Band":"20m","Mode":"CW",
+			"Freq":"7.0"}`, 422, ""},
+		{"bad band", "POST", "/api/v1/qsos", `{"Call":"VK3XYZ","Band":"banana","Mode":"CW"}`, 422, ""},
+		{"freq off band", "POST", "/api/v1/qsos", `{"Call":"VK3XYZ","Band":"20m","Mode":"CW",
+			"Freq":"7.074"}`, 422, ""},
+		{"unknown field", "POST", "/api/v1/qsos", `{"Callsign":"VK3XYZ"}`, 400, ""},
+		{"get", "GET", "/api/v1/qsos/1", "", 200, "JA1ABC"},
+		{"get missing", "GET", "/api/v1/qsos/99", "", 404, ""},
+		{"get bad id", "GET", "/api/v1/qsos/abc", "", 404, ""},
+		{"update", "PUT", "/api/v1/qsos/1", `{"Comment":"first JA"}`, 200, "JA1ABC"},
+		{"update missing", "PUT", "/api/v1/qsos/99", `{"Comment":"none"}`, 404, ""},
+		{"bad method", "PATCH", "/api/v1/qsos/1", "", 405, ""},
+		{"delete", "DELETE", "/api/v1/qsos/2", "", 204, ""},
+		{"delete again", "DELETE", "/api/v1/qsos/2", "", 404, ""},
+		{"no endpoint", "GET", "/api/v1/foo", "", 404, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var v interface{}
+			l := &LogsRow{}
+			if tt.wantCall != "" {
+				v = l
+			}
+			code := apiRequest(t, app, tt.method, tt.path, tt.body, v)
+			if code != tt.wantCode {
+				t.Errorf("expected %d got %d", tt.wantCode, code)
+			}
+			if l.Call != tt.wantCall {
+				t.Errorf("want %q, got %q", tt.wantCall, l.Call)
+			}
+		})
+	}
+
+	l, err := app.logsModel.getLogByID(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.Band != "20m" || l.Mode != "CW" || l.Comment != "first JA" || l.Freq != "14.025000" {
+		t.Errorf("want 20m CW on 14.025000 with a comment, got %+v", l)
+	}
+	h, err := app.logsModel.getHistory(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(h) != 2 || h[0].Source != sourceAPI {
+		t.Errorf("want an API insert and update in the history, got %v", h)
+	}
+
+	page := &apiQSOPage{}
+	code := apiRequest(t, app, "GET", "/api/v1/qsos?call=JA*&size=10", "", page)
+	if code != 200 || page.Total != 1 || len(page.QSOs) != 1 || page.QSOs[0].Call != "JA1ABC" {
+		t.Errorf("want JA1ABC found, got %d %+v", code, page)
+	}
+	e := &apiError{}
+	code = apiRequest(t, app, "GET", "/api/v1/qsos?page=0&startdate=May", "", e)
+	if code != 422 || len(e.Fields["page"]) != 1 || len(e.Fields["startdate"]) != 1 {
+		t.Errorf("want page and start date errors, got %d %+v", code, e)
+	}
+}
+
+func TestAPIStation(t *testing.T) {
+	app := newTestSQLiteApp(t)
+	app.setStationCall("N2VY")
+
+	d := map[string]string{}
+	code := apiRequest(t, app, "PUT", "/api/v1/defaults", `{"band":"40m","mode":"CW","freq":"7.030000"}`, nil)
+	if code != 422 {
+		t.Errorf("want freq refused, got %d", code)
+	}
+	code = apiRequest(t, app, "PUT", "/api/v1/defaults", `{"band":"40m","mode":"CW","profile":"7"}`, nil)
+	if code != 422 {
+		t.Errorf("want a missing profile refused, got %d", code)
+	}
+	code = apiRequest(t, app, "PUT", "/api/v1/defaults", `{"band":"40m","mode":"CW"}`, &d)
+	if code != 200 || d["band"] != "40m" || d["mode"] != "CW" {
+		t.Errorf("want 40m CW, got %d %v", code, d)
+	}
+	err := app.otherModel.updateDefault("freq", "7.030000")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &apiStation{}
+	code = apiRequest(t, app, "GET", "/api/v1/station", "", s)
+	want := apiStation{Call: "N2VY", Profile: "Home", Band: "40m", Mode: "CW", Freq: "7.030000"}
+	if code != 200 || *s != want {
+		t.Errorf("want %+v, got %d %+v", want, code, s)
+	}
+
+	e := &apiError{}
+	code = apiRequest(t, app, "GET", "/api/v1/spots", "", e)
+	if code != 503 {
+		t.Errorf("want 503 without the spider, got %d %+v", code, e)
+	}
+}
+
+func TestAPILookup(t *testing.T) {
+	app := newTestSQLiteApp(t)
+	err := app.qrzModel.insertQRZ(&Ctype{Call: "AA7BQ", Fname: "Fred", State: "AZ"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &Ctype{}
+	code := apiRequest(t, app, "GET", "/api/v1/lookup/aa7bq", "", c)
+	if code != 200 || c.Fname != "Fred" || c.State != "AZ" {
+		t.Errorf("want Fred in AZ, got %d %+v", code, c)
+	}
+	code = apiRequest(t, app, "GET", "/api/v1/lookup/A", "", nil)
+	if code != 400 {
+		t.Errorf("want 400 for a bad call, got %d", code)
+	}
+	code = apiRequest(t, app, "POST", "/api/v1/lookup/AA7BQ", "", nil)
+	if code != 405 {
+		t.Errorf("want 405, got %d", code)
+	}
+}
@@ -0,0 +1,413 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+//Every insert, update and delete on stationlogs goes through audited, which
+//saves the whole row before and after the change in the auditlog table
+//along with where the change came from.  The history page shows the
+//changes to a QSO and any of them can be reverted.
+
+// the actions in the audit log
+const (
+	auditInsert = "insert"
+	auditUpdate = "update"
+	auditDelete = "delete"
+)
+
+// where the changes come from
+const (
+	sourceWeb    = "web"
+	sourceWSJTX  = "wsjtx"
+	sourceLoTW   = "lotw"
+	sourceEQSL   = "eqsl"
+	sourceQRZ    = "qrz"
+	sourceClub   = "clublog"
+	sourceImport = "import"
+	sourceRevert = "revert"
+	sourceAPI    = "api"
+	sourceCheck  = "check" //fixes from the log check
+)
+
+// AuditRow is one change to a QSO
+type AuditRow struct {
+	Id      int
+	Time    time.Time
+	LogId   int
+	Action  string
+	Source  string
+	Before  *LogsRow //nil for inserts
+	After   *LogsRow //nil for deletes
+	Changes []fieldChange
+}
+
+type fieldChange struct {
+	Field  string
+	Before string
+	After  string
+}
+
+var (
+	errNoChange = errors.New("no such change in the audit log")
+	errConflict = errors.New("changed again since")
+)
+
+// sql.DB and sql.Tx both run the audit statements
+type dbtx interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// all the columns of stationlogs, in the order scanLog reads them
+const logColumns = `id, time, callsign, mode, sent, rcvd, band, name, country,
+	comment, lotwsent, lotwrcvd, lotwqsodate, lotwqsldate, contest, exchsent,
+	exchrcvd, contestname, field1Sent, field2Sent, field3Sent, field4Sent,
+	field5Sent, field1Rcvd, field2Rcvd, field3Rcvd, field4Rcvd, field5Rcvd,
+	freq, freq_rx, dxcc, cqz, ituz, cont, state, cnty, gridsquare, distance,
+	eqsl_sent, eqsl_rcvd, eqsl_qslrdate, eqsl_ag, qrz_rcvd, qrz_qslrdate,
+	clublog_sent, clublog_date, qsl_sent, qsl_rcvd, qsl_sent_via, qsl_rcvd_via,
+	qsl_sdate, qsl_rdate`
+
+// the stationlogs column of each LogsRow field that has one
+var logFieldColumns = map[string]string{
+	"Time": "time", "Call": "callsign", "Mode": "mode", "Sent": "sent",
+	"Rcvd": "rcvd", "Band": "band", "Name": "name", "Country": "country",
+	"Comment": "comment", "Lotwsent": "lotwsent", "Lotwrcvd": "lotwrcvd",
+	"LotwQSOdate": "lotwqsodate", "LotwQSLdate": "lotwqsldate",
+	"Contest": "contest", "ExchSent": "exchsent", "ExchRcvd": "exchrcvd",
+	"ContestName": "contestname", "Field1Sent": "field1Sent",
+	"Field2Sent": "field2Sent", "Field3Sent": "field3Sent",
+	"Field4Sent": "field4Sent", "Field5Sent": "field5Sent",
+	"Field1Rcvd": "field1Rcvd", "Field2Rcvd": "field2Rcvd",
+	"Field3Rcvd": "field3Rcvd", "Field4Rcvd": "field4Rcvd",
+	"Field5Rcvd": "field5Rcvd", "Freq": "freq", "FreqRx": "freq_rx",
+	"DXCC": "dxcc", "CQZone": "cqz", "ITUZone": "ituz", "Continent": "cont",
+	"State": "state", "County": "cnty", "Grid": "gridsquare",
+	"Distance": "distance", "Eqslsent": "eqsl_sent", "Eqslrcvd": "eqsl_rcvd",
+	"EqslQSLdate": "eqsl_qslrdate", "EqslAG": "eqsl_ag", "Qrzrcvd": "qrz_rcvd",
+	"QrzQSLdate": "qrz_qslrdate", "Clublogsent": "clublog_sent",
+	"ClublogDate": "clublog_date", "QSLsent": "qsl_sent", "QSLrcvd": "qsl_rcvd",
+	"QSLsentVia": "qsl_sent_via", "QSLrcvdVia": "qsl_rcvd_via",
+	"QSLsdate": "qsl_sdate", "QSLrdate": "qsl_rdate",
+}
+
+// reads every column of a QSO
+func getFullLog(q dbtx, id int) (*LogsRow, error) {
+	row := q.QueryRow(`SELECT `+logColumns+` FROM stationlogs WHERE id = ?`, id)
+	s := &LogsRow{}
+	err := row.Scan(&s.Id, &s.Time, &s.Call, &s.Mode, &s.Sent, &s.Rcvd,
+		&s.Band, &s.Name, &s.Country, &s.Comment, &s.Lotwsent, &s.Lotwrcvd,
+		&s.LotwQSOdate, &s.LotwQSLdate, &s.Contest, &s.ExchSent, &s.ExchRcvd,
+		&s.ContestName,
+		&s.Field1Sent, &s.Field2Sent, &s.Field3Sent, &s.Field4Sent, &s.Field5Sent,
+		&s.Field1Rcvd, &s.Field2Rcvd, &s.Field3Rcvd, &s.Field4Rcvd, &s.Field5Rcvd,
+		&s.Freq, &s.FreqRx, &s.DXCC, &s.CQZone, &s.ITUZone, &s.Continent,
+		&s.State, &s.County, &s.Grid, &s.Distance,
+		&s.Eqslsent, &s.Eqslrcvd, &s.EqslQSLdate, &s.EqslAG, &s.Qrzrcvd,
+		&s.QrzQSLdate, &s.Clublogsent, &s.ClublogDate, &s.QSLsent, &s.QSLrcvd,
+		&s.QSLsentVia, &s.QSLrcvdVia, &s.QSLsdate, &s.QSLrdate)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errNoRecord
+		}
+		return nil, err
+	}
+	return s, nil
+}
+
+// runs change in a transaction and saves the row before and after it in
+// the audit log.  change returns the id of the row it changed, the new id
+// for inserts.  Updates that change nothing are not saved.
+func (m *logsModel) audited(id int, action, source string,
+	change func(tx *sql.Tx) (int, error)) (int, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var before, after *LogsRow
+	if action != auditInsert {
+		before, err = getFullLog(tx, id)
+		if err != nil {
+			return 0, err
+		}
+	}
+	id, err = change(tx)
+	if err != nil {
+		return 0, err
+	}
+	if action != auditDelete {
+		after, err = getFullLog(tx, id)
+		if err != nil {
+			return 0, err
+		}
+	}
+	b, err := auditJSON(before)
+	if err != nil {
+		return 0, err
+	}
+	a, err := auditJSON(after)
+	if err != nil {
+		return 0, err
+	}
+	if action == auditUpdate && a == b {
+		return id, tx.Commit()
+	}
+	stmt := `INSERT INTO auditlog (time, logid, action, source, old_row, new_row)
+	VALUES (?, ?, ?, ?, ?, ?)`
+	_, err = tx.Exec(stmt, time.Now().UTC(), id, action, source, b, a)
+	if err != nil {
+		return 0, err
+	}
+	return id, tx.Commit()
+}
+
+func auditJSON(l *LogsRow) (string, error) {
+	if l == nil {
+		return "", nil
+	}
+	b, err := json.Marshal(l)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func auditRow(s string) (*LogsRow, error) {
+	if s == "" {
+		return nil, nil
+	}
+	l := &LogsRow{}
+	err := json.Unmarshal([]byte(s), l)
+	if err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// returns the changes to a QSO, the latest first
+func (m *logsModel) getHistory(id int) ([]AuditRow, error) {
+	stmt := `SELECT id, time, logid, action, source, old_row, new_row
+	FROM auditlog WHERE logid = ? ORDER BY id DESC`
+
+	rows, err := m.DB.Query(stmt, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tr := []AuditRow{}
+	for rows.Next() {
+		a := AuditRow{}
+		var before, after string
+		err := rows.Scan(&a.Id, &a.Time, &a.LogId, &a.Action, &a.Source, &before, &after)
+		if err != nil {
+			return nil, err
+		}
+		a.Before, err = auditRow(before)
+		if err != nil {
+			return nil, err
+		}
+		a.After, err = auditRow(after)
+		if err != nil {
+			return nil, err
+		}
+		a.Changes = logDiff(a.Before, a.After)
+		tr = append(tr, a)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return tr, nil
+}
+
+func (m *logsModel) getChange(id int) (*AuditRow, error) {
+	stmt := `SELECT id, time, logid, action, source, old_row, new_row
+	FROM auditlog WHERE id = ?`
+
+	a := &AuditRow{}
+	var before, after string
+	err := m.DB.QueryRow(stmt, id).Scan(&a.Id, &a.Time, &a.LogId, &a.Action,
+		&a.Source, &before, &after)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errNoChange
+		}
+		return nil, err
+	}
+	a.Before, err = auditRow(before)
+	if err != nil {
+		return nil, err
+	}
+	a.After, err = auditRow(after)
+	if err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// undoes a change: an update puts back the fields it changed, an insert
+// is moved to the trash and a delete is put back with its old id.  An
+// update is not reverted when one of its fields has been changed again
+// since.  The revert is itself a change in the audit log.
+func (m *logsModel) revertChange(id int) error {
+	a, err := m.getChange(id)
+	if err != nil {
+		return err
+	}
+	switch a.Action {
+	case auditInsert:
+		_, err = m.audited(a.LogId, auditDelete, sourceRevert, func(tx *sql.Tx) (int, error) {
+			return a.LogId, trashLog(tx, a.LogId)
+		})
+	case auditUpdate:
+		_, err = m.audited(a.LogId, auditUpdate, sourceRevert, func(tx *sql.Tx) (int, error) {
+			return a.LogId, revertFields(tx, a)
+		})
+	case auditDelete:
+		_, err = m.audited(a.LogId, auditInsert, sourceRevert, func(tx *sql.Tx) (int, error) {
+			return a.LogId, untrashLog(tx, a.Before)
+		})
+	default:
+		return fmt.Errorf("can not revert a %s", a.Action)
+	}
+	return err
+}
+
+// puts back the columns an update changed, as long as they still have the
+// values it left.  The columns the audit row does not have, those added
+// after it was saved, are the same before and after and so left alone.
+func revertFields(tx dbtx, a *AuditRow) error {
+	cur, err := getFullLog(tx, a.LogId)
+	if err != nil {
+		return err
+	}
+	b := reflect.ValueOf(*a.Before)
+	now := reflect.ValueOf(*cur)
+	sets, args, conflicts := []string{}, []interface{}{}, []string{}
+	for _, c := range logDiff(a.Before, a.After) {
+		column, ok := logFieldColumns[c.Field]
+		if !ok {
+			continue
+		}
+		if fieldString(now.FieldByName(c.Field)) != c.After {
+			conflicts = append(conflicts, c.Field)
+			continue
+		}
+		v := b.FieldByName(c.Field).Interface()
+		if t, ok := v.(time.Time); ok {
+			v = t.UTC()
+		}
+		sets = append(sets, column+" = ?")
+		args = append(args, v)
+	}
+	if len(conflicts) > 0 {
+		return fmt.Errorf("%w: %s", errConflict, strings.Join(conflicts, ", "))
+	}
+	if len(sets) == 0 {
+		return nil
+	}
+	_, err = tx.Exec(`UPDATE stationlogs SET `+strings.Join(sets, ", ")+` WHERE id = ?`,
+		append(args, a.LogId)...)
+	return err
+}
+
+// writes every column of l back to its row
+func restoreLog(tx dbtx, l *LogsRow) error {
+	stmt := `UPDATE stationlogs SET time = ?, callsign = ?, mode = ?, sent = ?,
+	rcvd = ?, band = ?, name = ?, country = ?, comment = ?, lotwsent = ?,
+	lotwrcvd = ?, lotwqsodate = ?, lotwqsldate = ?, contest = ?, exchsent = ?,
+	exchrcvd = ?, contestname = ?, field1Sent = ?, field2Sent = ?,
+	field3Sent = ?, field4Sent = ?, field5Sent = ?, field1Rcvd = ?,
+	field2Rcvd = ?, field3Rcvd = ?, field4Rcvd = ?, field5Rcvd = ?, freq = ?,
+	freq_rx = ?, dxcc = ?, cqz = ?, ituz = ?, cont = ?, state = ?, cnty = ?,
+	gridsquare = ?, distance = ?, eqsl_sent = ?, eqsl_rcvd = ?,
+	eqsl_qslrdate = ?, eqsl_ag = ?, qrz_rcvd = ?, qrz_qslrdate = ?,
+	clublog_sent = ?, clublog_date = ?, qsl_sent = ?, qsl_rcvd = ?,
+	qsl_sent_via = ?, qsl_rcvd_via = ?, qsl_sdate = ?, qsl_rdate = ? WHERE id = ?`
+	_, err := tx.Exec(stmt, l.Time.UTC(), l.Call, l.Mode, l.Sent, l.Rcvd,
+		l.Band, l.Name, l.Country, l.Comment, l.Lotwsent, l.Lotwrcvd,
+		l.LotwQSOdate.UTC(), l.LotwQSLdate.UTC(), l.Contest, l.ExchSent,
+		l.ExchRcvd, l.ContestName,
+		l.Field1Sent, l.Field2Sent, l.Field3Sent, l.Field4Sent, l.Field5Sent,
+		l.Field1Rcvd, l.Field2Rcvd, l.Field3Rcvd, l.Field4Rcvd, l.Field5Rcvd,
+		l.Freq, l.FreqRx, l.DXCC, l.CQZone, l.ITUZone, l.Continent, l.State,
+		l.County, l.Grid, l.Distance, l.Eqslsent, l.Eqslrcvd,
+		l.EqslQSLdate.UTC(), l.EqslAG, l.Qrzrcvd, l.QrzQSLdate.UTC(),
+		l.Clublogsent, l.ClublogDate.UTC(), l.QSLsent, l.QSLrcvd, l.QSLsentVia,
+		l.QSLrcvdVia, l.QSLsdate.UTC(), l.QSLrdate.UTC(), l.Id)
+	return err
+}
+
+// puts a deleted row back with its old id
+func reinsertLog(tx dbtx, l *LogsRow) error {
+	stmt := `INSERT INTO stationlogs (` + logColumns + `) VALUES (?, ?, ?, ?,
+	?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?,
+	?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	_, err := tx.Exec(stmt, l.Id, l.Time.UTC(), l.Call, l.Mode, l.Sent, l.Rcvd,
+		l.Band, l.Name, l.Country, l.Comment, l.Lotwsent, l.Lotwrcvd,
+		l.LotwQSOdate.UTC(), l.LotwQSLdate.UTC(), l.Contest, l.ExchSent,
+		l.ExchRcvd, l.ContestName,
+		l.Field1Sent, l.Field2Sent, l.Field3Sent, l.Field4Sent, l.Field5Sent,
+		l.Field1Rcvd, l.Field2Rcvd, l.Field3Rcvd, l.Field4Rcvd, l.Field5Rcvd,
+		l.Freq, l.FreqRx, l.DXCC, l.CQZone, l.ITUZone, l.Continent, l.State,
+		l.County, l.Grid, l.Distance, l.Eqslsent, l.Eqslrcvd,
+		l.EqslQSLdate.UTC(), l.EqslAG, l.Qrzrcvd, l.QrzQSLdate.UTC(),
+		l.Clublogsent, l.ClublogDate.UTC(), l.QSLsent, l.QSLrcvd, l.QSLsentVia,
+		l.QSLrcvdVia, l.QSLsdate.UTC(), l.QSLrdate.UTC())
+	return err
+}
+
+// lists the fields that differ between the two versions of a row, either
+// of which can be nil
+func logDiff(before, after *LogsRow) []fieldChange {
+	changes := []fieldChange{}
+	var b, a reflect.Value
+	if before != nil {
+		b = reflect.ValueOf(*before)
+	}
+	if after != nil {
+		a = reflect.ValueOf(*after)
+	}
+	t := reflect.TypeOf(LogsRow{})
+	for i := 0; i < t.NumField(); i++ {
+		var bs, as string
+		if b.IsValid() {
+			bs = fieldString(b.Field(i))
+		}
+		if a.IsValid() {
+			as = fieldString(a.Field(i))
+		}
+		if bs != as {
+			changes = append(changes, fieldChange{t.Field(i).Name, bs, as})
+		}
+	}
+	return changes
+}
+
+func fieldString(v reflect.Value) string {
+	switch x := v.Interface().(type) {
+	case time.Time:
+		if x.IsZero() {
+			return ""
+		}
+		return x.UTC().Format("2006-01-02 15:04:05")
+	case bool:
+		if !x {
+			return ""
+		}
+	case int:
+		if x == 0 {
+			return ""
+		}
+	}
+	return fmt.Sprint(v.Interface())
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLogDiff(t *testing.T) {
+	qso := time.Date(2021, 7, 4, 14, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		before *LogsRow
+		after  *LogsRow
+		want   []fieldChange
+	}{
+		{"insert", nil, &LogsRow{Id: 3, Call: "K1AR"},
+			[]fieldChange{{"Id", "", "3"}, {"Call", "", "K1AR"}}},
+		{"update", &LogsRow{Id: 3, Call: "K1AR", Band: "20m", Time: qso},
+			&LogsRow{Id: 3, Call: "K1AR", Band: "40m", Time: qso},
+			[]fieldChange{{"Band", "20m", "40m"}}},
+		{"delete", &LogsRow{Id: 3, Time: qso}, nil,
+			[]fieldChange{{"Id", "3", ""}, {"Time", "2021-07-04 14:30:00", ""}}},
+		{"no change", &LogsRow{Id: 3}, &LogsRow{Id: 3}, []fieldChange{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := logDiff(tt.before, tt.after)
+			if len(got) != len(tt.want) {
+				t.Fatalf("want %v, got %v", tt.want, got)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("want %v, got %v", tt.want[i], got[i])
+				}
+			}
+		})
+	}
+}
+
+func TestSQLiteAudit(t *testing.T) {
+	app := newTestSQLiteApp(t)
+
+	l := &LogsRow{Call: "W1AW", Mode: "CW", Sent: "599", Rcvd: "599",
+		Band: "40m", Name: "Hiram"}
+	id, err := app.logsModel.insertLog(l, sourceWSJTX)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Band = "20m"
+	l.Comment = "QSB"
+	err = app.logsModel.updateLog(l, id, sourceWeb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	//saving the same values again is not a change
+	err = app.logsModel.updateLog(l, id, sourceWeb)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h, err := app.logsModel.getHistory(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(h) != 2 {
+		t.Fatalf("want 2 changes, got %d", len(h))
+	}
+	if h[0].Action != auditUpdate || h[0].Source != sourceWeb ||
+		h[1].Action != auditInsert || h[1].Source != sourceWSJTX {
+		t.Errorf("want a web update after a wsjtx insert, got %s %s and %s %s",
+			h[0].Source, h[0].Action, h[1].Source, h[1].Action)
+	}
+	if len(h[0].Changes) != 2 || h[0].Changes[0].Field != "Band" ||
+		h[0].Changes[0].Before != "40m" || h[0].Changes[0].After != "20m" {
+		t.Errorf("want band and comment changes, got %v", h[0].Changes)
+	}
+
+	//revert the update
+	err = app.logsModel.revertChange(h[0].Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := app.logsModel.getLogByID(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Band != "40m" || got.Comment != "" {
+		t.Errorf("want 40m with no comment, got %s %q", got.Band, got.Comment)
+	}
+
+	//revert the insert, then revert that delete
+	err = app.logsModel.revertChange(h[1].Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = app.logsModel.getLogByID(id)
+	if !errors.Is(err, errNoRecord) {
+		t.Errorf("want errNoRecord after reverting the insert, got %v", err)
+	}
+	h, err = app.logsModel.getHistory(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(h) != 4 || h[0].Action != auditDelete || h[0].Source != sourceRevert {
+		t.Fatalf("want a revert delete as the 4th change, got %v", h)
+	}
+	err = app.logsModel.revertChange(h[0].Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err = app.logsModel.getLogByID(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Call != "W1AW" || got.Band != "40m" {
+		t.Errorf("want W1AW on 40m back, got %s %s", got.Call, got.Band)
+	}
+
+	err = app.logsModel.revertChange(100)
+	if !errors.Is(err, errNoChange) {
+		t.Errorf("want errNoChange, got %v", err)
+	}
+}
+
+func TestRevertKeepsLaterChanges(t *testing.T) {
+	app := newTestSQLiteApp(t)
+	l := &LogsRow{Call: "W1AW", Mode: "CW", Sent: "599", Rcvd: "599", Band: "40m"}
+	id, err := app.logsModel.insertLog(l, sourceWeb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Band = "20m"
+	err = app.logsModel.updateLog(l, id, sourceWeb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	qsl := time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC)
+	err = app.logsModel.confirmQSO(id, qsl, qsl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h, err := app.logsModel.getHistory(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edit := h[1].Id //the latest first, the confirmation is h[0]
+
+	err = app.logsModel.revertChange(edit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := app.logsModel.getLogByID(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Band != "40m" || got.Lotwrcvd != "YES" {
+		t.Errorf("want 40m back and the LoTW confirmation kept, got %s %q", got.Band, got.Lotwrcvd)
+	}
+
+	//the band has changed again since the edit
+	l.Band = "80m"
+	err = app.logsModel.updateLog(l, id, sourceWeb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = app.logsModel.revertChange(edit)
+	if !errors.Is(err, errConflict) {
+		t.Errorf("want %v, got %v", errConflict, err)
+	}
+	if got, _ = app.logsModel.getLogByID(id); got.Band != "80m" {
+		t.Errorf("want the conflicting revert to change nothing, got %s", got.Band)
+	}
+}
@@ -0,0 +1,403 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+//<<===================== Logbook backups and restore =====================>>
+
+//A backup is a directory named after the time it was taken.  It holds
+//snapshot.json, every row of the snapshot tables with the trash and the
+//audit log of the QSOs, which the restore command reads back, and
+//stationlogs.adi, the whole log as ADIF for any
+//other logger.  The directory is written under a .tmp name and renamed
+//when complete so a half written backup is never restored or counted.
+
+// backed up and restored in this order
+var snapshotTables = []string{"stationlogs", "qrztable", "contests", "defaults",
+	"stationprofiles", "lotwbatches", "lotwbatchqsos",
+	"lotwunmatched", "qrzlogqsos", "trashlogs", "auditlog"}
+
+const (
+	snapshotPrefix = "stationmaster-"
+	snapshotLayout = "20060102-150405"
+	snapshotFile   = "snapshot.json"
+	snapshotADIF   = "stationlogs.adi"
+)
+
+var errNoBackupDir = errors.New("there is no backupdir in config.yaml")
+
+type backupType interface {
+	snapshot() (*snapshot, error)
+	restore(*snapshot) error
+}
+
+type backupModel struct {
+	DB *sql.DB
+}
+
+// snapshot is the content of every snapshot table
+type snapshot struct {
+	Schema  int //schema version of the database it was taken from
+	Created time.Time
+	Tables  []snapshotTable
+}
+
+// snapshotTable is the rows of one table.  The DATETIME columns are
+// listed so their RFC 3339 strings can be turned back into times.
+type snapshotTable struct {
+	Name    string
+	Columns []string
+	Times   []string
+	Rows    [][]interface{}
+}
+
+// backupInfo is one backup in the backup directory
+type backupInfo struct {
+	Name string
+	Time time.Time
+	Size int64 //bytes of snapshot.json
+}
+
+// backupStatus is the backup page
+type backupStatus struct {
+	Dir     string
+	Every   time.Duration //zero when there is no schedule
+	Keep    int           //zero keeps them all
+	Backups []backupInfo  //the latest first
+}
+
+// reads every snapshot table in one transaction so the tables agree
+func (m *backupModel) snapshot() (*snapshot, error) {
+	version, err := (&migrator{db: m.DB}).version()
+	if err != nil {
+		return nil, err
+	}
+	snap := &snapshot{Schema: version, Created: time.Now().UTC()}
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	for _, table := range snapshotTables {
+		st, err := dumpTable(tx, table)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", table, err)
+		}
+		snap.Tables = append(snap.Tables, *st)
+	}
+	return snap, tx.Commit()
+}
+
+func dumpTable(tx *sql.Tx, table string) (*snapshotTable, error) {
+	rows, err := tx.Query(`SELECT * FROM ` + table + ` ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	st := &snapshotTable{Name: table, Rows: [][]interface{}{}}
+	st.Columns, err = rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	isTime := make([]bool, len(st.Columns))
+	for rows.Next() {
+		vals := make([]interface{}, len(st.Columns))
+		ptrs := make([]interface{}, len(vals))
+		for i := range vals {
+			ptrs[i] = &vals[i]
+		}
+		err = rows.Scan(ptrs...)
+		if err != nil {
+			return nil, err
+		}
+		for i, v := range vals {
+			switch x := v.(type) {
+			case []byte:
+				vals[i] = string(x)
+			case time.Time:
+				vals[i] = x.UTC()
+				isTime[i] = true
+			}
+		}
+		st.Rows = append(st.Rows, vals)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	for i, t := range isTime {
+		if t {
+			st.Times = append(st.Times, st.Columns[i])
+		}
+	}
+	return st, nil
+}
+
+// replaces the rows of the snapshot tables with the ones in the snapshot,
+// all or nothing.  A table the snapshot does not have, one added since it
+// was taken, is emptied so the trash and the audit log do not keep the
+// QSOs of the log that is replaced.
+func (m *backupModel) restore(snap *snapshot) error {
+	version, err := (&migrator{db: m.DB}).version()
+	if err != nil {
+		return err
+	}
+	if snap.Schema > version {
+		return fmt.Errorf("the snapshot is from schema version %d, the database is at %d",
+			snap.Schema, version)
+	}
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	restored := map[string]bool{}
+	for _, st := range snap.Tables {
+		err = restoreTable(tx, &st)
+		if err != nil {
+			return fmt.Errorf("%s: %v", st.Name, err)
+		}
+		restored[st.Name] = true
+	}
+	for _, table := range snapshotTables {
+		if restored[table] {
+			continue
+		}
+		_, err = tx.Exec(`DELETE FROM ` + table)
+		if err != nil {
+			return fmt.Errorf("%s: %v", table, err)
+		}
+	}
+	return tx.Commit()
+}
+
+func restoreTable(tx *sql.Tx, st *snapshotTable) error {
+	known := false
+	for _, t := range snapshotTables {
+		known = known || t == st.Name
+	}
+	if !known {
+		return fmt.Errorf("not a snapshot table")
+	}
+	//the names go into the statement so they had better be names
+	for _, c := range st.Columns {
+		if c == "" || strings.Trim(strings.ToLower(c), "abcdefghijklmnopqrstuvwxyz0123456789_") != "" {
+			return fmt.Errorf("bad column name %q", c)
+		}
+	}
+	_, err := tx.Exec(`DELETE FROM ` + st.Name)
+	if err != nil {
+		return err
+	}
+	stmt := `INSERT INTO ` + st.Name + ` (` + strings.Join(st.Columns, ", ") +
+		`) VALUES (?` + strings.Repeat(", ?", len(st.Columns)-1) + `)`
+	for _, r := range st.Rows {
+		if len(r) != len(st.Columns) {
+			return fmt.Errorf("row has %d values for %d columns", len(r), len(st.Columns))
+		}
+		_, err = tx.Exec(stmt, r...)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reads snapshot.json of a backup, given by its name in the backup
+// directory or by its path
+func (app *application) readSnapshot(name string) (*snapshot, error) {
+	dir := name
+	if _, err := os.Stat(dir); err != nil && app.backupDir != "" {
+		dir = filepath.Join(app.backupDir, name)
+	}
+	f, err := os.Open(filepath.Join(dir, snapshotFile))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	d := json.NewDecoder(f)
+	d.UseNumber()
+	snap := &snapshot{}
+	err = d.Decode(snap)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", snapshotFile, err)
+	}
+	for i := range snap.Tables {
+		err = snap.Tables[i].decodeValues()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s: %v", snapshotFile, snap.Tables[i].Name, err)
+		}
+	}
+	return snap, nil
+}
+
+// JSON leaves numbers as json.Number (with UseNumber) and times as strings
+func (st *snapshotTable) decodeValues() error {
+	isTime := make([]bool, len(st.Columns))
+	for i, c := range st.Columns {
+		for _, t := range st.Times {
+			isTime[i] = isTime[i] || c == t
+		}
+	}
+	for _, r := range st.Rows {
+		for i, v := range r {
+			switch x := v.(type) {
+			case json.Number:
+				if n, err := x.Int64(); err == nil {
+					r[i] = n
+					continue
+				}
+				f, err := x.Float64()
+				if err != nil {
+					return err
+				}
+				r[i] = f
+			case string:
+				if i < len(isTime) && isTime[i] {
+					t, err := time.Parse(time.RFC3339Nano, x)
+					if err != nil {
+						return err
+					}
+					r[i] = t
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// writes a backup to the backup directory and drops the oldest ones
+// beyond the number kept.  Returns the directory of the backup.
+func (app *application) backup() (string, error) {
+	if app.backupDir == "" {
+		return "", errNoBackupDir
+	}
+	app.backupLock.Lock()
+	defer app.backupLock.Unlock()
+	snap, err := app.backupModel.snapshot()
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(app.backupDir, snapshotPrefix+snap.Created.Format(snapshotLayout))
+	if _, err := os.Stat(dir); err == nil {
+		return "", fmt.Errorf("backup %s already exists", filepath.Base(dir))
+	}
+	tmp := dir + ".tmp"
+	err = os.MkdirAll(tmp, 0755)
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(tmp)
+	data, err := json.Marshal(snap)
+	if err != nil {
+		return "", err
+	}
+	err = os.WriteFile(filepath.Join(tmp, snapshotFile), data, 0644)
+	if err != nil {
+		return "", err
+	}
+	rows, err := app.logsModel.getExportData(&logFilter{})
+	if err != nil {
+		return "", err
+	}
+	err = app.genFullADIFFile(filepath.Join(tmp, snapshotADIF), rows)
+	if err != nil {
+		return "", err
+	}
+	err = os.Rename(tmp, dir)
+	if err != nil {
+		return "", err
+	}
+	return dir, app.rotateBackups()
+}
+
+// the backups in the backup directory, the latest first
+func (app *application) listBackups() ([]backupInfo, error) {
+	entries, err := os.ReadDir(app.backupDir)
+	if errors.Is(err, os.ErrNotExist) {
+		return []backupInfo{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	backups := []backupInfo{}
+	for _, e := range entries {
+		if !e.IsDir() || !strings.HasPrefix(e.Name(), snapshotPrefix) {
+			continue
+		}
+		t, err := time.Parse(snapshotLayout, strings.TrimPrefix(e.Name(), snapshotPrefix))
+		if err != nil {
+			continue //the .tmp ones among others
+		}
+		b := backupInfo{Name: e.Name(), Time: t}
+		if fi, err := os.Stat(filepath.Join(app.backupDir, e.Name(), snapshotFile)); err == nil {
+			b.Size = fi.Size()
+		}
+		backups = append(backups, b)
+	}
+	sort.Slice(backups, func(i, j int) bool { return backups[i].Time.After(backups[j].Time) })
+	return backups, nil
+}
+
+// removes the oldest backups beyond the number kept
+func (app *application) rotateBackups() error {
+	if app.backupKeep <= 0 {
+		return nil
+	}
+	backups, err := app.listBackups()
+	if err != nil {
+		return err
+	}
+	for i := app.backupKeep; i < len(backups); i++ {
+		err = os.RemoveAll(filepath.Join(app.backupDir, backups[i].Name))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// takes a backup every so often for as long as the program runs
+func (app *application) backupLoop(every time.Duration) {
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+	for range ticker.C {
+		dir, err := app.backup()
+		if err != nil {
+			app.errorLog.Printf("scheduled backup failed: %v", err)
+			continue
+		}
+		app.infoLog.Printf("log backed up to %s", dir)
+	}
+}
+
+// backs up the log as it is and then replaces it with a backup
+func (app *application) restoreBackup(name string) error {
+	if name == "" {
+		return fmt.Errorf("name the backup to restore, e.g. %s%s", snapshotPrefix,
+			time.Now().UTC().Format(snapshotLayout))
+	}
+	snap, err := app.readSnapshot(name)
+	if err != nil {
+		return err
+	}
+	dir, err := app.backup()
+	if err != nil {
+		return fmt.Errorf("backing up the log before the restore: %v", err)
+	}
+	app.infoLog.Printf("log backed up to %s before the restore", dir)
+	err = app.backupModel.restore(snap)
+	if err != nil {
+		return err
+	}
+	app.infoLog.Printf("restored the backup of %s", snap.Created.Format(time.RFC1123))
+	return nil
+}
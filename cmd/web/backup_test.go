@@ -0,0 +1,191 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSQLiteBackup(t *testing.T) {
+	old := writeControl
+	writeControl = &fileWrite{}
+	t.Cleanup(func() { writeControl = old })
+	app := newTestSQLiteApp(t)
+	app.backupDir = t.TempDir()
+
+	qsoTime := time.Date(2023, 4, 15, 14, 15, 0, 0, time.UTC)
+	for _, call := range []string{"AA7BQ", "DL1AB"} {
+		_, err := app.logsModel.importLog(&LogsRow{Call: call, Time: qsoTime, Band: "20m",
+			Mode: "CW", Sent: "599", Rcvd: "599", Freq: "14.025000"}, sourceImport)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, err := app.logsModel.importLog(&LogsRow{Call: "W1AW", Time: qsoTime, Band: "20m",
+		Mode: "CW"}, sourceImport)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = app.logsModel.deleteLogs([]int{3}, sourceWeb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = app.qrzModel.insertQRZ(&Ctype{Call: "AA7BQ", Fname: "Fred", State: "AZ"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = app.otherModel.updateDefault("band", "20m")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir, err := app.backup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range []string{snapshotFile, snapshotADIF} {
+		if _, err := os.Stat(filepath.Join(dir, f)); err != nil {
+			t.Errorf("want %s in the backup: %v", f, err)
+		}
+	}
+	backups, err := app.listBackups()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != 1 || backups[0].Name != filepath.Base(dir) || backups[0].Size == 0 {
+		t.Fatalf("want the backup listed, got %+v", backups)
+	}
+
+	//the log is lost and rebuilt from the backup on a new database
+	lost := newTestSQLiteApp(t)
+	lost.backupDir = app.backupDir
+	_, err = lost.logsModel.importLog(&LogsRow{Call: "K1XX", Time: qsoTime, Band: "40m",
+		Mode: "CW"}, sourceImport)
+	if err != nil {
+		t.Fatal(err)
+	}
+	snap, err := lost.readSnapshot(backups[0].Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = lost.backupModel.restore(snap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := lost.logsModel.getExportData(&logFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 || rows[0].Call != "AA7BQ" || !rows[0].Time.Equal(qsoTime) ||
+		rows[0].Freq != "14.025000" || rows[0].State != "AZ" {
+		t.Errorf("want AA7BQ in AZ and DL1AB back, got %+v", rows)
+	}
+	band, err := lost.otherModel.getDefault("band")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if band != "20m" {
+		t.Errorf("want the 20m default back, got %q", band)
+	}
+	trash, err := lost.logsModel.getTrash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trash) != 1 || trash[0].Call != "W1AW" {
+		t.Errorf("want W1AW back in the trash, got %+v", trash)
+	}
+	history, err := lost.logsModel.getHistory(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 1 || history[0].Source != sourceImport {
+		t.Errorf("want the history of AA7BQ, not of K1XX, got %+v", history)
+	}
+
+	//a snapshot from before the trash and the audit log were backed up
+	//empties them
+	tables := snap.Tables
+	snap.Tables = nil
+	for _, st := range tables {
+		if st.Name != "trashlogs" && st.Name != "auditlog" {
+			snap.Tables = append(snap.Tables, st)
+		}
+	}
+	err = lost.backupModel.restore(snap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	trash, err = lost.logsModel.getTrash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	history, err = lost.logsModel.getHistory(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trash) != 0 || len(history) != 0 {
+		t.Errorf("want the trash and the audit log emptied, got %+v %+v", trash, history)
+	}
+
+	snap.Schema++
+	err = lost.backupModel.restore(snap)
+	if err == nil {
+		t.Errorf("want a snapshot from a newer schema refused")
+	}
+	snap.Schema--
+	snap.Tables[0].Columns[1] = "time; DROP TABLE qrztable"
+	err = lost.backupModel.restore(snap)
+	if err == nil {
+		t.Errorf("want a bad column name refused")
+	}
+}
+
+func TestRotateBackups(t *testing.T) {
+	app := newTestApp()
+	app.backupDir = t.TempDir()
+	names := []string{"stationmaster-20230101-000000", "stationmaster-20230103-000000",
+		"stationmaster-20230102-000000", "stationmaster-20230104-000000.tmp", "other"}
+	for _, n := range names {
+		err := os.Mkdir(filepath.Join(app.backupDir, n), 0755)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		keep int
+		want []string
+	}{
+		{0, []string{"stationmaster-20230103-000000", "stationmaster-20230102-000000",
+			"stationmaster-20230101-000000"}},
+		{2, []string{"stationmaster-20230103-000000", "stationmaster-20230102-000000"}},
+		{1, []string{"stationmaster-20230103-000000"}},
+	}
+	for _, tt := range tests {
+		app.backupKeep = tt.keep
+		err := app.rotateBackups()
+		if err != nil {
+			t.Fatal(err)
+		}
+		backups, err := app.listBackups()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := []string{}
+		for _, b := range backups {
+			got = append(got, b.Name)
+		}
+		if len(got) != len(tt.want) {
+			t.Fatalf("keep %d: want %v, got %v", tt.keep, tt.want, got)
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("keep %d: want %v, got %v", tt.keep, tt.want, got)
+			}
+		}
+	}
+	if _, err := os.Stat(filepath.Join(app.backupDir, "other")); err != nil {
+		t.Errorf("want other directories left alone: %v", err)
+	}
+}
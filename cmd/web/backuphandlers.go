@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"path/filepath"
+)
+
+//<<=========================== Backup handlers ===========================>>
+
+func (app *application) backups(w http.ResponseWriter, r *http.Request) {
+	app.renderBackups(w, r, initTemplateData())
+}
+
+// takes a backup now instead of waiting for the schedule
+func (app *application) backupNow(w http.ResponseWriter, r *http.Request) {
+	td := initTemplateData()
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		app.clientError(w, http.StatusMethodNotAllowed)
+		return
+	}
+	dir, err := app.backup()
+	switch {
+	case err == errNoBackupDir:
+		td.Message = "Add backupdir to config.yaml to back up the log"
+	case err != nil:
+		app.serverError(w, err)
+		return
+	default:
+		td.Message = fmt.Sprintf("Log backed up to %s", filepath.Base(dir))
+	}
+	app.renderBackups(w, r, td)
+}
+
+func (app *application) renderBackups(w http.ResponseWriter, r *http.Request, td *templateData) {
+	td.Backup = &backupStatus{Dir: app.backupDir, Every: app.backupEvery, Keep: app.backupKeep}
+	if app.backupDir != "" {
+		var err error
+		td.Backup.Backups, err = app.listBackups()
+		if err != nil {
+			app.serverError(w, err)
+			return
+		}
+	}
+	app.render(w, r, "backups.page.html", td)
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBackupPages(t *testing.T) {
+	old := writeControl
+	writeControl = &fileWrite{}
+	t.Cleanup(func() { writeControl = old })
+	app := newTestSQLiteApp(t)
+
+	tests := []struct {
+		name     string
+		dir      string
+		method   string
+		path     string
+		wantCode int
+		wantBody string
+	}{
+		{"no dir", "", "GET", "/backups", 200, "Add backupdir to config.yaml"},
+		{"no dir backup", "", "POST", "/backup-now", 200, "Add backupdir to config.yaml"},
+		{"page", t.TempDir(), "GET", "/backups", 200, "Back Up Now"},
+		{"backup", t.TempDir(), "POST", "/backup-now", 200, "Log backed up to stationmaster-"},
+		{"get backup", t.TempDir(), "GET", "/backup-now", 405, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app.backupDir = tt.dir
+			rr := httptest.NewRecorder()
+			r, err := http.NewRequest(tt.method, tt.path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			app.routes().ServeHTTP(rr, r)
+			if rr.Code != tt.wantCode {
+				t.Errorf("expected %d got %d", tt.wantCode, rr.Code)
+			}
+			if !strings.Contains(rr.Body.String(), tt.wantBody) {
+				t.Errorf("expected %q in the body, did not get", tt.wantBody)
+			}
+		})
+	}
+}
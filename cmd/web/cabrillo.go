@@ -3,7 +3,10 @@ package main
 import (
 	"bytes"
 	"fmt"
+	"io"
+	"math"
 	"os"
+	"strconv"
 	"strings"
 	"text/tabwriter"
 	"time"
@@ -52,19 +55,23 @@ func (app *application) genCabrilloFile(rows []LogsRow, cd *contestData) error {
 	if err != nil {
 		return err
 	}
-	header := writeCabrilloHeader(cabData, cd.name, cd.score)
+	p, err := app.activeProfile()
+	if err != nil {
+		return err
+	}
+	header := writeCabrilloHeader(cabData, cd.name, cd.score, p)
 	w := tabwriter.NewWriter(dd, 1, 2, 1, ' ', 0)
 	for _, row := range rows {
 		s := ""
 		s1 := ""
 		s += "QSO:\t"
-		s += bandNormalize(row.Band) + "\t"
+		s += cabrilloFreq(row) + "\t"
 		s += row.Mode + "\t"
 		dd, dt := cookTime(row.Time)
 		s += dd + "\t"
 		t := strings.Split(strings.TrimSuffix(dt, "Z"), ":")
 		s += strings.Join(t[0:2], "") + "\t"
-		s += myCall + "\t"
+		s += p.Call + "\t"
 		s1 += row.Call + "\t"
 		if tst.FieldCount < 3 {
 			s += row.Field1Sent + "\t"
@@ -108,12 +115,16 @@ func (app *application) genNewCabrilloFile(rows []LogsRow, cd *contestData) erro
 	//cabData = cabBuffer{}
 	//dd := make(cabBuffer, 10)
 	cd.score = ""
-	writeNewCabrilloHeader(b, cd.name, cd.score)
+	p, err := app.activeProfile()
+	if err != nil {
+		return err
+	}
+	writeNewCabrilloHeader(b, cd.name, cd.score, p)
 	//w := tabwriter.NewWriter(dd, 1, 2, 1, ' ', 0)
 	for _, row := range rows {
 		s := ""
 		s += "QSO: "
-		band := bandNormalize(row.Band)
+		band := cabrilloFreq(row)
 		bandLen := utf8.RuneCountInString(band)
 		if bandLen == 5 {
 			s += band + " "
@@ -125,11 +136,11 @@ func (app *application) genNewCabrilloFile(rows []LogsRow, cd *contestData) erro
 		s += dd + " "
 		t := strings.Split(strings.TrimSuffix(dt, "Z"), ":")
 		s += strings.Join(t[0:2], "") + " "
-		callGap := cd.callWidth - utf8.RuneCountInString(myCall)
+		callGap := cd.callWidth - utf8.RuneCountInString(p.Call)
 		if callGap < 0 {
 			return fmt.Errorf("caller call sign too wide by: %d", callGap)
 		}
-		s += myCall + strings.Repeat(" ", callGap+1)
+		s += p.Call + strings.Repeat(" ", callGap+1)
 		if cd.fieldCount >= 2 {
 			gap1 := cd.field1Width - utf8.RuneCountInString(row.Field1Sent)
 			if gap1 < 0 {
@@ -223,6 +234,16 @@ func (app *application) genNewCabrilloFile(rows []LogsRow, cd *contestData) erro
 
 }
 
+// Cabrillo wants the frequency in kHz, the band edge is used for the QSOs
+// logged before the frequency was kept
+func cabrilloFreq(row LogsRow) string {
+	mhz, err := strconv.ParseFloat(row.Freq, 64)
+	if err != nil || mhz <= 0 {
+		return bandNormalize(row.Band)
+	}
+	return strconv.Itoa(int(math.Round(mhz * 1000)))
+}
+
 func bandNormalize(band string) string {
 	band = strings.ToUpper(band)
 	switch band {
@@ -277,22 +298,8 @@ func lengthNormalize(x string, l int) []byte {
 	return append(y, byte(space))
 }
 
-func writeCabrilloHeader(b cabBuffer, contest, score string) cabBuffer {
-	b.Write([]byte("START-OF-LOG: 3.0\n"))
-	b.Write([]byte("CONTEST: " + contest + "\n"))
-	b.Write([]byte("LOCATION: NJ\n"))
-	b.Write([]byte("CALLSIGN: " + myCall + "\n"))
-	b.Write([]byte("CATEGORY-OPERATOR: SINGLE-OP\n"))
-	b.Write([]byte("CATEGORY-ASSISTED: NON-ASSISTED\n"))
-	b.Write([]byte("CATEGORY-BAND: All\n"))
-	b.Write([]byte("CATEGORY-POWER: LOW\n"))
-	b.Write([]byte("CATEGORY-MODE: CW\n"))
-	b.Write([]byte("CATEGORY-STATION: FIXED\n"))
-	b.Write([]byte("CATEGORY-TRANSMITTER: ONE\n"))
-	b.Write([]byte("CLAIMED-SCORE: " + score + "\n"))
-	b.Write([]byte("CLUB: DVRA\n"))
-	b.Write([]byte("NAME: Asadolah Seghatoleslami\n"))
-	b.Write([]byte("ADDRESS: 11 Silvers Lane, Cranbury NJ 08512\n"))
+func writeCabrilloHeader(b cabBuffer, contest, score string, p *ProfileRow) cabBuffer {
+	writeNewCabrilloHeader(&b, contest, score, p)
 	return b
 }
 
@@ -309,20 +316,30 @@ func writeCab(filename string, c []byte) error {
 	return nil
 }
 
-func writeNewCabrilloHeader(b *bytes.Buffer, contest, score string) {
+// the station lines come from the active station profile, the ones the
+// profile leaves blank are left out
+func writeNewCabrilloHeader(b io.Writer, contest, score string, p *ProfileRow) {
 	b.Write([]byte("START-OF-LOG: 3.0\n"))
 	b.Write([]byte("CONTEST: " + contest + "\n"))
-	b.Write([]byte("LOCATION: NJ\n"))
-	b.Write([]byte("CALLSIGN: " + myCall + "\n"))
+	cabrilloLine(b, "LOCATION", p.Location)
+	b.Write([]byte("CALLSIGN: " + p.Call + "\n"))
 	b.Write([]byte("CATEGORY-OPERATOR: SINGLE-OP\n"))
 	b.Write([]byte("CATEGORY-ASSISTED: NON-ASSISTED\n"))
 	b.Write([]byte("CATEGORY-BAND: All\n"))
-	b.Write([]byte("CATEGORY-POWER: LOW\n"))
+	b.Write([]byte("CATEGORY-POWER: " + p.powerCategory() + "\n"))
 	b.Write([]byte("CATEGORY-MODE: CW\n"))
 	b.Write([]byte("CATEGORY-STATION: FIXED\n"))
 	b.Write([]byte("CATEGORY-TRANSMITTER: ONE\n"))
 	b.Write([]byte("CLAIMED-SCORE: " + score + "\n"))
-	b.Write([]byte("CLUB: DVRA\n"))
-	b.Write([]byte("NAME: Asadolah Seghatoleslami\n"))
-	b.Write([]byte("ADDRESS: 11 Silvers Lane, Cranbury NJ 08512\n"))
+	cabrilloLine(b, "GRID-LOCATOR", p.Grid)
+	cabrilloLine(b, "CLUB", p.Club)
+	cabrilloLine(b, "NAME", p.Operator)
+	cabrilloLine(b, "ADDRESS", p.Address)
+}
+
+func cabrilloLine(b io.Writer, tag, value string) {
+	if value == "" {
+		return
+	}
+	b.Write([]byte(tag + ": " + value + "\n"))
 }
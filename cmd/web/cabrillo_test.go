@@ -0,0 +1,45 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestCabrilloFreq(t *testing.T) {
+	tests := []struct {
+		name string
+		row  LogsRow
+		want string
+	}{
+		{"exact", LogsRow{Band: "20m", Freq: "14.025500"}, "14026"},
+		{"40m", LogsRow{Band: "40m", Freq: "7.074000"}, "7074"},
+		{"no freq", LogsRow{Band: "20m"}, "14000"},
+		{"bad freq", LogsRow{Band: "80m", Freq: "junk"}, "3500"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := cabrilloFreq(tt.row)
+			if got != tt.want {
+				t.Errorf("want %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestWriteNewCabrilloHeader(t *testing.T) {
+	p := &ProfileRow{Call: "W2ZQ", Operator: "Club Station", Location: "NJ",
+		Club: "DVRA", Power: "5"}
+	b := new(bytes.Buffer)
+	writeNewCabrilloHeader(b, "CWOPS", "100", p)
+	for _, line := range []string{"CALLSIGN: W2ZQ\n", "LOCATION: NJ\n",
+		"CATEGORY-POWER: QRP\n", "CLUB: DVRA\n", "NAME: Club Station\n"} {
+		if !strings.Contains(b.String(), line) {
+			t.Errorf("header did not have %q", line)
+		}
+	}
+	//blank profile fields are left out
+	if strings.Contains(b.String(), "ADDRESS:") || strings.Contains(b.String(), "GRID-LOCATOR:") {
+		t.Errorf("header has lines for blank fields:\n%s", b.String())
+	}
+}
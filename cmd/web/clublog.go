@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+//<<========================= Club Log upload =============================>>
+
+//Each QSO is pushed to Club Log as it is logged (by hand, in a contest,
+//from WSJT-X or through the API) with realtime.php.  Upload To Club Log
+//sends the QSOs Club Log does not have yet, the whole log the first time,
+//as one ADIF file to putlogs.php.  A QSO is marked sent with the date once
+//Club Log took it, a push that failed leaves it for the next upload.  The
+//Club Log email, the callsign of the log and the API key are set in
+//config.yaml (clublogemail, clublogcall and clublogapi) and the password
+//is given on the command line (-clublogpw).
+
+const clublogSiteURL = "https://clublog.org"
+
+var (
+	errClublogUser = errors.New("no Club Log login, set clublogemail, clublogcall and clublogapi in config.yaml and start with -clublogpw")
+	errClublog     = errors.New("Club Log upload failed")
+)
+
+func (app *application) clublogSite() string {
+	if app.clublogURL != "" {
+		return app.clublogURL
+	}
+	return clublogSiteURL
+}
+
+// all of the Club Log login is there
+func (app *application) clublogReady() bool {
+	return app.clublogEmail != "" && app.clublogpw != "" && app.clublogCall != "" &&
+		app.clublogAPI != ""
+}
+
+// the login fields every Club Log call takes
+func (app *application) clublogLogin() url.Values {
+	v := url.Values{}
+	v.Set("email", app.clublogEmail)
+	v.Set("password", app.clublogpw)
+	v.Set("callsign", app.clublogCall)
+	v.Set("api", app.clublogAPI)
+	return v
+}
+
+// what a Club Log answer means, nil when the QSOs were taken
+func clublogResult(resp *http.Response) error {
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errClublog, err)
+	}
+	msg := strings.TrimSpace(string(data))
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusForbidden:
+		return fmt.Errorf("%w, check the login and API key: %s", errClublog, msg)
+	}
+	return fmt.Errorf("%w: status %d %s", errClublog, resp.StatusCode, msg)
+}
+
+// pushes a newly logged QSO in the background
+func (app *application) pushClublogLater(id int) {
+	if !app.clublogReady() {
+		return
+	}
+	go func() {
+		err := app.pushClublog(id)
+		if err != nil {
+			app.errorLog.Printf("Club Log push of QSO %d failed, it goes with the next upload: %v", id, err)
+		}
+	}()
+}
+
+// sends one QSO to Club Log as it is logged
+func (app *application) pushClublog(id int) error {
+	if !app.clublogReady() {
+		return errClublogUser
+	}
+	app.clublogLock.Lock()
+	defer app.clublogLock.Unlock()
+	l, err := app.logsModel.getLogByID(id)
+	if err != nil {
+		return err
+	}
+	var b bytes.Buffer
+	b = writeQSOs(b, []LogsRow{*l})
+	v := app.clublogLogin()
+	v.Set("adif", b.String())
+	resp, err := client.Post(app.clublogSite()+"/realtime.php",
+		"application/x-www-form-urlencoded", strings.NewReader(v.Encode()))
+	if err != nil {
+		return fmt.Errorf("%w: %v", errClublog, err)
+	}
+	defer resp.Body.Close()
+	err = clublogResult(resp)
+	if err != nil {
+		return err
+	}
+	return app.logsModel.updateClublogSent(id, time.Now().UTC().Truncate(time.Second))
+}
+
+// uploads the QSOs Club Log does not have yet and returns how many
+func (app *application) uploadClublog() (int, error) {
+	if !app.clublogReady() {
+		return 0, errClublogUser
+	}
+	app.clublogLock.Lock()
+	defer app.clublogLock.Unlock()
+	rows, err := app.logsModel.getClublogData()
+	if err != nil {
+		return 0, err
+	}
+	if len(rows) == 0 {
+		return 0, nil
+	}
+	var adif bytes.Buffer
+	adif = writeHeader(adif, app.clublogCall)
+	adif = writeQSOs(adif, rows)
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	for k, v := range app.clublogLogin() {
+		err = form.WriteField(k, v[0])
+		if err != nil {
+			return 0, err
+		}
+	}
+	err = form.WriteField("clear", "0")
+	if err != nil {
+		return 0, err
+	}
+	part, err := form.CreateFormFile("file", "stationmaster.adi")
+	if err != nil {
+		return 0, err
+	}
+	part.Write(adif.Bytes())
+	if err = form.Close(); err != nil {
+		return 0, err
+	}
+	resp, err := client.Post(app.clublogSite()+"/putlogs.php", form.FormDataContentType(), &body)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", errClublog, err)
+	}
+	defer resp.Body.Close()
+	err = clublogResult(resp)
+	if err != nil {
+		return 0, err
+	}
+	sent := time.Now().UTC().Truncate(time.Second)
+	for _, row := range rows {
+		err = app.logsModel.updateClublogSent(row.Id, sent)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return len(rows), nil
+}
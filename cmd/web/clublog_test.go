@@ -0,0 +1,155 @@
+package main
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// a Club Log stand-in for realtime.php and putlogs.php that keeps what it
+// was sent and answers with the status
+type clublogServer struct {
+	status int
+	form   map[string]string //the fields of the last call
+	adif   string            //the QSO or file of the last call
+}
+
+func newClublogServer(t *testing.T, app *application) *clublogServer {
+	s := &clublogServer{status: http.StatusOK}
+	keep := func(r *http.Request) {
+		s.form = map[string]string{}
+		for _, k := range []string{"email", "password", "callsign", "api", "clear"} {
+			s.form[k] = r.FormValue(k)
+		}
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/realtime.php", func(w http.ResponseWriter, r *http.Request) {
+		keep(r)
+		s.adif = r.FormValue("adif")
+		w.WriteHeader(s.status)
+		w.Write([]byte("QSO OK"))
+	})
+	mux.HandleFunc("/putlogs.php", func(w http.ResponseWriter, r *http.Request) {
+		keep(r)
+		f, _, err := r.FormFile("file")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		data, _ := ioutil.ReadAll(f)
+		s.adif = string(data)
+		w.WriteHeader(s.status)
+		w.Write([]byte("Upload OK"))
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	old := client
+	client = srv.Client()
+	t.Cleanup(func() { client = old })
+	app.clublogURL = srv.URL
+	app.clublogEmail, app.clublogpw = "n2vy@example.com", "secret"
+	app.clublogCall, app.clublogAPI = "N2VY", "0123abcd"
+	return s
+}
+
+func isClublogSent(t *testing.T, app *application, id int) bool {
+	l, err := app.logsModel.getLogByID(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return l.Clublogsent == "YES"
+}
+
+func TestPushClublog(t *testing.T) {
+	app, _ := newTestUploadApp(t, "DL1ABC", "G3AB")
+	s := newClublogServer(t, app)
+
+	err := app.pushClublog(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.form["email"] != "n2vy@example.com" || s.form["callsign"] != "N2VY" || s.form["api"] != "0123abcd" ||
+		!strings.Contains(s.adif, "<call:6>DL1ABC") {
+		t.Errorf("want the login and QSO pushed, got %v %q", s.form, s.adif)
+	}
+	if !isClublogSent(t, app, 1) {
+		t.Error("the pushed QSO is not marked sent")
+	}
+
+	s.status = http.StatusForbidden
+	err = app.pushClublog(2)
+	if !errors.Is(err, errClublog) || !strings.Contains(err.Error(), "API key") {
+		t.Errorf("want the login error, got %v", err)
+	}
+	if isClublogSent(t, app, 2) {
+		t.Error("the refused QSO is marked sent")
+	}
+
+	app.clublogAPI = ""
+	if err = app.pushClublog(2); !errors.Is(err, errClublogUser) {
+		t.Errorf("want %v, got %v", errClublogUser, err)
+	}
+}
+
+func TestUploadClublog(t *testing.T) {
+	app, _ := newTestUploadApp(t, "DL1ABC", "G3AB", "K1ABC")
+	s := newClublogServer(t, app)
+	err := app.pushClublog(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.status = http.StatusInternalServerError
+	_, err = app.uploadClublog()
+	if !errors.Is(err, errClublog) {
+		t.Errorf("want %v, got %v", errClublog, err)
+	}
+	if isClublogSent(t, app, 2) {
+		t.Error("a QSO is marked sent after a failed upload")
+	}
+
+	s.status = http.StatusOK
+	n, err := app.uploadClublog()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 || s.form["clear"] != "0" || strings.Contains(s.adif, "DL1ABC") ||
+		!strings.Contains(s.adif, "<call:4>G3AB") || !strings.Contains(s.adif, "<EOH>") {
+		t.Errorf("want the 2 QSOs not pushed uploaded as a file, got %d %v %q", n, s.form, s.adif)
+	}
+	for id := 1; id <= 3; id++ {
+		if !isClublogSent(t, app, id) {
+			t.Errorf("QSO %d is not marked sent", id)
+		}
+	}
+	if n, err = app.uploadClublog(); err != nil || n != 0 {
+		t.Errorf("want nothing left to upload, got %d %v", n, err)
+	}
+}
+
+func TestClublogUploadHandler(t *testing.T) {
+	app, _ := newTestUploadApp(t, "DL1ABC")
+	newClublogServer(t, app)
+
+	rr := httptest.NewRecorder()
+	app.routes().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/clublog-upload", nil))
+	if rr.Code != http.StatusMethodNotAllowed {
+		t.Errorf("want 405 for a GET, got %d", rr.Code)
+	}
+	for _, want := range []string{"Uploaded 1 QSOs to Club Log", "There is nothing to upload to Club Log"} {
+		rr = httptest.NewRecorder()
+		app.routes().ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/clublog-upload", nil))
+		if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), want) {
+			t.Errorf("want %q, got %d %q", want, rr.Code, rr.Body.String())
+		}
+	}
+	app.clublogpw = ""
+	rr = httptest.NewRecorder()
+	app.routes().ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/clublog-upload", nil))
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), "-clublogpw") {
+		t.Errorf("want the missing login message, got %d", rr.Code)
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+//<<==================== CSV export and import handlers ====================>>
+
+// writes the QSOs the search filters select as a CSV download with the
+// picked columns (col, in the order of csvColumns)
+func (app *application) exportCSV(w http.ResponseWriter, r *http.Request) {
+	td := initTemplateData()
+	td.Logger = true
+	q := r.URL.Query()
+	f := newForm(q)
+	lf := f.logFilter()
+	cols := f.csvColumns()
+	if !f.valid() {
+		td.FormData = f
+		td.Columns = csvChoices(q["col"])
+		app.render(w, r, "search.page.html", td)
+		return
+	}
+	rows, err := app.logsModel.getExportData(lf)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q",
+		"stationlogs-"+time.Now().UTC().Format("20060102")+".csv"))
+	err = writeCSV(w, rows, cols)
+	if err != nil {
+		app.errorLog.Println(err)
+	}
+}
+
+// the picked export columns, the default ones if none are picked
+func (f *formData) csvColumns() []csvColumn {
+	picked := f.Values["col"]
+	if len(picked) == 0 {
+		picked = csvDefaultColumns
+	}
+	cols := []csvColumn{}
+	for _, c := range csvColumns {
+		for _, p := range picked {
+			if p == c.Name {
+				cols = append(cols, c)
+			}
+		}
+	}
+	for _, p := range picked {
+		if _, ok := findCSVColumn(p); !ok {
+			f.Errors.add("col", fmt.Sprintf("%s is not a log column", p))
+		}
+	}
+	return cols
+}
+
+func (app *application) csvImportPage(w http.ResponseWriter, r *http.Request) {
+	app.render(w, r, "csvimport.page.html", initTemplateData())
+}
+
+// reads an uploaded CSV file and shows its columns for mapping
+func (app *application) csvUpload(w http.ResponseWriter, r *http.Request) {
+	td := initTemplateData()
+	err := r.ParseMultipartForm(32 << 20)
+	if err != nil {
+		app.errorLog.Println(err)
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	file, header, err := r.FormFile("csvfile")
+	if err != nil {
+		td.Message = "Please pick a CSV file to import"
+		app.render(w, r, "csvimport.page.html", td)
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	td.CSV, err = newCSVImport(header.Filename, string(data))
+	if err != nil {
+		td.Message = fmt.Sprintf("Can not read %s: %v", header.Filename, err)
+	}
+	app.render(w, r, "csvimport.page.html", td)
+}
+
+// checks the rows of a mapped file and shows what an import would do,
+// or with step=import logs the good rows
+func (app *application) csvImport(w http.ResponseWriter, r *http.Request) {
+	td := initTemplateData()
+	err := r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	f := newForm(r.PostForm)
+	td.FormData = f
+	td.CSV = f.csvImport()
+	if !f.valid() {
+		app.render(w, r, "csvimport.page.html", td)
+		return
+	}
+	dryRun := f.Get("step") != "import"
+	td.Import, err = app.importCSV(td.CSV, dryRun)
+	if err != nil {
+		if dryRun {
+			app.serverError(w, err)
+			return
+		}
+		td.Message = fmt.Sprintf("Import of %s stopped: %v", td.CSV.File, err)
+	}
+	app.render(w, r, "csvimport.page.html", td)
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestCSVUpload(t *testing.T) {
+	app := newTestSQLiteApp(t)
+
+	var b bytes.Buffer
+	mw := multipart.NewWriter(&b)
+	fw, err := mw.CreateFormFile("csvfile", "fieldday.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fw.Write([]byte(csvImportData))
+	mw.Close()
+
+	rr := httptest.NewRecorder()
+	r, err := http.NewRequest(http.MethodPost, "/csv-upload", &b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	app.csvUpload(rr, r)
+	rs := rr.Result()
+	defer rs.Body.Close()
+	bod, err := io.ReadAll(rs.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rs.StatusCode != http.StatusOK {
+		t.Errorf("expected %d got %d", http.StatusOK, rs.StatusCode)
+	}
+	if !bytes.Contains(bod, []byte("fieldday.csv: 7 rows")) ||
+		!bytes.Contains(bod, []byte(`<option value="rst_sent" selected>`)) {
+		t.Errorf("expected the mapping of the columns in the body, did not get")
+	}
+}
+
+func TestCSVImport(t *testing.T) {
+	app := newTestSQLiteApp(t)
+	mapping := []string{"call", "date", "time", "band", "freq", "mode", "rst_sent",
+		"rst_rcvd", "comment"}
+
+	tests := []struct {
+		name     string
+		step     string
+		date     string
+		mapping  []string
+		wantBody string
+		wantQSOs int
+	}{
+		{"no call", "check", "mm/dd/yyyy", append([]string{""}, mapping[1:]...),
+			"map a column to call", 0},
+		{"twice", "check", "mm/dd/yyyy", append([]string{"call", "call"}, mapping[2:]...),
+			"call is mapped to more than one column", 0},
+		{"bad date format", "check", "yy", mapping, "pick one of the date formats", 0},
+		{"check", "check", "mm/dd/yyyy", mapping, "3 to add, 0 skipped, 4 rejected", 0},
+		{"import", "import", "mm/dd/yyyy", mapping, "2 added, 1 skipped, 4 rejected", 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{}
+			form.Set("csvname", "fieldday.csv")
+			form.Set("csvdata", csvImportData)
+			form.Set("dateformat", tt.date)
+			form.Set("step", tt.step)
+			form["map"] = tt.mapping
+			rr := httptest.NewRecorder()
+			r, err := http.NewRequest(http.MethodPost, "/csv-check", strings.NewReader(form.Encode()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			app.csvImport(rr, r)
+			if rr.Code != http.StatusOK {
+				t.Errorf("expected %d got %d", http.StatusOK, rr.Code)
+			}
+			if !strings.Contains(rr.Body.String(), tt.wantBody) {
+				t.Errorf("expected %q in the body, did not get", tt.wantBody)
+			}
+			rows, err := app.logsModel.getExportData(&logFilter{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(rows) != tt.wantQSOs {
+				t.Errorf("want %d QSOs in the log, got %d", tt.wantQSOs, len(rows))
+			}
+		})
+	}
+}
+
+func TestExportCSV(t *testing.T) {
+	app := newTestSQLiteApp(t)
+	ci, err := newCSVImport("fieldday.csv", csvImportData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ci.DateFormat = "mm/dd/yyyy"
+	_, err = app.importCSV(ci, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		query    string
+		wantCode int
+		wantBody string
+	}{
+		{"default columns", "", 200, "date,time,call,band,freq,mode,rst_sent,rst_rcvd,name,country,comment\n" +
+			"2023-04-15,14:15:00,AA7BQ,20m,14.250000,USB,59,57,,,Field Day\n" +
+			"2023-04-15,14:20:00,DL1AB,20m,14.074000,FT8,-10,-12,,,\n"},
+		{"picked columns", "?call=DL*&col=call&col=mode", 200, "call,mode\nDL1AB,FT8\n"},
+		{"bad column", "?col=power", 200, "power is not a log column"},
+		{"bad filter", "?startdate=May&col=call", 200, "incorrect date format"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			r, err := http.NewRequest(http.MethodGet, "/export-csv"+tt.query, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			app.exportCSV(rr, r)
+			if rr.Code != tt.wantCode {
+				t.Errorf("expected %d got %d", tt.wantCode, rr.Code)
+			}
+			if !strings.Contains(rr.Body.String(), tt.wantBody) {
+				t.Errorf("expected %q in the body, got %q", tt.wantBody, rr.Body.String())
+			}
+		})
+	}
+}
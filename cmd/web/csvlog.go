@@ -0,0 +1,481 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//<<================= CSV export and import of the log =================>>
+
+//Spreadsheets and paper logs typed into one come in every shape, so the
+//import shows the columns of the file and lets the operator map each one
+//to a log field before anything is checked or logged.
+
+// csvColumn is a log field a CSV file can carry
+type csvColumn struct {
+	Name   string //column header in exports, choice in the import mapping
+	Title  string
+	Import bool //the log keeps it, the id does not come from a file
+	get    func(l *LogsRow) string
+}
+
+var csvColumns = []csvColumn{
+	{"date", "Date (UTC)", true, func(l *LogsRow) string { return l.Time.UTC().Format("2006-01-02") }},
+	{"time", "Time (UTC)", true, func(l *LogsRow) string { return l.Time.UTC().Format("15:04:05") }},
+	{"call", "Call", true, func(l *LogsRow) string { return l.Call }},
+	{"band", "Band", true, func(l *LogsRow) string { return l.Band }},
+	{"freq", "Frequency (MHz)", true, func(l *LogsRow) string { return l.Freq }},
+	{"freq_rx", "RX frequency (MHz)", true, func(l *LogsRow) string { return l.FreqRx }},
+	{"mode", "Mode", true, func(l *LogsRow) string { return l.Mode }},
+	{"rst_sent", "RST sent", true, func(l *LogsRow) string { return l.Sent }},
+	{"rst_rcvd", "RST received", true, func(l *LogsRow) string { return l.Rcvd }},
+	{"name", "Name", true, func(l *LogsRow) string { return l.Name }},
+	{"country", "Country", true, func(l *LogsRow) string { return l.Country }},
+	{"dxcc", "DXCC", true, func(l *LogsRow) string { return csvNumber(l.DXCC) }},
+	{"cqz", "CQ zone", true, func(l *LogsRow) string { return csvNumber(l.CQZone) }},
+	{"ituz", "ITU zone", true, func(l *LogsRow) string { return csvNumber(l.ITUZone) }},
+	{"cont", "Continent", true, func(l *LogsRow) string { return l.Continent }},
+	{"state", "State", true, func(l *LogsRow) string { return l.State }},
+	{"county", "County", true, func(l *LogsRow) string { return l.County }},
+	{"grid", "Grid", true, func(l *LogsRow) string { return l.Grid }},
+	{"distance", "Distance (km)", true, func(l *LogsRow) string { return csvNumber(l.Distance) }},
+	{"comment", "Comment", true, func(l *LogsRow) string { return l.Comment }},
+	{"contest", "Contest name", true, func(l *LogsRow) string { return l.ContestName }},
+	{"exch_sent", "Exchange sent", true, func(l *LogsRow) string { return l.ExchSent }},
+	{"exch_rcvd", "Exchange received", true, func(l *LogsRow) string { return l.ExchRcvd }},
+	{"lotw_sent", "LOTW sent", true, func(l *LogsRow) string { return l.Lotwsent }},
+	{"lotw_rcvd", "LOTW received", true, func(l *LogsRow) string { return l.Lotwrcvd }},
+	{"eqsl_sent", "eQSL sent", true, func(l *LogsRow) string { return l.Eqslsent }},
+	{"eqsl_rcvd", "eQSL received", true, func(l *LogsRow) string { return l.Eqslrcvd }},
+	{"id", "Log id", false, func(l *LogsRow) string { return fmt.Sprint(l.Id) }},
+}
+
+// exported when no columns are picked
+var csvDefaultColumns = []string{"date", "time", "call", "band", "freq", "mode",
+	"rst_sent", "rst_rcvd", "name", "country", "comment"}
+
+// other spellings of the column headers seen in spreadsheet logs, the
+// headers are compared in lower case without spaces, dashes and the like
+var csvHeaderNames = map[string]string{
+	"callsign": "call", "station": "call", "worked": "call",
+	"qsodate": "date", "day": "date",
+	"utc": "time", "timeon": "time", "qsotime": "time", "timeutc": "time",
+	"frequency": "freq", "mhz": "freq", "freqmhz": "freq", "freqrx": "freq_rx",
+	"rstsent": "rst_sent", "sent": "rst_sent", "rsts": "rst_sent", "his": "rst_sent",
+	"rstrcvd": "rst_rcvd", "rcvd": "rst_rcvd", "rstr": "rst_rcvd", "mine": "rst_rcvd",
+	"rstreceived": "rst_rcvd", "received": "rst_rcvd",
+	"op": "name", "operator": "name",
+	"comments": "comment", "notes": "comment", "remarks": "comment",
+	"contestname": "contest", "contestid": "contest",
+	"exchsent": "exch_sent", "exchrcvd": "exch_rcvd", "exchreceived": "exch_rcvd",
+	"lotwsent": "lotw_sent", "lotwrcvd": "lotw_rcvd",
+	"eqslsent": "eqsl_sent", "eqslrcvd": "eqsl_rcvd",
+	"dxccentity": "dxcc", "entity": "dxcc", "cqzone": "cqz", "ituzone": "ituz",
+	"continent": "cont", "st": "state", "cnty": "county",
+	"gridsquare": "grid", "locator": "grid", "km": "distance", "distancekm": "distance",
+}
+
+// csvDateFormat is a way of writing the QSO date the import understands
+type csvDateFormat struct {
+	Name   string
+	layout string
+}
+
+var csvDateFormats = []csvDateFormat{
+	{"yyyy-mm-dd", "2006-01-02"},
+	{"mm/dd/yyyy", "1/2/2006"},
+	{"dd/mm/yyyy", "2/1/2006"},
+	{"dd.mm.yyyy", "2.1.2006"},
+	{"yyyymmdd", "20060102"},
+}
+
+// csvChoice is an export column and whether it was picked
+type csvChoice struct {
+	Name    string
+	Title   string
+	Checked bool
+}
+
+// csvMapColumn is one column of an imported file and the log field it
+// goes to, blank to leave it out
+type csvMapColumn struct {
+	Header string
+	Field  string
+	Sample string //from the first row
+}
+
+// csvImport carries a file through the mapping, check and import steps
+type csvImport struct {
+	File        string
+	Data        string //the whole file, sent back with each step
+	Columns     []csvMapColumn
+	DateFormat  string
+	Rows        int
+	Fields      []csvColumn //the mapping choices
+	DateFormats []csvDateFormat
+}
+
+func findCSVColumn(name string) (csvColumn, bool) {
+	for _, c := range csvColumns {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return csvColumn{}, false
+}
+
+// the export columns with the picked ones checked, the default ones if
+// none are picked
+func csvChoices(picked []string) []csvChoice {
+	if len(picked) == 0 {
+		picked = csvDefaultColumns
+	}
+	choices := []csvChoice{}
+	for _, c := range csvColumns {
+		ch := csvChoice{Name: c.Name, Title: c.Title}
+		for _, p := range picked {
+			if p == c.Name {
+				ch.Checked = true
+			}
+		}
+		choices = append(choices, ch)
+	}
+	return choices
+}
+
+// writes the rows with a header line, the columns in the order picked
+func writeCSV(w io.Writer, rows []LogsRow, cols []csvColumn) error {
+	cw := csv.NewWriter(w)
+	rec := make([]string, len(cols))
+	for i, c := range cols {
+		rec[i] = c.Name
+	}
+	err := cw.Write(rec)
+	if err != nil {
+		return err
+	}
+	for i := range rows {
+		for j, c := range cols {
+			rec[j] = c.get(&rows[i])
+		}
+		err = cw.Write(rec)
+		if err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// reads a CSV file with a header line.  Rows may be short, the missing
+// cells are blank, and blank lines are dropped.
+func parseCSV(data string) ([]string, [][]string, error) {
+	cr := csv.NewReader(strings.NewReader(strings.TrimPrefix(data, "\ufeff")))
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	records, err := cr.ReadAll()
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(records) < 2 {
+		return nil, nil, fmt.Errorf("the file needs a header line and at least one QSO")
+	}
+	header := records[0]
+	rows := records[1:]
+	for i, r := range rows {
+		if len(r) > len(header) {
+			return nil, nil, fmt.Errorf("row %d has more cells than the header", i+1)
+		}
+	}
+	return header, rows, nil
+}
+
+// the log field a column header most likely means, blank if none
+func guessCSVField(header string) string {
+	h := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, strings.ToLower(header))
+	if f, ok := csvHeaderNames[h]; ok {
+		return f
+	}
+	for _, c := range csvColumns {
+		if c.Import && strings.ReplaceAll(c.Name, "_", "") == h {
+			return c.Name
+		}
+	}
+	return ""
+}
+
+// sets up the mapping step of a newly uploaded file
+func newCSVImport(fileName, data string) (*csvImport, error) {
+	header, rows, err := parseCSV(data)
+	if err != nil {
+		return nil, err
+	}
+	ci := &csvImport{File: fileName, Data: data, Rows: len(rows),
+		DateFormat: csvDateFormats[0].Name}
+	used := map[string]bool{}
+	for i, h := range header {
+		c := csvMapColumn{Header: h, Field: guessCSVField(h)}
+		if used[c.Field] {
+			c.Field = ""
+		}
+		used[c.Field] = true
+		if i < len(rows[0]) {
+			c.Sample = rows[0][i]
+		}
+		ci.Columns = append(ci.Columns, c)
+	}
+	ci.setChoices()
+	return ci, nil
+}
+
+func (ci *csvImport) setChoices() {
+	ci.Fields = []csvColumn{}
+	for _, c := range csvColumns {
+		if c.Import {
+			ci.Fields = append(ci.Fields, c)
+		}
+	}
+	ci.DateFormats = csvDateFormats
+}
+
+// reads the mapping of a posted file back into the import and checks it
+// covers the fields every QSO needs
+func (f *formData) csvImport() *csvImport {
+	ci := &csvImport{File: f.Get("csvname"), Data: f.Get("csvdata"),
+		DateFormat: f.Get("dateformat")}
+	ci.setChoices()
+	header, rows, err := parseCSV(ci.Data)
+	if err != nil {
+		f.Errors.add("csvdata", err.Error())
+		return ci
+	}
+	ci.Rows = len(rows)
+	mapping := f.Values["map"]
+	if len(mapping) != len(header) {
+		f.Errors.add("map", "map each column of the file or leave it out")
+		return ci
+	}
+	used := map[string]bool{}
+	for i, h := range header {
+		c := csvMapColumn{Header: h, Field: mapping[i]}
+		if i < len(rows[0]) {
+			c.Sample = rows[0][i]
+		}
+		ci.Columns = append(ci.Columns, c)
+		if c.Field == "" {
+			continue
+		}
+		if col, ok := findCSVColumn(c.Field); !ok || !col.Import {
+			f.Errors.add("map", fmt.Sprintf("%s is not a log field", c.Field))
+		}
+		if used[c.Field] {
+			f.Errors.add("map", fmt.Sprintf("%s is mapped to more than one column", c.Field))
+		}
+		used[c.Field] = true
+	}
+	for _, need := range []string{"call", "date", "time", "mode"} {
+		if !used[need] {
+			f.Errors.add("map", fmt.Sprintf("map a column to %s", need))
+		}
+	}
+	if !used["band"] && !used["freq"] {
+		f.Errors.add("map", "map a column to band or freq")
+	}
+	if _, ok := ci.dateLayout(); !ok {
+		f.Errors.add("dateformat", "pick one of the date formats")
+	}
+	return ci
+}
+
+func (ci *csvImport) dateLayout() (string, bool) {
+	for _, d := range csvDateFormats {
+		if d.Name == ci.DateFormat {
+			return d.layout, true
+		}
+	}
+	return "", false
+}
+
+// the cells of each row by the log field they are mapped to
+func (ci *csvImport) records() ([]map[string]string, error) {
+	_, rows, err := parseCSV(ci.Data)
+	if err != nil {
+		return nil, err
+	}
+	records := []map[string]string{}
+	for _, row := range rows {
+		rec := map[string]string{}
+		for i, c := range ci.Columns {
+			if c.Field != "" && i < len(row) {
+				rec[c.Field] = strings.TrimSpace(row[i])
+			}
+		}
+		records = append(records, rec)
+	}
+	return records, nil
+}
+
+// maps one row to a log row the same way adifToLog does an ADIF record
+func csvToLog(rec map[string]string, dateLayout string) (*LogsRow, error) {
+	l := &LogsRow{}
+	l.Call = strings.ToUpper(rec["call"])
+	if l.Call == "" {
+		return l, fmt.Errorf("no call sign")
+	}
+	if !validCall(l.Call) {
+		return l, fmt.Errorf("bad call sign %s", l.Call)
+	}
+	t, err := csvTime(rec["date"], rec["time"], dateLayout)
+	if err != nil {
+		return l, err
+	}
+	l.Time = t
+
+	l.Freq, l.FreqRx = adifFreq(rec["freq"]), adifFreq(rec["freq_rx"])
+	if rec["freq"] != "" && l.Freq == "" {
+		return l, fmt.Errorf("bad frequency %q", rec["freq"])
+	}
+	l.Band, err = importBand(rec["band"], l.Freq)
+	if err != nil {
+		return l, err
+	}
+	l.Mode = adifMode(rec["mode"], "", l.Band)
+	if l.Mode == "" {
+		return l, fmt.Errorf("no mode")
+	}
+
+	l.Sent = rec["rst_sent"]
+	l.Rcvd = rec["rst_rcvd"]
+	l.Name = rec["name"]
+	l.Country = rec["country"]
+	l.Continent = strings.ToUpper(rec["cont"])
+	l.State = rec["state"]
+	l.County = rec["county"]
+	l.Grid = rec["grid"]
+	l.Comment = rec["comment"]
+	for _, n := range []struct {
+		name  string
+		value *int
+	}{{"dxcc", &l.DXCC}, {"cqz", &l.CQZone}, {"ituz", &l.ITUZone},
+		{"distance", &l.Distance}} {
+		if rec[n.name] == "" {
+			continue
+		}
+		v, err := strconv.Atoi(rec[n.name])
+		if err != nil {
+			return l, fmt.Errorf("%s %q is not a number", n.name, rec[n.name])
+		}
+		*n.value = v
+	}
+	if csvYes(rec["lotw_sent"]) {
+		l.Lotwsent = "YES"
+	}
+	if csvYes(rec["lotw_rcvd"]) {
+		l.Lotwrcvd = "YES"
+	}
+	if csvYes(rec["eqsl_sent"]) {
+		l.Eqslsent = "YES"
+	}
+	if csvYes(rec["eqsl_rcvd"]) {
+		l.Eqslrcvd = "YES"
+	}
+	if c := rec["contest"]; c != "" {
+		l.Contest = "Yes"
+		l.ContestName = c
+		l.ExchSent = rec["exch_sent"]
+		l.ExchRcvd = rec["exch_rcvd"]
+	}
+	if len(l.Sent) > 10 || len(l.Rcvd) > 10 || len(l.Mode) > 20 ||
+		len(l.ContestName) > 50 || len(l.ExchSent) > 10 || len(l.ExchRcvd) > 10 {
+		return l, fmt.Errorf("field too long for the log")
+	}
+	return l, nil
+}
+
+// blank for the numbers that are not known
+func csvNumber(n int) string {
+	if n == 0 {
+		return ""
+	}
+	return strconv.Itoa(n)
+}
+
+// the date is in the layout picked for the file, the time in UTC as
+// HH:MM, HH:MM:SS, HHMM or HHMMSS (a leading zero may be missing)
+func csvTime(d, t, layout string) (time.Time, error) {
+	day, err := time.Parse(layout, d)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("bad QSO date %q", d)
+	}
+	t = strings.ReplaceAll(t, ":", "")
+	if len(t) == 3 || len(t) == 5 {
+		t = "0" + t
+	}
+	if len(t) != 4 && len(t) != 6 {
+		return time.Time{}, fmt.Errorf("bad QSO time %q", t)
+	}
+	return adifTime(day.Format("20060102"), t)
+}
+
+func csvYes(s string) bool {
+	switch strings.ToUpper(s) {
+	case "Y", "YES", "V":
+		return true
+	}
+	return false
+}
+
+// checks every row of the file against the mapping and, unless it is a
+// dry run, logs the good ones.  The dry run reports what an import would
+// add, skip and reject.
+func (app *application) importCSV(ci *csvImport, dryRun bool) (*importSummary, error) {
+	sum := &importSummary{File: ci.File, DryRun: dryRun}
+	layout, _ := ci.dateLayout()
+	records, err := ci.records()
+	if err != nil {
+		return sum, err
+	}
+	for i, rec := range records {
+		res := importResult{Record: i + 1, Call: rec["call"],
+			Time: strings.TrimSpace(rec["date"] + " " + rec["time"])}
+		l, err := csvToLog(rec, layout)
+		if err != nil {
+			res.Reason = err.Error()
+			sum.Rejected = append(sum.Rejected, res)
+			continue
+		}
+		dupe, err := app.logsModel.findDupe(l, importDupeWindow)
+		if err != nil {
+			return sum, err
+		}
+		if dupe {
+			res.Reason = "already in the log"
+			sum.Skipped = append(sum.Skipped, res)
+			continue
+		}
+		if l.Country == "" {
+			l.Country = app.entityOf(*l)
+		}
+		err = app.fillLocation(l)
+		if err != nil {
+			return sum, err
+		}
+		if !dryRun {
+			l.Id, err = app.logsModel.importLog(l, sourceImport)
+			if err != nil {
+				return sum, err
+			}
+		}
+		sum.Added = append(sum.Added, *l)
+	}
+	return sum, nil
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+const csvImportData = "Callsign,Date,UTC,Band,Freq,Mode,His,Mine,Notes\n" +
+	"aa7bq,04/15/2023,14:15,20m,14.250,SSB,59,57,Field Day\n" +
+	"DL1AB,04/15/2023,1420,,14.074,FT8,-10,-12,\n" +
+	"G4ABC.,04/15/2023,1430,40m,,CW,599,599,\n" +
+	"W1AW,15/04/2023,1430,40m,,CW,599,599,\n" +
+	"K1XX,04/15/2023,1500,40m,14.200,CW,599,599,\n" +
+	"N2VY,04/15/2023,1510,11m,,CW,599,599,\n" +
+	"AA7BQ,04/15/2023,1416,20m,,USB,59,59\n"
+
+func TestParseCSV(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		cols    int
+		rows    int
+		wantErr bool
+	}{
+		{"log", csvImportData, 9, 7, false},
+		{"bom and quotes", "\ufeffcall,comment\nN2VY,\"hello, there\"\n", 2, 1, false},
+		{"short rows", "call,band,mode\nN2VY\n", 3, 1, false},
+		{"header only", "call,band\n", 0, 0, true},
+		{"long row", "call\nN2VY,20m\n", 0, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header, rows, err := parseCSV(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("want error %v, got %v", tt.wantErr, err)
+			}
+			if len(header) != tt.cols || len(rows) != tt.rows {
+				t.Errorf("want %d columns and %d rows, got %d %d", tt.cols, tt.rows,
+					len(header), len(rows))
+			}
+		})
+	}
+}
+
+func TestGuessCSVField(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"Callsign", "call"},
+		{"QSO Date", "date"},
+		{"Time (UTC)", "time"},
+		{"RST_Sent", "rst_sent"},
+		{"freq rx", "freq_rx"},
+		{"Band", "band"},
+		{"Power", ""},
+		{"State", "state"},
+		{"CQ Zone", "cqz"},
+		{"Log id", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			if got := guessCSVField(tt.header); got != tt.want {
+				t.Errorf("want %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestCSVToLog(t *testing.T) {
+	tests := []struct {
+		name    string
+		rec     map[string]string
+		layout  string
+		band    string
+		mode    string
+		wantErr bool
+	}{
+		{"ssb on 40", map[string]string{"call": "n2vy", "date": "2023-01-01",
+			"time": "0100", "band": "40M", "mode": "SSB"}, "2006-01-02", "40m", "LSB", false},
+		{"band from freq", map[string]string{"call": "N2VY", "date": "1/2/2023",
+			"time": "9:05", "freq": "21.074", "mode": "ft8"}, "1/2/2006", "15m", "FT8", false},
+		{"no call", map[string]string{"date": "2023-01-01", "time": "0100",
+			"band": "40m", "mode": "CW"}, "2006-01-02", "", "", true},
+		{"bad date", map[string]string{"call": "N2VY", "date": "2023-13-01",
+			"time": "0100", "band": "40m", "mode": "CW"}, "2006-01-02", "", "", true},
+		{"bad time", map[string]string{"call": "N2VY", "date": "2023-01-01",
+			"time": "1", "band": "40m", "mode": "CW"}, "2006-01-02", "", "", true},
+		{"70cm", map[string]string{"call": "N2VY", "date": "2023-01-01",
+			"time": "0100", "band": "70CM", "mode": "FM"}, "2006-01-02", "70cm", "FM", false},
+		{"60m from freq", map[string]string{"call": "N2VY", "date": "2023-01-01",
+			"time": "0100", "freq": "5.3575", "mode": "FT8"}, "2006-01-02", "60m", "FT8", false},
+		{"not a band", map[string]string{"call": "N2VY", "date": "2023-01-01",
+			"time": "0100", "band": "11m", "mode": "CW"}, "2006-01-02", "", "", true},
+		{"freq off band", map[string]string{"call": "N2VY", "date": "2023-01-01",
+			"time": "0100", "band": "40m", "freq": "14.025", "mode": "CW"}, "2006-01-02", "", "", true},
+		{"bad freq", map[string]string{"call": "N2VY", "date": "2023-01-01",
+			"time": "0100", "band": "40m", "freq": "7 MHz", "mode": "CW"}, "2006-01-02", "", "", true},
+		{"no mode", map[string]string{"call": "N2VY", "date": "2023-01-01",
+			"time": "0100", "band": "40m"}, "2006-01-02", "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, err := csvToLog(tt.rec, tt.layout)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("want error %v, got %v", tt.wantErr, err)
+			}
+			if tt.wantErr {
+				return
+			}
+			if l.Band != tt.band || l.Mode != tt.mode {
+				t.Errorf("want %s %s, got %s %s", tt.band, tt.mode, l.Band, l.Mode)
+			}
+		})
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	rows := []LogsRow{{Id: 3, Time: time.Date(2023, 4, 15, 14, 15, 0, 0, time.UTC),
+		Call: "AA7BQ", Band: "20m", Mode: "USB", Comment: "Fred, AZ"}}
+	cols := []csvColumn{}
+	for _, n := range []string{"date", "time", "call", "comment", "id"} {
+		c, _ := findCSVColumn(n)
+		cols = append(cols, c)
+	}
+	var b bytes.Buffer
+	err := writeCSV(&b, rows, cols)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "date,time,call,comment,id\n2023-04-15,14:15:00,AA7BQ,\"Fred, AZ\",3\n"
+	if b.String() != want {
+		t.Errorf("want %q, got %q", want, b.String())
+	}
+}
+
+func TestImportCSV(t *testing.T) {
+	app := newTestSQLiteApp(t)
+
+	ci, err := newCSVImport("fieldday.csv", csvImportData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ci.DateFormat = "mm/dd/yyyy"
+	sum, err := app.importCSV(ci, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sum.Added) != 3 || len(sum.Skipped) != 0 || len(sum.Rejected) != 4 {
+		t.Fatalf("want 3 to add, 0 skipped, 4 rejected, got %d %d %d",
+			len(sum.Added), len(sum.Skipped), len(sum.Rejected))
+	}
+	rows, err := app.logsModel.getExportData(&logFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 0 {
+		t.Fatalf("the check logged %d QSOs", len(rows))
+	}
+
+	sum, err = app.importCSV(ci, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	//the second AA7BQ is a dupe of the first once that is logged
+	if len(sum.Added) != 2 || len(sum.Skipped) != 1 || len(sum.Rejected) != 4 {
+		t.Fatalf("want 2 added, 1 skipped, 4 rejected, got %d %d %d",
+			len(sum.Added), len(sum.Skipped), len(sum.Rejected))
+	}
+	l, err := app.logsModel.getLogByID(sum.Added[0].Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := time.Date(2023, 4, 15, 14, 15, 0, 0, time.UTC)
+	if !l.Time.Equal(want) || l.Call != "AA7BQ" || l.Mode != "USB" || l.Comment != "Field Day" {
+		t.Errorf("want AA7BQ USB at %v, got %+v", want, l)
+	}
+	if sum.Added[1].Band != "20m" || sum.Added[1].Freq != "14.074000" {
+		t.Errorf("want DL1AB on 20m at 14.074000, got %+v", sum.Added[1])
+	}
+}
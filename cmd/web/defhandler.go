@@ -15,15 +15,15 @@ func (app *application) setYaesu(w http.ResponseWriter, r *http.Request) {
 	app.otherModel.updateDefault(radio, yaesu)
 	err := app.clearPorts()
 	if err != nil {
-		app.errorLog.Println("failed to clear ports in Yaesu %v", err)
+		app.errorLog.Printf("failed to clear ports in Yaesu %v", err)
 	}
 	err = app.classifyRemotes()
 	if err != nil {
-		app.errorLog.Println("failed to start remote radio in USB %v", err)
+		app.errorLog.Printf("failed to start remote radio in USB %v", err)
 	}
 	err = app.initRadio()
 	if err != nil {
-		app.errorLog.Println("failed to initialize radio in USB %v", err)
+		app.errorLog.Printf("failed to initialize radio in USB %v", err)
 	}
 
 	//	fmt.Println(yaesu)
@@ -35,12 +35,12 @@ func (app *application) setTenTec(w http.ResponseWriter, r *http.Request) {
 	fmt.Println(tentec)
 	err := app.clearPorts()
 	if err != nil {
-		app.errorLog.Println("failed to clear ports in Ten Tec %v", err)
+		app.errorLog.Printf("failed to clear ports in Ten Tec %v", err)
 	}
 
 	err = app.classifyRemotes()
 	if err != nil {
-		app.errorLog.Println("failed to start Ten Tec CW in USB %v", err)
+		app.errorLog.Printf("failed to start Ten Tec CW in USB %v", err)
 	}
 
 	app.defaults(w, r)
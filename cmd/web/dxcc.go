@@ -0,0 +1,250 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Saied74/stationmaster/pkg/cty"
+)
+
+//<<======================== DXCC entities of calls ========================>>
+
+//QRZ knows the home country of a call, which is wrong for a portable call
+//(EA8/DL1ABC is worked in the Canary Islands, not in Germany) and missing
+//for a call QRZ does not have.  With a country file (ctyfile in
+//config.yaml) the entity of a QSO comes from the call itself, offline.
+//Without one the country QRZ gave is used as before.
+//
+//Each QSO also keeps its DXCC entity number, zones, continent, state,
+//county and grid.  They are filled in when the QSO is logged, from the
+//QRZ lookup of the call and then the country file, and the backfill fills
+//them in for the QSOs logged before.  With a country file the awards count
+//entities by their number only, the one the QSO has or else the one of
+//the country file, so QRZ spellings do not count twice.
+
+// the source of the changes the backfill makes
+const sourceBackfill = "backfill"
+
+// the country to log for a call
+func (app *application) qsoCountry(call, qrzCountry string) string {
+	if app.cty == nil {
+		return qrzCountry
+	}
+	if qrzCountry != "" && !strings.Contains(call, "/") {
+		return qrzCountry
+	}
+	if e, ok := app.cty.Lookup(call); ok {
+		return e.Name
+	}
+	return qrzCountry
+}
+
+// the DXCC entity of a logged QSO, the logged country when the call is in
+// no entity the country file knows
+func (app *application) entityOf(l LogsRow) string {
+	if app.cty == nil {
+		return l.Country
+	}
+	if e, ok := app.cty.LookupAt(l.Call, l.Time); ok {
+		return e.Name
+	}
+	return l.Country
+}
+
+// the entity number of a QSO and the name to show for it, from the country
+// file when the QSO has no number.  0 when neither has one.
+func (app *application) qsoEntity(l LogsRow) (int, string) {
+	n := l.DXCC
+	var e cty.Entity
+	ok := false
+	if app.cty != nil {
+		e, ok = app.cty.LookupAt(l.Call, l.Time)
+	}
+	if n == 0 && ok {
+		n = e.DXCC
+	}
+	switch {
+	case n == 0:
+		return 0, ""
+	case ok && e.DXCC == n:
+		return n, e.Name
+	}
+	if d, found := cty.FindDXCC(n); found {
+		return n, d.Name
+	}
+	return n, fmt.Sprintf("DXCC %d", n)
+}
+
+// fills in the location fields a QSO does not have yet.  A portable call
+// is not at the QRZ address of its home call so only the country file is
+// used for it.
+func (app *application) fillLocation(l *LogsRow) error {
+	if !strings.Contains(l.Call, "/") {
+		c, err := app.qrzModel.getQRZ(l.Call)
+		switch {
+		case err == nil:
+			qrzLocation(l, c)
+		case !errors.Is(err, errNoRecord):
+			return err
+		}
+	}
+	defer app.fillDistance(l)
+	if app.cty == nil {
+		return nil
+	}
+	t := l.Time
+	if t.IsZero() {
+		t = time.Now().UTC()
+	}
+	if e, ok := app.cty.LookupAt(l.Call, t); ok {
+		ctyLocation(l, e)
+	}
+	return nil
+}
+
+func qrzLocation(l *LogsRow, c *Ctype) {
+	fill := func(n *int, s string) {
+		if v, err := strconv.Atoi(strings.TrimSpace(s)); err == nil && *n == 0 {
+			*n = v
+		}
+	}
+	fill(&l.DXCC, c.Dxcc)
+	fill(&l.CQZone, c.CQzone)
+	fill(&l.ITUZone, c.ITUzone)
+	if l.State == "" {
+		l.State = c.State
+	}
+	if l.County == "" {
+		l.County = c.County
+	}
+	if l.Grid == "" {
+		l.Grid = c.Grid
+	}
+}
+
+func ctyLocation(l *LogsRow, e cty.Entity) {
+	if l.DXCC == 0 {
+		l.DXCC = e.DXCC
+	}
+	if l.CQZone == 0 {
+		l.CQZone = e.CQZone
+	}
+	if l.ITUZone == 0 {
+		l.ITUZone = e.ITUZone
+	}
+	if l.Continent == "" {
+		l.Continent = e.Continent
+	}
+}
+
+// fills in the location fields of the QSOs logged before they were kept,
+// from the QRZ data already looked up and the country file.  Returns the
+// number of QSOs changed.
+func (app *application) backfillLocations() (int, error) {
+	rows, err := app.logsModel.getExportData(&logFilter{})
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, r := range rows {
+		l, err := app.logsModel.getLogByID(r.Id)
+		if err != nil {
+			return n, err
+		}
+		before := *l
+		err = app.fillLocation(l)
+		if err != nil {
+			return n, err
+		}
+		if *l == before {
+			continue
+		}
+		err = app.logsModel.replaceLog(l, sourceBackfill)
+		if err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+// marks the spots of entities not confirmed on LOTW as needed
+func (app *application) findNeed(dx []DXClusters) ([]DXClusters, error) {
+	if app.cty == nil {
+		return app.logsModel.findNeed(dx)
+	}
+	rows, err := app.logsModel.getSimpleLogs("%", "YES", "%")
+	if err != nil {
+		return nil, err
+	}
+	confirmed := map[int]bool{}
+	for _, l := range rows {
+		if n, _ := app.qsoEntity(l); n != 0 {
+			confirmed[n] = true
+		}
+	}
+	newDX := []DXClusters{}
+	for _, d := range dx {
+		d.Need = "Yes"
+		if e, ok := app.cty.Lookup(d.DXStation); ok && confirmed[e.DXCC] {
+			d.Need = "No"
+		}
+		newDX = append(newDX, d)
+	}
+	return newDX, nil
+}
+
+// the entities worked in a mode ("%" for all), confirmed on LOTW when
+// confirmed is YES, in alphabetical order.  With a country file they are
+// counted by number, a QSO without one is left out until the backfill
+// fills it in.  Without one they are the countries QRZ gave as before.
+func (app *application) countries(mode, confirmed string) ([]LogsRow, error) {
+	if app.cty == nil {
+		switch {
+		case mode == "%" && confirmed == "%":
+			return app.logsModel.getUniqueCountries()
+		case mode == "%":
+			return app.logsModel.getConfirmedCountries()
+		}
+		return app.logsModel.getUniqueCountry(mode, confirmed)
+	}
+	rows, err := app.logsModel.getSimpleLogs(mode, confirmed, "%")
+	if err != nil {
+		return nil, err
+	}
+	seen := map[int]bool{}
+	t := []LogsRow{}
+	for _, l := range rows {
+		n, name := app.qsoEntity(l)
+		if n == 0 || seen[n] {
+			continue
+		}
+		seen[n] = true
+		t = append(t, LogsRow{Country: name, DXCC: n})
+	}
+	sort.Slice(t, func(i, j int) bool { return t[i].Country < t[j].Country })
+	return t, nil
+}
+
+// the QSOs with an entity by its number, or without a number by the
+// country logged, the latest first
+func (app *application) entityLogs(dxcc int, name string) ([]LogsRow, error) {
+	if dxcc == 0 {
+		return app.logsModel.getLogsByCountry(name)
+	}
+	rows, err := app.logsModel.getSimpleLogs("%", "%", "%")
+	if err != nil {
+		return nil, err
+	}
+	t := []LogsRow{}
+	for _, l := range rows {
+		if app.dxccNumber(l) == dxcc {
+			t = append(t, l)
+		}
+	}
+	return t, nil
+}
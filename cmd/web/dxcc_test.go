@@ -0,0 +1,233 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Saied74/stationmaster/pkg/cty"
+)
+
+func loadTestCty(t *testing.T) *cty.Database {
+	d, err := cty.Load("../../pkg/cty/testdata/cty.dat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func TestQSOCountry(t *testing.T) {
+	app := newTestApp()
+	app.cty = loadTestCty(t)
+	tests := []struct {
+		call string
+		qrz  string
+		want string
+	}{
+		{"DL1ABC", "Germany", "Germany"},
+		{"EA8/DL1ABC", "Germany", "Canary Islands"},
+		{"DL1ABC", "", "Fed. Rep. of Germany"},
+		{"DL1ABC/MM", "Germany", "Germany"},
+		{"Q1ABC", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.call, func(t *testing.T) {
+			if got := app.qsoCountry(tt.call, tt.qrz); got != tt.want {
+				t.Errorf("want %q, got %q", tt.want, got)
+			}
+		})
+	}
+	app.cty = nil
+	if got := app.qsoCountry("EA8/DL1ABC", "Germany"); got != "Germany" {
+		t.Errorf("without a country file want Germany, got %q", got)
+	}
+}
+
+func TestCountries(t *testing.T) {
+	app := newTestSQLiteApp(t)
+	qso := time.Date(2023, 4, 15, 14, 15, 0, 0, time.UTC)
+	for i, l := range []LogsRow{
+		{Call: "DL1ABC", Mode: "CW", Country: "Germany", DXCC: 230, Lotwrcvd: "YES"},
+		{Call: "DL2XYZ", Mode: "SSB", Country: "Germany", Lotwrcvd: "YES"},
+		{Call: "EA8/DL1ABC", Mode: "CW", Country: "Germany", Lotwrcvd: "NO"},
+		{Call: "TA1ABC", Mode: "FT8", Country: "", Lotwrcvd: "YES"},
+	} {
+		l.Time = qso.Add(time.Duration(i) * time.Minute)
+		l.Band = "20m"
+		_, err := app.logsModel.importLog(&l, sourceImport)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	names := func(rows []LogsRow) []string {
+		n := []string{}
+		for _, r := range rows {
+			n = append(n, r.Country)
+		}
+		return n
+	}
+	same := func(a, b []string) bool {
+		if len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if a[i] != b[i] {
+				return false
+			}
+		}
+		return true
+	}
+
+	app.cty = loadTestCty(t)
+	tests := []struct {
+		mode      string
+		confirmed string
+		want      []string
+	}{
+		{"%", "%", []string{"Canary Islands", "Fed. Rep. of Germany", "Turkey"}},
+		{"%", "YES", []string{"Fed. Rep. of Germany", "Turkey"}},
+		{"CW", "YES", []string{"Fed. Rep. of Germany"}},
+		{"CW", "%", []string{"Canary Islands", "Fed. Rep. of Germany"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode+" "+tt.confirmed, func(t *testing.T) {
+			rows, err := app.countries(tt.mode, tt.confirmed)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := names(rows); !same(got, tt.want) {
+				t.Errorf("want %v, got %v", tt.want, got)
+			}
+		})
+	}
+
+	//DL1ABC has the number, DL2XYZ gets it from the country file, Germany
+	//is one entity
+	rows, err := app.countries("%", "%")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 3 || rows[1].DXCC != 230 {
+		t.Errorf("want Germany once as 230, got %v", rows)
+	}
+	rows, err = app.entityLogs(230, "Fed. Rep. of Germany")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 {
+		t.Errorf("want both German QSOs, got %v", rows)
+	}
+	rows, err = app.entityLogs(29, "Canary Islands")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1 || rows[0].Call != "EA8/DL1ABC" {
+		t.Errorf("want the EA8/DL1ABC QSO, got %v", rows)
+	}
+
+	dx, err := app.findNeed([]DXClusters{{DXStation: "DL5AA", Country: "Germany"},
+		{DXStation: "EA8BB", Country: "Canary Is."}, {DXStation: "TA1CC", Country: "Turkey"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	need := []string{}
+	for _, d := range dx {
+		need = append(need, d.Need)
+	}
+	if want := []string{"No", "Yes", "No"}; !same(need, want) {
+		t.Errorf("want %v, got %v", want, need)
+	}
+
+	app.cty = nil
+	rows, err = app.countries("%", "YES")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"", "Germany"}; !same(names(rows), want) {
+		t.Errorf("without a country file want %v, got %v", want, names(rows))
+	}
+}
+
+func TestBackfillLocations(t *testing.T) {
+	app := newTestSQLiteApp(t)
+	err := app.qrzModel.insertQRZ(&Ctype{Call: "DL1ABC", Dxcc: "230", CQzone: "14",
+		ITUzone: "28", Grid: "JO62", Country: "Germany"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	qso := time.Date(2023, 4, 15, 14, 15, 0, 0, time.UTC)
+	for i, l := range []LogsRow{
+		{Call: "DL1ABC", Country: "Germany", Lotwrcvd: "YES"},
+		{Call: "EA8/DL1ABC", Country: "Germany"},
+		{Call: "Q1ABC"},
+		{Call: "K1LZ", DXCC: 291, CQZone: 4, ITUZone: 7, Continent: "SA", State: "MA"},
+	} {
+		l.Time = qso.Add(time.Duration(i) * time.Minute)
+		l.Band, l.Mode = "20m", "CW"
+		_, err := app.logsModel.importLog(&l, sourceImport)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	app.cty = loadTestCty(t)
+	n, err := app.backfillLocations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("want 2 QSOs filled in, got %d", n)
+	}
+
+	tests := []struct {
+		id   int
+		dxcc int
+		cq   int
+		itu  int
+		cont string
+		grid string
+	}{
+		{1, 230, 14, 28, "EU", "JO62"},
+		{2, 29, 33, 36, "AF", ""},
+		{3, 0, 0, 0, "", ""},
+		{4, 291, 4, 7, "SA", ""},
+	}
+	for _, tt := range tests {
+		l, err := app.logsModel.getLogByID(tt.id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if l.DXCC != tt.dxcc || l.CQZone != tt.cq || l.ITUZone != tt.itu ||
+			l.Continent != tt.cont || l.Grid != tt.grid {
+			t.Errorf("QSO %d: want %d %d %d %s %s, got %d %d %d %s %s", tt.id,
+				tt.dxcc, tt.cq, tt.itu, tt.cont, tt.grid,
+				l.DXCC, l.CQZone, l.ITUZone, l.Continent, l.Grid)
+		}
+	}
+	h, err := app.logsModel.getHistory(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(h) != 2 || h[0].Source != sourceBackfill {
+		t.Errorf("want the backfill in the history, got %v", h)
+	}
+
+	n, err = app.backfillLocations()
+	if err != nil || n != 0 {
+		t.Errorf("a second backfill want 0 changes, got %d %v", n, err)
+	}
+
+	rows, err := app.countries("%", "YES")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1 || rows[0].DXCC != 230 || rows[0].Country != "Fed. Rep. of Germany" {
+		t.Errorf("want entity 230, got %v", rows)
+	}
+	rows, err = app.entityLogs(230, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1 || rows[0].Call != "DL1ABC" {
+		t.Errorf("want the DL1ABC QSO, got %v", rows)
+	}
+}
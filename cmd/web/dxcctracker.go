@@ -0,0 +1,286 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Saied74/stationmaster/pkg/cty"
+)
+
+//<<========================== DXCC award tracker ===========================>>
+
+//The tracker counts the DXCC entities by their ADIF number, worked and
+//confirmed on each band of the DXCC Challenge (160 to 6 meters) and in
+//each DXCC mode (Mixed, CW, Phone and Digital).  A QSO is confirmed for
+//DXCC by LoTW or by a paper card, eQSL does not count.  The Challenge
+//total is the confirmed band slots of the current entities.  The deleted
+//entities are counted apart as ARRL does, and the needed list is the
+//current entities not confirmed yet.  A QSO without an entity number gets
+//it from the country file when there is one, the rest are only counted
+//(the backfill of the check page fills them in).  Both the matrix and the
+//QSOs that confirm each slot export as CSV for an award application.
+
+var challengeBands = []string{"160m", "80m", "40m", "30m", "20m", "17m", "15m", "12m", "10m", "6m"}
+
+var awardModes = []string{"Mixed", "CW", "Phone", "Digital"}
+
+// the standing of an entity on a band or in a mode, blank when not worked
+const (
+	dxccWorked    = "W"
+	dxccConfirmed = "C"
+)
+
+// DXCCEntity is the row of an entity in the tracker
+type DXCCEntity struct {
+	DXCC    int
+	Name    string
+	Deleted bool
+	Bands   []string //standing on each Challenge band
+	Modes   []string //standing in each award mode
+	Slots   int      //confirmed Challenge band slots
+}
+
+// DXCCTotal is how many entities are worked and confirmed on a band or in
+// a mode
+type DXCCTotal struct {
+	Name      string
+	Worked    int
+	Confirmed int
+}
+
+// DXCCCredit is the first QSO that confirms an entity on a band or in a mode
+type DXCCCredit struct {
+	Award string //the band or the mode
+	DXCC  int
+	Name  string
+	QSO   LogsRow
+}
+
+// DXCCTracker is the DXCC award standing of the log
+type DXCCTracker struct {
+	Bands      []string
+	Modes      []string
+	Entities   []DXCCEntity //current entities worked, by number
+	Deleted    []DXCCEntity //deleted entities worked
+	Needed     []DXCCEntity //current entities not confirmed in Mixed
+	BandTotals []DXCCTotal  //of the current entities
+	ModeTotals []DXCCTotal
+	Challenge  int
+	NoEntity   int //QSOs without an entity number
+	Credits    []DXCCCredit
+}
+
+// the entity row before any QSO
+func newDXCCEntity(number int) *DXCCEntity {
+	e := &DXCCEntity{DXCC: number, Name: fmt.Sprintf("DXCC %d", number),
+		Bands: make([]string, len(challengeBands)), Modes: make([]string, len(awardModes))}
+	if d, ok := cty.FindDXCC(number); ok {
+		e.Name, e.Deleted = d.Name, d.Deleted
+	}
+	return e
+}
+
+// the entity number of a QSO, from the country file when the QSO has none
+func (app *application) dxccNumber(l LogsRow) int {
+	if l.DXCC != 0 || app.cty == nil {
+		return l.DXCC
+	}
+	if e, ok := app.cty.LookupAt(l.Call, l.Time); ok {
+		return e.DXCC
+	}
+	return 0
+}
+
+// how the QSO is confirmed for DXCC, blank when it is not
+func dxccQSL(l LogsRow) string {
+	switch {
+	case l.Lotwrcvd == "YES":
+		return "LoTW"
+	case l.QSLrcvd == "YES":
+		return "Card"
+	}
+	return ""
+}
+
+// the DXCC mode of a mode
+func awardMode(mode string) string {
+	switch modeGroup(mode) {
+	case "CW":
+		return "CW"
+	case "PHONE":
+		return "Phone"
+	}
+	return "Digital"
+}
+
+// the place of the band in challengeBands, -1 for the bands that do not
+// count for the Challenge
+func challengeBand(band string) int {
+	band = strings.ToLower(strings.TrimSpace(band))
+	for i, b := range challengeBands {
+		if b == band {
+			return i
+		}
+	}
+	return -1
+}
+
+// the standing after one more QSO
+func dxccStanding(was string, confirmed bool) string {
+	if confirmed || was == dxccConfirmed {
+		return dxccConfirmed
+	}
+	return dxccWorked
+}
+
+func (app *application) dxccTracker() (*DXCCTracker, error) {
+	rows, err := app.logsModel.getExportData(&logFilter{})
+	if err != nil {
+		return nil, err
+	}
+	tr := &DXCCTracker{Bands: challengeBands, Modes: awardModes}
+	worked := map[int]*DXCCEntity{}
+	first := map[string]LogsRow{} //by award and entity
+	for _, l := range rows {
+		n := app.dxccNumber(l)
+		if n == 0 {
+			tr.NoEntity++
+			continue
+		}
+		e, ok := worked[n]
+		if !ok {
+			e = newDXCCEntity(n)
+			worked[n] = e
+		}
+		confirmed := dxccQSL(l) != ""
+		awards := []string{awardModes[0], awardMode(l.Mode)}
+		for i, m := range awardModes {
+			if m == awards[0] || m == awards[1] {
+				e.Modes[i] = dxccStanding(e.Modes[i], confirmed)
+			}
+		}
+		if b := challengeBand(l.Band); b >= 0 {
+			e.Bands[b] = dxccStanding(e.Bands[b], confirmed)
+			awards = append(awards, challengeBands[b])
+		}
+		if !confirmed {
+			continue
+		}
+		for _, a := range awards {
+			key := fmt.Sprintf("%s#%d", a, n)
+			if f, ok := first[key]; !ok || l.Time.Before(f.Time) {
+				first[key] = l
+			}
+		}
+	}
+
+	numbers := []int{}
+	for n := range worked {
+		numbers = append(numbers, n)
+	}
+	sort.Ints(numbers)
+	tr.BandTotals = dxccTotals(challengeBands)
+	tr.ModeTotals = dxccTotals(awardModes)
+	for _, n := range numbers {
+		e := worked[n]
+		for _, b := range e.Bands {
+			if b == dxccConfirmed {
+				e.Slots++
+			}
+		}
+		if e.Deleted {
+			tr.Deleted = append(tr.Deleted, *e)
+			continue
+		}
+		tr.Entities = append(tr.Entities, *e)
+		tr.Challenge += e.Slots
+		countDXCC(tr.BandTotals, e.Bands)
+		countDXCC(tr.ModeTotals, e.Modes)
+	}
+	for _, d := range cty.DXCCList() {
+		if d.Deleted {
+			continue
+		}
+		e, ok := worked[d.Number]
+		if !ok {
+			e = newDXCCEntity(d.Number)
+		}
+		if e.Modes[0] != dxccConfirmed {
+			tr.Needed = append(tr.Needed, *e)
+		}
+	}
+	for _, a := range append(append([]string{}, awardModes...), challengeBands...) {
+		for _, n := range numbers {
+			if l, ok := first[fmt.Sprintf("%s#%d", a, n)]; ok {
+				tr.Credits = append(tr.Credits, DXCCCredit{Award: a, DXCC: n, Name: worked[n].Name, QSO: l})
+			}
+		}
+	}
+	return tr, nil
+}
+
+func dxccTotals(names []string) []DXCCTotal {
+	t := make([]DXCCTotal, len(names))
+	for i, n := range names {
+		t[i].Name = n
+	}
+	return t
+}
+
+func countDXCC(totals []DXCCTotal, standing []string) {
+	for i, s := range standing {
+		if s != "" {
+			totals[i].Worked++
+		}
+		if s == dxccConfirmed {
+			totals[i].Confirmed++
+		}
+	}
+}
+
+// writes the matrix of the entities worked, the deleted ones last
+func (tr *DXCCTracker) writeEntities(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	head := append(append([]string{"DXCC", "Entity", "Deleted"}, tr.Modes...), tr.Bands...)
+	err := cw.Write(append(head, "Challenge"))
+	if err != nil {
+		return err
+	}
+	for _, e := range append(append([]DXCCEntity{}, tr.Entities...), tr.Deleted...) {
+		deleted := ""
+		if e.Deleted {
+			deleted = "Y"
+		}
+		rec := []string{strconv.Itoa(e.DXCC), e.Name, deleted}
+		rec = append(append(rec, e.Modes...), e.Bands...)
+		err = cw.Write(append(rec, strconv.Itoa(e.Slots)))
+		if err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// writes the QSO that confirms each entity on each band and in each mode
+func (tr *DXCCTracker) writeCredits(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	err := cw.Write([]string{"Award", "DXCC", "Entity", "Call", "Date", "Time", "Band", "Mode", "QSL"})
+	if err != nil {
+		return err
+	}
+	for _, c := range tr.Credits {
+		q := c.QSO
+		err = cw.Write([]string{c.Award, strconv.Itoa(c.DXCC), c.Name, q.Call,
+			q.Time.UTC().Format("2006-01-02"), q.Time.UTC().Format("1504"), q.Band, q.Mode, dxccQSL(q)})
+		if err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// a log with Germany on 20m and 40m, the USA on 60m, the deleted Germany
+// of before 1973 and a QSO without an entity number
+func newTestDXCCApp(t *testing.T) *application {
+	app := newTestSQLiteApp(t)
+	at := time.Date(2023, 4, 15, 14, 15, 0, 0, time.UTC)
+	qsos := []LogsRow{
+		{Call: "DL1ABC", DXCC: 230, Band: "20m", Mode: "CW", Lotwrcvd: "YES"},
+		{Call: "DL2XYZ", DXCC: 230, Band: "40m", Mode: "FT8"},
+		{Call: "DL3AAA", DXCC: 230, Band: "20m", Mode: "USB", QSLrcvd: "YES"},
+		{Call: "K1ABC", DXCC: 291, Band: "60m", Mode: "CW", Lotwrcvd: "YES"},
+		{Call: "DL0OLD", DXCC: 81, Band: "20m", Mode: "CW", QSLrcvd: "YES"},
+		{Call: "XX0X", Band: "20m", Mode: "CW", Lotwrcvd: "YES"},
+	}
+	for i, l := range qsos {
+		l.Time = at.Add(time.Duration(i) * time.Hour)
+		_, err := app.logsModel.importLog(&l, sourceImport)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return app
+}
+
+func TestDXCCTracker(t *testing.T) {
+	app := newTestDXCCApp(t)
+	tr, err := app.dxccTracker()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tr.Entities) != 2 || len(tr.Deleted) != 1 || tr.Deleted[0].DXCC != 81 || tr.NoEntity != 1 {
+		t.Fatalf("want 2 current and 1 deleted entity and a QSO without one, got %v %v %d",
+			tr.Entities, tr.Deleted, tr.NoEntity)
+	}
+	de := tr.Entities[0]
+	if want := []string{"C", "C", "C", "W"}; de.DXCC != 230 || !reflect.DeepEqual(de.Modes, want) {
+		t.Errorf("want Germany %v by mode, got %d %v", want, de.DXCC, de.Modes)
+	}
+	if de.Bands[challengeBand("20m")] != dxccConfirmed || de.Bands[challengeBand("40m")] != dxccWorked ||
+		de.Slots != 1 {
+		t.Errorf("want Germany confirmed on 20m and worked on 40m, got %v", de.Bands)
+	}
+	if us := tr.Entities[1]; us.Slots != 0 || us.Modes[0] != dxccConfirmed {
+		t.Errorf("want the USA confirmed on no Challenge band, got %v", us)
+	}
+	if tr.Challenge != 1 {
+		t.Errorf("want 1 Challenge slot, the deleted entity does not count, got %d", tr.Challenge)
+	}
+	if m := tr.ModeTotals[0]; m.Worked != 2 || m.Confirmed != 2 {
+		t.Errorf("want 2 entities worked and confirmed in Mixed, got %v", m)
+	}
+	if d := tr.ModeTotals[3]; d.Worked != 1 || d.Confirmed != 0 {
+		t.Errorf("want 1 entity worked in Digital, got %v", d)
+	}
+	if len(tr.Needed) != 338 {
+		t.Errorf("want 338 entities needed, got %d", len(tr.Needed))
+	}
+	for _, c := range tr.Credits {
+		if c.Award == "20m" && c.DXCC == 230 && c.QSO.Call != "DL1ABC" {
+			t.Errorf("want the first QSO to confirm Germany on 20m, got %s", c.QSO.Call)
+		}
+	}
+	if len(tr.Credits) != 9 {
+		t.Errorf("want 9 credits, got %d", len(tr.Credits))
+	}
+}
+
+func TestDXCCHandlers(t *testing.T) {
+	app := newTestDXCCApp(t)
+	get := func(path string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		app.routes().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+		return rr
+	}
+	rr := get("/dxcc")
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), "FEDERAL REPUBLIC OF GERMANY") ||
+		!strings.Contains(rr.Body.String(), "Needed (338)") {
+		t.Errorf("want the tracker page, got %d", rr.Code)
+	}
+
+	rr = get("/dxcc-export")
+	lines := strings.Split(strings.TrimSpace(rr.Body.String()), "\n")
+	if rr.Header().Get("Content-Type") != "text/csv; charset=utf-8" || len(lines) != 4 ||
+		lines[0] != "DXCC,Entity,Deleted,Mixed,CW,Phone,Digital,160m,80m,40m,30m,20m,17m,15m,12m,10m,6m,Challenge" ||
+		lines[1] != "230,FEDERAL REPUBLIC OF GERMANY,,C,C,C,W,,,W,,C,,,,,,1" || !strings.HasPrefix(lines[3], "81,GERMANY,Y,") {
+		t.Errorf("want the matrix as CSV, got %q", lines)
+	}
+	rr = get("/dxcc-export?list=credits")
+	if !strings.Contains(rr.Body.String(), "Phone,230,FEDERAL REPUBLIC OF GERMANY,DL3AAA,2023-04-15,1615,20m,USB,Card") {
+		t.Errorf("want the confirming QSOs as CSV, got %q", rr.Body.String())
+	}
+	if rr = get("/dxcc-export?list=awards"); rr.Code != http.StatusBadRequest {
+		t.Errorf("want 400 for an unknown list, got %d", rr.Code)
+	}
+}
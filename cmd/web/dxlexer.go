@@ -41,6 +41,7 @@ const (
 
 type dxLexer struct {
 	name    string      // used only for error reports.
+	call    string      // the spider prompt ends the spots.
 	input   string      // the string being scanned.
 	start   int         // start position of this item.
 	pos     int         // current position in the input.
@@ -70,9 +71,10 @@ func (l *dxLexer) run() {
 	close(l.dxItems) // No more tokens will be delivered.
 }
 
-func dxLex(name, input string) (*dxLexer, chan dxItem) {
+func dxLex(name, call, input string) (*dxLexer, chan dxItem) {
 	l := &dxLexer{
 		name:    name,
+		call:    call,
 		input:   input,
 		dxItems: make(chan dxItem),
 	}
@@ -226,7 +228,7 @@ func dxLexText(l *dxLexer) dxStateFn {
 // skip spaces before frequency
 func dxLexPreFreq(l *dxLexer) dxStateFn {
 	for {
-		if strings.HasPrefix(l.input[l.pos:], myCall) {
+		if strings.HasPrefix(l.input[l.pos:], l.call) {
 			if l.pos > l.start {
 				l.emit(dxItemEOF)
 				return nil
@@ -245,7 +247,6 @@ func dxLexPreFreq(l *dxLexer) dxStateFn {
 			continue
 		}
 	}
-	return nil
 }
 
 // Frequency is composed of numbers and a dot
@@ -265,7 +266,6 @@ func dxLexFreq(l *dxLexer) dxStateFn {
 			return l.errorf("bad item in freq field %v", l.input)
 		}
 	}
-	return nil
 }
 
 // skip over spaces until alphanumeric
@@ -283,7 +283,6 @@ func dxLexPostFreq(l *dxLexer) dxStateFn {
 			return l.errorf("fell out of the bottom of lexPostFreq %v", l.input)
 		}
 	}
-	return nil
 }
 
 // call sign is alphanumerics
@@ -303,7 +302,6 @@ func dxLexDX(l *dxLexer) dxStateFn {
 			return l.errorf("fell out of the bottom of lexDX %v", l.input)
 		}
 	}
-	return nil
 }
 
 // skip over space until reach alphanumeric
@@ -321,7 +319,6 @@ func dxLexPostDX(l *dxLexer) dxStateFn {
 			return l.errorf("fell out of the bottom of lexPostDX %v", l.input)
 		}
 	}
-	return nil
 }
 
 // date is alphanumeric + "-"
@@ -341,7 +338,6 @@ func dxLexDate(l *dxLexer) dxStateFn {
 			return l.errorf("fell out of the bottom of lexDate %v", l.input)
 		}
 	}
-	return nil
 
 }
 
@@ -360,7 +356,6 @@ func dxLexPostDate(l *dxLexer) dxStateFn {
 			return l.errorf("fell out of the bottom of lexPostDate %v", l.input)
 		}
 	}
-	return nil
 }
 
 // time is numeric + Z
@@ -380,7 +375,6 @@ func dxLexTime(l *dxLexer) dxStateFn {
 			return l.errorf("fell out of the bottom of LexTime %v", l.input)
 		}
 	}
-	return nil
 }
 
 // much everything until reach deStart which is <; emit info
@@ -440,10 +434,10 @@ type DXClusters struct {
 	Need      string
 }
 
-func lexResults(pattern string) ([]DXClusters, error) {
+func lexResults(call, pattern string) ([]DXClusters, error) {
 	dx := []DXClusters{}
 	l := DXClusters{}
-	_, c := dxLex("dxspiders", pattern)
+	_, c := dxLex("dxspiders", call, pattern)
 	//l := lineType{}
 	b := false
 	for {
@@ -41,17 +41,32 @@ func (app *application) initSpider() (spider, error) {
 		w: bufio.NewWriter(c),
 	}
 	//fmt.Println(app.call)
-	err = dx.logIn( /*c, */ app.call)
+	err = dx.logIn( /*c, */ app.stationCall())
 	if err != nil {
 		return spider{}, err
 	}
 	return dx, nil
 }
 
+// whether the DX spider is logged in
+func (app *application) spiderUp() bool {
+	app.spLock.Lock()
+	defer app.spLock.Unlock()
+	return app.sp.w != nil
+}
+
+func (app *application) setSpider(sp spider) {
+	app.spLock.Lock()
+	defer app.spLock.Unlock()
+	app.sp = sp
+}
+
 func (app *application) changeBand(band string) error {
 	if band == "WWV" || band == "AUX" || band == "160m" {
 		return nil
 	}
+	app.spLock.Lock()
+	defer app.spLock.Unlock()
 	b := make([]byte, 500)
 
 	_, err := app.sp.w.WriteString(fmt.Sprintf("accept/spot 4 on %s\n", band))
@@ -70,7 +85,7 @@ func (app *application) changeBand(band string) error {
 			return err
 		}
 		b = append(b, bb)
-		if strings.Contains(string(b), myCall) {
+		if strings.Contains(string(b), app.stationCall()) {
 			break
 		}
 	}
@@ -79,6 +94,8 @@ func (app *application) changeBand(band string) error {
 
 func (app *application) getSpider(band string, lineCnt int) ([]DXClusters, error) {
 	b := make([]byte, 500)
+	app.spLock.Lock()
+	defer app.spLock.Unlock()
 	if app.sp.w == nil {
 		return []DXClusters{}, nil
 	}
@@ -104,7 +121,7 @@ func (app *application) getSpider(band string, lineCnt int) ([]DXClusters, error
 		b = append(b, bb)
 		sB = string(b)
 
-		if strings.HasSuffix(sB, myCall) && len(sB) >= msgLength {
+		if strings.HasSuffix(sB, app.stationCall()) && len(sB) >= msgLength {
 			break
 		}
 		if strings.Contains(sB, disconnect) {
@@ -202,7 +219,7 @@ func (s *spider) logIn(call string) error {
 			return err
 		}
 		b = append(b, bb)
-		if strings.Contains(string(b), myCall) {
+		if strings.Contains(string(b), call) {
 			break
 		}
 	}
@@ -212,15 +229,17 @@ func (s *spider) logIn(call string) error {
 }
 
 func (app *application) spiderError(err error) error {
+	app.spLock.Lock()
+	defer app.spLock.Unlock()
 	if errors.Is(err, errTimeout) {
 		app.infoLog.Printf("timeout error from calling getSpider in updateDX %v\n", err)
-		err = app.sp.logIn(app.call)
+		err = app.sp.logIn(app.stationCall())
 		if err != nil {
 			return err
 		}
 	}
 	if errors.Is(err, errDisconnect) {
-		err = app.sp.logIn(app.call)
+		err = app.sp.logIn(app.stationCall())
 		if err != nil {
 			return err
 		}
@@ -235,22 +254,22 @@ func (app *application) spiderError(err error) error {
 		return nil
 	}
 	if errors.Is(err, syscall.ECONNRESET) {
-		err = app.sp.logIn(app.call)
+		err = app.sp.logIn(app.stationCall())
 		if err != nil {
 			return err
 		}
 	}
 	if err != nil {
-		fmt.Errorf("error from calling spiderError %v\n", err)
+		app.errorLog.Printf("error from calling spiderError %v\n", err)
 		return err
 	}
 	return nil
 }
 
-func (app *application) byeSpider() error {
-	_, err := app.sp.w.WriteString("bye\n")
+func (s *spider) bye() error {
+	_, err := s.w.WriteString("bye\n")
 	if err != nil {
 		return err
 	}
-	return nil
+	return s.w.Flush()
 }
@@ -0,0 +1,364 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+//<<======================== eQSL upload and inbox =========================>>
+
+//The QSOs not yet sent to eQSL go up as one ADIF file, written like the
+//LoTW one with the eQSL user name and password in the header, posted to
+//ImportADIF.cfm.  The eQSLs received come from the inbox, DownloadInBox.cfm
+//gives a page with a link to an ADIF file of them.  Only the eQSLs received
+//since the last download are asked for, the time is kept in the defaults
+//table.  An eQSL confirms the one QSO it matches the way a LoTW QSL does
+//(call, band, mode group and time) and is marked Authenticity Guaranteed
+//when the inbox says so or the sender is on the eQSL AG member list.  The
+//user name and password are given on the command line (-eqsluser and
+//-eqslpw).
+
+const eqslSiteURL = "https://www.eqsl.cc/qslcard"
+
+// the defaults key of the time of the last inbox download
+const eqslSinceKey = "eqslrcvdsince"
+
+var (
+	errEQSLUser = errors.New("no eQSL user name and password, start with -eqsluser and -eqslpw")
+	errEQSL     = errors.New("eQSL failed")
+)
+
+var eqslADIFLink = regexp.MustCompile(`(?i)href="([^"]+\.adi)"`)
+var htmlTag = regexp.MustCompile(`<[^>]*>`)
+
+// eqslUpload is the outcome of one upload
+type eqslUpload struct {
+	QSOs     int      //QSOs sent and marked
+	Result   string   //what eQSL said it did with them
+	Rejected []string //the records eQSL would not take
+}
+
+// eqslDownload is the outcome of one inbox download
+type eqslDownload struct {
+	Since     string //the eQSLs since this time were asked for, blank for all
+	QSLs      int    //eQSLs in the inbox file
+	Confirmed int
+	AG        int //of the confirmed, the Authenticity Guaranteed ones
+	Unmatched int
+	Ambiguous int
+}
+
+func (app *application) eqslSite() string {
+	if app.eqslURL != "" {
+		return app.eqslURL
+	}
+	return eqslSiteURL
+}
+
+// the lines of an eQSL page without the HTML
+func eqslLines(page string) []string {
+	page = strings.NewReplacer("<BR>", "\n", "<br>", "\n", "<Br>", "\n").Replace(page)
+	lines := []string{}
+	for _, line := range strings.Split(htmlTag.ReplaceAllString(page, ""), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// the first line of the page that starts with the prefix, blank if none
+func eqslLine(lines []string, prefix string) string {
+	for _, line := range lines {
+		if strings.HasPrefix(line, prefix) {
+			return line
+		}
+	}
+	return ""
+}
+
+// the call of a rejected record line, eQSL writes them as
+// "Warning: Y=2023 M=04 D=15 DL1ABC 20M CW Bad record: ..."
+func rejectedCall(line string) string {
+	fields := strings.Fields(line)
+	for i, f := range fields {
+		if strings.HasPrefix(f, "D=") && i+1 < len(fields) {
+			return strings.ToUpper(fields[i+1])
+		}
+	}
+	return ""
+}
+
+// gets an eQSL page, eQSL answers a bad login with a page that says Error:
+// and not an error status
+func getEQSL(u string) (string, error) {
+	resp, err := client.Get(u)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", errEQSL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%w: status %d", errEQSL, resp.StatusCode)
+	}
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", errEQSL, err)
+	}
+	return string(data), nil
+}
+
+// the ADIF file eQSL imports, the header has the user name and password
+func (app *application) eqslADIF(rows []LogsRow) []byte {
+	var b bytes.Buffer
+	b = writeHeader(b, app.stationCall())
+	header := b.String()
+	n := strings.Index(header, "<EOH>")
+	var h bytes.Buffer
+	h.WriteString(header[:n])
+	adifField(&h, "eqsl_user", app.eqsluser)
+	adifField(&h, "eqsl_pswd", app.eqslpw)
+	h.WriteString(header[n:])
+	h = writeQSOs(h, rows)
+	return h.Bytes()
+}
+
+// sends the QSOs not yet sent to eQSL and marks the ones eQSL took.
+// Duplicates eQSL already has count as sent.
+func (app *application) uploadEQSL() (*eqslUpload, error) {
+	if app.eqsluser == "" || app.eqslpw == "" {
+		return nil, errEQSLUser
+	}
+	rows, err := app.logsModel.getEQSLData()
+	if err != nil {
+		return nil, err
+	}
+	u := &eqslUpload{}
+	if len(rows) == 0 {
+		return u, nil
+	}
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("Filename", "stationmaster.adi")
+	if err != nil {
+		return nil, err
+	}
+	part.Write(app.eqslADIF(rows))
+	if err = form.Close(); err != nil {
+		return nil, err
+	}
+	resp, err := client.Post(app.eqslSite()+"/ImportADIF.cfm", form.FormDataContentType(), &body)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errEQSL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: status %d", errEQSL, resp.StatusCode)
+	}
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errEQSL, err)
+	}
+	lines := eqslLines(string(data))
+	u.Result = eqslLine(lines, "Result:")
+	if u.Result == "" {
+		msg := eqslLine(lines, "Error:")
+		if msg == "" {
+			msg = "no result in the answer"
+		}
+		return nil, fmt.Errorf("%w: %s", errEQSL, msg)
+	}
+	rejected := map[string]bool{}
+	for _, line := range lines {
+		if (strings.HasPrefix(line, "Warning:") || strings.HasPrefix(line, "Error:")) &&
+			!strings.Contains(line, "Duplicate") {
+			u.Rejected = append(u.Rejected, line)
+			if call := rejectedCall(line); call != "" {
+				rejected[call] = true
+			}
+		}
+	}
+	for _, row := range rows {
+		if rejected[strings.ToUpper(row.Call)] {
+			continue
+		}
+		err = app.logsModel.updateEQSLSent(row.Id)
+		if err != nil {
+			return nil, err
+		}
+		u.QSOs++
+	}
+	return u, nil
+}
+
+// the URL of the inbox page of the eQSLs received since a time, all of
+// them when since is blank
+func (app *application) eqslInboxQuery(since string) string {
+	v := url.Values{}
+	v.Set("UserName", app.eqsluser)
+	v.Set("Password", app.eqslpw)
+	if since != "" {
+		v.Set("RcvdSince", since)
+	}
+	return app.eqslSite() + "/DownloadInBox.cfm?" + v.Encode()
+}
+
+// the calls of the Authenticity Guaranteed members
+func (app *application) eqslAGMembers() (map[string]bool, error) {
+	list, err := getEQSL(app.eqslSite() + "/DownloadedFiles/AGMemberList.txt")
+	if err != nil {
+		return nil, err
+	}
+	ag := map[string]bool{}
+	for _, line := range strings.Split(list, "\n") {
+		line = strings.ToUpper(strings.TrimSpace(line))
+		if line != "" && !strings.Contains(line, " ") {
+			ag[line] = true
+		}
+	}
+	return ag, nil
+}
+
+// the inbox ADIF file of the eQSLs since a time, blank when there are none
+func (app *application) eqslInbox(since string) (string, error) {
+	inbox := app.eqslInboxQuery(since)
+	page, err := getEQSL(inbox)
+	if err != nil {
+		return "", err
+	}
+	link := eqslADIFLink.FindStringSubmatch(page)
+	if link == nil {
+		lines := eqslLines(page)
+		if msg := eqslLine(lines, "Error:"); msg != "" {
+			return "", fmt.Errorf("%w: %s", errEQSL, msg)
+		}
+		if strings.Contains(strings.ToLower(page), "no log entries") {
+			return "", nil
+		}
+		return "", fmt.Errorf("%w: no ADIF file in the inbox page", errEQSL)
+	}
+	base, err := url.Parse(inbox)
+	if err != nil {
+		return "", err
+	}
+	file, err := base.Parse(link[1])
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", errEQSL, err)
+	}
+	return getEQSL(file.String())
+}
+
+// downloads the eQSLs received since the last download and confirms the
+// QSOs they match
+func (app *application) downloadEQSL() (*eqslDownload, error) {
+	if app.eqsluser == "" || app.eqslpw == "" {
+		return nil, errEQSLUser
+	}
+	since, err := app.otherModel.getDefault(eqslSinceKey)
+	if err != nil && !errors.Is(err, errNoRecord) {
+		return nil, err
+	}
+	start := time.Now().UTC()
+	adif, err := app.eqslInbox(since)
+	if err != nil {
+		return nil, err
+	}
+	d := &eqslDownload{Since: since}
+	records := []adifRecord{}
+	if adif != "" {
+		records, err = parseADIF(adif)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", errEQSL, err)
+		}
+	}
+	var ag map[string]bool
+	for _, r := range records {
+		call := strings.ToUpper(strings.TrimSpace(r["CALL"]))
+		t, err := adifTime(r["QSO_DATE"], r["TIME_ON"])
+		if call == "" || err != nil {
+			return nil, fmt.Errorf("%w: a record in the inbox has no call or QSO time", errEQSL)
+		}
+		d.QSLs++
+		band := strings.ToLower(strings.TrimSpace(r["BAND"]))
+		mode := strings.TrimSpace(r["MODE"])
+		matches, err := app.matchQSOs(call, band, mode, t)
+		if err != nil {
+			return nil, err
+		}
+		isAG := adifYes(r["APP_EQSL_AG"])
+		if !isAG {
+			if ag == nil {
+				ag, err = app.eqslAGMembers()
+				if err != nil {
+					return nil, err
+				}
+			}
+			isAG = ag[call]
+		}
+		rcvd, err := time.Parse("20060102", strings.TrimSpace(r["QSLRDATE"]))
+		if err != nil {
+			rcvd = start.Truncate(time.Second)
+		}
+		if len(matches) != 1 {
+			//kept for review, the inbox does not give it again
+			u := &UnmatchedQSL{Received: start.Truncate(time.Second), Reason: qslUnmatched,
+				Source: sourceEQSL, AG: isAG, LoTWQSL: LoTWQSL{Call: call, Band: band,
+					Mode: mode, Time: t, RxQSO: rcvd, RxQSL: rcvd}}
+			if len(matches) > 1 {
+				u.Reason = qslAmbiguous
+				d.Ambiguous++
+			} else {
+				d.Unmatched++
+			}
+			err = app.qslModel.insertUnmatched(u)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		err = app.logsModel.confirmEQSL(matches[0].Id, rcvd, isAG)
+		if err != nil {
+			return nil, err
+		}
+		d.Confirmed++
+		if isAG {
+			d.AG++
+		}
+	}
+	err = app.otherModel.updateDefault(eqslSinceKey, start.Format("200601021504"))
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (u *eqslUpload) String() string {
+	if u.Result == "" {
+		return "There is nothing to upload to eQSL"
+	}
+	msg := fmt.Sprintf("Sent %d QSOs to eQSL, %s", u.QSOs, u.Result)
+	if len(u.Rejected) > 0 {
+		msg += "; not taken: " + strings.Join(u.Rejected, "; ")
+	}
+	return msg
+}
+
+func (d *eqslDownload) String() string {
+	msg := fmt.Sprintf("Downloaded %d eQSLs", d.QSLs)
+	if d.Since != "" {
+		msg += " received since " + d.Since
+	}
+	msg += fmt.Sprintf(", confirmed %d QSOs (%d AG)", d.Confirmed, d.AG)
+	if d.Unmatched > 0 || d.Ambiguous > 0 {
+		msg += fmt.Sprintf(", %d matched no QSO and %d more than one and wait for review",
+			d.Unmatched, d.Ambiguous)
+	}
+	return msg
+}
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// an eQSL inbox file as DownloadInBox.cfm links to it
+const eqslTestInbox = `eQSL.cc DownloadInBox
+<PROGRAMID:21>eQSL.cc DownloadInBox
+<ADIF_Ver:5>1.00
+<EOH>
+<CALL:6>DL1ABC<QSO_DATE:8>20230415<TIME_ON:4>1415<BAND:3>20M<MODE:2>CW<RST_SENT:3>599<QSL_SENT:1>Y<QSL_SENT_VIA:1>E<QSLRDATE:8>20230420<APP_EQSL_AG:1>Y<EOR>
+<CALL:4>G3AB<QSO_DATE:8>20230415<TIME_ON:4>1416<BAND:3>20M<MODE:2>CW<RST_SENT:3>599<QSL_SENT:1>Y<QSL_SENT_VIA:1>E<EOR>
+<CALL:5>K1ABC<QSO_DATE:8>20230415<TIME_ON:4>1417<BAND:3>20M<MODE:2>CW<RST_SENT:3>599<QSL_SENT:1>Y<QSL_SENT_VIA:1>E<EOR>
+<CALL:6>JA1XYZ<QSO_DATE:8>20230415<TIME_ON:4>1417<BAND:3>15M<MODE:3>FT8<QSL_SENT:1>Y<QSL_SENT_VIA:1>E<EOR>
+`
+
+// what the eQSL stand-in answers and what it was sent
+type eqslServer struct {
+	upload  string //the ImportADIF.cfm page
+	inbox   string //the DownloadInBox.cfm page
+	file    string //the ADIF file the last upload posted
+	queries []url.Values
+}
+
+// an eQSL stand-in for the upload, the inbox page, the inbox file and the
+// AG member list
+func newEQSLServer(t *testing.T, app *application) *eqslServer {
+	s := &eqslServer{
+		upload: "<HTML><BODY>Result: 0 out of 0 records added<BR></BODY></HTML>",
+		inbox: `<HTML><BODY>Your ADIF log file has been built.<BR>
+<LI><A HREF="downloadedfiles/n2vy.adi">.ADI file</A></BODY></HTML>`,
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/qslcard/ImportADIF.cfm", func(w http.ResponseWriter, r *http.Request) {
+		f, _, err := r.FormFile("Filename")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		data, _ := ioutil.ReadAll(f)
+		s.file = string(data)
+		w.Write([]byte(s.upload))
+	})
+	mux.HandleFunc("/qslcard/DownloadInBox.cfm", func(w http.ResponseWriter, r *http.Request) {
+		s.queries = append(s.queries, r.URL.Query())
+		w.Write([]byte(s.inbox))
+	})
+	mux.HandleFunc("/qslcard/downloadedfiles/n2vy.adi", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(eqslTestInbox))
+	})
+	mux.HandleFunc("/qslcard/DownloadedFiles/AGMemberList.txt", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("List of eQSL.cc Authenticity Guaranteed members\r\nG3AB\r\nW1AW\r\n"))
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	old := client
+	client = srv.Client()
+	t.Cleanup(func() { client = old })
+	app.eqslURL = srv.URL + "/qslcard"
+	app.eqsluser, app.eqslpw = "n2vy", "secret"
+	return s
+}
+
+func TestUploadEQSL(t *testing.T) {
+	app, _ := newTestUploadApp(t, "DL1ABC", "G3AB", "K1ABC")
+	s := newEQSLServer(t, app)
+	s.upload = `<HTML><BODY>Result: 1 out of 3 records added<BR>
+Warning: Y=2023 M=04 D=15 G3AB 20M CW Bad record: Duplicate<BR>
+Warning: Y=2023 M=04 D=15 K1ABC 20M CW Bad record: No match on date<BR></BODY></HTML>`
+
+	u, err := app.uploadEQSL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.QSOs != 2 || u.Result != "Result: 1 out of 3 records added" || len(u.Rejected) != 1 {
+		t.Errorf("want 2 QSOs sent and K1ABC rejected, got %v", u)
+	}
+	for _, want := range []string{"<eqsl_user:4>n2vy", "<eqsl_pswd:6>secret", "<call:6>DL1ABC", "<call:5>K1ABC"} {
+		if !strings.Contains(s.file, want) {
+			t.Errorf("want %s in the upload, got %q", want, s.file)
+		}
+	}
+	if strings.Index(s.file, "<eqsl_pswd") > strings.Index(s.file, "<EOH>") {
+		t.Error("want the user name and password in the header")
+	}
+	for id, want := range map[int]string{1: "YES", 2: "YES", 3: ""} {
+		l, err := app.logsModel.getLogByID(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if l.Eqslsent != want {
+			t.Errorf("want QSO %d eQSL sent %q, got %q", id, want, l.Eqslsent)
+		}
+	}
+
+	s.upload = "Result: 1 out of 1 records added<BR>"
+	u, err = app.uploadEQSL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.QSOs != 1 || strings.Contains(s.file, "DL1ABC") {
+		t.Errorf("want only the rejected QSO sent again, got %v %q", u, s.file)
+	}
+	u, err = app.uploadEQSL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.String() != "There is nothing to upload to eQSL" {
+		t.Errorf("want nothing to upload, got %q", u.String())
+	}
+}
+
+func TestUploadEQSLErrors(t *testing.T) {
+	app, _ := newTestUploadApp(t, "DL1ABC")
+	s := newEQSLServer(t, app)
+	s.upload = "<HTML><BODY>Error: No match on eQSL_User/eQSL_Pswd<BR></BODY></HTML>"
+	_, err := app.uploadEQSL()
+	if !errors.Is(err, errEQSL) || !strings.Contains(err.Error(), "No match on eQSL_User") {
+		t.Errorf("want the eQSL error, got %v", err)
+	}
+	app.eqslpw = ""
+	if _, err = app.uploadEQSL(); !errors.Is(err, errEQSLUser) {
+		t.Errorf("want %v, got %v", errEQSLUser, err)
+	}
+	if isLoTWSent(t, app, 1) {
+		t.Error("the failed eQSL upload marked the QSO LoTW sent")
+	}
+	l, err := app.logsModel.getLogByID(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.Eqslsent == "YES" {
+		t.Error("the QSO is marked eQSL sent after a failed upload")
+	}
+}
+
+func TestDownloadEQSL(t *testing.T) {
+	app, _ := newTestUploadApp(t, "DL1ABC", "G3AB", "K1ABC")
+	s := newEQSLServer(t, app)
+
+	d, err := app.downloadEQSL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := eqslDownload{QSLs: 4, Confirmed: 3, AG: 2, Unmatched: 1}
+	if *d != want {
+		t.Errorf("want %v, got %v", want, *d)
+	}
+	if s.queries[0].Get("UserName") != "n2vy" || s.queries[0].Get("Password") != "secret" ||
+		s.queries[0].Has("RcvdSince") {
+		t.Errorf("want the first download of the whole inbox, got %v", s.queries[0])
+	}
+	for _, tt := range []struct {
+		id   int
+		ag   bool
+		rcvd time.Time
+	}{
+		{1, true, time.Date(2023, 4, 20, 0, 0, 0, 0, time.UTC)},
+		{2, true, time.Time{}},
+		{3, false, time.Time{}},
+	} {
+		l, err := app.logsModel.getLogByID(tt.id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if l.Eqslrcvd != "YES" || l.EqslAG != tt.ag {
+			t.Errorf("want QSO %d confirmed with AG %v, got %q %v", tt.id, tt.ag, l.Eqslrcvd, l.EqslAG)
+		}
+		if !tt.rcvd.IsZero() && !l.EqslQSLdate.Equal(tt.rcvd) {
+			t.Errorf("want QSO %d received %v, got %v", tt.id, tt.rcvd, l.EqslQSLdate)
+		}
+	}
+	u, err := app.qslModel.getUnmatchedQSLs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(u) != 1 || u[0].Source != sourceEQSL || u[0].Call != "JA1XYZ" || u[0].Reason != qslUnmatched {
+		t.Fatalf("want the JA1XYZ eQSL kept for review, got %v", u)
+	}
+	l, err := app.addQSL(u[0].Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err = app.logsModel.getLogByID(l.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.Eqslrcvd != "YES" || l.Eqslsent != "YES" || l.Lotwrcvd == "YES" {
+		t.Errorf("want the QSO added from the eQSL confirmed by eQSL, got %v", l)
+	}
+
+	s.inbox = "<HTML><BODY>You have no log entries since the date given<BR></BODY></HTML>"
+	d, err = app.downloadEQSL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.QSLs != 0 || d.Since == "" || s.queries[1].Get("RcvdSince") != d.Since {
+		t.Errorf("want the second download since the first, got %v %v", d, s.queries[1])
+	}
+}
+
+func TestDownloadEQSLErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		user  string
+		inbox string
+		want  error
+	}{
+		{"no user", "", "", errEQSLUser},
+		{"bad login", "n2vy", "<HTML><BODY>Error: No such Username/Password found<BR></BODY></HTML>", errEQSL},
+		{"no file", "n2vy", "<HTML><BODY>Try again later</BODY></HTML>", errEQSL},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestSQLiteApp(t)
+			s := newEQSLServer(t, app)
+			app.eqsluser = tt.user
+			s.inbox = tt.inbox
+			_, err := app.downloadEQSL()
+			if !errors.Is(err, tt.want) {
+				t.Errorf("want %v, got %v", tt.want, err)
+			}
+			since, err := app.otherModel.getDefault(eqslSinceKey)
+			if !errors.Is(err, errNoRecord) {
+				t.Errorf("a failed download kept the time %q", since)
+			}
+		})
+	}
+}
+
+func TestEQSLHandlers(t *testing.T) {
+	app, _ := newTestUploadApp(t, "DL1ABC")
+	s := newEQSLServer(t, app)
+	s.upload = "Result: 1 out of 1 records added<BR>"
+	for _, path := range []string{"/eqsl-upload", "/eqsl-download"} {
+		rr := httptest.NewRecorder()
+		app.routes().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+		if rr.Code != http.StatusMethodNotAllowed {
+			t.Errorf("%s: want 405 for a GET, got %d", path, rr.Code)
+		}
+	}
+	for path, want := range map[string]string{
+		"/eqsl-upload":   "Sent 1 QSOs to eQSL, Result: 1 out of 1 records added",
+		"/eqsl-download": "Downloaded 4 eQSLs, confirmed 1 QSOs (1 AG), 3 matched no QSO and 0 more than one and wait for review",
+	} {
+		rr := httptest.NewRecorder()
+		app.routes().ServeHTTP(rr, httptest.NewRequest(http.MethodPost, path, nil))
+		if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), want) {
+			t.Errorf("%s: want %q, got %d %q", path, want, rr.Code, rr.Body.String())
+		}
+	}
+	app.eqsluser = ""
+	rr := httptest.NewRecorder()
+	app.routes().ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/eqsl-download", nil))
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), "-eqsluser") {
+		t.Errorf("want the missing user message, got %d", rr.Code)
+	}
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -56,35 +57,177 @@ func (r *fileRead) read(fileName string) ([]byte, error) {
 var writeControl anyWrite
 var readControl anyRead
 
+// the QSL dates of a QSO that has not been confirmed are this or earlier
+var noQSL = time.Date(1970, 1, 2, 0, 0, 0, 0, time.UTC)
+
 //ADIF file format can be found at https://adif.org/312/ADIF_312.htm#QSO_Fields
 //As I enhance the program (and my skills at ham radio), this will be re-written
 
 func (app *application) genADIFFile(rows []LogsRow) error {
+	return app.writeLoTWFile(app.adifFile, rows)
+}
+
+// writes the fields of the rows LoTW takes to the file
+func (app *application) writeLoTWFile(fileName string, rows []LogsRow) error {
 	var b bytes.Buffer
-	b = writeHeader(b)
+	b = writeHeader(b, app.stationCall())
+	b = writeQSOs(b, rows)
+	l := b.Len()
+	p := make([]byte, l)
+	b.Read(p)
+
+	err := writeControl.write(fileName, p)
+	if err != nil {
+		return err
+	}
+	return nil
+}
 
+// writes the QSO records LoTW and eQSL take
+func writeQSOs(b bytes.Buffer, rows []LogsRow) bytes.Buffer {
 	for _, row := range rows {
 		b = writeDateTime(b, row.Time)
 		b.Write([]byte(fmt.Sprintf("<call:%d>%s\n", len(row.Call), row.Call)))
 		b.Write([]byte(fmt.Sprintf("<band:%d>%s\n", len(row.Band), strings.ToUpper(row.Band))))
 		mode := normalizeMode(row.Mode)
 		b.Write([]byte(fmt.Sprintf("<mode:%d>%s\n", len(mode), mode)))
+		if row.Freq != "" {
+			b.Write([]byte(fmt.Sprintf("<freq:%d>%s\n", len(row.Freq), row.Freq)))
+		}
+		if row.FreqRx != "" {
+			b.Write([]byte(fmt.Sprintf("<freq_rx:%d>%s\n", len(row.FreqRx), row.FreqRx)))
+		}
 		b.Write([]byte(fmt.Sprintf("<rst_sent:%d>%s\n", len(row.Sent), row.Sent)))
 		b.Write([]byte(fmt.Sprintf("<rst_rcvd:%d>%s\n", len(row.Rcvd), row.Rcvd)))
 		b.Write([]byte(fmt.Sprintf("<name:%d>%s\n", len(row.Name), row.Name)))
 		b.Write([]byte("<eor>\n\n"))
 	}
-	l := b.Len()
-	p := make([]byte, l)
-	b.Read(p)
+	return b
+}
+
+// writes every field the log has for the rows, for moving the log to
+// other programs.  The contest fields that ADIF has no name for go in
+// application defined fields.
+func (app *application) genFullADIFFile(fileName string, rows []LogsRow) error {
+	var b bytes.Buffer
+	b = writeHeader(b, app.stationCall())
 
-	err := writeControl.write(app.adifFile, p)
+	for _, row := range rows {
+		b = writeDateTime(b, row.Time.UTC())
+		adifField(&b, "call", row.Call)
+		adifField(&b, "band", strings.ToUpper(row.Band))
+		mode, submode := adifModeFields(row.Mode)
+		adifField(&b, "mode", mode)
+		adifField(&b, "submode", submode)
+		adifField(&b, "freq", row.Freq)
+		adifField(&b, "freq_rx", row.FreqRx)
+		adifField(&b, "rst_sent", row.Sent)
+		adifField(&b, "rst_rcvd", row.Rcvd)
+		adifField(&b, "name", row.Name)
+		adifField(&b, "country", row.Country)
+		if row.DXCC != 0 {
+			adifField(&b, "dxcc", strconv.Itoa(row.DXCC))
+		}
+		if row.CQZone != 0 {
+			adifField(&b, "cqz", strconv.Itoa(row.CQZone))
+		}
+		if row.ITUZone != 0 {
+			adifField(&b, "ituz", strconv.Itoa(row.ITUZone))
+		}
+		adifField(&b, "cont", row.Continent)
+		adifField(&b, "state", row.State)
+		if row.County != "" && row.State != "" {
+			adifField(&b, "cnty", row.State+","+row.County)
+		}
+		adifField(&b, "gridsquare", row.Grid)
+		if row.Distance != 0 {
+			adifField(&b, "distance", strconv.Itoa(row.Distance))
+		}
+		adifField(&b, "comment", row.Comment)
+		adifField(&b, "contest_id", row.ContestName)
+		adifField(&b, "stx_string", row.ExchSent)
+		adifField(&b, "srx_string", row.ExchRcvd)
+		sent := []string{row.Field1Sent, row.Field2Sent, row.Field3Sent, row.Field4Sent, row.Field5Sent}
+		rcvd := []string{row.Field1Rcvd, row.Field2Rcvd, row.Field3Rcvd, row.Field4Rcvd, row.Field5Rcvd}
+		for i := range sent {
+			adifField(&b, fmt.Sprintf("app_stationmaster_field%d_sent", i+1), sent[i])
+			adifField(&b, fmt.Sprintf("app_stationmaster_field%d_rcvd", i+1), rcvd[i])
+		}
+		adifField(&b, "lotw_qsl_sent", adifYN(row.Lotwsent))
+		adifField(&b, "lotw_qsl_rcvd", adifYN(row.Lotwrcvd))
+		if strings.EqualFold(row.Lotwrcvd, "YES") && row.LotwQSLdate.After(noQSL) {
+			adifField(&b, "lotw_qslrdate", row.LotwQSLdate.UTC().Format("20060102"))
+		}
+		adifField(&b, "eqsl_qsl_sent", adifYN(row.Eqslsent))
+		adifField(&b, "eqsl_qsl_rcvd", adifYN(row.Eqslrcvd))
+		if strings.EqualFold(row.Eqslrcvd, "YES") {
+			if row.EqslQSLdate.After(noQSL) {
+				adifField(&b, "eqsl_qslrdate", row.EqslQSLdate.UTC().Format("20060102"))
+			}
+			ag := "N"
+			if row.EqslAG {
+				ag = "Y"
+			}
+			adifField(&b, "eqsl_ag", ag)
+		}
+		switch {
+		case strings.EqualFold(row.QSLsent, "YES"):
+			adifField(&b, "qsl_sent", "Y")
+			if row.QSLsdate.After(noQSL) {
+				adifField(&b, "qslsdate", row.QSLsdate.UTC().Format("20060102"))
+			}
+		case strings.EqualFold(row.QSLsent, cardQueued):
+			adifField(&b, "qsl_sent", "Q")
+		}
+		adifField(&b, "qsl_sent_via", routeVia(row.QSLsentVia))
+		if strings.EqualFold(row.QSLrcvd, "YES") {
+			adifField(&b, "qsl_rcvd", "Y")
+			if row.QSLrdate.After(noQSL) {
+				adifField(&b, "qslrdate", row.QSLrdate.UTC().Format("20060102"))
+			}
+		}
+		adifField(&b, "qsl_rcvd_via", routeVia(row.QSLrcvdVia))
+		if strings.EqualFold(row.Clublogsent, "YES") {
+			adifField(&b, "clublog_qso_upload_status", "Y")
+			adifField(&b, "clublog_qso_upload_date", row.ClublogDate.UTC().Format("20060102"))
+		}
+		b.Write([]byte("<eor>\n\n"))
+	}
+
+	err := writeControl.write(fileName, b.Bytes())
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
+// writes one field, empty ones are left out
+func adifField(b *bytes.Buffer, name, value string) {
+	if value == "" {
+		return
+	}
+	b.Write([]byte(fmt.Sprintf("<%s:%d>%s\n", name, len(value), value)))
+}
+
+func adifYN(s string) string {
+	if strings.EqualFold(s, "YES") {
+		return "Y"
+	}
+	return "N"
+}
+
+// the log keeps the sidebands and the digital submodes as the mode, ADIF
+// wants them as a submode of SSB and MFSK
+func adifModeFields(mode string) (string, string) {
+	switch strings.ToUpper(mode) {
+	case "USB", "LSB":
+		return "SSB", strings.ToUpper(mode)
+	case "FT4", "JS8", "Q65":
+		return "MFSK", strings.ToUpper(mode)
+	}
+	return strings.ToUpper(mode), ""
+}
+
 func cookTime(t time.Time) (string, string) {
 	tt := fmt.Sprintf("%v", t)
 	times := strings.Split(tt, " ")
@@ -92,18 +235,19 @@ func cookTime(t time.Time) (string, string) {
 	return times[0], ztimes[0] + "Z"
 }
 
-func topLine() string {
+func topLine(call string) string {
 	t := time.Now()
 	dt, tt := cookTime(t)
-	return fmt.Sprintf("Generated on %s at %s for %s\n", dt, tt, myCall)
+	return fmt.Sprintf("Generated on %s at %s for %s\n", dt, tt, call)
 }
 
-func writeHeader(b bytes.Buffer) bytes.Buffer {
-	line := topLine()
+// call is the call sign of the active station profile
+func writeHeader(b bytes.Buffer, call string) bytes.Buffer {
+	line := topLine(call)
 	b.Write([]byte(line))
 	b.Write([]byte("\n"))
 	b.Write([]byte("<adif_ver:5>3.0.5\n"))
-	programID := myCall + " Stationmaster"
+	programID := call + " Stationmaster"
 	b.Write([]byte(fmt.Sprintf("<programid:%d>%s\n", len(programID), programID)))
 	// userDef := "AD2CC stationmaster:github.com/Saied74/stationmaster"
 	// b.Write([]byte(fmt.Sprintf("<USERDEF1:%d:S>%s\n", len(userDef), userDef)))
@@ -187,7 +331,6 @@ const eof = -1
 var printSeq = []itemType{itemCall, itemBand, itemMode, itemQSOTimeStamp, itemQSLrcvd, itemRxQSO, itemRxQSL}
 
 func (app *application) getQSLData(fileName string) ([]map[itemType]string, error) {
-	output := []map[itemType]string{}
 	var records []byte
 	var err error
 	fileName = filepath.Join(app.qslDir, fileName)
@@ -196,8 +339,13 @@ func (app *application) getQSLData(fileName string) ([]map[itemType]string, erro
 	if err != nil {
 		return []map[itemType]string{}, err
 	}
+	return parseQSLData(string(records))
+}
 
-	_, c := lex("adif", string(records))
+// the confirmations of a LoTW report
+func parseQSLData(records string) ([]map[itemType]string, error) {
+	output := []map[itemType]string{}
+	_, c := lex("adif", records)
 	var b bool
 	row := map[itemType]string{}
 	for {
@@ -210,7 +358,7 @@ func (app *application) getQSLData(fileName string) ([]map[itemType]string, erro
 			b = true
 			break
 		case itemError:
-			reportError(d)
+			return nil, reportError(d)
 		default:
 			row[d.typ] = d.val
 		}
@@ -218,13 +366,14 @@ func (app *application) getQSLData(fileName string) ([]map[itemType]string, erro
 			break
 		}
 	}
-
 	return output, nil
 }
 
-func reportError(d item) {
-	fmt.Printf("Error reported from the channel %v \n", d)
-	os.Exit(1)
+var errQSLReport = errors.New("bad LoTW report")
+
+// the lexer stops at a field length that is not a number
+func reportError(d item) error {
+	return fmt.Errorf("%w: %q is not a field length", errQSLReport, d.val)
 }
 
 func timeIt(s string) (time.Time, error) {
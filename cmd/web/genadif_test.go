@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"testing"
+	"time"
 )
 
 // <<<--------------------   Generating ADIF file     ---------------------->>>
@@ -124,6 +125,55 @@ func TestGenADIFFile(t *testing.T) {
 	}
 }
 
+func TestGenFullADIFFile(t *testing.T) {
+	rows := []LogsRow{
+		{Time: time.Date(2023, 4, 15, 14, 15, 0, 0, time.UTC), Call: "AA7BQ",
+			Band: "20m", Mode: "USB", Sent: "59", Rcvd: "57", Name: "Fred",
+			Country: "United States", State: "AZ", County: "Maricopa",
+			Grid: "DM32af", Comment: "nice signal", Lotwsent: "YES", Lotwrcvd: "YES",
+			Freq: "14.250000", FreqRx: "14.255000",
+			LotwQSLdate: time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC)},
+		{Time: time.Date(2023, 4, 16, 1, 2, 0, 0, time.UTC), Call: "K1XX",
+			Band: "40m", Mode: "FT4", ContestName: "CWOPS", ExchSent: "NJ",
+			ExchRcvd: "MA", Field1Sent: "Saied", Field1Rcvd: "Joe"},
+	}
+	writeControl = &mockWrite{}
+	app := &application{}
+	err := app.genFullADIFFile("full.adi", rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testBuffer, _ := writeControl.read("full.adi")
+	for _, item := range []string{"<mode:3>SSB", "<submode:3>USB", "<state:2>AZ",
+		"<cnty:11>AZ,Maricopa", "<gridsquare:6>DM32af", "<lotw_qsl_rcvd:1>Y",
+		"<lotw_qslrdate:8>20230501", "<submode:3>FT4", "<contest_id:5>CWOPS",
+		"<srx_string:2>MA", "<app_stationmaster_field1_sent:5>Saied",
+		"<freq:9>14.250000", "<freq_rx:9>14.255000"} {
+		if !bytes.Contains(testBuffer, []byte(item)) {
+			t.Errorf("ADIF file did not contain %s", item)
+		}
+	}
+
+	//and it reads back in
+	records, err := parseADIF(string(testBuffer))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("want 2 records, got %d", len(records))
+	}
+	for i, r := range records {
+		l, err := adifToLog(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if l.Call != rows[i].Call || l.Mode != rows[i].Mode || !l.Time.Equal(rows[i].Time) ||
+			l.Field1Rcvd != rows[i].Field1Rcvd || l.Freq != rows[i].Freq || l.FreqRx != rows[i].FreqRx {
+			t.Errorf("want %v back, got %v", rows[i], *l)
+		}
+	}
+}
+
 // <<<------------------   Testing parsing ADIF file  ---------------------->>>
 type genPat struct {
 	name  string
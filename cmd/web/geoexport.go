@@ -0,0 +1,244 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//<<===================== GeoJSON and KML map exports =====================>>
+
+//The map exports put the QSOs the search filters select on a map, one
+//point per QSO.  The point is the QRZ latitude and longitude of the call,
+//or the centre of the grid square when the QSO has a six character grid
+//(WSJT-X sends where the station really is) or QRZ has no position.
+//Portable calls are not at the QRZ address so only their grid is used.
+//QSOs with neither are left out.  The points are coloured by band or by
+//whether LOTW confirmed the QSO.
+
+const (
+	mapByBand      = "band"
+	mapByConfirmed = "confirmed"
+)
+
+// marker colours by band, from the bottom of the spectrum up
+var mapBandColors = map[string]string{
+	"160m": "#800000", "80m": "#e6194b", "60m": "#f58231", "40m": "#ffe119",
+	"30m": "#bfef45", "20m": "#3cb44b", "17m": "#42d4f4", "15m": "#4363d8",
+	"12m": "#911eb4", "10m": "#f032e6", "6m": "#a9a9a9", "2m": "#000075",
+}
+
+const (
+	mapOtherColor       = "#808080"
+	mapConfirmedColor   = "#3cb44b"
+	mapUnconfirmedColor = "#e6194b"
+)
+
+// mapPoint is a QSO with the place of the other station
+type mapPoint struct {
+	LogsRow
+	Lat float64
+	Lon float64
+}
+
+// the style name and colour of a point
+func (p *mapPoint) style(by string) (string, string) {
+	if by == mapByConfirmed {
+		if p.Lotwrcvd == "YES" {
+			return "confirmed", mapConfirmedColor
+		}
+		return "unconfirmed", mapUnconfirmedColor
+	}
+	if c, ok := mapBandColors[p.Band]; ok {
+		return p.Band, c
+	}
+	return "other", mapOtherColor
+}
+
+// the QSOs the filter selects that have a place, and how many do not
+func (app *application) mapPoints(lf *logFilter) ([]mapPoint, int, error) {
+	rows, err := app.logsModel.getExportData(lf)
+	if err != nil {
+		return nil, 0, err
+	}
+	qrz := map[string]*Ctype{}
+	points := []mapPoint{}
+	missing := 0
+	for _, l := range rows {
+		lat, lon, ok := gridLatLon(l.Grid)
+		if len(strings.TrimSpace(l.Grid)) < 6 && !strings.Contains(l.Call, "/") {
+			c, seen := qrz[l.Call]
+			if !seen {
+				c, err = app.qrzModel.getQRZ(l.Call)
+				if err != nil && !errors.Is(err, errNoRecord) {
+					return nil, 0, err
+				}
+				qrz[l.Call] = c
+			}
+			if qLat, qLon, qOK := qrzLatLon(c); qOK {
+				lat, lon, ok = qLat, qLon, true
+			}
+		}
+		if !ok {
+			missing++
+			continue
+		}
+		points = append(points, mapPoint{LogsRow: l, Lat: lat, Lon: lon})
+	}
+	return points, missing, nil
+}
+
+// the latitude and longitude of a QRZ record, false when it has none
+func qrzLatLon(c *Ctype) (float64, float64, bool) {
+	if c == nil {
+		return 0, 0, false
+	}
+	lat, err := strconv.ParseFloat(strings.TrimSpace(c.Lat), 64)
+	if err != nil || lat < -90 || lat > 90 {
+		return 0, 0, false
+	}
+	lon, err := strconv.ParseFloat(strings.TrimSpace(c.Long), 64)
+	if err != nil || lon < -180 || lon > 180 || lat == 0 && lon == 0 {
+		return 0, 0, false
+	}
+	return lat, lon, true
+}
+
+//<-------------------------------- GeoJSON -------------------------------->
+
+type geoFeatureCollection struct {
+	Type     string       `json:"type"`
+	Features []geoFeature `json:"features"`
+}
+
+type geoFeature struct {
+	Type       string                 `json:"type"`
+	Geometry   geoPoint               `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+type geoPoint struct {
+	Type        string     `json:"type"`
+	Coordinates [2]float64 `json:"coordinates"` //longitude first
+}
+
+// the points as a GeoJSON feature collection, with the simplestyle
+// marker-color most map viewers understand
+func geoJSON(points []mapPoint, by string) *geoFeatureCollection {
+	fc := &geoFeatureCollection{Type: "FeatureCollection", Features: []geoFeature{}}
+	for _, p := range points {
+		style, color := p.style(by)
+		fc.Features = append(fc.Features, geoFeature{
+			Type:     "Feature",
+			Geometry: geoPoint{Type: "Point", Coordinates: [2]float64{p.Lon, p.Lat}},
+			Properties: map[string]interface{}{
+				"id":           p.Id,
+				"call":         p.Call,
+				"time":         p.Time.UTC().Format("2006-01-02T15:04:05Z"),
+				"band":         p.Band,
+				"mode":         p.Mode,
+				"country":      p.Country,
+				"grid":         p.Grid,
+				"distance":     p.Distance,
+				"confirmed":    p.Lotwrcvd == "YES",
+				"style":        style,
+				"marker-color": color,
+			},
+		})
+	}
+	return fc
+}
+
+func writeGeoJSON(w io.Writer, points []mapPoint, by string) error {
+	return json.NewEncoder(w).Encode(geoJSON(points, by))
+}
+
+//<---------------------------------- KML ---------------------------------->
+
+type kmlDoc struct {
+	XMLName  xml.Name    `xml:"kml"`
+	NS       string      `xml:"xmlns,attr"`
+	Document kmlDocument `xml:"Document"`
+}
+
+type kmlDocument struct {
+	XMLName xml.Name    `xml:"Document"`
+	Name    string      `xml:"name"`
+	Styles  []kmlStyle  `xml:"Style"`
+	Folders []kmlFolder `xml:"Folder"`
+}
+
+type kmlStyle struct {
+	XMLName xml.Name `xml:"Style"`
+	Id      string   `xml:"id,attr"`
+	Color   string   `xml:"IconStyle>color"`
+}
+
+type kmlFolder struct {
+	XMLName    xml.Name       `xml:"Folder"`
+	Name       string         `xml:"name"`
+	Placemarks []kmlPlacemark `xml:"Placemark"`
+}
+
+type kmlPlacemark struct {
+	XMLName     xml.Name `xml:"Placemark"`
+	Name        string   `xml:"name"`
+	Description string   `xml:"description"`
+	StyleURL    string   `xml:"styleUrl"`
+	Coordinates string   `xml:"Point>coordinates"`
+}
+
+// writes the points as KML with a folder and a style for each band or
+// confirmation status
+func writeKML(w io.Writer, name string, points []mapPoint, by string) error {
+	doc := kmlDocument{Name: name}
+	folders := map[string]*kmlFolder{}
+	for _, p := range points {
+		style, color := p.style(by)
+		f, ok := folders[style]
+		if !ok {
+			f = &kmlFolder{Name: style}
+			folders[style] = f
+			doc.Styles = append(doc.Styles, kmlStyle{Id: style, Color: kmlColor(color)})
+		}
+		f.Placemarks = append(f.Placemarks, kmlPlacemark{
+			Name: p.Call,
+			Description: fmt.Sprintf("%s %s %s %s", p.Time.UTC().Format("2006-01-02 15:04"),
+				p.Band, p.Mode, p.Country),
+			StyleURL:    "#" + style,
+			Coordinates: fmt.Sprintf("%.5f,%.5f", p.Lon, p.Lat),
+		})
+	}
+	//bands from the lowest, the rest by name
+	sort.Slice(doc.Styles, func(i, j int) bool {
+		a, b := bandOrder(doc.Styles[i].Id), bandOrder(doc.Styles[j].Id)
+		if a != b {
+			return a < b
+		}
+		return doc.Styles[i].Id < doc.Styles[j].Id
+	})
+	for _, s := range doc.Styles {
+		doc.Folders = append(doc.Folders, *folders[s.Id])
+	}
+	_, err := io.WriteString(w, xml.Header)
+	if err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	return enc.Encode(&kmlDoc{NS: "http://www.opengis.net/kml/2.2", Document: doc})
+}
+
+// KML colours are alpha, blue, green and red
+func kmlColor(rgb string) string {
+	c := strings.TrimPrefix(rgb, "#")
+	if len(c) != 6 {
+		return "ff808080"
+	}
+	return "ff" + c[4:6] + c[2:4] + c[0:2]
+}
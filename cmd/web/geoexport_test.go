@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+// a log with QSOs placed each way the map export places them
+func newTestMapApp(t *testing.T) *application {
+	app := newTestSQLiteApp(t)
+	err := app.qrzModel.insertQRZ(&Ctype{Call: "DL1ABC", Lat: "52.52", Long: "13.40",
+		Grid: "JO62", Country: "Germany"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	qso := time.Date(2023, 4, 15, 14, 15, 0, 0, time.UTC)
+	for i, l := range []LogsRow{
+		{Call: "DL1ABC", Band: "20m", Mode: "CW", Grid: "JO62", Lotwrcvd: "YES"},
+		{Call: "W1AW", Band: "40m", Mode: "FT8", Grid: "FN31pr"},
+		{Call: "EA8/DL1ABC", Band: "20m", Mode: "SSB"},
+		{Call: "K1ABC", Band: "15m", Mode: "CW"},
+		{Call: "VK2ABC", Band: "4m", Mode: "FT8", Grid: "QF56"},
+	} {
+		l.Time = qso.Add(time.Duration(i) * time.Minute)
+		_, err := app.logsModel.importLog(&l, sourceImport)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return app
+}
+
+func TestMapPoints(t *testing.T) {
+	app := newTestMapApp(t)
+	points, missing, err := app.mapPoints(&logFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if missing != 2 {
+		t.Errorf("want 2 QSOs without a place, got %d", missing)
+	}
+	want := map[string][2]float64{
+		"DL1ABC": {52.52, 13.40},
+		"W1AW":   {41.7292, -72.7083},
+		"VK2ABC": {-33.5, 151},
+	}
+	if len(points) != len(want) {
+		t.Fatalf("want %d points, got %d", len(want), len(points))
+	}
+	for _, p := range points {
+		w, ok := want[p.Call]
+		if !ok || math.Abs(p.Lat-w[0]) > 0.001 || math.Abs(p.Lon-w[1]) > 0.001 {
+			t.Errorf("%s: want %v, got %v %v", p.Call, w, p.Lat, p.Lon)
+		}
+	}
+
+	points, _, err = app.mapPoints(&logFilter{Band: "20m"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(points) != 1 || points[0].Call != "DL1ABC" {
+		t.Errorf("want the 20m point, got %v", points)
+	}
+}
+
+func TestWriteGeoJSON(t *testing.T) {
+	app := newTestMapApp(t)
+	points, _, err := app.mapPoints(&logFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		by    string
+		style []string
+		color string
+	}{
+		{mapByBand, []string{"20m", "40m", "other"}, "#3cb44b"},
+		{mapByConfirmed, []string{"confirmed", "unconfirmed", "unconfirmed"}, mapConfirmedColor},
+	}
+	for _, tt := range tests {
+		t.Run(tt.by, func(t *testing.T) {
+			b := &bytes.Buffer{}
+			err := writeGeoJSON(b, points, tt.by)
+			if err != nil {
+				t.Fatal(err)
+			}
+			fc := geoFeatureCollection{}
+			err = json.Unmarshal(b.Bytes(), &fc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if fc.Type != "FeatureCollection" || len(fc.Features) != 3 {
+				t.Fatalf("want 3 features, got %v", fc)
+			}
+			for i, f := range fc.Features {
+				if f.Properties["style"] != tt.style[i] {
+					t.Errorf("feature %d: want style %s, got %v", i, tt.style[i], f.Properties["style"])
+				}
+			}
+			f := fc.Features[0]
+			if f.Geometry.Coordinates != [2]float64{13.40, 52.52} ||
+				f.Properties["call"] != "DL1ABC" || f.Properties["marker-color"] != tt.color {
+				t.Errorf("want DL1ABC at 13.40 52.52 in %s, got %v", tt.color, f)
+			}
+		})
+	}
+}
+
+func TestWriteKML(t *testing.T) {
+	app := newTestMapApp(t)
+	points, _, err := app.mapPoints(&logFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := &bytes.Buffer{}
+	err = writeKML(b, "test", points, mapByBand)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(b.String(), "<?xml") {
+		t.Errorf("want an XML header, got %q", b.String()[:20])
+	}
+	doc := kmlDoc{}
+	err = xml.Unmarshal(b.Bytes(), &doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	folders := []string{}
+	for _, f := range doc.Document.Folders {
+		folders = append(folders, f.Name)
+	}
+	if got := strings.Join(folders, ","); got != "40m,20m,other" {
+		t.Errorf("want the folders 40m,20m,other, got %s", got)
+	}
+	if len(doc.Document.Styles) != 3 || doc.Document.Styles[1].Color != "ff4bb43c" {
+		t.Errorf("want the 20m style in ff4bb43c, got %v", doc.Document.Styles)
+	}
+	p := doc.Document.Folders[1].Placemarks[0]
+	if p.Name != "DL1ABC" || p.StyleURL != "#20m" || p.Coordinates != "13.40000,52.52000" {
+		t.Errorf("want the DL1ABC placemark, got %v", p)
+	}
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//<<===================== Grid squares, distance and heading =====================>>
+
+//The station grid is the grid of the active station profile.  The other
+//end is the grid of the QSO (WSJT-X sends it, QRZ has it) or the latitude
+//and longitude of the QRZ lookup.  Distances are great circle distances on
+//a sphere, which is within half a percent of the real thing.
+
+const earthRadius = 6371.0 //km
+
+// qsoPath is the way to the other station
+type qsoPath struct {
+	Km     int
+	Miles  int
+	Short  int //short path beam heading, degrees from true north
+	Long   int //long path beam heading
+	LongKm int
+}
+
+// the latitude and longitude of the centre of a grid square of 2, 4, 6
+// or 8 characters
+func gridLatLon(grid string) (float64, float64, bool) {
+	g := strings.ToUpper(strings.TrimSpace(grid))
+	if len(g) < 2 || len(g) > 8 || len(g)%2 != 0 {
+		return 0, 0, false
+	}
+	lon, lat := -180.0, -90.0
+	lonSize, latSize := 360.0, 180.0
+	for i := 0; i < len(g); i += 2 {
+		var base, n byte
+		switch i {
+		case 0:
+			base, n = 'A', 18
+		case 4:
+			base, n = 'A', 24
+		default:
+			base, n = '0', 10
+		}
+		x, y := g[i]-base, g[i+1]-base
+		if g[i] < base || g[i+1] < base || x >= n || y >= n {
+			return 0, 0, false
+		}
+		lonSize /= float64(n)
+		latSize /= float64(n)
+		lon += float64(x) * lonSize
+		lat += float64(y) * latSize
+	}
+	return lat + latSize/2, lon + lonSize/2, true
+}
+
+// the great circle path from one point to another, in degrees
+func pathBetween(lat1, lon1, lat2, lon2 float64) qsoPath {
+	rad := math.Pi / 180
+	lat1, lat2 = lat1*rad, lat2*rad
+	dLon := (lon2 - lon1) * rad
+	a := math.Pow(math.Sin((lat2-lat1)/2), 2) +
+		math.Cos(lat1)*math.Cos(lat2)*math.Pow(math.Sin(dLon/2), 2)
+	km := 2 * earthRadius * math.Asin(math.Min(1, math.Sqrt(a)))
+	y := math.Sin(dLon) * math.Cos(lat2)
+	x := math.Cos(lat1)*math.Sin(lat2) - math.Sin(lat1)*math.Cos(lat2)*math.Cos(dLon)
+	short := math.Mod(math.Atan2(y, x)/rad+360, 360)
+	p := qsoPath{
+		Km:     int(math.Round(km)),
+		Miles:  int(math.Round(km / 1.609344)),
+		Short:  int(math.Round(short)) % 360,
+		LongKm: int(math.Round(2*math.Pi*earthRadius - km)),
+	}
+	p.Long = (p.Short + 180) % 360
+	return p
+}
+
+func (p *qsoPath) String() string {
+	return fmt.Sprintf("%d km (%d mi), short path %d°, long path %d° (%d km)",
+		p.Km, p.Miles, p.Short, p.Long, p.LongKm)
+}
+
+// the station grid, false when the active profile does not have one
+func (app *application) myLatLon() (float64, float64, bool) {
+	p, err := app.activeProfile()
+	if err != nil {
+		app.errorLog.Printf("no station grid: %v", err)
+		return 0, 0, false
+	}
+	return gridLatLon(p.Grid)
+}
+
+// the path to a grid square, nil when either grid is missing
+func (app *application) pathToGrid(grid string) *qsoPath {
+	lat, lon, ok := gridLatLon(grid)
+	if !ok {
+		return nil
+	}
+	return app.pathTo(lat, lon)
+}
+
+func (app *application) pathTo(lat, lon float64) *qsoPath {
+	myLat, myLon, ok := app.myLatLon()
+	if !ok {
+		return nil
+	}
+	p := pathBetween(myLat, myLon, lat, lon)
+	return &p
+}
+
+// the path to the station of a QRZ lookup, from its latitude and
+// longitude or else its grid
+func (app *application) pathToQRZ(c *Ctype) *qsoPath {
+	lat, err1 := strconv.ParseFloat(strings.TrimSpace(c.Lat), 64)
+	lon, err2 := strconv.ParseFloat(strings.TrimSpace(c.Long), 64)
+	if err1 == nil && err2 == nil && (lat != 0 || lon != 0) {
+		return app.pathTo(lat, lon)
+	}
+	return app.pathToGrid(c.Grid)
+}
+
+// the standard time at the station of a QRZ lookup, from its UTC offset or
+// else from its longitude.  QRZ does not say when daylight saving time is
+// on so it is left out.
+func qrzLocalTime(c *Ctype, now time.Time) string {
+	offset, err := strconv.ParseFloat(strings.TrimSpace(c.GMTOffset), 64)
+	if err != nil {
+		lon, err := strconv.ParseFloat(strings.TrimSpace(c.Long), 64)
+		if err != nil {
+			var ok bool
+			if _, lon, ok = gridLatLon(c.Grid); !ok {
+				return ""
+			}
+		}
+		offset = math.Round(lon / 15)
+	}
+	t := now.UTC().Add(time.Duration(offset * float64(time.Hour)))
+	return fmt.Sprintf("%s (UTC%+g, standard time)", t.Format("Mon 15:04"), offset)
+}
+
+// sets the distance of a QSO that has a grid and does not have one
+func (app *application) fillDistance(l *LogsRow) {
+	if l.Distance != 0 {
+		return
+	}
+	if p := app.pathToGrid(l.Grid); p != nil {
+		l.Distance = p.Km
+	}
+}
+
+// the longest QSO of each band and mode, by band from the lowest and then
+// by mode
+func (app *application) longestQSOs() ([]LogsRow, error) {
+	rows, err := app.logsModel.getExportData(&logFilter{})
+	if err != nil {
+		return nil, err
+	}
+	best := map[string]LogsRow{}
+	for _, l := range rows {
+		if l.Distance == 0 {
+			continue
+		}
+		key := l.Band + " " + l.Mode
+		if b, ok := best[key]; !ok || l.Distance > b.Distance {
+			best[key] = l
+		}
+	}
+	t := []LogsRow{}
+	for _, l := range best {
+		t = append(t, l)
+	}
+	sort.Slice(t, func(i, j int) bool {
+		if t[i].Band != t[j].Band {
+			return bandOrder(t[i].Band) < bandOrder(t[j].Band)
+		}
+		return t[i].Mode < t[j].Mode
+	})
+	return t, nil
+}
+
+// the lower edge of a band in MHz, anything that is not a band last
+func bandOrder(band string) float64 {
+	for _, b := range adifBands {
+		if b.name == band {
+			return b.lower
+		}
+	}
+	return math.MaxFloat64
+}
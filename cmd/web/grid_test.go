@@ -0,0 +1,142 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGridLatLon(t *testing.T) {
+	tests := []struct {
+		grid string
+		lat  float64
+		lon  float64
+		ok   bool
+	}{
+		{"FN20", 40.5, -75, true},
+		{"fn20", 40.5, -75, true},
+		{"JO62", 52.5, 13, true},
+		{"FN", 45, -70, true},
+		{"FN20qr", 40.7292, -74.625, true},
+		{"FN20qr55", 40.7313, -74.6208, true},
+		{"FN2", 0, 0, false},
+		{"SN20", 0, 0, false},
+		{"FNA0", 0, 0, false},
+		{"FN20zz", 0, 0, false},
+		{"", 0, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.grid, func(t *testing.T) {
+			lat, lon, ok := gridLatLon(tt.grid)
+			if ok != tt.ok || math.Abs(lat-tt.lat) > 0.001 || math.Abs(lon-tt.lon) > 0.001 {
+				t.Errorf("want %v %v %v, got %v %v %v", tt.lat, tt.lon, tt.ok, lat, lon, ok)
+			}
+		})
+	}
+}
+
+func TestPathBetween(t *testing.T) {
+	tests := []struct {
+		name                   string
+		lat1, lon1, lat2, lon2 float64
+		want                   qsoPath
+	}{
+		{"FN20 to JO62", 40.5, -75, 52.5, 13, qsoPath{6438, 4001, 46, 226, 33592}},
+		{"JO62 to FN20", 52.5, 13, 40.5, -75, qsoPath{6438, 4001, 296, 116, 33592}},
+		{"along the equator", 0, 0, 0, 90, qsoPath{10008, 6218, 90, 270, 30023}},
+		{"FN20 to Sydney", 40.5, -75, -33.5, 151, qsoPath{15904, 9882, 266, 86, 24126}},
+		{"due north", 0, 0, 45, 0, qsoPath{5004, 3109, 0, 180, 35026}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := pathBetween(tt.lat1, tt.lon1, tt.lat2, tt.lon2); got != tt.want {
+				t.Errorf("want %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestQRZLocalTime(t *testing.T) {
+	now := time.Date(2023, 4, 15, 14, 15, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		c    Ctype
+		want string
+	}{
+		{"offset", Ctype{GMTOffset: "-5", Long: "13"}, "Sat 09:15 (UTC-5, standard time)"},
+		{"half hour", Ctype{GMTOffset: "5.5"}, "Sat 19:45 (UTC+5.5, standard time)"},
+		{"longitude", Ctype{Long: "151.2"}, "Sun 00:15 (UTC+10, standard time)"},
+		{"grid", Ctype{Grid: "JO62"}, "Sat 15:15 (UTC+1, standard time)"},
+		{"nothing", Ctype{}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := qrzLocalTime(&tt.c, now); got != tt.want {
+				t.Errorf("want %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestLongestQSOs(t *testing.T) {
+	app := newTestSQLiteApp(t)
+	qso := time.Date(2023, 4, 15, 14, 15, 0, 0, time.UTC)
+	for i, l := range []LogsRow{
+		{Call: "DL1ABC", Band: "20m", Mode: "CW", Grid: "JO62"},
+		{Call: "VK2ABC", Band: "20m", Mode: "CW", Grid: "QF56"},
+		{Call: "G3ABC", Band: "40m", Mode: "CW", Grid: "IO91"},
+		{Call: "W1AW", Band: "20m", Mode: "SSB", Distance: 150},
+		{Call: "K2ABC", Band: "20m", Mode: "SSB"},
+		{Call: "W2ABC", Band: "2m", Mode: "FM", Grid: "FN31"},
+		{Call: "N1ABC", Band: "60m", Mode: "CW", Grid: "FN42"},
+	} {
+		l.Time = qso.Add(time.Duration(i) * time.Minute)
+		_, err := app.logsModel.importLog(&l, sourceImport)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	n, err := app.backfillLocations()
+	if err != nil || n != 0 {
+		t.Fatalf("without a station grid want no changes, got %d %v", n, err)
+	}
+	p, err := app.activeProfile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Grid = "FN20"
+	err = app.profileModel.updateProfile(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err = app.backfillLocations()
+	if err != nil || n != 5 {
+		t.Fatalf("want 5 distances filled in, got %d %v", n, err)
+	}
+
+	rows, err := app.longestQSOs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := []string{}
+	for _, r := range rows {
+		got = append(got, r.Band+" "+r.Mode+" "+r.Call)
+	}
+	want := []string{"60m CW N1ABC", "40m CW G3ABC", "20m CW VK2ABC", "20m SSB W1AW", "2m FM W2ABC"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("want %v, got %v", want, got)
+	}
+	if rows[2].Distance < 15000 || rows[2].Distance > 17000 {
+		t.Errorf("want about 16000 km to QF56, got %d", rows[2].Distance)
+	}
+
+	w := httptest.NewRecorder()
+	app.routes().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/longest", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "VK2ABC") {
+		t.Errorf("want the longest page with VK2ABC, got %d", w.Code)
+	}
+}
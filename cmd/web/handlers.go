@@ -224,7 +224,7 @@ func (app *application) startVFO(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	dx, err = app.logsModel.findNeed(dx)
+	dx, err = app.findNeed(dx)
 	if err != nil {
 		app.serverError(w, err)
 		//app.render(w, r, "vfo.page.html", td)
@@ -270,6 +270,21 @@ func (app *application) updateVFO(w http.ResponseWriter, r *http.Request) {
 		app.serverError(w, err)
 		return
 	}
+	//the frequencies the next QSO is logged with
+	err = app.otherModel.updateDefault("freq", v.XFreq)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	rx := ""
+	if v.Split == "Split: On" {
+		rx = v.RFreq
+	}
+	err = app.otherModel.updateDefault("freqrx", rx)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
 	vfoSet := vfoMemory[band]
 	lowerLimit, err := strconv.ParseFloat(vfoSet.LowerLimit, 64)
 	if err != nil {
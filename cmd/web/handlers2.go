@@ -3,9 +3,11 @@ package main
 import (
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"path/filepath"
 	"strconv"
+	"time"
 )
 
 func (app *application) adif(w http.ResponseWriter, r *http.Request) {
@@ -16,7 +18,7 @@ func (app *application) adif(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	td.Table = t
-	app.render(w, r, "adif.page.html", td)
+	app.renderADIF(w, r, td)
 }
 
 func (app *application) genadif(w http.ResponseWriter, r *http.Request) {
@@ -26,24 +28,110 @@ func (app *application) genadif(w http.ResponseWriter, r *http.Request) {
 		app.serverError(w, err)
 		return
 	}
+	//the QSOs are marked sent when TQSL uploads them, not when the file
+	//is written
 	err = app.genADIFFile(t)
 	if err != nil {
 		app.serverError(w, err)
 		return
 	}
-	for _, row := range t {
-		app.logsModel.updateLOTWSent(row.Id)
+	td.Table = t
+	td.Message = fmt.Sprintf("%d QSOs written to %s", len(t), app.adifFile)
+	app.renderADIF(w, r, td)
+}
+
+// sent batches listed on the ADIF page
+const batchesShown = 10
+
+// the ADIF page with the latest LoTW upload batches
+func (app *application) renderADIF(w http.ResponseWriter, r *http.Request, td *templateData) {
+	var err error
+	td.Batches, err = app.uploadModel.getBatches(batchesShown)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	td.QRZQueue, err = app.qrzLogModel.queuedQRZ()
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	app.render(w, r, "adif.page.html", td)
+}
+
+// writes the QSOs picked by the filter with all their fields to an ADIF
+// file in the QSL directory
+func (app *application) exportADIF(w http.ResponseWriter, r *http.Request) {
+	td := initTemplateData()
+	err := r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	f := newForm(r.PostForm)
+	f.required("exportfile")
+	f.fileExtCheck("exportfile", "adi", "adif")
+	lf := f.logFilter()
+	if !f.valid() {
+		td.FormData = f
+		td.Table, err = app.logsModel.getADIFData()
 		if err != nil {
 			app.serverError(w, err)
+			return
 		}
+		app.renderADIF(w, r, td)
+		return
 	}
-	t, err = app.logsModel.getADIFData()
+	rows, err := app.logsModel.getExportData(lf)
 	if err != nil {
 		app.serverError(w, err)
 		return
 	}
-	td.Table = t
-	app.render(w, r, "adif.page.html", td)
+	fileName := filepath.Join(app.qslDir, f.Get("exportfile"))
+	err = app.genFullADIFFile(fileName, rows)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	td.FormData = f
+	td.Table = rows
+	td.Message = fmt.Sprintf("%d QSOs written to %s", len(rows), fileName)
+	app.renderADIF(w, r, td)
+}
+
+func (app *application) adifImport(w http.ResponseWriter, r *http.Request) {
+	td := initTemplateData()
+	app.render(w, r, "adifimport.page.html", td)
+}
+
+// imports an ADIF file from another logger and shows what was added,
+// skipped as a dupe and rejected
+func (app *application) adifImportFile(w http.ResponseWriter, r *http.Request) {
+	td := initTemplateData()
+	err := r.ParseMultipartForm(32 << 20)
+	if err != nil {
+		app.errorLog.Println(err)
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	file, header, err := r.FormFile("adiffile")
+	if err != nil {
+		td.Message = "Please pick an ADIF file to import"
+		app.render(w, r, "adifimport.page.html", td)
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	sum, err := app.importADIF(header.Filename, string(data))
+	td.Import = sum
+	if err != nil {
+		td.Message = fmt.Sprintf("Import of %s stopped: %v", header.Filename, err)
+	}
+	app.render(w, r, "adifimport.page.html", td)
 }
 
 func (app *application) confirmQSLs(w http.ResponseWriter, r *http.Request) {
@@ -56,20 +144,25 @@ func (app *application) confirmQSLs(w http.ResponseWriter, r *http.Request) {
 	}
 	qslFile := r.PostForm.Get("qslfile")
 
+	td := initTemplateData()
 	output, err := app.getQSLData(qslFile)
 	if err != nil {
 		app.clientError(w, http.StatusBadRequest)
 		return
 	}
-	for _, row := range output {
-		err = app.logsModel.updateQSO(row)
-		if err != nil {
-			app.serverError(w, err)
-			return
+	m, err := app.matchQSLs(output)
+	switch {
+	case errors.Is(err, errQSLReport):
+		td.Message = err.Error()
+	case err != nil:
+		app.serverError(w, err)
+		return
+	default:
+		td.Message = fmt.Sprintf("Confirmed %d QSOs", m.Confirmed)
+		if note := m.String(); note != "" {
+			td.Message += ", " + note
 		}
 	}
-
-	td := initTemplateData()
 	td.Logger = true
 	td.Table, err = app.logsModel.getLatestLogs(app.displayLines)
 	if err != nil {
@@ -91,6 +184,212 @@ func (app *application) confirmQSLs(w http.ResponseWriter, r *http.Request) {
 	app.render(w, r, "log.page.html", td)
 }
 
+// downloads the QSLs since the last download from LoTW
+func (app *application) lotwDownload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		app.clientError(w, http.StatusMethodNotAllowed)
+		return
+	}
+	td := initTemplateData()
+	d, err := app.downloadLoTW()
+	switch {
+	case errors.Is(err, errLoTWUser) || errors.Is(err, errLoTW):
+		td.Message = err.Error()
+	case err != nil:
+		app.serverError(w, err)
+		return
+	case d.Since == "":
+		td.Message = fmt.Sprintf("Downloaded %d QSLs from LoTW", d.QSLs)
+	default:
+		td.Message = fmt.Sprintf("Downloaded %d QSLs from LoTW received since %s", d.QSLs, d.Since)
+	}
+	if d != nil {
+		if note := d.qslMatch.String(); note != "" {
+			td.Message += ", " + note
+		}
+	}
+	td.Table, err = app.logsModel.getADIFData()
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	app.renderADIF(w, r, td)
+}
+
+// signs and uploads the QSOs not yet sent to LoTW with TQSL, trying the
+// queued batches again first
+func (app *application) lotwUpload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		app.clientError(w, http.StatusMethodNotAllowed)
+		return
+	}
+	td := initTemplateData()
+	batches, err := app.uploadLoTW()
+	switch {
+	case errors.Is(err, errNoTQSL):
+		td.Message = err.Error()
+	case err != nil:
+		app.serverError(w, err)
+		return
+	default:
+		td.Message = uploadMessage(batches)
+	}
+	td.Table, err = app.logsModel.getADIFData()
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	app.renderADIF(w, r, td)
+}
+
+// tries one queued batch again
+func (app *application) lotwRetry(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		app.clientError(w, http.StatusMethodNotAllowed)
+		return
+	}
+	err := r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	id, err := strconv.Atoi(r.PostForm.Get("batch"))
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	td := initTemplateData()
+	b, err := app.retryLoTW(id)
+	switch {
+	case errors.Is(err, errNoTQSL):
+		td.Message = err.Error()
+	case errors.Is(err, errNoRecord):
+		app.notFound(w)
+		return
+	case err != nil:
+		app.serverError(w, err)
+		return
+	default:
+		td.Message = uploadMessage([]UploadBatch{*b})
+	}
+	td.Table, err = app.logsModel.getADIFData()
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	app.renderADIF(w, r, td)
+}
+
+// sends the QSOs not yet sent to eQSL
+func (app *application) eqslUpload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		app.clientError(w, http.StatusMethodNotAllowed)
+		return
+	}
+	td := initTemplateData()
+	u, err := app.uploadEQSL()
+	switch {
+	case errors.Is(err, errEQSLUser) || errors.Is(err, errEQSL):
+		td.Message = err.Error()
+	case err != nil:
+		app.serverError(w, err)
+		return
+	default:
+		td.Message = u.String()
+	}
+	td.Table, err = app.logsModel.getADIFData()
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	app.renderADIF(w, r, td)
+}
+
+// downloads the eQSLs received since the last download
+func (app *application) eqslDownload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		app.clientError(w, http.StatusMethodNotAllowed)
+		return
+	}
+	td := initTemplateData()
+	d, err := app.downloadEQSL()
+	switch {
+	case errors.Is(err, errEQSLUser) || errors.Is(err, errEQSL):
+		td.Message = err.Error()
+	case err != nil:
+		app.serverError(w, err)
+		return
+	default:
+		td.Message = d.String()
+	}
+	td.Table, err = app.logsModel.getADIFData()
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	app.renderADIF(w, r, td)
+}
+
+// sends the QSOs queued for the QRZ Logbook and fetches its new
+// confirmations
+func (app *application) qrzLogSync(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		app.clientError(w, http.StatusMethodNotAllowed)
+		return
+	}
+	td := initTemplateData()
+	s, err := app.syncQRZLog()
+	switch {
+	case errors.Is(err, errQRZLogKey) || errors.Is(err, errQRZLog):
+		td.Message = err.Error()
+	case err != nil:
+		app.serverError(w, err)
+		return
+	default:
+		td.Message = s.String()
+	}
+	td.Table, err = app.logsModel.getADIFData()
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	app.renderADIF(w, r, td)
+}
+
+// uploads the QSOs Club Log does not have yet
+func (app *application) clublogUpload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		app.clientError(w, http.StatusMethodNotAllowed)
+		return
+	}
+	td := initTemplateData()
+	n, err := app.uploadClublog()
+	switch {
+	case errors.Is(err, errClublogUser) || errors.Is(err, errClublog):
+		td.Message = err.Error()
+	case err != nil:
+		app.serverError(w, err)
+		return
+	case n == 0:
+		td.Message = "There is nothing to upload to Club Log"
+	default:
+		td.Message = fmt.Sprintf("Uploaded %d QSOs to Club Log", n)
+	}
+	td.Table, err = app.logsModel.getADIFData()
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	app.renderADIF(w, r, td)
+}
+
 //<---------------------------  Cabrillo ---------------------------------->
 
 func (app *application) cabrillo(w http.ResponseWriter, r *http.Request) {
@@ -279,13 +578,13 @@ func (app *application) analysis(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	td.Stats.RepeatContacts = len(t)
-	t, err = app.logsModel.getUniqueCountries()
+	t, err = app.countries("%", "%")
 	if err != nil {
 		app.serverError(w, err)
 		return
 	}
 	td.Stats.Country = len(t)
-	t, err = app.logsModel.getConfirmedCountries()
+	t, err = app.countries("%", "YES")
 	if err != nil {
 		app.serverError(w, err)
 		return
@@ -297,7 +596,7 @@ func (app *application) analysis(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	td.Stats.State = len(t)
-	t, err = app.logsModel.getConfirmedStates()
+	t, err = app.logsModel.getConfirmedStates(false)
 	if err != nil {
 		app.serverError(w, err)
 		return
@@ -310,7 +609,7 @@ func (app *application) analysis(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	td.Stats.County = len(t)
-	t, err = app.logsModel.getConfirmedCounties()
+	t, err = app.logsModel.getConfirmedCounties(false)
 	if err != nil {
 		app.serverError(w, err)
 		return
@@ -328,7 +627,7 @@ func (app *application) analysis(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	td.Stats.ConfirmedCW = len(t)
-	t, err = app.logsModel.getUniqueCountry("CW", "YES")
+	t, err = app.countries("CW", "YES")
 	if err != nil {
 		app.serverError(w, err)
 		return
@@ -345,7 +644,7 @@ func (app *application) analysis(w http.ResponseWriter, r *http.Request) {
 
 func (app *application) country(w http.ResponseWriter, r *http.Request) {
 	td := initTemplateData()
-	t, err := app.logsModel.getUniqueCountries()
+	t, err := app.countries("%", "%")
 	if err != nil {
 		app.serverError(w, err)
 		return
@@ -356,7 +655,7 @@ func (app *application) country(w http.ResponseWriter, r *http.Request) {
 
 func (app *application) countryConfirmed(w http.ResponseWriter, r *http.Request) {
 	td := initTemplateData()
-	t, err := app.logsModel.getConfirmedCountries()
+	t, err := app.countries("%", "YES")
 	if err != nil {
 		app.serverError(w, err)
 		return
@@ -378,12 +677,14 @@ func (app *application) state(w http.ResponseWriter, r *http.Request) {
 
 func (app *application) stateConfirmed(w http.ResponseWriter, r *http.Request) {
 	td := initTemplateData()
-	t, err := app.logsModel.getConfirmedStates()
+	td.Paper = r.URL.Query().Get("paper") == "yes"
+	t, err := app.logsModel.getConfirmedStates(td.Paper)
 	if err != nil {
 		app.serverError(w, err)
 		return
 	}
 	td.Table = t
+	td.Confirmed = r.URL.Path
 	app.render(w, r, "state.page.html", td)
 }
 
@@ -409,11 +710,65 @@ func (app *application) repeat(w http.ResponseWriter, r *http.Request) {
 	app.render(w, r, "log.page.html", td)
 }
 
+// the longest QSO of each band and mode
+func (app *application) longest(w http.ResponseWriter, r *http.Request) {
+	td := initTemplateData()
+	t, err := app.longestQSOs()
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	td.Longest = t
+	app.render(w, r, "longest.page.html", td)
+}
+
+// the DXCC standing by band and mode
+func (app *application) dxcc(w http.ResponseWriter, r *http.Request) {
+	td := initTemplateData()
+	var err error
+	td.DXCC, err = app.dxccTracker()
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	app.render(w, r, "dxcc.page.html", td)
+}
+
+// the DXCC matrix, or with list=credits the QSOs that confirm each slot,
+// as CSV
+func (app *application) dxccExport(w http.ResponseWriter, r *http.Request) {
+	list := r.URL.Query().Get("list")
+	if list == "" {
+		list = "entities"
+	}
+	if list != "entities" && list != "credits" {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	tr, err := app.dxccTracker()
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q",
+		"dxcc-"+list+"-"+time.Now().UTC().Format("20060102")+".csv"))
+	if list == "credits" {
+		err = tr.writeCredits(w)
+	} else {
+		err = tr.writeEntities(w)
+	}
+	if err != nil {
+		app.errorLog.Println(err)
+	}
+}
+
 func (app *application) countrySelect(w http.ResponseWriter, r *http.Request) {
 
 	td := initTemplateData()
 	country := r.URL.Query().Get("sel")
-	t, err := app.logsModel.getLogsByCountry(country)
+	dxcc, _ := strconv.Atoi(r.URL.Query().Get("dxcc"))
+	t, err := app.entityLogs(dxcc, country)
 	if err != nil {
 		app.serverError(w, err)
 		return
@@ -435,13 +790,15 @@ func (app *application) county(w http.ResponseWriter, r *http.Request) {
 
 func (app *application) countyConfirmed(w http.ResponseWriter, r *http.Request) {
 	td := initTemplateData()
-	t, err := app.logsModel.getConfirmedCounties()
+	td.Paper = r.URL.Query().Get("paper") == "yes"
+	t, err := app.logsModel.getConfirmedCounties(td.Paper)
 	if err != nil {
 		app.serverError(w, err)
 		return
 	}
 	td.Top.Cnty = true
 	td.Table = t
+	td.Confirmed = r.URL.Path
 	app.render(w, r, "county.page.html", td)
 }
 
@@ -516,7 +873,7 @@ func (app *application) cwConfirmedState(w http.ResponseWriter, r *http.Request)
 
 func (app *application) cwConfirmedCountry(w http.ResponseWriter, r *http.Request) {
 	td := initTemplateData()
-	t, err := app.logsModel.getUniqueCountry("CW", "YES")
+	t, err := app.countries("CW", "YES")
 	if err != nil {
 		app.serverError(w, err)
 		return
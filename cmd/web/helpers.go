@@ -65,6 +65,7 @@ func (app *application) render(w http.ResponseWriter, r *http.Request,
 			name))
 		return
 	}
+	td.Call = app.stationCall()
 	buf := new(bytes.Buffer)
 	err := ts.Execute(buf, td)
 	if err != nil {
@@ -108,6 +109,8 @@ func copyPostForm(r *http.Request) LogsRow {
 		Lotwrcvd: r.PostForm.Get("lotwrcvd"),
 		ExchSent: r.PostForm.Get("exchsent"),
 		ExchRcvd: r.PostForm.Get("exchrcvd"),
+		Freq:     strings.TrimSpace(r.PostForm.Get("freq")),
+		FreqRx:   strings.TrimSpace(r.PostForm.Get("freqrx")),
 	}
 }
 
@@ -140,6 +143,45 @@ func (app *application) lookupDefault(def string) (string, error) {
 	return v, nil
 }
 
+// a loose check of the call sign syntax, letters and digits with optional
+// portable designators (e.g. VP2E/N2VY/P), at least one digit and one letter
+func validCall(call string) bool {
+	if len(call) < 3 || len(call) > 20 {
+		return false
+	}
+	var digit, letter bool
+	for _, c := range call {
+		switch {
+		case c >= '0' && c <= '9':
+			digit = true
+		case c >= 'A' && c <= 'Z':
+			letter = true
+		case c == '/':
+		default:
+			return false
+		}
+	}
+	return digit && letter && !strings.HasPrefix(call, "/") && !strings.HasSuffix(call, "/")
+}
+
+// a Maidenhead grid square of 4 or 6 characters, e.g. FN20 or FN20qh
+func validGrid(grid string) bool {
+	if len(grid) != 4 && len(grid) != 6 {
+		return false
+	}
+	g := strings.ToUpper(grid)
+	if g[0] < 'A' || g[0] > 'R' || g[1] < 'A' || g[1] > 'R' {
+		return false
+	}
+	if g[2] < '0' || g[2] > '9' || g[3] < '0' || g[3] > '9' {
+		return false
+	}
+	if len(g) == 6 && (g[4] < 'A' || g[4] > 'X' || g[5] < 'A' || g[5] > 'X') {
+		return false
+	}
+	return true
+}
+
 //<+++++++++++++++++++++  Form Error Handling  ++++++++++++++++++++++++>
 
 func (e formErrors) add(field, message string) {
@@ -191,6 +233,19 @@ func (f *formData) checkAllLogMax() {
 	f.maxLength("comment", 85)
 	f.maxLength("lotwrcvd", 10)
 	f.maxLength("lotwsent", 10)
+	f.isFreq("freq")
+	f.isFreq("freqrx")
+}
+
+// an optional frequency in MHz that has to be in one of the ham bands
+func (f *formData) isFreq(field string) {
+	value := strings.TrimSpace(f.Get(field))
+	if value == "" {
+		return
+	}
+	if mhzToBand(value) == "" {
+		f.Errors.add(field, "this field must be a frequency in MHz in a ham band")
+	}
 }
 
 func (f *formData) minLength(field string, d int) {
@@ -278,6 +333,25 @@ func (f *formData) fileCheck(d string) {
 	}
 }
 
+// the file name must have one of the extensions (e.g. "adi")
+func (f *formData) fileExtCheck(d string, exts ...string) {
+	v := f.Get(d)
+	if v == "" {
+		return
+	}
+	if strings.ContainsAny(v, `/\`) {
+		f.Errors.add(d, "file name can not include a directory")
+		return
+	}
+	ext := strings.TrimPrefix(filepath.Ext(v), ".")
+	for _, e := range exts {
+		if strings.EqualFold(ext, e) {
+			return
+		}
+	}
+	f.Errors.add(d, fmt.Sprintf("file name must end in .%s", strings.Join(exts, " or .")))
+}
+
 func (f *formData) valid() bool {
 	return len(f.Errors) == 0
 }
@@ -408,6 +482,86 @@ func (app *application) getVFOUpdate() (*VFO, error) {
 	return v, nil
 }
 
+// adifBand is a band of the ADIF Band enumeration, its limits in MHz
+type adifBand struct {
+	name  string
+	lower float64
+	upper float64
+}
+
+// the ham bands, the log takes any of them while vfoMemory is only the bands
+// the radio is driven on
+var adifBands = []adifBand{
+	{"2190m", 0.1357, 0.1378}, {"630m", 0.472, 0.479}, {"560m", 0.501, 0.504},
+	{"160m", 1.8, 2.0}, {"80m", 3.5, 4.0}, {"60m", 5.06, 5.45}, {"40m", 7.0, 7.3},
+	{"30m", 10.1, 10.15}, {"20m", 14.0, 14.35}, {"17m", 18.068, 18.168},
+	{"15m", 21.0, 21.45}, {"12m", 24.89, 24.99}, {"10m", 28.0, 29.7},
+	{"8m", 40, 45}, {"6m", 50, 54}, {"5m", 54.000001, 69.9}, {"4m", 70, 71},
+	{"2m", 144, 148}, {"1.25m", 222, 225}, {"70cm", 420, 450}, {"33cm", 902, 928},
+	{"23cm", 1240, 1300}, {"13cm", 2300, 2450}, {"9cm", 3300, 3500},
+	{"6cm", 5650, 5925}, {"3cm", 10000, 10500}, {"1.25cm", 24000, 24250},
+	{"6mm", 47000, 47200}, {"4mm", 75500, 81000}, {"2.5mm", 119980, 123000},
+	{"2mm", 134000, 149000}, {"1mm", 241000, 250000}, {"submm", 300000, 7500000},
+}
+
+// returns the band of a frequency in MHz, "" if it is not in a ham band
+func mhzToBand(f string) string {
+	mhz, err := strconv.ParseFloat(strings.TrimSpace(f), 64)
+	if err != nil {
+		return ""
+	}
+	for _, b := range adifBands {
+		if mhz >= b.lower && mhz <= b.upper {
+			return b.name
+		}
+	}
+	return ""
+}
+
+// whether the band (lower case) is one of the ham bands
+func isBand(band string) bool {
+	for _, b := range adifBands {
+		if b.name == band {
+			return true
+		}
+	}
+	return false
+}
+
+// the radio reports its frequency in Hz, the log keeps MHz
+func hzToMHz(hz int) string {
+	return fmt.Sprintf("%.6f", float64(hz)/1e6)
+}
+
+// returns the transmit and (when split) receive frequencies to log a QSO
+// on band with.  The radio and the VFO (including DX spot clicks) keep the
+// freq and freqrx defaults current.  When they are on some other band, the
+// band was typed in by hand and the VFO memory for the band is used.
+func (app *application) currentFreq(band string) (string, string, error) {
+	freq, err := app.otherModel.getDefault("freq")
+	if err != nil && !errors.Is(err, errNoRecord) {
+		return "", "", err
+	}
+	if freq != "" && mhzToBand(freq) == band {
+		rx, err := app.otherModel.getDefault("freqrx")
+		if err != nil && !errors.Is(err, errNoRecord) {
+			return "", "", err
+		}
+		return freq, rx, nil
+	}
+	freq, err = app.otherModel.getDefault(band + "xfreq")
+	if err != nil {
+		if errors.Is(err, errNoRecord) {
+			return "", "", nil
+		}
+		return "", "", err
+	}
+	if mhzToBand(freq) != band {
+		return "", "", nil
+	}
+	return freq, "", nil
+}
+
 func (app *application) pickZone(zone string, dxData []DXClusters) ([]DXClusters, error) {
 	newData := []DXClusters{}
 	i := 0
@@ -798,3 +952,9 @@ func (app *application) saveBandMode(r *http.Request) error {
 	}
 	return nil
 }
+
+// hands a newly logged QSO to the online logbooks it is pushed to
+func (app *application) logged(id int) {
+	app.queueQRZLog(id)
+	app.pushClublogLater(id)
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"net/url"
 	"testing"
 	"time"
 )
@@ -69,3 +70,94 @@ func TestContextStore(t *testing.T) {
 	}
 
 }
+
+func TestCurrentFreq(t *testing.T) {
+	app := newTestSQLiteApp(t)
+
+	freq, rx, err := app.currentFreq("20m")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if freq != "" || rx != "" {
+		t.Errorf("want no frequency on a new install, got %q %q", freq, rx)
+	}
+
+	defaults := map[string]string{"freq": "14.025500", "freqrx": "14.027000",
+		"40mxfreq": "7.074000"}
+	for k, v := range defaults {
+		err = app.otherModel.updateDefault(k, v)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		band string
+		freq string
+		rx   string
+	}{
+		{"20m", "14.025500", "14.027000"},
+		{"40m", "7.074000", ""},
+		{"15m", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.band, func(t *testing.T) {
+			freq, rx, err := app.currentFreq(tt.band)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if freq != tt.freq || rx != tt.rx {
+				t.Errorf("want %q %q, got %q %q", tt.freq, tt.rx, freq, rx)
+			}
+		})
+	}
+}
+
+func TestValidGrid(t *testing.T) {
+	tests := []struct {
+		grid string
+		want bool
+	}{
+		{"FN20", true},
+		{"fn20qh", true},
+		{"FN2", false},
+		{"SN20", false},
+		{"FN20zz", false},
+		{"20FN", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.grid, func(t *testing.T) {
+			if got := validGrid(tt.grid); got != tt.want {
+				t.Errorf("want %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestMhzToBand(t *testing.T) {
+	tests := []struct {
+		freq string
+		want string
+	}{
+		{"14.025500", "20m"},
+		{"5.357000", "60m"},
+		{"146.520000", "2m"},
+		{"10.000000", ""},
+		{"11m", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.freq, func(t *testing.T) {
+			if got := mhzToBand(tt.freq); got != tt.want {
+				t.Errorf("want %q, got %q", tt.want, got)
+			}
+		})
+	}
+
+	//a 60m and a 2m QSO logged by hand with their frequencies
+	for _, freq := range []string{"5.357", "146.52"} {
+		f := newForm(url.Values{"freq": {freq}})
+		f.isFreq("freq")
+		if !f.valid() {
+			t.Errorf("want %s MHz taken, got %v", freq, f.Errors)
+		}
+	}
+}
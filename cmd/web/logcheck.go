@@ -0,0 +1,295 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+//<<=========================== Log integrity check ===========================>>
+
+//The check reads the whole log and tests each QSO against the rules below.
+//Problems that have one right answer (the band of the frequency, a call
+//sign in lower case) can be fixed from the check page, through the same
+//audited path as an edit, the rest are listed to be fixed by hand.
+
+// the rules, in the order the check page shows them
+const (
+	ruleBand    = "band"
+	ruleCall    = "call"
+	ruleCountry = "country"
+	ruleState   = "state"
+	ruleDate    = "date"
+	ruleDupe    = "dupe"
+	ruleLoTW    = "lotw"
+	ruleReport  = "report"
+	ruleContest = "contest"
+)
+
+type checkRule struct {
+	Name  string
+	Title string
+}
+
+var checkRules = []checkRule{
+	{ruleBand, "Band does not match the frequency"},
+	{ruleCall, "Malformed call sign"},
+	{ruleCountry, "No country"},
+	{ruleState, "No state for a US QSO"},
+	{ruleDate, "Impossible date"},
+	{ruleDupe, "Duplicate QSO"},
+	{ruleLoTW, "LOTW flags disagree"},
+	{ruleReport, "Malformed signal report"},
+	{ruleContest, "Contest fields missing"},
+}
+
+// how a problem is fixed
+const (
+	fixEdit   = "edit"   //changes fields of the QSO
+	fixDelete = "delete" //moves the QSO to the trash
+	fixLookup = "lookup" //fills in the QRZ data
+)
+
+// QSOs before this are typos
+var firstQSO = time.Date(1920, 1, 1, 0, 0, 0, 0, time.UTC)
+
+var errQRZUnknown = errors.New("QRZ does not know this call sign")
+
+// logProblem is one QSO that breaks one rule
+type logProblem struct {
+	Rule   string
+	Id     int
+	Call   string
+	Time   time.Time
+	Detail string
+	Fix    string //what the fix does, blank when it has to be fixed by hand
+	kind   string
+	edit   func(l *LogsRow) //for fixEdit
+}
+
+// ruleProblems is what one rule found
+type ruleProblems struct {
+	checkRule
+	Problems []logProblem
+	Fixable  int
+}
+
+// logCheck is the outcome of checking the whole log
+type logCheck struct {
+	QSOs     int
+	Problems int
+	Rules    []ruleProblems //only the rules that found something
+}
+
+// tests the rows against the rules.  The rows are in time order so the
+// dupes are the later QSOs.
+func checkLogs(rows []LogsRow, now time.Time) []logProblem {
+	problems := []logProblem{}
+	seen := map[string]LogsRow{}
+	for _, l := range rows {
+		add := func(rule, detail, fix, kind string, edit func(l *LogsRow)) {
+			problems = append(problems, logProblem{Rule: rule, Id: l.Id, Call: l.Call,
+				Time: l.Time, Detail: detail, Fix: fix, kind: kind, edit: edit})
+		}
+
+		if l.Freq != "" {
+			b := mhzToBand(l.Freq)
+			switch {
+			case b == "":
+				add(ruleBand, fmt.Sprintf("%s MHz is not on a band", l.Freq), "", "", nil)
+			case !strings.EqualFold(b, l.Band):
+				add(ruleBand, fmt.Sprintf("logged on %s, %s MHz is on %s", l.Band, l.Freq, b),
+					"set the band to "+b, fixEdit, func(l *LogsRow) { l.Band = b })
+			}
+		}
+
+		if !validCall(l.Call) {
+			c := cleanCall(l.Call)
+			if validCall(c) {
+				add(ruleCall, fmt.Sprintf("%q is not a call sign", l.Call), "change it to "+c,
+					fixEdit, func(l *LogsRow) { l.Call = c })
+			} else {
+				add(ruleCall, fmt.Sprintf("%q is not a call sign", l.Call), "", "", nil)
+			}
+		}
+
+		if strings.TrimSpace(l.Country) == "" {
+			add(ruleCountry, "the country is blank", "fill it in from QRZ", fixLookup, nil)
+		} else if strings.EqualFold(l.Country, "United States") && l.State == "" {
+			add(ruleState, "there is no state in the QRZ data", "look it up on QRZ", fixLookup, nil)
+		}
+
+		if l.Time.Before(firstQSO) || l.Time.After(now.Add(24*time.Hour)) {
+			add(ruleDate, fmt.Sprintf("logged on %s", l.Time.UTC().Format("2006-01-02 15:04")), "", "", nil)
+		}
+
+		key := strings.ToUpper(l.Call) + " " + strings.ToLower(l.Band) + " " + strings.ToUpper(l.Mode)
+		if p, ok := seen[key]; ok && l.Time.Sub(p.Time) <= importDupeWindow {
+			add(ruleDupe, fmt.Sprintf("same call, band and mode as QSO %d at %s", p.Id,
+				p.Time.UTC().Format("15:04:05")), "move it to the trash", fixDelete, nil)
+		} else {
+			seen[key] = l
+		}
+
+		sent := strings.EqualFold(l.Lotwsent, "YES")
+		rcvd := strings.EqualFold(l.Lotwrcvd, "YES")
+		switch {
+		case rcvd && !sent:
+			add(ruleLoTW, "confirmed on LOTW but not marked sent", "mark it sent",
+				fixEdit, func(l *LogsRow) { l.Lotwsent = "YES" })
+		case !rcvd && l.LotwQSLdate.After(noQSL):
+			add(ruleLoTW, fmt.Sprintf("has a LOTW QSL of %s but is not marked confirmed",
+				l.LotwQSLdate.Format("2006-01-02")), "mark it confirmed",
+				fixEdit, func(l *LogsRow) { l.Lotwrcvd = "YES" })
+		}
+
+		for _, r := range []struct{ name, report string }{{"sent", l.Sent}, {"received", l.Rcvd}} {
+			if validReport(r.report) {
+				continue
+			}
+			c := cleanReport(r.report)
+			detail := fmt.Sprintf("report %s %q is not an RS(T) or dB report", r.name, r.report)
+			if !validReport(c) {
+				add(ruleReport, detail, "", "", nil)
+				continue
+			}
+			sentReport := r.name == "sent"
+			add(ruleReport, detail, "change it to "+c, fixEdit, func(l *LogsRow) {
+				if sentReport {
+					l.Sent = c
+				} else {
+					l.Rcvd = c
+				}
+			})
+		}
+
+		contest := strings.EqualFold(l.Contest, "Yes")
+		switch {
+		case !contest && l.ContestName != "":
+			add(ruleContest, fmt.Sprintf("has the contest name %s but is not a contest QSO",
+				l.ContestName), "make it a contest QSO", fixEdit, func(l *LogsRow) { l.Contest = "Yes" })
+		case contest && l.ContestName == "":
+			add(ruleContest, "contest QSO without a contest name", "", "", nil)
+		case contest && l.ExchRcvd == "" && l.Field1Rcvd == "":
+			add(ruleContest, "contest QSO without a received exchange", "", "", nil)
+		}
+	}
+	return problems
+}
+
+// upper case without the spaces and stray punctuation of a typo
+func cleanCall(call string) string {
+	call = strings.ToUpper(strings.Join(strings.Fields(call), ""))
+	return strings.Trim(call, ".,;:-_'\"")
+}
+
+// RS or RST (59, 599) or a WSJT-X dB report (-12, +05), blank if none
+// was logged
+func validReport(r string) bool {
+	if r == "" {
+		return true
+	}
+	if len(r) == 2 || len(r) == 3 {
+		ok := r[0] >= '1' && r[0] <= '5'
+		for _, c := range r[1:] {
+			ok = ok && c >= '1' && c <= '9'
+		}
+		if ok {
+			return true
+		}
+	}
+	d := strings.TrimLeft(r, "+-")
+	if len(r)-len(d) > 1 || len(d) == 0 || len(d) > 2 {
+		return false
+	}
+	for _, c := range d {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return len(d) < len(r) //a dB report has its sign
+}
+
+// without the spaces and with the cut numbers of CW contests (5NN)
+func cleanReport(r string) string {
+	r = strings.ToUpper(strings.Join(strings.Fields(r), ""))
+	return strings.NewReplacer("N", "9", "T", "0").Replace(r)
+}
+
+// checks the whole log
+func (app *application) checkLog() (*logCheck, []logProblem, error) {
+	rows, err := app.logsModel.getExportData(&logFilter{})
+	if err != nil {
+		return nil, nil, err
+	}
+	problems := checkLogs(rows, time.Now().UTC())
+	lc := &logCheck{QSOs: len(rows), Problems: len(problems)}
+	for _, r := range checkRules {
+		rp := ruleProblems{checkRule: r}
+		for _, p := range problems {
+			if p.Rule != r.Name {
+				continue
+			}
+			rp.Problems = append(rp.Problems, p)
+			if p.Fix != "" {
+				rp.Fixable++
+			}
+		}
+		if len(rp.Problems) > 0 {
+			lc.Rules = append(lc.Rules, rp)
+		}
+	}
+	return lc, problems, nil
+}
+
+// applies the fix of a problem
+func (app *application) fixProblem(p logProblem) error {
+	switch p.kind {
+	case fixEdit:
+		l, err := app.logsModel.getLogByID(p.Id)
+		if err != nil {
+			return err
+		}
+		p.edit(l)
+		return app.logsModel.replaceLog(l, sourceCheck)
+	case fixDelete:
+		_, err := app.logsModel.deleteLogs([]int{p.Id}, sourceCheck)
+		return err
+	case fixLookup:
+		return app.fillFromQRZ(p)
+	}
+	return fmt.Errorf("QSO %d has to be fixed by hand", p.Id)
+}
+
+// fills in the country and location of a QSO from the QRZ data, from the
+// cache when it has the country.  A missing state needs a fresh lookup.
+func (app *application) fillFromQRZ(p logProblem) error {
+	c, err := app.qrzModel.getQRZ(p.Call)
+	if err != nil && !errors.Is(err, errNoRecord) {
+		return err
+	}
+	if err != nil || c.Country == "" || p.Rule == ruleState {
+		q, err := app.getHamInfo(p.Call)
+		if err != nil {
+			return err
+		}
+		if q.Callsign.Call == "" {
+			return errQRZUnknown
+		}
+		c = &q.Callsign
+		err = app.qrzModel.insertQRZ(c)
+		if err != nil {
+			return err
+		}
+	}
+	l, err := app.logsModel.getLogByID(p.Id)
+	if err != nil {
+		return err
+	}
+	if l.Country == "" {
+		l.Country = c.Country
+	}
+	qrzLocation(l, c)
+	return app.logsModel.replaceLog(l, sourceCheck)
+}
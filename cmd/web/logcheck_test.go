@@ -0,0 +1,95 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestValidReport(t *testing.T) {
+	tests := []struct {
+		report string
+		want   bool
+	}{
+		{"", true},
+		{"599", true},
+		{"59", true},
+		{"-12", true},
+		{"+05", true},
+		{"5NN", false},
+		{"05", false},
+		{"609", false},
+		{"--5", false},
+		{"S9", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.report, func(t *testing.T) {
+			if got := validReport(tt.report); got != tt.want {
+				t.Errorf("want %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestCheckLogs(t *testing.T) {
+	now := time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC)
+	qso := time.Date(2023, 4, 15, 14, 15, 0, 0, time.UTC)
+	good := LogsRow{Id: 1, Time: qso, Call: "AA7BQ", Band: "20m", Mode: "CW",
+		Freq: "14.025000", Sent: "599", Rcvd: "599", Country: "United States", State: "AZ"}
+	row := func(f func(l *LogsRow)) LogsRow {
+		l := good
+		f(&l)
+		return l
+	}
+
+	tests := []struct {
+		name string
+		rows []LogsRow
+		rule string //blank for no problems
+		fix  string
+	}{
+		{"good", []LogsRow{good}, "", ""},
+		{"band", []LogsRow{row(func(l *LogsRow) { l.Band = "40m" })}, ruleBand, "set the band to 20m"},
+		{"60m", []LogsRow{row(func(l *LogsRow) { l.Band = "60m"; l.Freq = "5.357000" })}, "", ""},
+		{"2m", []LogsRow{row(func(l *LogsRow) { l.Band = "2m"; l.Freq = "146.520000" })}, "", ""},
+		{"off band", []LogsRow{row(func(l *LogsRow) { l.Freq = "14.500000" })}, ruleBand, ""},
+		{"lower case call", []LogsRow{row(func(l *LogsRow) { l.Call = "aa7bq " })}, ruleCall,
+			"change it to AA7BQ"},
+		{"bad call", []LogsRow{row(func(l *LogsRow) { l.Call = "AAB" })}, ruleCall, ""},
+		{"no country", []LogsRow{row(func(l *LogsRow) { l.Country = "" })}, ruleCountry,
+			"fill it in from QRZ"},
+		{"no state", []LogsRow{row(func(l *LogsRow) { l.State = "" })}, ruleState, "look it up on QRZ"},
+		{"no state abroad", []LogsRow{row(func(l *LogsRow) { l.State = ""; l.Country = "Germany" })}, "", ""},
+		{"future", []LogsRow{row(func(l *LogsRow) { l.Time = now.Add(48 * time.Hour) })}, ruleDate, ""},
+		{"zero time", []LogsRow{row(func(l *LogsRow) { l.Time = time.Time{} })}, ruleDate, ""},
+		{"dupe", []LogsRow{good, row(func(l *LogsRow) { l.Id = 2; l.Time = qso.Add(time.Minute) })},
+			ruleDupe, "move it to the trash"},
+		{"not a dupe", []LogsRow{good, row(func(l *LogsRow) { l.Id = 2; l.Time = qso.Add(time.Hour) })}, "", ""},
+		{"confirmed unsent", []LogsRow{row(func(l *LogsRow) { l.Lotwrcvd = "YES" })}, ruleLoTW, "mark it sent"},
+		{"qsl date", []LogsRow{row(func(l *LogsRow) { l.Lotwsent = "YES"; l.LotwQSLdate = qso })},
+			ruleLoTW, "mark it confirmed"},
+		{"cut numbers", []LogsRow{row(func(l *LogsRow) { l.Sent = "5NN" })}, ruleReport, "change it to 599"},
+		{"bad report", []LogsRow{row(func(l *LogsRow) { l.Rcvd = "S9" })}, ruleReport, ""},
+		{"contest name", []LogsRow{row(func(l *LogsRow) { l.ContestName = "NJQP" })}, ruleContest,
+			"make it a contest QSO"},
+		{"no exchange", []LogsRow{row(func(l *LogsRow) { l.Contest = "Yes"; l.ContestName = "NJQP" })},
+			ruleContest, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			problems := checkLogs(tt.rows, now)
+			if tt.rule == "" {
+				if len(problems) != 0 {
+					t.Errorf("want no problems, got %+v", problems)
+				}
+				return
+			}
+			if len(problems) != 1 {
+				t.Fatalf("want one problem, got %+v", problems)
+			}
+			if problems[0].Rule != tt.rule || problems[0].Fix != tt.fix {
+				t.Errorf("want %s fixed by %q, got %s %q", tt.rule, tt.fix, problems[0].Rule,
+					problems[0].Fix)
+			}
+		})
+	}
+}
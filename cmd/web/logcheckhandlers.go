@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+//<<========================== Log check handlers ==========================>>
+
+func (app *application) logCheck(w http.ResponseWriter, r *http.Request) {
+	app.renderCheck(w, r, initTemplateData())
+}
+
+// fixes one problem (rule and id) or all the fixable problems of a rule
+// (rule without an id) and checks the log again
+func (app *application) fixLog(w http.ResponseWriter, r *http.Request) {
+	td := initTemplateData()
+	err := r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	rule := r.PostForm.Get("rule")
+	id := 0
+	if v := r.PostForm.Get("id"); v != "" {
+		id, err = strconv.Atoi(v)
+		if err != nil {
+			app.clientError(w, http.StatusBadRequest)
+			return
+		}
+	}
+	_, problems, err := app.checkLog()
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	var tried, fixed int
+	var fixErr error
+	for _, p := range problems {
+		if p.Rule != rule || p.Fix == "" || (id != 0 && p.Id != id) {
+			continue
+		}
+		tried++
+		err = app.fixProblem(p)
+		if err != nil {
+			//a QRZ lookup that fails should not stop the rest
+			app.errorLog.Printf("fixing QSO %d: %v", p.Id, err)
+			if fixErr == nil {
+				fixErr = fmt.Errorf("QSO %d: %v", p.Id, err)
+			}
+			continue
+		}
+		fixed++
+	}
+	switch {
+	case tried == 0:
+		td.Message = "There is nothing to fix, the log may have changed"
+	case fixErr != nil:
+		td.Message = fmt.Sprintf("Fixed %d of %d problems, %v", fixed, tried, fixErr)
+	default:
+		td.Message = fmt.Sprintf("Fixed %d problems", fixed)
+	}
+	app.renderCheck(w, r, td)
+}
+
+// fills in the DXCC entity, zones and location of the QSOs that do not
+// have them
+func (app *application) backfill(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		app.clientError(w, http.StatusMethodNotAllowed)
+		return
+	}
+	td := initTemplateData()
+	n, err := app.backfillLocations()
+	if err != nil {
+		td.Message = fmt.Sprintf("Filled in %d QSOs before %v", n, err)
+	} else {
+		td.Message = fmt.Sprintf("Filled in the location of %d QSOs", n)
+	}
+	app.renderCheck(w, r, td)
+}
+
+func (app *application) renderCheck(w http.ResponseWriter, r *http.Request, td *templateData) {
+	var err error
+	td.Logger = true
+	td.Check, _, err = app.checkLog()
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	app.render(w, r, "check.page.html", td)
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFixLog(t *testing.T) {
+	app := newTestSQLiteApp(t)
+	qso := time.Date(2023, 4, 15, 14, 15, 0, 0, time.UTC)
+	rows := []LogsRow{
+		{Call: "DL1AB", Band: "40m", Freq: "14.074000", Mode: "FT8", Country: "Germany"},
+		{Call: "dl1ab", Band: "20m", Freq: "14.074000", Mode: "FT8", Country: "Germany"},
+		{Call: "DL1AB", Band: "20m", Freq: "14.074000", Mode: "FT8", Country: "Germany"},
+		{Call: "G4ABC", Band: "20m", Mode: "CW", Country: ""},
+		{Call: "N2VY", Band: "20m", Mode: "CW", Country: "Germany", Sent: "S9"},
+	}
+	for i := range rows {
+		rows[i].Time = qso.Add(time.Duration(i) * time.Hour)
+		if i == 2 {
+			//a dupe of the one before
+			rows[i].Time = rows[i-1].Time.Add(time.Minute)
+		}
+		_, err := app.logsModel.importLog(&rows[i], sourceImport)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := app.qrzModel.insertQRZ(&Ctype{Call: "G4ABC", Country: "England"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		form     url.Values
+		wantBody string
+	}{
+		{"page", nil, "found 5 problems"},
+		{"band", url.Values{"rule": {ruleBand}, "id": {"1"}}, "Fixed 1 problems"},
+		{"call", url.Values{"rule": {ruleCall}}, "Fixed 1 problems"},
+		{"dupe", url.Values{"rule": {ruleDupe}, "id": {"3"}}, "Fixed 1 problems"},
+		{"country", url.Values{"rule": {ruleCountry}, "id": {"4"}}, "Fixed 1 problems"},
+		{"by hand", url.Values{"rule": {ruleReport}, "id": {"5"}}, "nothing to fix"},
+		{"bad id", url.Values{"rule": {ruleBand}, "id": {"x"}}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			var r *http.Request
+			var err error
+			if tt.form == nil {
+				r, err = http.NewRequest(http.MethodGet, "/check", nil)
+			} else {
+				r, err = http.NewRequest(http.MethodPost, "/fix-log", strings.NewReader(tt.form.Encode()))
+				r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			app.routes().ServeHTTP(rr, r)
+			wantCode := http.StatusOK
+			if tt.wantBody == "" {
+				wantCode = http.StatusBadRequest
+			}
+			if rr.Code != wantCode {
+				t.Errorf("expected %d got %d", wantCode, rr.Code)
+			}
+			if !strings.Contains(rr.Body.String(), tt.wantBody) {
+				t.Errorf("expected %q in the body, did not get", tt.wantBody)
+			}
+		})
+	}
+
+	l, err := app.logsModel.getLogByID(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.Band != "20m" {
+		t.Errorf("want the band fixed to 20m, got %s", l.Band)
+	}
+	l, err = app.logsModel.getLogByID(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.Country != "England" {
+		t.Errorf("want the country from QRZ, got %q", l.Country)
+	}
+	trash, err := app.logsModel.getTrash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trash) != 1 || trash[0].Id != 3 {
+		t.Errorf("want the dupe in the trash, got %+v", trash)
+	}
+	h, err := app.logsModel.getHistory(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(h) != 2 || h[0].Source != sourceCheck {
+		t.Errorf("want the call fix in the history, got %+v", h)
+	}
+}
+
+func TestBackfill(t *testing.T) {
+	app := newTestSQLiteApp(t)
+	l := LogsRow{Call: "DL1AB", Band: "20m", Mode: "CW", Time: time.Now().UTC()}
+	_, err := app.logsModel.importLog(&l, sourceImport)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = app.qrzModel.insertQRZ(&Ctype{Call: "DL1AB", Dxcc: "230", Grid: "JO62"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	app.routes().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/backfill", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("GET want %d, got %d", http.StatusMethodNotAllowed, w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	app.routes().ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/backfill", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("want %d, got %d", http.StatusOK, w.Code)
+	}
+	if !strings.Contains(w.Body.String(), "Filled in the location of 1 QSOs") {
+		t.Errorf("want the count in the message, got %s", w.Body.String())
+	}
+	got, err := app.logsModel.getLogByID(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.DXCC != 230 || got.Grid != "JO62" {
+		t.Errorf("want 230 JO62, got %d %s", got.DXCC, got.Grid)
+	}
+}
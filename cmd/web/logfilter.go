@@ -0,0 +1,139 @@
+package main
+
+import (
+	"strings"
+	"time"
+)
+
+// logFilter selects the QSOs for exports and searches.  Empty fields do
+// not filter.  The state is from the QRZ lookups so the where clause needs
+// the qrztable join of exportFrom.
+type logFilter struct {
+	Call        string    //* matches any characters and ? any one, e.g. DL*
+	Start       time.Time //first day, inclusive
+	End         time.Time //last day, inclusive
+	Band        string
+	Mode        string //SSB matches USB and LSB too
+	Country     string
+	State       string
+	ContestName string
+	Confirmed   string //"yes", "no" or "" for both
+	Comment     string //anywhere in the comment
+}
+
+const (
+	confirmedYes = "yes"
+	confirmedNo  = "no"
+)
+
+// makes the LIKE wildcards in a search string match themselves
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// builds the WHERE clause (without the WHERE) and its arguments
+func (lf *logFilter) where() (string, []interface{}) {
+	clauses := []string{}
+	args := []interface{}{}
+	if strings.ContainsAny(lf.Call, "*?") {
+		clauses = append(clauses, "stationlogs.callsign LIKE ?")
+		args = append(args, strings.NewReplacer("*", "%", "?", "_").Replace(lf.Call))
+	} else if lf.Call != "" {
+		clauses = append(clauses, "stationlogs.callsign = ?")
+		args = append(args, lf.Call)
+	}
+	if !lf.Start.IsZero() {
+		clauses = append(clauses, "stationlogs.time >= ?")
+		args = append(args, lf.Start.UTC())
+	}
+	if !lf.End.IsZero() {
+		clauses = append(clauses, "stationlogs.time < ?")
+		args = append(args, lf.End.UTC().Add(24*time.Hour))
+	}
+	if lf.Band != "" {
+		clauses = append(clauses, "stationlogs.band = ?")
+		args = append(args, lf.Band)
+	}
+	switch lf.Mode {
+	case "":
+	case "SSB":
+		clauses = append(clauses, "stationlogs.mode IN (?, ?, ?)")
+		args = append(args, "SSB", "USB", "LSB")
+	default:
+		clauses = append(clauses, "stationlogs.mode = ?")
+		args = append(args, lf.Mode)
+	}
+	if lf.Country != "" {
+		clauses = append(clauses, "stationlogs.country = ?")
+		args = append(args, lf.Country)
+	}
+	if lf.State != "" {
+		clauses = append(clauses, exportState+" = ?")
+		args = append(args, lf.State)
+	}
+	if lf.ContestName != "" {
+		clauses = append(clauses, "stationlogs.contestname = ?")
+		args = append(args, lf.ContestName)
+	}
+	switch lf.Confirmed {
+	case confirmedYes:
+		clauses = append(clauses, "stationlogs.lotwrcvd = ?")
+		args = append(args, "YES")
+	case confirmedNo:
+		clauses = append(clauses, "stationlogs.lotwrcvd <> ?")
+		args = append(args, "YES")
+	}
+	if lf.Comment != "" {
+		//the escape character is an argument, MySQL and SQLite quote
+		//backslashes differently
+		clauses = append(clauses, "stationlogs.comment LIKE ? ESCAPE ?")
+		args = append(args, "%"+likeEscaper.Replace(lf.Comment)+"%", `\`)
+	}
+	if len(clauses) == 0 {
+		return "1 = 1", args
+	}
+	return strings.Join(clauses, " AND "), args
+}
+
+// reads the filter fields of a form, dates are yyyy-mm-dd
+func (f *formData) logFilter() *logFilter {
+	lf := &logFilter{
+		Call:        strings.ToUpper(strings.TrimSpace(f.Get("call"))),
+		Band:        strings.ToLower(strings.TrimSpace(f.Get("band"))),
+		Mode:        strings.ToUpper(strings.TrimSpace(f.Get("mode"))),
+		Country:     strings.TrimSpace(f.Get("country")),
+		State:       strings.ToUpper(strings.TrimSpace(f.Get("state"))),
+		ContestName: strings.TrimSpace(f.Get("contestname")),
+		Confirmed:   f.Get("confirmed"),
+		Comment:     strings.TrimSpace(f.Get("comment")),
+	}
+	f.maxLength("call", 20)
+	for _, c := range lf.Call {
+		if !strings.ContainsRune("ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789/*?", c) {
+			f.Errors.add("call", "only letters, digits, / and the * and ? wildcards")
+			break
+		}
+	}
+	lf.Start = f.filterDate("startdate")
+	lf.End = f.filterDate("enddate")
+	if !lf.Start.IsZero() && !lf.End.IsZero() && lf.End.Before(lf.Start) {
+		f.Errors.add("enddate", "end date is before the start date")
+	}
+	switch lf.Confirmed {
+	case "", confirmedYes, confirmedNo:
+	default:
+		f.Errors.add("confirmed", "must be yes, no or blank")
+	}
+	return lf
+}
+
+// an optional date field, zero if blank or bad (bad ones are flagged)
+func (f *formData) filterDate(field string) time.Time {
+	if strings.TrimSpace(f.Get(field)) == "" {
+		return time.Time{}
+	}
+	t, err := time.Parse("2006-01-02", strings.TrimSpace(f.Get(field)))
+	if err != nil {
+		f.Errors.add(field, "incorrect date format (yyyy-mm-dd)")
+		return time.Time{}
+	}
+	return t
+}
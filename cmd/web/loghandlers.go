@@ -24,37 +24,60 @@ type tTop interface{}
 
 // for feeding dynamic data and error reports to templates
 type templateData struct {
-	FormData   *formData //for form validation error handling
-	LookUp     *Ctype    //Full suite of QRZ individual ham data
-	Speed      int8      //code sending speed
-	Tone       int16     //Practice tone
-	Volume     int8      //Practice volume
-	Mode       string    //keying mode, tutor or keyer
-	Band       string
-	Top        headRow   //Log table column titles
-	Table      []LogsRow //full set of log table rows
-	LogEdit    *LogsRow  //single row of the log table for editing
-	Show       bool
-	Edit       bool
-	StopCode   bool
-	Logger     bool
-	Contest    string
-	Stats      *Stats
-	VFO        *VFO
-	Message    string
-	FieldCount int
-	Seq        string
-	F1         string //contesting function keys
-	F2         string
-	F3         string
-	F4         string
-	F5         string
-	F6         string
-	F7         string
-	F8         string
-	F9         string
-	F10        string
-	FieldNames []string
+	FormData      *formData //for form validation error handling
+	LookUp        *Ctype    //Full suite of QRZ individual ham data
+	Speed         int8      //code sending speed
+	Tone          int16     //Practice tone
+	Volume        int8      //Practice volume
+	Mode          string    //keying mode, tutor or keyer
+	Band          string
+	Top           headRow   //Log table column titles
+	Table         []LogsRow //full set of log table rows
+	LogEdit       *LogsRow  //single row of the log table for editing
+	Show          bool
+	Edit          bool
+	StopCode      bool
+	Logger        bool
+	Contest       string
+	Stats         *Stats
+	VFO           *VFO
+	Message       string
+	FieldCount    int
+	Seq           string
+	F1            string //contesting function keys
+	F2            string
+	F3            string
+	F4            string
+	F5            string
+	F6            string
+	F7            string
+	F8            string
+	F9            string
+	F10           string
+	FieldNames    []string
+	Import        *importSummary //outcome of an ADIF import
+	Call          string         //call sign of the active station profile
+	Profiles      []ProfileRow
+	History       []AuditRow  //changes to one QSO, the latest first
+	Trash         []TrashRow  //deleted QSOs
+	Pager         *pager      //page of the search results
+	Columns       []csvChoice //CSV export columns
+	CSV           *csvImport  //CSV file being mapped and imported
+	Backup        *backupStatus
+	Check         *logCheck //problems the log check found
+	ActiveProfile int
+	Path          *qsoPath       //way to the station looked up or being edited
+	LocalTime     string         //standard time at the station looked up
+	Longest       []LogsRow      //longest QSO of each band and mode
+	Batches       []UploadBatch  //LoTW uploads, the queued ones first
+	QRZQueue      []QRZLogQSO    //QSOs waiting for the QRZ Logbook
+	Unmatched     []UnmatchedQSL //LoTW QSLs waiting for review
+	Cards         []QSLCard      //paper cards to send
+	Layouts       []string       //Avery numbers of the label layouts
+	Layout        string         //the one picked
+	Paper         bool           //paper cards count as confirmations
+	Confirmed     string         //path of the confirmed page shown
+	DXCC          *DXCCTracker
 }
 
 type Stats struct {
@@ -77,6 +100,8 @@ type Stats struct {
 type LogType struct {
 	Name    string `json:"Name"`
 	Country string `json:"Country"`
+	Path    string `json:"Path"`
+	Local   string `json:"LocalTime"`
 	Band    string `json:"Band"` //todo, I don't think this is used anymore
 	Mode    string `json:"Mode"` //todo, I don't think this is used anymore
 }
@@ -94,14 +119,21 @@ type QRZType struct {
 	Class    string `json:"Class"`
 	TimeZone string `json:"TimeZone"`
 	QSLCount string `json:"QSOCount"`
+	Path     string `json:"Path"`
+	Local    string `json:"LocalTime"`
 }
 
 //<++++++++++++++++++++++++++++  Logger  ++++++++++++++++++++++++++++++>
 
 func (app *application) qsolog(w http.ResponseWriter, r *http.Request) {
+	app.renderLog(w, r, initTemplateData())
+}
+
+// renders the log page with the latest QSOs, or the latest contest QSOs in
+// the contest mode
+func (app *application) renderLog(w http.ResponseWriter, r *http.Request, td *templateData) {
 	var err error
 
-	td := initTemplateData()
 	td.Logger = true
 	v, err := app.otherModel.getDefault("contest") //Yes or No
 	if err != nil {
@@ -251,15 +283,28 @@ func (app *application) addlog(w http.ResponseWriter, r *http.Request) {
 	// td.FormData.Set("sent", v)
 
 	//<++++++++++++++  Save the new log
+	if tr.Freq == "" {
+		tr.Freq, tr.FreqRx, err = app.currentFreq(tr.Band)
+		if err != nil {
+			app.serverError(w, err)
+			return
+		}
+	}
 	if contestOn == "Yes" {
 		tr.Contest = contestOn
 		tr.ContestName = name
 	}
-	_, err = app.logsModel.insertLog(&tr)
+	err = app.fillLocation(&tr)
 	if err != nil {
 		app.serverError(w, err)
 		return
 	}
+	id, err := app.logsModel.insertLog(&tr, sourceWeb)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	app.logged(id)
 	//<+++++++++++++  New log saved
 
 	//<+++++++++++++  Set up the new display
@@ -346,11 +391,76 @@ func (app *application) editlog(w http.ResponseWriter, r *http.Request) {
 	}
 	app.putId(id)
 	td.LogEdit = tr
+	td.Path = app.pathToGrid(tr.Grid)
 	td.Show = true
 	td.Edit = true
 	app.render(w, r, "log.page.html", td)
 }
 
+// shows every change to a QSO with a revert button for each
+func (app *application) logHistory(w http.ResponseWriter, r *http.Request) {
+	td := initTemplateData()
+	td.Logger = true
+	id, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	app.renderHistory(w, r, td, id)
+}
+
+func (app *application) revertChange(w http.ResponseWriter, r *http.Request) {
+	td := initTemplateData()
+	td.Logger = true
+	err := r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	change, err := strconv.Atoi(r.PostForm.Get("change"))
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	id, err := strconv.Atoi(r.PostForm.Get("id"))
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	err = app.logsModel.revertChange(change)
+	if err != nil {
+		if !errors.Is(err, errNoChange) && !errors.Is(err, errNoRecord) &&
+			!errors.Is(err, errConflict) {
+			app.serverError(w, err)
+			return
+		}
+		td.Message = fmt.Sprintf("Change %d could not be reverted: %v", change, err)
+	} else {
+		td.Message = fmt.Sprintf("Reverted change %d", change)
+	}
+	app.renderHistory(w, r, td, id)
+}
+
+func (app *application) renderHistory(w http.ResponseWriter, r *http.Request,
+	td *templateData, id int) {
+	var err error
+	td.History, err = app.logsModel.getHistory(id)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	td.LogEdit.Id = id
+	if len(td.History) > 0 {
+		h := td.History[0]
+		if h.After != nil {
+			td.LogEdit = h.After
+		} else {
+			td.LogEdit = h.Before
+		}
+	}
+	app.render(w, r, "loghistory.page.html", td)
+}
+
 func (app *application) updatedb(w http.ResponseWriter, r *http.Request) {
 	td := initTemplateData()
 	td.Logger = true
@@ -385,7 +495,7 @@ func (app *application) updatedb(w http.ResponseWriter, r *http.Request) {
 	tr := copyPostForm(r)
 
 	id := app.getId()
-	err = app.logsModel.updateLog(&tr, id)
+	err = app.logsModel.updateLog(&tr, id, sourceWeb)
 	if err != nil {
 		app.serverError(w, err)
 		return
@@ -435,7 +545,11 @@ func (app *application) getConn(w http.ResponseWriter, r *http.Request) {
 	}
 	update := &LogType{
 		Name:    fmt.Sprintf("%s %s", c.Fname, c.Lname),
-		Country: c.Country,
+		Country: app.qsoCountry(callSign, c.Country),
+		Local:   qrzLocalTime(c, time.Now()),
+	}
+	if p := app.pathToQRZ(c); p != nil {
+		update.Path = p.String()
 	}
 	b, err := json.Marshal(update)
 	if err != nil {
@@ -485,6 +599,12 @@ func (app *application) callSearch(w http.ResponseWriter, r *http.Request) {
 		TimeZone: fmt.Sprintf("Time Zone: %s", c.TimeZone),
 		QSLCount: fmt.Sprintf("QSO Count: %d", c.QSOCount),
 	}
+	if p := app.pathToQRZ(c); p != nil {
+		update.Path = fmt.Sprintf("Path: %s", p)
+	}
+	if t := qrzLocalTime(c, time.Now()); t != "" {
+		update.Local = fmt.Sprintf("Local Time: %s", t)
+	}
 
 	nn := c.NickName
 	if nn == "" {
@@ -797,6 +917,10 @@ func (app *application) contacts(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 	td.LookUp = c
+	if err == nil {
+		td.Path = app.pathToQRZ(c)
+		td.LocalTime = qrzLocalTime(c, time.Now())
+	}
 	app.render(w, r, "contacts.page.html", td)
 }
 
@@ -1032,7 +1156,7 @@ func (app *application) updateLog(w http.ResponseWriter, r *http.Request) {
 		Band:        band,
 		Mode:        mode,
 		Name:        c.Fname + " " + c.Lname,
-		Country:     c.Country,
+		Country:     app.qsoCountry(v.Call, c.Country),
 		Comment:     "",
 	}
 	//Get Sent Fields
@@ -1196,11 +1320,22 @@ func (app *application) updateLog(w http.ResponseWriter, r *http.Request) {
 
 	//<++++++++++++++  Save the new log
 	fmt.Println("on exit field1Sent: ", tr.Field1Sent)
-	_, err = app.logsModel.insertLog(&tr)
+	tr.Freq, tr.FreqRx, err = app.currentFreq(band)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	err = app.fillLocation(&tr)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	id, err := app.logsModel.insertLog(&tr, sourceWeb)
 	if err != nil {
 		app.serverError(w, err)
 		return
 	}
+	app.logged(id)
 	//<+++++++++++++  New log saved
 
 }
@@ -668,3 +668,67 @@ func TestContacts(t *testing.T) {
 		})
 	}
 }
+
+func TestRevertChange(t *testing.T) {
+	app := newTestSQLiteApp(t)
+	l := &LogsRow{Call: "K2LE", Mode: "SSB", Sent: "59", Rcvd: "59", Band: "20m"}
+	id, err := app.logsModel.insertLog(l, sourceWeb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Band = "15m"
+	err = app.logsModel.updateLog(l, id, sourceWeb)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	r, err := http.NewRequest(http.MethodGet, fmt.Sprintf("/loghistory?id=%d", id), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	app.logHistory(rr, r)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected %d got %d", http.StatusOK, rr.Code)
+	}
+	if !strings.Contains(rr.Body.String(), "History of QSO 1 with K2LE") {
+		t.Errorf("the history page did not show the QSO")
+	}
+
+	tests := []struct {
+		name     string
+		change   string
+		wantCode int
+		wantBody string
+		wantBand string
+	}{
+		{"bad change", "abc", 400, "", "15m"},
+		{"no such change", "20", 200, "could not be reverted", "15m"},
+		{"revert the update", "2", 200, "Reverted change 2", "20m"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			body := fmt.Sprintf("change=%s&id=%d", tt.change, id)
+			r, err := http.NewRequest(http.MethodPost, "/revert-change", strings.NewReader(body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			app.revertChange(rr, r)
+			if rr.Code != tt.wantCode {
+				t.Errorf("expected %d got %d", tt.wantCode, rr.Code)
+			}
+			if !strings.Contains(rr.Body.String(), tt.wantBody) {
+				t.Errorf("expected %q in the body, did not get it", tt.wantBody)
+			}
+			got, err := app.logsModel.getLogByID(id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Band != tt.wantBand {
+				t.Errorf("want band %s, got %s", tt.wantBand, got.Band)
+			}
+		})
+	}
+}
@@ -8,25 +8,47 @@ import (
 )
 
 type logsType interface {
-	insertLog(*LogsRow) (int, error)
+	insertLog(*LogsRow, string) (int, error)
+	importLog(*LogsRow, string) (int, error)
+	findDupe(*LogsRow, time.Duration) (bool, error)
 	getLogByID(int) (*LogsRow, error)
 	getLogsByCall(string) ([]*LogsRow, error)
 	getLatestLogs(int) ([]LogsRow, error)
 	getContestLogs(int) ([]LogsRow, error)
 	getADIFData() ([]LogsRow, error)
+	getExportData(*logFilter) ([]LogsRow, error)
+	searchLogs(*logFilter, int, int) ([]LogsRow, int, error)
 	getCabrilloData(*contestData) ([]LogsRow, error)
 	getNewCabrilloData(*contestData) ([]LogsRow, error)
 	updateLOTWSent(int) error
-	updateLog(*LogsRow, int) error
+	getEQSLData() ([]LogsRow, error)
+	updateEQSLSent(int) error
+	getClublogData() ([]LogsRow, error)
+	updateClublogSent(int, time.Time) error
+	updateLog(*LogsRow, int, string) error
+	replaceLog(*LogsRow, string) error
+	getHistory(int) ([]AuditRow, error)
+	revertChange(int) error
+	deleteLogs([]int, string) (int, error)
+	restoreLogs([]int, string) (int, error)
+	purgeLogs([]int) (int, error)
+	getTrash() ([]TrashRow, error)
+	bulkEditLogs([]int, *bulkEdit, string) (int, error)
 	calcContestScore(*contestData) (int, error)
 	getUniqueCountries() ([]LogsRow, error)
 	getConfirmedCountries() ([]LogsRow, error)
 	getLogsByCountry(string) ([]LogsRow, error)
 	getLogsByCounty(string) ([]LogsRow, error)
-	getConfirmedCounties() ([]LogsRow, error)
+	getConfirmedCounties(paper bool) ([]LogsRow, error)
 	getConfirmedContacts() ([]LogsRow, error)
-	updateQSO(map[itemType]string) error
-	getConfirmedStates() ([]LogsRow, error)
+	confirmQSO(int, time.Time, time.Time) error
+	confirmEQSL(int, time.Time, bool) error
+	confirmQRZ(int, time.Time) error
+	getCardQueue() ([]CardQSO, error)
+	queueCards(string) (int, error)
+	cardSent(int, string, time.Time) error
+	cardReceived(int, string, time.Time) error
+	getConfirmedStates(paper bool) ([]LogsRow, error)
 	getLogsByState(string) ([]LogsRow, error)
 	findNeed([]DXClusters) ([]DXClusters, error)
 	getSimpleLogs(string, string, string) ([]LogsRow, error)
@@ -66,6 +88,28 @@ type LogsRow struct {
 	Lotwrcvd    string
 	LotwQSOdate time.Time
 	LotwQSLdate time.Time
+	Eqslsent    string
+	Eqslrcvd    string
+	EqslQSLdate time.Time
+	EqslAG      bool //the eQSL is from an Authenticity Guaranteed member
+	Qrzrcvd     string
+	QrzQSLdate  time.Time
+	Clublogsent string
+	ClublogDate time.Time //uploaded to Club Log
+	QSLsent     string    //paper card, QUEUED until it is sent
+	QSLrcvd     string
+	QSLsentVia  string //bureau, direct or manager
+	QSLrcvdVia  string
+	QSLsdate    time.Time
+	QSLrdate    time.Time
+	Grid        string
+	DXCC        int //ADIF entity number, 0 when not known
+	CQZone      int
+	ITUZone     int
+	Continent   string
+	Distance    int    //km, 0 when a grid is missing
+	Freq        string //MHz, transmit frequency when split
+	FreqRx      string //MHz, only when split
 	Field1Name  string
 	Field2Name  string
 	Field3Name  string
@@ -107,6 +151,7 @@ type headRow struct {
 	Field3Name  string
 	Field4Name  string
 	Field5Name  string
+	Freq        string
 }
 
 var tableHead = headRow{
@@ -133,11 +178,17 @@ var tableHead = headRow{
 	"",
 	"",
 	"",
+	"Freq (MHz)",
 }
 
-// will insert a new record into the stationlogs table
-func (m *logsModel) insertLog(l *LogsRow) (int, error) {
-	fmt.Println("l.Field1Sent: ", l.Field1Sent)
+// will insert a new record into the stationlogs table, logged now
+func (m *logsModel) insertLog(l *LogsRow, source string) (int, error) {
+	l.Time = time.Now().UTC()
+	return m.importLog(l, source)
+}
+
+// trims the log fields that can be longer than their stationlogs columns
+func trimLog(l *LogsRow) {
 	if len(l.Name) > 100 {
 		l.Name = l.Name[0:100]
 	}
@@ -147,57 +198,81 @@ func (m *logsModel) insertLog(l *LogsRow) (int, error) {
 	if len(l.Comment) > 100 {
 		l.Comment = l.Comment[0:100]
 	}
+	if len(l.State) > 50 {
+		l.State = l.State[0:50]
+	}
+	if len(l.County) > 50 {
+		l.County = l.County[0:50]
+	}
+	if len(l.Grid) > 10 {
+		l.Grid = l.Grid[0:10]
+	}
+	if len(l.Continent) > 2 {
+		l.Continent = l.Continent[0:2]
+	}
+}
+
+// will insert a record from another logger, unlike insertLog the QSO time
+// comes with the record
+func (m *logsModel) importLog(l *LogsRow, source string) (int, error) {
+	trimLog(l)
 
 	stmt := `INSERT INTO stationlogs (time, callsign, mode, sent, rcvd,
 	band, name, country, comment, lotwsent, lotwrcvd, contest, exchsent,
 	exchrcvd, contestname,
 	field1Sent, field2Sent, field3Sent, field4Sent, field5Sent,
-	field1Rcvd, field2Rcvd, field3Rcvd, field4Rcvd, field5Rcvd)
-	VALUES (UTC_TIMESTAMP(), ?, ?, ?, ?,
+	field1Rcvd, field2Rcvd, field3Rcvd, field4Rcvd, field5Rcvd, freq, freq_rx,
+	dxcc, cqz, ituz, cont, state, cnty, gridsquare, distance, eqsl_sent, eqsl_rcvd,
+	qsl_sent, qsl_rcvd, qsl_sent_via, qsl_rcvd_via)
+	VALUES (?, ?, ?, ?, ?,
 		?, ?, ?, ?, ?, ?, ?, ?,
 		?, ?,
 		?, ?, ?, ?, ?,
-		?, ?, ?, ?, ?)`
-
-	result, err := m.DB.Exec(stmt,
-		l.Call, l.Mode, l.Sent, l.Rcvd,
-		l.Band, l.Name, l.Country, l.Comment, l.Lotwsent, l.Lotwrcvd,
-		l.Contest, l.ExchSent, l.ExchRcvd, l.ContestName,
-		l.Field1Sent, l.Field2Sent, l.Field3Sent, l.Field4Sent, l.Field5Sent,
-		l.Field1Rcvd, l.Field2Rcvd, l.Field3Rcvd, l.Field4Rcvd, l.Field5Rcvd)
-	if err != nil {
-		return 0, err
-	}
-
-	id, err := result.LastInsertId()
-	if err != nil {
-		return 0, err
-	}
-	return int(id), nil
+		?, ?, ?, ?, ?, ?, ?,
+		?, ?, ?, ?, ?, ?, ?, ?, ?, ?,
+		?, ?, ?, ?)`
+
+	return m.audited(0, auditInsert, source, func(tx *sql.Tx) (int, error) {
+		result, err := tx.Exec(stmt, l.Time.UTC(),
+			l.Call, l.Mode, l.Sent, l.Rcvd,
+			l.Band, l.Name, l.Country, l.Comment, l.Lotwsent, l.Lotwrcvd,
+			l.Contest, l.ExchSent, l.ExchRcvd, l.ContestName,
+			l.Field1Sent, l.Field2Sent, l.Field3Sent, l.Field4Sent, l.Field5Sent,
+			l.Field1Rcvd, l.Field2Rcvd, l.Field3Rcvd, l.Field4Rcvd, l.Field5Rcvd,
+			l.Freq, l.FreqRx,
+			l.DXCC, l.CQZone, l.ITUZone, l.Continent, l.State, l.County, l.Grid,
+			l.Distance, l.Eqslsent, l.Eqslrcvd,
+			l.QSLsent, l.QSLrcvd, l.QSLsentVia, l.QSLrcvdVia)
+		if err != nil {
+			return 0, err
+		}
+		id, err := result.LastInsertId()
+		return int(id), err
+	})
 }
 
-// will get a record given its id
-func (m *logsModel) getLogByID(id int) (*LogsRow, error) {
-	stmt := `SELECT id, time, callsign, mode, sent, rcvd,
-	band, name, country, comment, lotwsent, lotwrcvd
-	FROM stationlogs WHERE id = ?`
-
-	row := m.DB.QueryRow(stmt, id)
-	s := &LogsRow{}
-
-	err := row.Scan(&s.Id, &s.Time, &s.Call, &s.Mode,
-		&s.Sent, &s.Rcvd, &s.Band, &s.Name, &s.Country,
-		&s.Comment, &s.Lotwsent, &s.Lotwrcvd)
+// returns true if there is a QSO with the same call, band and mode within
+// window of the time of l
+func (m *logsModel) findDupe(l *LogsRow, window time.Duration) (bool, error) {
+	stmt := `SELECT id FROM stationlogs WHERE callsign = ? AND band = ? AND mode = ?
+	AND time BETWEEN ? AND ?`
 
+	t := l.Time.UTC()
+	row := m.DB.QueryRow(stmt, l.Call, l.Band, l.Mode, t.Add(-window), t.Add(window))
+	var id int
+	err := row.Scan(&id)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return nil, errNoRecord
-		} else {
-			return nil, err
+			return false, nil
 		}
-
+		return false, err
 	}
-	return s, nil
+	return true, nil
+}
+
+// will get a record given its id
+func (m *logsModel) getLogByID(id int) (*LogsRow, error) {
+	return getFullLog(m.DB, id)
 }
 
 // returns true if dupe
@@ -255,7 +330,7 @@ func (m *logsModel) getLogsByCall(call string) ([]*LogsRow, error) {
 func (m *logsModel) getLatestLogs(n int) ([]LogsRow, error) {
 	stmt := fmt.Sprintf(`SELECT id, time, callsign, mode, sent, rcvd,
 	band, name, country, comment, lotwsent, lotwrcvd, contest, exchsent,
-	exchrcvd, contestname	FROM stationlogs ORDER BY time DESC LIMIT %d`, n)
+	exchrcvd, contestname, freq	FROM stationlogs ORDER BY time DESC LIMIT %d`, n)
 
 	rows, err := m.DB.Query(stmt)
 	if err != nil {
@@ -270,7 +345,7 @@ func (m *logsModel) getLatestLogs(n int) ([]LogsRow, error) {
 		err = rows.Scan(&s.Id, &s.Time, &s.Call, &s.Mode,
 			&s.Sent, &s.Rcvd, &s.Band, &s.Name, &s.Country,
 			&s.Comment, &s.Lotwsent, &s.Lotwrcvd, &s.Contest,
-			&s.ExchSent, &s.ExchRcvd, &s.ContestName)
+			&s.ExchSent, &s.ExchRcvd, &s.ContestName, &s.Freq)
 
 		if err != nil {
 			return nil, err
@@ -329,7 +404,7 @@ func (m *logsModel) getConfirmedContacts() ([]LogsRow, error) {
 func (m *logsModel) getContestLogs(n int) ([]LogsRow, error) {
 	stmt := fmt.Sprintf(`SELECT id, time, callsign, mode, sent, rcvd,
 	band, name, country, comment, lotwsent, lotwrcvd, contest, exchsent,
-	exchrcvd, contestname	FROM stationlogs WHERE contest='Yes' ORDER BY time DESC LIMIT %d`, n)
+	exchrcvd, contestname, freq	FROM stationlogs WHERE contest='Yes' ORDER BY time DESC LIMIT %d`, n)
 
 	rows, err := m.DB.Query(stmt)
 	if err != nil {
@@ -344,7 +419,7 @@ func (m *logsModel) getContestLogs(n int) ([]LogsRow, error) {
 		err = rows.Scan(&s.Id, &s.Time, &s.Call, &s.Mode,
 			&s.Sent, &s.Rcvd, &s.Band, &s.Name, &s.Country,
 			&s.Comment, &s.Lotwsent, &s.Lotwrcvd, &s.Contest,
-			&s.ExchSent, &s.ExchRcvd, &s.ContestName)
+			&s.ExchSent, &s.ExchRcvd, &s.ContestName, &s.Freq)
 
 		if err != nil {
 			return nil, err
@@ -414,22 +489,122 @@ func (m *logsModel) calcContestScore(cd *contestData) (int, error) {
 	return 2 * len(tr1) * len(tr2), nil
 }
 
-func (m *logsModel) updateLog(l *LogsRow, id int) error {
+func (m *logsModel) updateLog(l *LogsRow, id int, source string) error {
 	stmt := `UPDATE stationlogs SET callsign = ?, mode = ?, sent = ?,
 rcvd = ?, band = ?, name = ?, country = ?, comment = ?, lotwsent = ?,
-lotwrcvd = ?  WHERE id = ?`
-	_, err := m.DB.Exec(stmt,
-		l.Call, l.Mode, l.Sent, l.Rcvd,
-		l.Band, l.Name, l.Country, l.Comment, l.Lotwsent, l.Lotwrcvd, id)
+lotwrcvd = ?, freq = ?, freq_rx = ?  WHERE id = ?`
+	_, err := m.audited(id, auditUpdate, source, func(tx *sql.Tx) (int, error) {
+		_, err := tx.Exec(stmt,
+			l.Call, l.Mode, l.Sent, l.Rcvd,
+			l.Band, l.Name, l.Country, l.Comment, l.Lotwsent, l.Lotwrcvd,
+			l.Freq, l.FreqRx, id)
+		return id, err
+	})
+	return err
+}
+
+// writes every field of l to the QSO with its id
+func (m *logsModel) replaceLog(l *LogsRow, source string) error {
+	trimLog(l)
+	_, err := m.audited(l.Id, auditUpdate, source, func(tx *sql.Tx) (int, error) {
+		return l.Id, restoreLog(tx, l)
+	})
+	return err
+}
+
+// the columns getExportData and searchLogs read.  A QSO logged before the
+// location columns were added gets the state, county and grid of the
+// latest QRZ lookup of its call until the backfill fills them in.
+const exportSelect = `SELECT stationlogs.id, stationlogs.time, stationlogs.callsign,
+	stationlogs.mode, stationlogs.sent, stationlogs.rcvd, stationlogs.band,
+	stationlogs.name, stationlogs.country, stationlogs.comment,
+	stationlogs.lotwsent, stationlogs.lotwrcvd, stationlogs.lotwqsldate,
+	stationlogs.contest, stationlogs.exchsent, stationlogs.exchrcvd,
+	stationlogs.contestname,
+	stationlogs.field1Sent, stationlogs.field2Sent, stationlogs.field3Sent,
+	stationlogs.field4Sent, stationlogs.field5Sent,
+	stationlogs.field1Rcvd, stationlogs.field2Rcvd, stationlogs.field3Rcvd,
+	stationlogs.field4Rcvd, stationlogs.field5Rcvd,
+	stationlogs.freq, stationlogs.freq_rx,
+	stationlogs.dxcc, stationlogs.cqz, stationlogs.ituz, stationlogs.cont,
+	stationlogs.distance, stationlogs.eqsl_sent, stationlogs.eqsl_rcvd,
+	stationlogs.eqsl_qslrdate, stationlogs.eqsl_ag, stationlogs.clublog_sent,
+	stationlogs.clublog_date, stationlogs.qsl_sent, stationlogs.qsl_rcvd,
+	stationlogs.qsl_sent_via, stationlogs.qsl_rcvd_via, stationlogs.qsl_sdate,
+	stationlogs.qsl_rdate, ` + exportState + `, COALESCE(NULLIF(stationlogs.cnty, ''), qrztable.county, ''),
+	COALESCE(NULLIF(stationlogs.gridsquare, ''), qrztable.grid, '')`
+
+// the logFilter state is matched against this
+const exportState = `COALESCE(NULLIF(stationlogs.state, ''), qrztable.state, '')`
+
+// the logFilter where clause can use the qrztable columns
+const exportFrom = ` FROM stationlogs LEFT JOIN qrztable ON qrztable.id =
+	(SELECT MAX(q.id) FROM qrztable q WHERE q.callsign = stationlogs.callsign)`
+
+// returns all the fields of the QSOs selected by the filter
+func (m *logsModel) getExportData(lf *logFilter) ([]LogsRow, error) {
+	where, args := lf.where()
+	stmt := exportSelect + exportFrom + ` WHERE ` + where + ` ORDER BY stationlogs.time`
+	return m.queryExportRows(stmt, args...)
+}
+
+// returns one page of the QSOs selected by the filter, the latest first,
+// and how many QSOs the filter selects
+func (m *logsModel) searchLogs(lf *logFilter, offset, limit int) ([]LogsRow, int, error) {
+	where, args := lf.where()
+	var total int
+	err := m.DB.QueryRow(`SELECT COUNT(*)`+exportFrom+` WHERE `+where, args...).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+	stmt := exportSelect + exportFrom + ` WHERE ` + where +
+		` ORDER BY stationlogs.time DESC, stationlogs.id DESC LIMIT ? OFFSET ?`
+	rows, err := m.queryExportRows(stmt, append(args, limit, offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	return rows, total, nil
+}
+
+func (m *logsModel) queryExportRows(stmt string, args ...interface{}) ([]LogsRow, error) {
+	rows, err := m.DB.Query(stmt, args...)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	defer rows.Close()
+
+	tr := []*LogsRow{}
+	for rows.Next() {
+		s := &LogsRow{}
+		err := rows.Scan(&s.Id, &s.Time, &s.Call, &s.Mode,
+			&s.Sent, &s.Rcvd, &s.Band, &s.Name, &s.Country,
+			&s.Comment, &s.Lotwsent, &s.Lotwrcvd, &s.LotwQSLdate,
+			&s.Contest, &s.ExchSent, &s.ExchRcvd, &s.ContestName,
+			&s.Field1Sent, &s.Field2Sent, &s.Field3Sent, &s.Field4Sent, &s.Field5Sent,
+			&s.Field1Rcvd, &s.Field2Rcvd, &s.Field3Rcvd, &s.Field4Rcvd, &s.Field5Rcvd,
+			&s.Freq, &s.FreqRx, &s.DXCC, &s.CQZone, &s.ITUZone, &s.Continent,
+			&s.Distance, &s.Eqslsent, &s.Eqslrcvd, &s.EqslQSLdate, &s.EqslAG,
+			&s.Clublogsent, &s.ClublogDate, &s.QSLsent, &s.QSLrcvd, &s.QSLsentVia,
+			&s.QSLrcvdVia, &s.QSLsdate, &s.QSLrdate,
+			&s.State, &s.County, &s.Grid)
+		if err != nil {
+			return nil, err
+		}
+		tr = append(tr, s)
 	}
-	return nil
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	t := []LogsRow{}
+	for _, item := range tr {
+		t = append(t, *item)
+	}
+	return t, nil
 }
 
 func (m *logsModel) getADIFData() ([]LogsRow, error) {
 	stmt := `SELECT id, time, callsign, mode, sent, rcvd,
-	band, name, country, comment, lotwsent, lotwrcvd
+	band, name, country, comment, lotwsent, lotwrcvd, freq, freq_rx
 	FROM stationlogs WHERE lotwsent <> ? ORDER BY time DESC`
 
 	rows, err := m.DB.Query(stmt, "YES")
@@ -443,7 +618,7 @@ func (m *logsModel) getADIFData() ([]LogsRow, error) {
 		s := &LogsRow{}
 		err := rows.Scan(&s.Id, &s.Time, &s.Call, &s.Mode,
 			&s.Sent, &s.Rcvd, &s.Band, &s.Name, &s.Country,
-			&s.Comment, &s.Lotwsent, &s.Lotwrcvd)
+			&s.Comment, &s.Lotwsent, &s.Lotwrcvd, &s.Freq, &s.FreqRx)
 
 		if err != nil {
 			return nil, err
@@ -463,18 +638,50 @@ func (m *logsModel) getADIFData() ([]LogsRow, error) {
 
 func (m *logsModel) updateLOTWSent(id int) error {
 	stmt := `UPDATE stationlogs SET lotwsent = ? WHERE id = ?`
-	_, err := m.DB.Exec(stmt, "YES", id)
-	if err != nil {
-		return err
-	}
-	return nil
+	_, err := m.audited(id, auditUpdate, sourceLoTW, func(tx *sql.Tx) (int, error) {
+		_, err := tx.Exec(stmt, "YES", id)
+		return id, err
+	})
+	return err
+}
+
+// the QSOs not yet sent to eQSL, the oldest first
+func (m *logsModel) getEQSLData() ([]LogsRow, error) {
+	stmt := exportSelect + exportFrom + ` WHERE stationlogs.eqsl_sent <> ?
+	ORDER BY stationlogs.time`
+	return m.queryExportRows(stmt, "YES")
+}
+
+func (m *logsModel) updateEQSLSent(id int) error {
+	stmt := `UPDATE stationlogs SET eqsl_sent = ? WHERE id = ?`
+	_, err := m.audited(id, auditUpdate, sourceEQSL, func(tx *sql.Tx) (int, error) {
+		_, err := tx.Exec(stmt, "YES", id)
+		return id, err
+	})
+	return err
+}
+
+// the QSOs not yet uploaded to Club Log, the oldest first
+func (m *logsModel) getClublogData() ([]LogsRow, error) {
+	stmt := exportSelect + exportFrom + ` WHERE stationlogs.clublog_sent <> ?
+	ORDER BY stationlogs.time`
+	return m.queryExportRows(stmt, "YES")
+}
+
+func (m *logsModel) updateClublogSent(id int, sent time.Time) error {
+	stmt := `UPDATE stationlogs SET clublog_sent = ?, clublog_date = ? WHERE id = ?`
+	_, err := m.audited(id, auditUpdate, sourceClub, func(tx *sql.Tx) (int, error) {
+		_, err := tx.Exec(stmt, "YES", sent, id)
+		return id, err
+	})
+	return err
 }
 
 func (m *logsModel) getCabrilloData(cd *contestData) ([]LogsRow, error) {
 	stmt := `SELECT id, time, callsign, mode, sent, rcvd, band, name, country,
 	comment, lotwsent, lotwrcvd, contest, exchsent, exchrcvd, contestname, 
 	field1sent, field2sent, field3sent, field4sent, field5sent,
-	field1rcvd, field2rcvd, field3rcvd, field4rcvd, field5rcvd,
+	field1rcvd, field2rcvd, field3rcvd, field4rcvd, field5rcvd, freq
 	FROM stationlogs WHERE contest = ? AND contestname = ? AND time >= ? AND time <= ?
 	ORDER BY time DESC`
 
@@ -492,7 +699,8 @@ func (m *logsModel) getCabrilloData(cd *contestData) ([]LogsRow, error) {
 			&s.Comment, &s.Lotwsent, &s.Lotwrcvd, &s.Contest,
 			&s.ExchSent, &s.ExchRcvd, &s.ContestName,
 			&s.Field1Sent, &s.Field2Sent, &s.Field3Sent, &s.Field4Sent, &s.Field5Sent,
-			&s.Field1Rcvd, &s.Field2Rcvd, &s.Field3Rcvd, &s.Field4Rcvd, &s.Field5Rcvd)
+			&s.Field1Rcvd, &s.Field2Rcvd, &s.Field3Rcvd, &s.Field4Rcvd, &s.Field5Rcvd,
+			&s.Freq)
 
 		if err != nil {
 			return nil, err
@@ -511,7 +719,7 @@ func (m *logsModel) getNewCabrilloData(cd *contestData) ([]LogsRow, error) {
 	band, name, country, comment, lotwsent, lotwrcvd, contest, exchsent,
 	exchrcvd, contestname,
 	field1Sent, field2Sent, field3Sent, field4Sent, field5Sent,
-	Field1Rcvd, field2Rcvd, field3Rcvd, field4Rcvd, field5Rcvd
+	Field1Rcvd, field2Rcvd, field3Rcvd, field4Rcvd, field5Rcvd, freq
 	FROM stationlogs
 	WHERE contest = ? AND contestname = ? ORDER BY time DESC`
 
@@ -530,6 +738,7 @@ func (m *logsModel) getNewCabrilloData(cd *contestData) ([]LogsRow, error) {
 			&s.ExchSent, &s.ExchRcvd, &s.ContestName,
 			&s.Field1Sent, &s.Field2Sent, &s.Field3Sent, &s.Field4Sent, &s.Field5Sent,
 			&s.Field1Rcvd, &s.Field2Rcvd, &s.Field3Rcvd, &s.Field4Rcvd, &s.Field5Rcvd,
+			&s.Freq,
 		)
 
 		if err != nil {
@@ -721,16 +930,25 @@ func (m *logsModel) getLogsByState(state string) ([]LogsRow, error) {
 	return t, nil
 }
 
-func (m *logsModel) getConfirmedCounties() ([]LogsRow, error) {
+// the condition of a confirmed QSO, by LoTW alone or with paper cards too
+func confirmedBy(paper bool) string {
+	if paper {
+		return "(stationlogs.lotwrcvd = 'YES' OR stationlogs.qsl_rcvd = 'YES')"
+	}
+	return "stationlogs.lotwrcvd = 'YES'"
+}
+
+// the QSOs confirmed with a county, with paper the cards count too
+func (m *logsModel) getConfirmedCounties(paper bool) ([]LogsRow, error) {
 	stmt := `SELECT stationlogs.id, stationlogs.time, stationlogs.callsign,
 	stationlogs.mode, stationlogs.sent, stationlogs.rcvd,	stationlogs.band,
 	stationlogs.name, stationlogs.comment, stationlogs.lotwsent,
 	stationlogs.lotwrcvd, qrztable.county, qrztable.state
 	FROM stationlogs inner join qrztable on
-	stationlogs.callsign=qrztable.callsign WHERE stationlogs.lotwrcvd = ? and
-	stationlogs.country = ? and qrztable.county <> '' ORDER BY time DESC`
+	stationlogs.callsign=qrztable.callsign WHERE ` + confirmedBy(paper) + ` and
+	stationlogs.country = ? and qrztable.county <> '' ORDER BY stationlogs.time DESC`
 
-	rows, err := m.DB.Query(stmt, "YES", "United States")
+	rows, err := m.DB.Query(stmt, "United States")
 	if err != nil {
 		return nil, err
 	}
@@ -761,13 +979,14 @@ func (m *logsModel) getConfirmedCounties() ([]LogsRow, error) {
 	return t, nil
 }
 
-func (m *logsModel) getConfirmedStates() ([]LogsRow, error) {
+// the states confirmed, with paper the cards count too
+func (m *logsModel) getConfirmedStates(paper bool) ([]LogsRow, error) {
 	stmt := `SELECT DISTINCT qrztable.state
 	FROM stationlogs inner join qrztable on
-	stationlogs.callsign=qrztable.callsign WHERE stationlogs.lotwrcvd = ? and
-	stationlogs.country = ? and qrztable.state <> '' ORDER BY state ASC`
+	stationlogs.callsign=qrztable.callsign WHERE ` + confirmedBy(paper) + ` and
+	stationlogs.country = ? and qrztable.state <> '' ORDER BY qrztable.state ASC`
 
-	rows, err := m.DB.Query(stmt, "YES", "United States")
+	rows, err := m.DB.Query(stmt, "United States")
 	if err != nil {
 		return nil, err
 	}
@@ -796,51 +1015,120 @@ func (m *logsModel) getConfirmedStates() ([]LogsRow, error) {
 	return t, nil
 }
 
-func (m *logsModel) updateQSO(row map[itemType]string) error {
+// marks the QSO confirmed by LoTW with the times LoTW got the QSO and
+// matched the QSL
+func (m *logsModel) confirmQSO(id int, rxQSO, rxQSL time.Time) error {
+	stmt := `UPDATE stationlogs SET lotwrcvd = ?, lotwqsodate=?, lotwqsldate= ?
+		WHERE id = ?`
+	_, err := m.audited(id, auditUpdate, sourceLoTW, func(tx *sql.Tx) (int, error) {
+		_, err := tx.Exec(stmt, "YES", rxQSO, rxQSL, id)
+		return id, err
+	})
+	return err
+}
 
-	stmt := `SELECT id, time, callsign, mode, band FROM stationlogs
-	WHERE callSign = ?` // AND time = ?`
-	qsoTime := row[itemQSOTimeStamp]
-	t, err := time.Parse(time.RFC3339, qsoTime)
-	if err != nil {
-		return err
-	}
-	rows, err := m.DB.Query(stmt, row[itemCall]) //, t)
+func (m *logsModel) confirmEQSL(id int, rcvd time.Time, ag bool) error {
+	stmt := `UPDATE stationlogs SET eqsl_rcvd = ?, eqsl_qslrdate = ?, eqsl_ag = ?
+		WHERE id = ?`
+	_, err := m.audited(id, auditUpdate, sourceEQSL, func(tx *sql.Tx) (int, error) {
+		_, err := tx.Exec(stmt, "YES", rcvd, ag, id)
+		return id, err
+	})
+	return err
+}
+
+func (m *logsModel) confirmQRZ(id int, rcvd time.Time) error {
+	stmt := `UPDATE stationlogs SET qrz_rcvd = ?, qrz_qslrdate = ? WHERE id = ?`
+	_, err := m.audited(id, auditUpdate, sourceQRZ, func(tx *sql.Tx) (int, error) {
+		_, err := tx.Exec(stmt, "YES", rcvd, id)
+		return id, err
+	})
+	return err
+}
+
+// the QSOs a paper card is to be sent for, queued by hand or answering a
+// card received, by call and time with the QSL manager of the call
+func (m *logsModel) getCardQueue() ([]CardQSO, error) {
+	stmt := `SELECT stationlogs.id, stationlogs.time, stationlogs.callsign,
+	stationlogs.band, stationlogs.mode, stationlogs.sent, stationlogs.qsl_sent,
+	stationlogs.qsl_rcvd, stationlogs.qsl_sent_via, stationlogs.qsl_rcvd_via,
+	COALESCE(qrztable.qslmgr, '')` + exportFrom + ` WHERE stationlogs.qsl_sent = ?
+	OR (stationlogs.qsl_rcvd = ? AND stationlogs.qsl_sent = '')
+	ORDER BY stationlogs.callsign, stationlogs.time`
+
+	rows, err := m.DB.Query(stmt, cardQueued, "YES")
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer rows.Close()
-	var s = &LogsRow{}
+
+	qsos := []CardQSO{}
 	for rows.Next() {
-		s = &LogsRow{}
-		err := rows.Scan(&s.Id, &s.Time, &s.Call, &s.Mode, &s.Band)
+		q := CardQSO{}
+		err = rows.Scan(&q.Id, &q.Time, &q.Call, &q.Band, &q.Mode, &q.Sent,
+			&q.QSLsent, &q.QSLrcvd, &q.QSLsentVia, &q.QSLrcvdVia, &q.Manager)
 		if err != nil {
-			return err
-		}
-
-		if int(t.Sub(s.Time).Round(time.Minute)) == 0 {
-			break
+			return nil, err
 		}
+		qsos = append(qsos, q)
 	}
 	if err = rows.Err(); err != nil {
-		return err
+		return nil, err
 	}
+	return qsos, nil
+}
 
-	tQSO, err := timeIt(row[itemRxQSO])
+// queues a card for each QSO with the call that has none sent or queued
+func (m *logsModel) queueCards(call string) (int, error) {
+	rows, err := m.DB.Query(`SELECT id FROM stationlogs WHERE callsign = ? AND
+	qsl_sent = ''`, call)
 	if err != nil {
-		return err
+		return 0, err
 	}
-	tQSL, err := timeIt(row[itemRxQSL])
-	if err != nil {
-		return err
+	ids := []int{}
+	for rows.Next() {
+		var id int
+		if err = rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		ids = append(ids, id)
 	}
-	stmt = `UPDATE stationlogs SET lotwrcvd = ?, lotwqsodate=?, lotwqsldate= ?
-		WHERE id = ?`
-	_, err = m.DB.Exec(stmt, "YES", tQSO, tQSL, s.Id)
-	if err != nil {
-		return err
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return 0, err
+	}
+	stmt := `UPDATE stationlogs SET qsl_sent = ? WHERE id = ?`
+	for _, id := range ids {
+		_, err = m.audited(id, auditUpdate, sourceWeb, func(tx *sql.Tx) (int, error) {
+			_, err := tx.Exec(stmt, cardQueued, id)
+			return id, err
+		})
+		if err != nil {
+			return 0, err
+		}
 	}
-	return nil
+	return len(ids), nil
+}
+
+func (m *logsModel) cardSent(id int, route string, sent time.Time) error {
+	stmt := `UPDATE stationlogs SET qsl_sent = ?, qsl_sent_via = ?, qsl_sdate = ?
+		WHERE id = ?`
+	_, err := m.audited(id, auditUpdate, sourceWeb, func(tx *sql.Tx) (int, error) {
+		_, err := tx.Exec(stmt, "YES", route, sent, id)
+		return id, err
+	})
+	return err
+}
+
+func (m *logsModel) cardReceived(id int, route string, rcvd time.Time) error {
+	stmt := `UPDATE stationlogs SET qsl_rcvd = ?, qsl_rcvd_via = ?, qsl_rdate = ?
+		WHERE id = ?`
+	_, err := m.audited(id, auditUpdate, sourceWeb, func(tx *sql.Tx) (int, error) {
+		_, err := tx.Exec(stmt, "YES", route, rcvd, id)
+		return id, err
+	})
+	return err
 }
 
 func (m *logsModel) findNeed(dx []DXClusters) ([]DXClusters, error) {
@@ -868,7 +1156,7 @@ func (m *logsModel) findNeed(dx []DXClusters) ([]DXClusters, error) {
 
 func (m *logsModel) getSimpleLogs(mode, confirmed, country string) ([]LogsRow, error) {
 	stmt := `SELECT id, time, callsign, mode, sent, rcvd,
-	band, name, country, comment, lotwsent, lotwrcvd
+	band, name, country, comment, lotwsent, lotwrcvd, dxcc
 	FROM stationlogs WHERE mode like ? and lotwrcvd like ? and country like ? ORDER BY time DESC`
 
 	rows, err := m.DB.Query(stmt, mode, confirmed, country)
@@ -883,7 +1171,7 @@ func (m *logsModel) getSimpleLogs(mode, confirmed, country string) ([]LogsRow, e
 
 		err = rows.Scan(&s.Id, &s.Time, &s.Call, &s.Mode,
 			&s.Sent, &s.Rcvd, &s.Band, &s.Name, &s.Country,
-			&s.Comment, &s.Lotwsent, &s.Lotwrcvd)
+			&s.Comment, &s.Lotwsent, &s.Lotwrcvd, &s.DXCC)
 
 		if err != nil {
 			return nil, err
@@ -937,7 +1225,7 @@ func (m *logsModel) getUniqueState(mode, confirmed string) ([]LogsRow, error) {
 	stmt := `SELECT DISTINCT qrztable.state
 	FROM stationlogs inner join qrztable on
 	stationlogs.callsign=qrztable.callsign WHERE stationlogs.mode like ? and stationlogs.lotwrcvd like ? and
-	stationlogs.country = ? and qrztable.state <> '' ORDER BY state ASC`
+	stationlogs.country = ? and qrztable.state <> '' ORDER BY qrztable.state ASC`
 
 	rows, err := m.DB.Query(stmt, mode, confirmed, "United States")
 	if err != nil {
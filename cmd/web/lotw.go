@@ -0,0 +1,141 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+//<<======================== LoTW report download ========================>>
+
+//The confirmations come straight from the lotwreport.adi query of LoTW
+//with the user name and password given on the command line (-lotwuser and
+//-lotwpw).  LoTW sends the time of the last QSL in the report and the next
+//download asks only for the QSLs since then, so after the first one a
+//download is small.  The time is kept in the defaults table.  The download
+//runs from the ADIF page and, with lotwevery in config.yaml, on a schedule.
+
+const lotwReportURL = "https://lotw.arrl.org/lotwuser/lotwreport.adi"
+
+// the defaults key of the time of the last QSL downloaded
+const lotwSinceKey = "lotwqslsince"
+
+var (
+	errLoTWUser  = errors.New("no LoTW user name and password, start with -lotwuser and -lotwpw")
+	errLoTW      = errors.New("LoTW download failed")
+	errLoTWLogin = fmt.Errorf("%w, no report came back, check the user name and password", errLoTW)
+)
+
+// lotwDownload is the outcome of one download
+type lotwDownload struct {
+	Since string //the QSLs since this time were asked for, blank for all
+	Last  string //time of the last QSL LoTW has, the next Since
+	QSLs  int    //confirmations in the report
+	qslMatch
+}
+
+// the URL of the report of the QSLs since a time, all of them when since
+// is blank
+func (app *application) lotwQuery(since string) string {
+	v := url.Values{}
+	v.Set("login", app.lotwuser)
+	v.Set("password", app.lotwpw)
+	v.Set("qso_query", "1")
+	v.Set("qso_qsl", "yes")
+	if since != "" {
+		v.Set("qso_qslsince", since)
+	}
+	u := app.lotwURL
+	if u == "" {
+		u = lotwReportURL
+	}
+	return u + "?" + v.Encode()
+}
+
+// gets a LoTW report.  LoTW answers a bad login with a web page and not
+// an error status so a report without an ADIF header is taken as one.
+func getLoTWReport(u string) (string, error) {
+	resp, err := client.Get(u)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", errLoTW, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%w: status %d", errLoTW, resp.StatusCode)
+	}
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", errLoTW, err)
+	}
+	report := string(data)
+	if !strings.Contains(strings.ToUpper(report), "<EOH>") {
+		return "", errLoTWLogin
+	}
+	return report, nil
+}
+
+// the APP_LoTW_LASTQSL field of the report header, blank if it has none
+func lotwLastQSL(report string) string {
+	n := strings.Index(strings.ToUpper(report), "<EOH>")
+	m := strings.Index(report, "<")
+	if n == -1 || m == -1 || m > n {
+		return ""
+	}
+	records, err := parseADIF(report[m:n] + "<EOR>")
+	if err != nil || len(records) == 0 {
+		return ""
+	}
+	return strings.TrimSpace(records[0]["APP_LOTW_LASTQSL"])
+}
+
+// downloads the QSLs since the last download and confirms their QSOs
+func (app *application) downloadLoTW() (*lotwDownload, error) {
+	if app.lotwuser == "" || app.lotwpw == "" {
+		return nil, errLoTWUser
+	}
+	since, err := app.otherModel.getDefault(lotwSinceKey)
+	if err != nil && !errors.Is(err, errNoRecord) {
+		return nil, err
+	}
+	report, err := getLoTWReport(app.lotwQuery(since))
+	if err != nil {
+		return nil, err
+	}
+	d := &lotwDownload{Since: since, Last: since}
+	rows, err := parseQSLData(report)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errLoTW, err)
+	}
+	m, err := app.matchQSLs(rows)
+	if err != nil {
+		return nil, err
+	}
+	d.QSLs = len(rows)
+	d.qslMatch = *m
+	if last := lotwLastQSL(report); last != "" {
+		d.Last = last
+		err = app.otherModel.updateDefault(lotwSinceKey, last)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return d, nil
+}
+
+// downloads the new QSLs every so often for as long as the program runs
+func (app *application) lotwLoop(every time.Duration) {
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+	for range ticker.C {
+		d, err := app.downloadLoTW()
+		if err != nil {
+			app.errorLog.Printf("scheduled LoTW download failed: %v", err)
+			continue
+		}
+		app.infoLog.Printf("downloaded %d QSLs from LoTW", d.QSLs)
+	}
+}
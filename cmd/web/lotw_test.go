@@ -0,0 +1,199 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// a LoTW report as lotwreport.adi sends it, comments and all
+const lotwTestReport = `ARRL Logbook of the World Status Report
+Generated at 2023-04-20 12:00:00
+for n2vy
+Query:
+    QSL ONLY: YES
+QSL RX SINCE: 2023-04-01 00:00:00 (user supplied value)
+
+<PROGRAMID:4>LoTW
+<APP_LoTW_LASTQSL:19>2023-04-16 10:05:31
+<APP_LoTW_NUMREC:1>2
+
+<eoh>
+
+<APP_LoTW_OWNCALL:4>N2VY
+<STATION_CALLSIGN:4>N2VY
+<CALL:6>DL1ABC
+<BAND:3>20M
+<FREQ:8>14.02500
+<MODE:2>CW
+<APP_LoTW_MODEGROUP:2>CW
+<QSO_DATE:8>20230415
+<APP_LoTW_RXQSO:19>2023-04-15 18:00:00 // QSO record inserted/modified at LoTW
+<TIME_ON:6>141500
+<APP_LoTW_QSO_TIMESTAMP:20>2023-04-15T14:15:00Z // QSO Date & Time; ISO-8601
+<QSL_RCVD:1>Y
+<QSLRDATE:8>20230416
+<APP_LoTW_RXQSL:19>2023-04-16 10:05:31 // QSL record matched/modified at LoTW
+<eor>
+
+<APP_LoTW_OWNCALL:4>N2VY
+<STATION_CALLSIGN:4>N2VY
+<CALL:4>G3AB
+<BAND:3>40M
+<MODE:3>FT8
+<QSO_DATE:8>20230415
+<APP_LoTW_RXQSO:19>2023-04-15 19:00:00
+<TIME_ON:6>150000
+<APP_LoTW_QSO_TIMESTAMP:20>2023-04-15T15:00:00Z
+<QSL_RCVD:1>Y
+<APP_LoTW_RXQSL:19>2023-04-16 09:00:00
+<eor>
+
+<APP_LoTW_EOF>
+`
+
+// a LoTW stand-in that answers with the report and keeps the queries
+func newLoTWServer(t *testing.T, report string, status int) (*httptest.Server, *[]url.Values) {
+	queries := []url.Values{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.Query())
+		w.WriteHeader(status)
+		w.Write([]byte(report))
+	}))
+	t.Cleanup(srv.Close)
+	old := client
+	client = srv.Client()
+	t.Cleanup(func() { client = old })
+	return srv, &queries
+}
+
+func TestLoTWLastQSL(t *testing.T) {
+	tests := []struct {
+		name   string
+		report string
+		want   string
+	}{
+		{"report", lotwTestReport, "2023-04-16 10:05:31"},
+		{"no last QSL", "<PROGRAMID:4>LoTW\n<eoh>\n", ""},
+		{"no header", "<CALL:4>G3AB<eor>", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := lotwLastQSL(tt.report); got != tt.want {
+				t.Errorf("want %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestDownloadLoTW(t *testing.T) {
+	app := newTestSQLiteApp(t)
+	app.lotwuser, app.lotwpw = "n2vy", "secret"
+	for _, l := range []LogsRow{
+		{Call: "DL1ABC", Band: "20m", Mode: "CW", Time: time.Date(2023, 4, 15, 14, 15, 0, 0, time.UTC)},
+		{Call: "K1ABC", Band: "20m", Mode: "CW", Time: time.Date(2023, 4, 15, 14, 30, 0, 0, time.UTC)},
+	} {
+		_, err := app.logsModel.importLog(&l, sourceImport)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	srv, queries := newLoTWServer(t, lotwTestReport, http.StatusOK)
+	app.lotwURL = srv.URL
+
+	d, err := app.downloadLoTW()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.QSLs != 2 || d.Since != "" || d.Last != "2023-04-16 10:05:31" {
+		t.Errorf("want 2 QSLs up to 2023-04-16 10:05:31, got %v", d)
+	}
+	if d.Confirmed != 1 || d.Unmatched != 1 {
+		t.Errorf("want DL1ABC confirmed and G3AB unmatched, got %v", d.qslMatch)
+	}
+	q := (*queries)[0]
+	if q.Get("login") != "n2vy" || q.Get("password") != "secret" || q.Get("qso_qsl") != "yes" ||
+		q.Get("qso_query") != "1" || q.Get("qso_qslsince") != "" {
+		t.Errorf("bad first query %v", q)
+	}
+	l, err := app.logsModel.getLogByID(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.Lotwrcvd != "YES" || !l.LotwQSLdate.Equal(time.Date(2023, 4, 16, 10, 5, 31, 0, time.UTC)) {
+		t.Errorf("want DL1ABC confirmed on 2023-04-16 10:05:31, got %s %v", l.Lotwrcvd, l.LotwQSLdate)
+	}
+	l, err = app.logsModel.getLogByID(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.Lotwrcvd == "YES" {
+		t.Error("K1ABC is not in the report and was confirmed")
+	}
+
+	_, err = app.downloadLoTW()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := (*queries)[1].Get("qso_qslsince"); got != "2023-04-16 10:05:31" {
+		t.Errorf("want the second download since the last QSL, got %q", got)
+	}
+}
+
+func TestDownloadLoTWErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		user   string
+		report string
+		status int
+		want   error
+	}{
+		{"no user", "", lotwTestReport, http.StatusOK, errLoTWUser},
+		{"bad login", "n2vy", "<html><body>Username/password incorrect</body></html>", http.StatusOK, errLoTWLogin},
+		{"server error", "n2vy", "", http.StatusInternalServerError, errLoTW},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestSQLiteApp(t)
+			app.lotwuser, app.lotwpw = tt.user, "secret"
+			srv, _ := newLoTWServer(t, tt.report, tt.status)
+			app.lotwURL = srv.URL
+			_, err := app.downloadLoTW()
+			if !errors.Is(err, tt.want) {
+				t.Errorf("want %v, got %v", tt.want, err)
+			}
+			since, err := app.otherModel.getDefault(lotwSinceKey)
+			if !errors.Is(err, errNoRecord) {
+				t.Errorf("a failed download kept the time %q", since)
+			}
+		})
+	}
+}
+
+func TestLoTWDownloadHandler(t *testing.T) {
+	app := newTestSQLiteApp(t)
+	app.lotwuser, app.lotwpw = "n2vy", "secret"
+	srv, _ := newLoTWServer(t, lotwTestReport, http.StatusOK)
+	app.lotwURL = srv.URL
+
+	rr := httptest.NewRecorder()
+	app.routes().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/lotw-download", nil))
+	if rr.Code != http.StatusMethodNotAllowed {
+		t.Errorf("want 405 for a GET, got %d", rr.Code)
+	}
+	rr = httptest.NewRecorder()
+	app.routes().ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/lotw-download", nil))
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), "Downloaded 2 QSLs from LoTW") {
+		t.Errorf("want the download message, got %d %q", rr.Code, rr.Body.String())
+	}
+	app.lotwpw = ""
+	rr = httptest.NewRecorder()
+	app.routes().ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/lotw-download", nil))
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), "-lotwuser") {
+		t.Errorf("want the missing user message, got %d", rr.Code)
+	}
+}
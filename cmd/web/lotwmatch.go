@@ -0,0 +1,245 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+//<<==================== Matching LoTW confirmations ======================>>
+
+//A LoTW confirmation confirms the QSO in the log with the same call and
+//band, the same mode group (LoTW matches CW, phone and data modes as
+//groups) and a time within lotwwindow in config.yaml of the QSO time LoTW
+//has.  Blank, the window is the 30 minutes LoTW matches QSOs within.  A
+//confirmation that matches no QSO, or more than one, is kept for the LoTW
+//QSLs page to be linked to a QSO or logged as a new one.
+
+const defaultQSLWindow = 30 * time.Minute
+
+const (
+	qslUnmatched = "unmatched"
+	qslAmbiguous = "ambiguous"
+)
+
+// LoTWQSL is one confirmation in a LoTW report
+type LoTWQSL struct {
+	Call  string
+	Band  string
+	Mode  string
+	Time  time.Time //of the QSO
+	RxQSO time.Time //LoTW got the QSO
+	RxQSL time.Time //LoTW matched the QSL
+}
+
+// qslMatch is what came of matching the confirmations of a report
+type qslMatch struct {
+	Confirmed int
+	Unmatched int
+	Ambiguous int
+}
+
+// a note on the confirmations left for the LoTW QSLs page, blank if none
+func (m *qslMatch) String() string {
+	if m.Unmatched == 0 && m.Ambiguous == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%d matched no QSO and %d more than one, see LoTW QSLs",
+		m.Unmatched, m.Ambiguous)
+}
+
+func newLoTWQSL(row map[itemType]string) (*LoTWQSL, error) {
+	q := &LoTWQSL{
+		Call: strings.ToUpper(strings.TrimSpace(row[itemCall])),
+		Band: strings.ToLower(strings.TrimSpace(row[itemBand])),
+		Mode: strings.ToUpper(strings.TrimSpace(row[itemMode])),
+	}
+	var err error
+	q.Time, err = time.Parse(time.RFC3339, row[itemQSOTimeStamp])
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s has no QSO time: %v", errQSLReport, q.Call, err)
+	}
+	q.RxQSO, err = timeIt(row[itemRxQSO])
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s has no QSO received time: %v", errQSLReport, q.Call, err)
+	}
+	q.RxQSL, err = timeIt(row[itemRxQSL])
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s has no QSL received time: %v", errQSLReport, q.Call, err)
+	}
+	return q, nil
+}
+
+// the LoTW mode group of a mode
+func modeGroup(mode string) string {
+	switch strings.ToUpper(mode) {
+	case "CW":
+		return "CW"
+	case "SSB", "USB", "LSB", "AM", "FM", "PHONE":
+		return "PHONE"
+	}
+	return "DATA"
+}
+
+func (app *application) qslWindow() time.Duration {
+	if app.lotwWindow > 0 {
+		return app.lotwWindow
+	}
+	return defaultQSLWindow
+}
+
+// the QSOs with the call from the day before the time to the day after
+func (app *application) qsosNear(call string, t time.Time) ([]LogsRow, error) {
+	day := t.UTC().Truncate(24 * time.Hour)
+	return app.logsModel.getExportData(&logFilter{Call: call,
+		Start: day.Add(-24 * time.Hour), End: day.Add(24 * time.Hour)})
+}
+
+// the QSOs the confirmation matches
+func (app *application) matchQSL(q *LoTWQSL) ([]LogsRow, error) {
+	return app.matchQSOs(q.Call, q.Band, q.Mode, q.Time)
+}
+
+// the QSOs with the call, band and mode group within the window of the
+// time, the ones a confirmation of that QSO could be for
+func (app *application) matchQSOs(call, band, mode string, t time.Time) ([]LogsRow, error) {
+	rows, err := app.qsosNear(call, t)
+	if err != nil {
+		return nil, err
+	}
+	window := app.qslWindow()
+	matches := []LogsRow{}
+	for _, l := range rows {
+		d := l.Time.Sub(t)
+		if d < -window || d > window || !strings.EqualFold(l.Band, band) ||
+			modeGroup(l.Mode) != modeGroup(mode) {
+			continue
+		}
+		matches = append(matches, l)
+	}
+	return matches, nil
+}
+
+// confirms the QSOs the confirmations of a report match and keeps the
+// rest for review
+func (app *application) matchQSLs(rows []map[itemType]string) (*qslMatch, error) {
+	m := &qslMatch{}
+	received := time.Now().UTC().Truncate(time.Second)
+	for _, row := range rows {
+		q, err := newLoTWQSL(row)
+		if err != nil {
+			return m, err
+		}
+		matches, err := app.matchQSL(q)
+		if err != nil {
+			return m, err
+		}
+		if len(matches) == 1 {
+			err = app.logsModel.confirmQSO(matches[0].Id, q.RxQSO, q.RxQSL)
+			if err != nil {
+				return m, err
+			}
+			m.Confirmed++
+			continue
+		}
+		u := &UnmatchedQSL{Received: received, Reason: qslUnmatched, Source: sourceLoTW,
+			LoTWQSL: *q}
+		if len(matches) > 1 {
+			u.Reason = qslAmbiguous
+			m.Ambiguous++
+		} else {
+			m.Unmatched++
+		}
+		err = app.qslModel.insertUnmatched(u)
+		if err != nil {
+			return m, err
+		}
+	}
+	return m, nil
+}
+
+// the waiting confirmations with the QSOs each could be
+func (app *application) unmatchedQSLs() ([]UnmatchedQSL, error) {
+	qsls, err := app.qslModel.getUnmatchedQSLs()
+	if err != nil {
+		return nil, err
+	}
+	for i := range qsls {
+		qsls[i].Candidates, err = app.qsosNear(qsls[i].Call, qsls[i].Time)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return qsls, nil
+}
+
+// what a waiting confirmation came from, for the messages
+func qslSourceName(source string) string {
+	switch source {
+	case sourceEQSL:
+		return "eQSL"
+	case sourceQRZ:
+		return "QRZ Logbook"
+	}
+	return "LoTW"
+}
+
+// From is where the waiting confirmation came from, for the review page
+func (u UnmatchedQSL) From() string {
+	return qslSourceName(u.Source)
+}
+
+// confirms the QSO the way the source of the waiting confirmation does
+func (app *application) confirmWaiting(u *UnmatchedQSL, logID int) error {
+	switch u.Source {
+	case sourceEQSL:
+		return app.logsModel.confirmEQSL(logID, u.RxQSL, u.AG)
+	case sourceQRZ:
+		return app.logsModel.confirmQRZ(logID, u.RxQSL)
+	}
+	return app.logsModel.confirmQSO(logID, u.RxQSO, u.RxQSL)
+}
+
+// confirms the QSO with the waiting confirmation
+func (app *application) linkQSL(id, logID int) (*UnmatchedQSL, error) {
+	u, err := app.qslModel.getUnmatched(id)
+	if err != nil {
+		return nil, err
+	}
+	err = app.confirmWaiting(u, logID)
+	if err != nil {
+		return nil, err
+	}
+	return u, app.qslModel.deleteUnmatched(id)
+}
+
+// logs the waiting confirmation as a new QSO, sent to and confirmed by
+// where the confirmation came from
+func (app *application) addQSL(id int) (*LogsRow, error) {
+	u, err := app.qslModel.getUnmatched(id)
+	if err != nil {
+		return nil, err
+	}
+	l := &LogsRow{Time: u.Time, Call: u.Call, Band: u.Band, Mode: u.Mode}
+	switch u.Source {
+	case sourceEQSL:
+		l.Eqslsent = "YES"
+	case sourceLoTW:
+		l.Lotwsent = "YES"
+	}
+	l.Country = app.entityOf(*l)
+	err = app.fillLocation(l)
+	if err != nil {
+		return nil, err
+	}
+	l.Id, err = app.logsModel.importLog(l, u.Source)
+	if err != nil {
+		return nil, err
+	}
+	//the confirmation times are not imported
+	err = app.confirmWaiting(u, l.Id)
+	if err != nil {
+		return nil, err
+	}
+	return l, app.qslModel.deleteUnmatched(id)
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// a LoTW report record of the confirmation
+func qslRecord(call, band, mode, qsoTime string) map[itemType]string {
+	return map[itemType]string{
+		itemCall:         call,
+		itemBand:         band,
+		itemMode:         mode,
+		itemQSOTimeStamp: qsoTime,
+		itemQSLrcvd:      "Y",
+		itemRxQSO:        "2023-04-15 18:00:00",
+		itemRxQSL:        "2023-04-16 10:05:31",
+	}
+}
+
+func TestMatchQSLs(t *testing.T) {
+	qso := time.Date(2023, 4, 15, 14, 15, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		window time.Duration
+		logged []LogsRow
+		qsl    map[itemType]string
+		want   qslMatch
+		reason string
+	}{
+		{"exact", 0, []LogsRow{{Call: "DL1ABC", Band: "20m", Mode: "CW", Time: qso}},
+			qslRecord("DL1ABC", "20M", "CW", "2023-04-15T14:15:00Z"), qslMatch{Confirmed: 1}, ""},
+		{"mode group", 0, []LogsRow{{Call: "DL1ABC", Band: "20m", Mode: "USB", Time: qso}},
+			qslRecord("DL1ABC", "20M", "SSB", "2023-04-15T14:15:00Z"), qslMatch{Confirmed: 1}, ""},
+		{"in the window", 0, []LogsRow{{Call: "DL1ABC", Band: "20m", Mode: "CW", Time: qso}},
+			qslRecord("DL1ABC", "20M", "CW", "2023-04-15T14:40:00Z"), qslMatch{Confirmed: 1}, ""},
+		{"out of a set window", 10 * time.Minute, []LogsRow{{Call: "DL1ABC", Band: "20m", Mode: "CW", Time: qso}},
+			qslRecord("DL1ABC", "20M", "CW", "2023-04-15T14:40:00Z"), qslMatch{Unmatched: 1}, qslUnmatched},
+		{"other band", 0, []LogsRow{{Call: "DL1ABC", Band: "40m", Mode: "CW", Time: qso}},
+			qslRecord("DL1ABC", "20M", "CW", "2023-04-15T14:15:00Z"), qslMatch{Unmatched: 1}, qslUnmatched},
+		{"other mode", 0, []LogsRow{{Call: "DL1ABC", Band: "20m", Mode: "FT8", Time: qso}},
+			qslRecord("DL1ABC", "20M", "CW", "2023-04-15T14:15:00Z"), qslMatch{Unmatched: 1}, qslUnmatched},
+		{"not in the log", 0, nil,
+			qslRecord("DL1ABC", "20M", "CW", "2023-04-15T14:15:00Z"), qslMatch{Unmatched: 1}, qslUnmatched},
+		{"two QSOs", 0, []LogsRow{
+			{Call: "DL1ABC", Band: "20m", Mode: "CW", Time: qso},
+			{Call: "DL1ABC", Band: "20m", Mode: "CW", Time: qso.Add(5 * time.Minute)}},
+			qslRecord("DL1ABC", "20M", "CW", "2023-04-15T14:15:00Z"), qslMatch{Ambiguous: 1}, qslAmbiguous},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestSQLiteApp(t)
+			app.lotwWindow = tt.window
+			for _, l := range tt.logged {
+				_, err := app.logsModel.importLog(&l, sourceImport)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			m, err := app.matchQSLs([]map[itemType]string{tt.qsl})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *m != tt.want {
+				t.Errorf("want %v, got %v", tt.want, *m)
+			}
+			confirmed := 0
+			for id := 1; id <= len(tt.logged); id++ {
+				l, err := app.logsModel.getLogByID(id)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if l.Lotwrcvd == "YES" {
+					confirmed++
+					if !l.LotwQSLdate.Equal(time.Date(2023, 4, 16, 10, 5, 31, 0, time.UTC)) {
+						t.Errorf("want the QSL date, got %v", l.LotwQSLdate)
+					}
+				}
+			}
+			if confirmed != tt.want.Confirmed {
+				t.Errorf("want %d QSOs confirmed, got %d", tt.want.Confirmed, confirmed)
+			}
+			//the same report again keeps the confirmation waiting once
+			_, err = app.matchQSLs([]map[itemType]string{tt.qsl})
+			if err != nil {
+				t.Fatal(err)
+			}
+			qsls, err := app.qslModel.getUnmatchedQSLs()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.reason == "" && len(qsls) != 0 || tt.reason != "" && (len(qsls) != 1 || qsls[0].Reason != tt.reason) {
+				t.Errorf("want %q waiting, got %v", tt.reason, qsls)
+			}
+		})
+	}
+}
+
+func TestParseQSLDataError(t *testing.T) {
+	_, err := parseQSLData("<eoh>\n<CALL:x>DL1ABC\n<eor>\n")
+	if !errors.Is(err, errQSLReport) {
+		t.Errorf("want %v, got %v", errQSLReport, err)
+	}
+	app := newTestSQLiteApp(t)
+	_, err = app.matchQSLs([]map[itemType]string{{itemCall: "DL1ABC", itemQSOTimeStamp: "yesterday"}})
+	if !errors.Is(err, errQSLReport) {
+		t.Errorf("want %v for a record without a time, got %v", errQSLReport, err)
+	}
+}
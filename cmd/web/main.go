@@ -7,12 +7,14 @@ import (
 	//	"flag"
 	"fmt"
 	"html/template"
+	"io"
 	"log"
 	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
 	"sync"
+	"time"
 
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/go-yaml/yaml"
@@ -20,29 +22,40 @@ import (
 
 	"github.com/Saied74/stationmaster/pkg/bandselect"
 	"github.com/Saied74/stationmaster/pkg/code"
+	"github.com/Saied74/stationmaster/pkg/cty"
 
 	//	"github.com/Saied74/stationmaster/pkg/code"
 	"github.com/Saied74/stationmaster/pkg/vfo"
 )
 
-const (
-	myCall string = "N2VY"
-)
-
 //The design of this program is along the lines of Alex Edward's
 //Let's Go except since it is a single user local program, it
 //ignore the rules for a shared over the internet application
 
 type configType struct {
-	DSN        string `yaml:"dsn"`
-	ConfigFile string `yaml:"configfile"`
-	ADIFFile   string `yaml:"adiffile"`
-	QSLdir     string `yaml:"qsldir"`
-	ContestDir string `yaml:"contestdir"`
-	Spider0    string `yaml:"spider0"` //also the default on flag
-	Spider1    string `yaml:"spider1"`
-	Spider2    string `yaml:"spider2"`
-	Spider3    string `yaml:"spider3"`
+	Driver      string `yaml:"driver"` //mysql (default) or sqlite3
+	DSN         string `yaml:"dsn"`
+	SQLiteFile  string `yaml:"sqlitefile"`
+	ConfigFile  string `yaml:"configfile"`
+	ADIFFile    string `yaml:"adiffile"`
+	QSLdir      string `yaml:"qsldir"`
+	ContestDir  string `yaml:"contestdir"`
+	Spider0     string `yaml:"spider0"` //also the default on flag
+	Spider1     string `yaml:"spider1"`
+	Spider2     string `yaml:"spider2"`
+	Spider3     string `yaml:"spider3"`
+	BackupDir   string `yaml:"backupdir"`
+	BackupEvery string `yaml:"backupevery"` //e.g. 24h, blank for no scheduled backups
+	BackupKeep  int    `yaml:"backupkeep"`  //backups kept, 0 keeps them all
+	CtyFile     string `yaml:"ctyfile"`     //cty.dat, cty.csv or cty.xml, blank to use QRZ
+	LoTWEvery   string `yaml:"lotwevery"`   //e.g. 6h, blank for no scheduled LoTW downloads
+	LoTWWindow  string `yaml:"lotwwindow"`  //QSL to QSO time match, blank for 30m
+	TQSL        string `yaml:"tqsl"`        //the tqsl program, blank for no LoTW uploads
+	TQSLStation string `yaml:"tqslstation"` //TQSL station location to sign with
+	QRZLogEvery string `yaml:"qrzlogevery"` //e.g. 1h, blank for no scheduled QRZ Logbook syncs
+	ClublogMail string `yaml:"clublogemail"`
+	ClublogCall string `yaml:"clublogcall"` //the callsign of the log at Club Log
+	ClublogAPI  string `yaml:"clublogapi"`  //Club Log API key, blank for no Club Log uploads
 }
 
 // for injecting data into handlers
@@ -55,6 +68,11 @@ type application struct {
 	qrzModel      qrzType
 	otherModel    otherType
 	contestModel  contestType
+	profileModel  profileType
+	backupModel   backupType
+	uploadModel   uploadType
+	qslModel      qslType
+	qrzLogModel   qrzLogType
 	putCancel     putCancelFunc
 	getCancel     getCancelFunc
 	putId         putIdFunc
@@ -62,18 +80,46 @@ type application struct {
 	sKey          sessionMgr
 	qrzuser       string
 	qrzpw         string
+	lotwuser      string
+	lotwpw        string
+	lotwURL       string //the LoTW report query, blank for the real one
+	lotwEvery     time.Duration
+	lotwWindow    time.Duration //zero for the default
+	eqsluser      string
+	eqslpw        string
+	eqslURL       string //the eQSL site, blank for the real one
+	qrzlogKey     string //QRZ Logbook API key
+	qrzlogURL     string //the QRZ Logbook API, blank for the real one
+	qrzlogEvery   time.Duration
+	qrzlogLock    sync.Mutex //one push at a time
+	clublogEmail  string
+	clublogpw     string
+	clublogCall   string
+	clublogAPI    string
+	clublogURL    string     //the Club Log site, blank for the real one
+	clublogLock   sync.Mutex //one upload at a time
+	tqsl          string     //path of the TQSL program
+	tqslStation   string
+	tqslLock      sync.Mutex //one upload at a time
 	adifFile      string
 	qslDir        string
 	contestDir    string
+	backupDir     string
+	backupKeep    int
+	backupEvery   time.Duration
+	backupLock    sync.Mutex
+	cty           *cty.Database //nil without a country file
 	vfoAdaptor    *raspi.Adaptor
 	bandData      *bandselect.BandData
 	cw            *cwData //*code.CwDriver
 	cqStat        [wsjtBuffer]int
 	qsoStat       [wsjtBuffer]int
 	wsjtPntr      int
-	call          string //user call sign, over ridden by call flag
+	callLock      sync.RWMutex
+	call          string //call sign of the active station profile, behind callLock
 	dxspider      string //<ip address>:<port number>
-	sp            spider
+	spLock        sync.Mutex
+	sp            spider //the DX spider connection, behind spLock
 	remLock       sync.Mutex
 	rem           remotes
 	portName      string
@@ -83,6 +129,7 @@ type application struct {
 
 type httpClient interface {
 	Get(url string) (*http.Response, error)
+	Post(url, contentType string, body io.Reader) (*http.Response, error)
 }
 
 type createFunction interface{}
@@ -105,8 +152,13 @@ func main() {
 	displayLines := flag.Int("lines", 20, "No. of lines to be displayed on logs")
 	qrzpw := flag.String("qrzpw", "", "QRZ.com Password")
 	qrzuser := flag.String("qrzuser", "", "QRZ.com User Name")
+	lotwpw := flag.String("lotwpw", "", "LoTW Password")
+	lotwuser := flag.String("lotwuser", "", "LoTW User Name")
+	eqslpw := flag.String("eqslpw", "", "eQSL Password")
+	eqsluser := flag.String("eqsluser", "", "eQSL User Name")
+	qrzlogkey := flag.String("qrzlogkey", "", "QRZ Logbook API Key")
+	clublogpw := flag.String("clublogpw", "", "Club Log Password")
 	dxSpider := flag.String("spider", "coax.w1wra.net:7300", "dxspider server ip:port address")
-	//myCall := flag.String("call", myCall, "your call sign")
 	vid := flag.String("vid", "2341", "USB Vendor ID default is Arduino SA")
 
 	flag.Parse()
@@ -131,58 +183,158 @@ func main() {
 		errorLog.Fatal(err)
 	}
 
-	dsn := fmt.Sprintf(config.DSN, *sqlpw)
+	home := os.Getenv("HOME")
 
-	db, err := openDB(dsn)
+	if config.Driver == "" {
+		config.Driver = mysqlDriver
+	}
+	var db *sql.DB
+	switch config.Driver {
+	case mysqlDriver:
+		dsn := fmt.Sprintf(config.DSN, *sqlpw)
+		db, err = openDB(mysqlDriver, dsn)
+	case sqliteDriver:
+		sqliteFile := strings.TrimPrefix(config.SQLiteFile, "$HOME/")
+		db, err = openSQLite(filepath.Join(home, sqliteFile))
+	default:
+		err = fmt.Errorf("unknown database driver %s in config.yaml", config.Driver)
+	}
 	if err != nil {
 		errorLog.Fatal(err)
 	}
 
 	defer db.Close()
 
+	version, err := migrate(db, config.Driver)
+	if err != nil {
+		errorLog.Fatal(err)
+	}
+	infoLog.Printf("database schema is at version %d", version)
+	//"stationmaster migrate" only brings the database schema up to date
+	if flag.Arg(0) == "migrate" {
+		return
+	}
+
 	putCancel, getCancel := contextStore()
 	putId, getId := saveId()
-	m := &otherModel{DB: db}
 
-	home := os.Getenv("HOME")
 	qslDir := strings.TrimPrefix(config.QSLdir, "$HOME/")
 	contestDir := strings.TrimPrefix(config.ContestDir, "$HOME/")
 	qslDir = filepath.Join(home, qslDir)
 	contestDir = filepath.Join(home, contestDir)
+	var backupDir string
+	if config.BackupDir != "" {
+		backupDir = filepath.Join(home, strings.TrimPrefix(config.BackupDir, "$HOME/"))
+	}
+	var backupEvery time.Duration
+	if config.BackupEvery != "" {
+		backupEvery, err = time.ParseDuration(config.BackupEvery)
+		if err != nil {
+			errorLog.Fatalf("bad backupevery in config.yaml: %v", err)
+		}
+	}
+	var lotwEvery time.Duration
+	if config.LoTWEvery != "" {
+		lotwEvery, err = time.ParseDuration(config.LoTWEvery)
+		if err != nil {
+			errorLog.Fatalf("bad lotwevery in config.yaml: %v", err)
+		}
+	}
+
+	var qrzlogEvery time.Duration
+	if config.QRZLogEvery != "" {
+		qrzlogEvery, err = time.ParseDuration(config.QRZLogEvery)
+		if err != nil {
+			errorLog.Fatalf("bad qrzlogevery in config.yaml: %v", err)
+		}
+	}
+
+	var lotwWindow time.Duration
+	if config.LoTWWindow != "" {
+		lotwWindow, err = time.ParseDuration(config.LoTWWindow)
+		if err != nil {
+			errorLog.Fatalf("bad lotwwindow in config.yaml: %v", err)
+		}
+	}
 
 	app := &application{
 		errorLog:      errorLog,
 		infoLog:       infoLog,
 		templateCache: templateCache,
 		displayLines:  *displayLines,
-		logsModel:     &logsModel{DB: db},
-		qrzModel:      &qrzModel{DB: db},
-		contestModel:  &contestModel{DB: db},
-		otherModel:    m,
 		putCancel:     putCancel,
 		getCancel:     getCancel,
 		putId:         putId,
 		getId:         getId,
-		sKey:          m.sKey, //sessionCache(),
 		qrzpw:         *qrzpw,
 		qrzuser:       *qrzuser,
+		lotwpw:        *lotwpw,
+		lotwuser:      *lotwuser,
+		lotwEvery:     lotwEvery,
+		lotwWindow:    lotwWindow,
+		eqslpw:        *eqslpw,
+		eqsluser:      *eqsluser,
+		qrzlogKey:     *qrzlogkey,
+		qrzlogEvery:   qrzlogEvery,
+		clublogEmail:  config.ClublogMail,
+		clublogpw:     *clublogpw,
+		clublogCall:   config.ClublogCall,
+		clublogAPI:    config.ClublogAPI,
+		tqsl:          config.TQSL,
+		tqslStation:   config.TQSLStation,
 		adifFile:      fmt.Sprintf("%s/%s", qslDir, config.ADIFFile),
 		qslDir:        qslDir,
 		contestDir:    contestDir,
+		backupDir:     backupDir,
+		backupKeep:    config.BackupKeep,
+		backupEvery:   backupEvery,
 		vfoAdaptor:    vfo.Initvfo(171798692),
 		cqStat:        [wsjtBuffer]int{},
 		qsoStat:       [wsjtBuffer]int{},
 		wsjtPntr:      0,
-		call:          myCall,
 		dxspider:      *dxSpider,
 	}
+	app.setModels(config.Driver, db)
+	if config.CtyFile != "" {
+		ctyFile := filepath.Join(home, strings.TrimPrefix(config.CtyFile, "$HOME/"))
+		app.cty, err = cty.Load(ctyFile)
+		if err != nil {
+			errorLog.Printf("countries come from QRZ, failed to load %s: %v", ctyFile, err)
+		} else {
+			infoLog.Printf("loaded %d prefixes and calls from %s", app.cty.Len(), ctyFile)
+		}
+	}
+	p, err := app.activeProfile()
+	if err != nil {
+		errorLog.Fatal(err)
+	}
+	app.setStationCall(p.Call)
+	//"stationmaster restore <backup>" replaces the log with a backup after
+	//backing up the log as it is
+	if flag.Arg(0) == "restore" {
+		err = app.restoreBackup(flag.Arg(1))
+		if err != nil {
+			errorLog.Fatal(err)
+		}
+		return
+	}
+	//"stationmaster backfill" fills in the DXCC entity, zones and location
+	//of the QSOs logged before they were kept
+	if flag.Arg(0) == "backfill" {
+		n, err := app.backfillLocations()
+		if err != nil {
+			errorLog.Fatal(err)
+		}
+		infoLog.Printf("filled in the location of %d QSOs", n)
+		return
+	}
 	//fmt.Println("calling spider")
 	sp, err := app.initSpider()
 	if err != nil {
-		app.errorLog.Printf("failed spider lognin: ", err)
+		app.errorLog.Printf("failed spider lognin: %v", err)
 	}
 	fmt.Println("returned from Spider")
-	app.sp = sp
+	app.setSpider(sp)
 	app.vid = vid
 
 	app.bandData = &bandselect.BandData{
@@ -197,6 +349,15 @@ func main() {
 	}
 
 	go app.wsjtxServe()
+	if app.backupEvery > 0 {
+		go app.backupLoop(app.backupEvery)
+	}
+	if app.lotwEvery > 0 {
+		go app.lotwLoop(app.lotwEvery)
+	}
+	if app.qrzlogEvery > 0 && app.qrzlogKey != "" {
+		go app.qrzLogLoop(app.qrzlogEvery)
+	}
 
 	app.initRemotes()
 	//fmt.Println("A: called classify remotes in main.go")
@@ -244,6 +405,7 @@ func (app *application) routes() *http.ServeMux {
 	mux.HandleFunc("/home", app.home)
 	mux.HandleFunc("/ktutor", app.ktutor)
 	mux.HandleFunc("/qsolog", app.qsolog)
+	mux.HandleFunc("/search", app.search)
 	mux.HandleFunc("/addlog", app.addlog)
 	mux.HandleFunc("/ant", app.ant)
 	mux.HandleFunc("/start", app.start)
@@ -251,6 +413,16 @@ func (app *application) routes() *http.ServeMux {
 	mux.HandleFunc("/stopcode", app.stopcode)
 	mux.HandleFunc("/editlog", app.editlog)
 	mux.HandleFunc("/updatedb", app.updatedb)
+	mux.HandleFunc("/loghistory", app.logHistory)
+	mux.HandleFunc("/revert-change", app.revertChange)
+	mux.HandleFunc("/delete-logs", app.deleteLogs)
+	mux.HandleFunc("/bulk-edit", app.bulkEditLogs)
+	mux.HandleFunc("/trash", app.trash)
+	mux.HandleFunc("/restore-logs", app.restoreLogs)
+	mux.HandleFunc("/purge-logs", app.purgeLogs)
+	mux.HandleFunc("/check", app.logCheck)
+	mux.HandleFunc("/fix-log", app.fixLog)
+	mux.HandleFunc("/backfill", app.backfill)
 	mux.HandleFunc("/quit", app.quit)
 	mux.HandleFunc("/getconn", app.getConn)
 	mux.HandleFunc("/callsearch", app.callSearch)
@@ -260,10 +432,21 @@ func (app *application) routes() *http.ServeMux {
 	mux.HandleFunc("/contacts", app.contacts)
 	mux.HandleFunc("/adif", app.adif)
 	mux.HandleFunc("/gen-adif", app.genadif)
+	mux.HandleFunc("/export-adif", app.exportADIF)
+	mux.HandleFunc("/adif-import", app.adifImport)
+	mux.HandleFunc("/adif-import-file", app.adifImportFile)
+	mux.HandleFunc("/export-csv", app.exportCSV)
+	mux.HandleFunc("/export-map", app.exportMap)
+	mux.HandleFunc("/csv-import", app.csvImportPage)
+	mux.HandleFunc("/csv-upload", app.csvUpload)
+	mux.HandleFunc("/csv-check", app.csvImport)
 	mux.HandleFunc("/cabrillo", app.cabrillo)
 	mux.HandleFunc("/gencabrillo", app.genCabrillo)
 	mux.HandleFunc("/gencabrilloNew", app.genCabrilloNew)
 	mux.HandleFunc("/analysis", app.analysis)
+	mux.HandleFunc("/longest", app.longest)
+	mux.HandleFunc("/dxcc", app.dxcc)
+	mux.HandleFunc("/dxcc-export", app.dxccExport)
 	mux.HandleFunc("/country", app.country)
 	mux.HandleFunc("/country-confirmed", app.countryConfirmed)
 	mux.HandleFunc("/countryselect", app.countrySelect)
@@ -272,6 +455,22 @@ func (app *application) routes() *http.ServeMux {
 	mux.HandleFunc("/countyselect", app.countySelect)
 	mux.HandleFunc("/repeat", app.repeat)
 	mux.HandleFunc("/confirmqsls", app.confirmQSLs)
+	mux.HandleFunc("/lotw-download", app.lotwDownload)
+	mux.HandleFunc("/lotw-upload", app.lotwUpload)
+	mux.HandleFunc("/lotw-retry", app.lotwRetry)
+	mux.HandleFunc("/lotw-qsls", app.lotwQSLs)
+	mux.HandleFunc("/lotw-link", app.lotwLink)
+	mux.HandleFunc("/lotw-add", app.lotwAdd)
+	mux.HandleFunc("/lotw-dismiss", app.lotwDismiss)
+	mux.HandleFunc("/eqsl-upload", app.eqslUpload)
+	mux.HandleFunc("/eqsl-download", app.eqslDownload)
+	mux.HandleFunc("/qrzlog-sync", app.qrzLogSync)
+	mux.HandleFunc("/clublog-upload", app.clublogUpload)
+	mux.HandleFunc("/qsl-cards", app.qslCards)
+	mux.HandleFunc("/qsl-queue", app.qslQueue)
+	mux.HandleFunc("/qsl-received", app.qslReceived)
+	mux.HandleFunc("/qsl-labels", app.qslLabels)
+	mux.HandleFunc("/qsl-sent", app.qslSent)
 	mux.HandleFunc("/contacts-confirmed", app.contactsConfirmed)
 	mux.HandleFunc("/state", app.state)
 	mux.HandleFunc("/state-confirmed", app.stateConfirmed)
@@ -291,11 +490,36 @@ func (app *application) routes() *http.ServeMux {
 	mux.HandleFunc("/set_Yaesu", app.setYaesu)
 	mux.HandleFunc("/set_TenTec", app.setTenTec)
 	mux.HandleFunc("/read-yaesu", app.readYaesu)
+	mux.HandleFunc("/profiles", app.profiles)
+	mux.HandleFunc("/save-profile", app.saveProfile)
+	mux.HandleFunc("/use-profile", app.useProfile)
+	mux.HandleFunc("/delete-profile", app.deleteProfile)
+	mux.HandleFunc("/backups", app.backups)
+	mux.HandleFunc("/backup-now", app.backupNow)
+	app.apiRoutes(mux)
 	return mux
 }
 
-func openDB(dsn string) (*sql.DB, error) {
-	db, err := sql.Open("mysql", dsn)
+// sets the models for the database driver picked in config.yaml
+func (app *application) setModels(driver string, db *sql.DB) {
+	m := &otherModel{DB: db}
+	app.logsModel = &logsModel{DB: db}
+	app.qrzModel = &qrzModel{DB: db}
+	if driver == sqliteDriver {
+		app.qrzModel = &sqliteQRZModel{qrzModel{DB: db}}
+	}
+	app.contestModel = &contestModel{DB: db}
+	app.profileModel = &profileModel{DB: db}
+	app.backupModel = &backupModel{DB: db}
+	app.uploadModel = &uploadModel{DB: db}
+	app.qslModel = &qslModel{DB: db}
+	app.qrzLogModel = &qrzLogModel{DB: db}
+	app.otherModel = m
+	app.sKey = m.sKey //sessionCache(),
+}
+
+func openDB(driver, dsn string) (*sql.DB, error) {
+	db, err := sql.Open(driver, dsn)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+//<<======================== Map export handlers ========================>>
+
+// writes the QSOs the search filters select with a place as a GeoJSON or
+// KML download (format), coloured by band or confirmation (mapcolor)
+func (app *application) exportMap(w http.ResponseWriter, r *http.Request) {
+	td := initTemplateData()
+	td.Logger = true
+	q := r.URL.Query()
+	f := newForm(q)
+	lf := f.logFilter()
+	format := f.mapFormat()
+	by := f.mapColor()
+	if !f.valid() {
+		td.FormData = f
+		td.Columns = csvChoices(q["col"])
+		app.render(w, r, "search.page.html", td)
+		return
+	}
+	points, missing, err := app.mapPoints(lf)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	if missing > 0 {
+		app.infoLog.Printf("%d QSOs have no place and are not on the map", missing)
+	}
+	name := "stationlogs-" + time.Now().UTC().Format("20060102")
+	buf := &bytes.Buffer{}
+	if format == "kml" {
+		err = writeKML(buf, name, points, by)
+		w.Header().Set("Content-Type", "application/vnd.google-earth.kml+xml")
+		name += ".kml"
+	} else {
+		err = writeGeoJSON(buf, points, by)
+		w.Header().Set("Content-Type", "application/geo+json")
+		name += ".geojson"
+	}
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	w.Write(buf.Bytes())
+}
+
+// the map file format, geojson when it is not given
+func (f *formData) mapFormat() string {
+	switch v := f.Get("format"); v {
+	case "", "geojson":
+		return "geojson"
+	case "kml":
+		return v
+	default:
+		f.Errors.add("format", "must be geojson or kml")
+		return ""
+	}
+}
+
+// what the map points are coloured by, the band when it is not given
+func (f *formData) mapColor() string {
+	switch v := f.Get("mapcolor"); v {
+	case "", mapByBand:
+		return mapByBand
+	case mapByConfirmed:
+		return v
+	default:
+		f.Errors.add("mapcolor", "must be band or confirmed")
+		return ""
+	}
+}
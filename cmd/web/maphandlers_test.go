@@ -0,0 +1,58 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestExportMap(t *testing.T) {
+	app := newTestMapApp(t)
+	tests := []struct {
+		name     string
+		query    string
+		wantType string
+		wantBody string
+	}{
+		{"default", "", "application/geo+json", `"call":"W1AW"`},
+		{"geojson by confirmation", "?format=geojson&mapcolor=confirmed", "application/geo+json", `"style":"confirmed"`},
+		{"kml", "?format=kml&band=20m", "application/vnd.google-earth.kml+xml", "<name>DL1ABC</name>"},
+		{"bad format", "?format=gpx", "text/html; charset=utf-8", "must be geojson or kml"},
+		{"bad colour", "?mapcolor=mode", "text/html; charset=utf-8", "must be band or confirmed"},
+		{"bad filter", "?startdate=May", "text/html; charset=utf-8", "incorrect date format"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			app.routes().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/export-map"+tt.query, nil))
+			if rr.Code != http.StatusOK {
+				t.Errorf("want 200, got %d", rr.Code)
+			}
+			if ct := rr.Header().Get("Content-Type"); ct != tt.wantType {
+				t.Errorf("want %q, got %q", tt.wantType, ct)
+			}
+			if !strings.Contains(rr.Body.String(), tt.wantBody) {
+				t.Errorf("want %q in the body, got %q", tt.wantBody, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestAPIMap(t *testing.T) {
+	app := newTestMapApp(t)
+	fc := geoFeatureCollection{}
+	code := apiRequest(t, app, http.MethodGet, "/api/v1/map?mode=FT8", "", &fc)
+	if code != http.StatusOK || len(fc.Features) != 2 {
+		t.Errorf("want the 2 FT8 points, got %d %v", code, fc)
+	}
+	e := apiError{}
+	code = apiRequest(t, app, http.MethodGet, "/api/v1/map?mapcolor=mode", "", &e)
+	if code != http.StatusUnprocessableEntity || e.Fields["mapcolor"] == nil {
+		t.Errorf("want the mapcolor error, got %d %v", code, e)
+	}
+	code = apiRequest(t, app, http.MethodPost, "/api/v1/map", "", nil)
+	if code != http.StatusMethodNotAllowed {
+		t.Errorf("want 405, got %d", code)
+	}
+}
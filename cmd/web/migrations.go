@@ -0,0 +1,597 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+//The database schema is built and upgraded by the migrations below instead
+//of by hand from SQL scripts.  Each migration has a version number and the
+//versions that have been applied are kept in the schema_version table.  On
+//startup (or with the migrate subcommand) all the pending migrations are
+//applied in order.  Migrations must never be edited or reordered once they
+//are released, new changes go in a new migration at the end of the list.
+//
+//Installs that were built from the old dbscripts already have some or all
+//of the tables and columns, so the helpers skip a table or column that is
+//already there.
+
+type migration struct {
+	version int
+	name    string
+	up      func(m *migrator) error
+}
+
+type migrator struct {
+	db     dbtx //the database, or the transaction of the migration being applied
+	driver string
+}
+
+var errSchemaTooNew = errors.New("database schema is newer than this program")
+
+var migrations = []migration{
+	{1, "create the base tables", baseTables},
+	{2, "add contest and lotw date columns to stationlogs", contestColumns},
+	{3, "add contest field columns to stationlogs", contestFieldColumns},
+	{4, "widen the qrz columns", widenQRZColumns},
+	{5, "add the QSO frequency columns", freqColumns},
+	{6, "add the station profiles", stationProfiles},
+	{7, "add the audit log", auditLog},
+	{8, "add the trash for deleted QSOs", trashLogs},
+	{9, "add the DXCC entity, zones and location to stationlogs", locationColumns},
+	{10, "add the QSO distance", distanceColumn},
+	{11, "add the LoTW upload batches", lotwBatches},
+	{12, "add the unmatched LoTW confirmations", lotwUnmatched},
+	{13, "add the eQSL columns to stationlogs", eqslColumns},
+	{14, "add the QRZ Logbook sync state", qrzLogQSOs},
+	{15, "add the Club Log upload status", clublogColumns},
+	{16, "add the paper QSL columns to stationlogs", paperQSLColumns},
+	{17, "keep the unmatched eQSL and QRZ confirmations too", unmatchedSource},
+}
+
+// the last schema version this program knows about
+func latestVersion() int {
+	return migrations[len(migrations)-1].version
+}
+
+// applies all the pending migrations and returns the resulting version
+func migrate(db *sql.DB, driver string) (int, error) {
+	m := &migrator{db: db, driver: driver}
+	err := m.exec(`CREATE TABLE IF NOT EXISTS schema_version (
+	version INTEGER NOT NULL PRIMARY KEY,
+	name VARCHAR(100) NOT NULL,
+	applied DATETIME NOT NULL
+	)`)
+	if err != nil {
+		return 0, err
+	}
+	current, err := m.version()
+	if err != nil {
+		return 0, err
+	}
+	if current > latestVersion() {
+		return current, fmt.Errorf("%w: database is at version %d, program knows up to %d",
+			errSchemaTooNew, current, latestVersion())
+	}
+	for _, mg := range migrations {
+		if mg.version <= current {
+			continue
+		}
+		err = m.apply(mg)
+		if err != nil {
+			return current, fmt.Errorf("migration %d (%s) failed: %w", mg.version, mg.name, err)
+		}
+		current = mg.version
+	}
+	return current, nil
+}
+
+// runs the migration and records its version.  SQLite does both in one
+// transaction so a failed migration leaves nothing behind, MySQL commits
+// each schema change on its own so there is nothing to roll back.
+func (m *migrator) apply(mg migration) error {
+	if m.driver != sqliteDriver {
+		err := mg.up(m)
+		if err != nil {
+			return err
+		}
+		return m.recordVersion(mg)
+	}
+	db, ok := m.db.(*sql.DB)
+	if !ok {
+		return fmt.Errorf("migrations can not be nested")
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	step := &migrator{db: tx, driver: m.driver}
+	err = mg.up(step)
+	if err == nil {
+		err = step.recordVersion(mg)
+	}
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func (m *migrator) recordVersion(mg migration) error {
+	_, err := m.db.Exec(`INSERT INTO schema_version (version, name, applied) VALUES (?, ?, ?)`,
+		mg.version, mg.name, time.Now().UTC())
+	return err
+}
+
+// returns the schema version of the database, 0 if nothing has been applied
+func (m *migrator) version() (int, error) {
+	var v sql.NullInt64
+	err := m.db.QueryRow(`SELECT MAX(version) FROM schema_version`).Scan(&v)
+	if err != nil {
+		return 0, err
+	}
+	return int(v.Int64), nil
+}
+
+func (m *migrator) exec(stmts ...string) error {
+	for _, stmt := range stmts {
+		_, err := m.db.Exec(stmt)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *migrator) tableExists(table string) (bool, error) {
+	q := `SELECT COUNT(*) FROM information_schema.tables
+	WHERE table_schema = DATABASE() AND table_name = ?`
+	if m.driver == sqliteDriver {
+		q = `SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?`
+	}
+	var n int
+	err := m.db.QueryRow(q, table).Scan(&n)
+	return n > 0, err
+}
+
+func (m *migrator) columnExists(table, column string) (bool, error) {
+	q := `SELECT COUNT(*) FROM information_schema.columns
+	WHERE table_schema = DATABASE() AND table_name = ? AND column_name = ?`
+	if m.driver == sqliteDriver {
+		q = `SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?`
+	}
+	var n int
+	err := m.db.QueryRow(q, table, column).Scan(&n)
+	return n > 0, err
+}
+
+// runs the statements (create table followed by its indexes) unless
+// the table is already there
+func (m *migrator) createTable(table string, stmts ...string) error {
+	ok, err := m.tableExists(table)
+	if err != nil || ok {
+		return err
+	}
+	return m.exec(stmts...)
+}
+
+func (m *migrator) addColumn(table, column, def string) error {
+	ok, err := m.columnExists(table, column)
+	if err != nil || ok {
+		return err
+	}
+	return m.exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, def))
+}
+
+// the primary key column definition
+func (m *migrator) id() string {
+	if m.driver == sqliteDriver {
+		return "INTEGER PRIMARY KEY AUTOINCREMENT"
+	}
+	return "INTEGER NOT NULL PRIMARY KEY AUTO_INCREMENT"
+}
+
+// the collation for text columns that are matched on in queries.  The
+// models rely on the case insensitive default collation of MySQL (e.g.
+// lotwrcvd = 'YES' matching "Yes") so SQLite has to be told.
+func (m *migrator) nocase() string {
+	if m.driver == sqliteDriver {
+		return " COLLATE NOCASE"
+	}
+	return ""
+}
+
+func baseTables(m *migrator) error {
+	nc := m.nocase()
+	err := m.createTable("stationlogs", `CREATE TABLE stationlogs (
+	id `+m.id()+`,
+	time DATETIME NOT NULL,
+	callsign VARCHAR(20) NOT NULL`+nc+`,
+	mode VARCHAR(20) NOT NULL`+nc+`,
+	sent VARCHAR(10) NOT NULL,
+	rcvd VARCHAR(10) NOT NULL,
+	band VARCHAR(10) NOT NULL`+nc+`,
+	name VARCHAR(100) NOT NULL`+nc+`,
+	country VARCHAR(100) NOT NULL`+nc+`,
+	comment VARCHAR(100) NOT NULL`+nc+`,
+	lotwsent VARCHAR(20) NOT NULL`+nc+`,
+	lotwrcvd VARCHAR(20) NOT NULL`+nc+`
+	)`,
+		`CREATE INDEX idx_stationmaster_callsign ON stationlogs(callsign)`)
+	if err != nil {
+		return err
+	}
+	err = m.createTable("qrztable", `CREATE TABLE qrztable (`+qrzColumns(m)+`)`,
+		`CREATE INDEX idx_qrztable_callsign ON qrztable(callsign)`)
+	if err != nil {
+		return err
+	}
+	err = m.createTable("stashtable", `CREATE TABLE stashtable (`+qrzColumns(m)+`)`)
+	if err != nil {
+		return err
+	}
+	//the stash table is always read and written as row 1
+	var n int
+	err = m.db.QueryRow(`SELECT COUNT(*) FROM stashtable WHERE id = 1`).Scan(&n)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		err = m.exec(`INSERT INTO stashtable (id, time, callsign, aliases, dxcc, first_name,
+	last_name, nickname, born, addr1, addr2, state, zip, country, country_code,
+	lat, lon, grid, county, fips, land, cqzone, ituzone, geolocation, effdate,
+	expdate, prevcall, class, codes, qslmgr, email, url, views, bio, image,
+	moddate, msa, areacode, timezone, gmtoffset, dst, eqsl, mqsl, attn,
+	qso_count) VALUES (1, CURRENT_TIMESTAMP, '', '', '', '', '', '', '', '', '', '',
+	'', '', '', '', '', '', '', '', '', '', '', '', '', '', '', '', '', '', '', '', '',
+	'', '', '', '', '', '', '', '', '', '', '', '')`)
+		if err != nil {
+			return err
+		}
+	}
+	err = m.createTable("defaults", `CREATE TABLE defaults (
+	id `+m.id()+`,
+	kee VARCHAR(20) NOT NULL,
+	val VARCHAR(100) NOT NULL
+	)`,
+		`CREATE INDEX idx_defaults_kee ON defaults(kee)`)
+	if err != nil {
+		return err
+	}
+	return m.createTable("contests", `CREATE TABLE contests (
+	id `+m.id()+`,
+	time DATETIME NOT NULL,
+	contestname VARCHAR(50) NOT NULL`+nc+`,
+	fieldCount TINYINT(1) NOT NULL,
+	field1Name VARCHAR(10) NOT NULL,
+	field2Name VARCHAR(10) NOT NULL,
+	field3Name VARCHAR(10) NOT NULL,
+	field4Name VARCHAR(10) NOT NULL,
+	field5Name VARCHAR(10) NOT NULL
+	)`,
+		`CREATE INDEX idx_contests_contestname ON contests(contestname)`)
+}
+
+// qrztable and stashtable share the same columns
+func qrzColumns(m *migrator) string {
+	nc := m.nocase()
+	return `
+	id           ` + m.id() + `,
+	time         DATETIME      NOT NULL,
+	callsign     VARCHAR(20)   NOT NULL` + nc + `,
+	aliases      VARCHAR(50)   NOT NULL,
+	dxcc         VARCHAR(5)    NOT NULL,
+	first_name   VARCHAR(100)  NOT NULL,
+	last_name    VARCHAR(100)  NOT NULL,
+	nickname     VARCHAR(50)   NOT NULL,
+	born         VARCHAR(5)    NOT NULL,
+	addr1        VARCHAR(50)   NOT NULL,
+	addr2        VARCHAR(50)   NOT NULL,
+	state        VARCHAR(20)   NOT NULL` + nc + `,
+	zip          VARCHAR(10)   NOT NULL,
+	country      VARCHAR(50)   NOT NULL` + nc + `,
+	country_code VARCHAR(5)    NOT NULL,
+	lat          VARCHAR(15)   NOT NULL,
+	lon          VARCHAR(15)   NOT NULL,
+	grid         VARCHAR(10)   NOT NULL,
+	county       VARCHAR(50)   NOT NULL` + nc + `,
+	fips         VARCHAR(10)   NOT NULL,
+	land         VARCHAR(50)   NOT NULL,
+	cqzone       VARCHAR(5)    NOT NULL,
+	ituzone      VARCHAR(5)    NOT NULL,
+	geolocation  VARCHAR(10)   NOT NULL,
+	effdate      VARCHAR(10)   NOT NULL,
+	expdate      VARCHAR(10)   NOT NULL,
+	prevcall     VARCHAR(10)   NOT NULL,
+	class        VARCHAR(5)    NOT NULL,
+	codes        VARCHAR(5)    NOT NULL,
+	qslmgr       VARCHAR(20)   NOT NULL,
+	email        VARCHAR(50)   NOT NULL,
+	url          VARCHAR(50)   NOT NULL,
+	views        VARCHAR(5)    NOT NULL,
+	bio          VARCHAR(50)   NOT NULL,
+	image        VARCHAR(50)   NOT NULL,
+	moddate      VARCHAR(30)   NOT NULL,
+	msa          VARCHAR(5)    NOT NULL,
+	areacode     VARCHAR(5)    NOT NULL,
+	timezone     VARCHAR(5)    NOT NULL,
+	gmtoffset    VARCHAR(5)    NOT NULL,
+	dst          VARCHAR(3)    NOT NULL,
+	eqsl         VARCHAR(3)    NOT NULL,
+	mqsl         VARCHAR(3)    NOT NULL,
+	attn         VARCHAR(20)   NOT NULL,
+	qso_count    VARCHAR(5)    NOT NULL
+`
+}
+
+// these were added by hand with dbscripts/addtostationlogs.txt and
+// friends on the older installs
+func contestColumns(m *migrator) error {
+	nc := m.nocase()
+	cols := []struct{ name, def string }{
+		{"lotwqsodate", "DATETIME NOT NULL DEFAULT '1970-01-02 00:00:00'"},
+		{"lotwqsldate", "DATETIME NOT NULL DEFAULT '1970-01-02 00:00:00'"},
+		{"contest", "VARCHAR(5) NOT NULL DEFAULT ''" + nc},
+		{"exchsent", "VARCHAR(10) NOT NULL DEFAULT ''" + nc},
+		{"exchrcvd", "VARCHAR(10) NOT NULL DEFAULT ''" + nc},
+		{"contestname", "VARCHAR(50) NOT NULL DEFAULT ''" + nc},
+	}
+	for _, c := range cols {
+		err := m.addColumn("stationlogs", c.name, c.def)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func contestFieldColumns(m *migrator) error {
+	for _, dir := range []string{"Sent", "Rcvd"} {
+		for i := 1; i <= 5; i++ {
+			col := fmt.Sprintf("field%d%s", i, dir)
+			err := m.addColumn("stationlogs", col, "VARCHAR(10) NOT NULL DEFAULT ''"+m.nocase())
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// QRZ returns longer values than the original columns held, these match
+// the truncation in trimQRZ.  SQLite does not enforce VARCHAR lengths.
+func widenQRZColumns(m *migrator) error {
+	if m.driver == sqliteDriver {
+		return nil
+	}
+	for _, table := range []string{"qrztable", "stashtable"} {
+		err := m.exec(fmt.Sprintf(`ALTER TABLE %s
+		MODIFY qslmgr VARCHAR(100) NOT NULL,
+		MODIFY views VARCHAR(20) NOT NULL,
+		MODIFY image VARCHAR(150) NOT NULL,
+		MODIFY timezone VARCHAR(20) NOT NULL,
+		MODIFY attn VARCHAR(100) NOT NULL`, table))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// the transmit and (when split) receive frequencies in MHz, as text so that
+// they round trip exactly to ADIF
+func freqColumns(m *migrator) error {
+	for _, col := range []string{"freq", "freq_rx"} {
+		err := m.addColumn("stationlogs", col, "VARCHAR(12) NOT NULL DEFAULT ''")
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// the first profile is what used to be compiled in as myCall and written
+// into the Cabrillo headers
+func stationProfiles(m *migrator) error {
+	err := m.createTable("stationprofiles", `CREATE TABLE stationprofiles (
+	id `+m.id()+`,
+	name VARCHAR(50) NOT NULL,
+	callsign VARCHAR(20) NOT NULL,
+	operator VARCHAR(100) NOT NULL,
+	address VARCHAR(150) NOT NULL,
+	grid VARCHAR(10) NOT NULL,
+	location VARCHAR(20) NOT NULL,
+	club VARCHAR(100) NOT NULL,
+	power VARCHAR(10) NOT NULL,
+	rig VARCHAR(100) NOT NULL,
+	antenna VARCHAR(100) NOT NULL
+	)`)
+	if err != nil {
+		return err
+	}
+	var n int
+	err = m.db.QueryRow(`SELECT COUNT(*) FROM stationprofiles`).Scan(&n)
+	if err != nil || n > 0 {
+		return err
+	}
+	return m.exec(`INSERT INTO stationprofiles (name, callsign, operator,
+	address, grid, location, club, power, rig, antenna)
+	VALUES ('Home', 'N2VY', 'Asadolah Seghatoleslami',
+	'11 Silvers Lane, Cranbury NJ 08512', '', 'NJ', 'DVRA', 'LOW', '', '')`)
+}
+
+// the whole row before and after each change to stationlogs, as JSON
+func auditLog(m *migrator) error {
+	return m.createTable("auditlog", `CREATE TABLE auditlog (
+	id `+m.id()+`,
+	time DATETIME NOT NULL,
+	logid INTEGER NOT NULL,
+	action VARCHAR(10) NOT NULL,
+	source VARCHAR(10) NOT NULL,
+	old_row TEXT NOT NULL,
+	new_row TEXT NOT NULL
+	)`,
+		`CREATE INDEX idx_auditlog_logid ON auditlog(logid)`)
+}
+
+// the DXCC entity, zones and location of each QSO, named as in ADIF.  They
+// used to come from the QRZ lookup of the call at the time of a report,
+// which is not where a portable call was worked from.
+func locationColumns(m *migrator) error {
+	nc := m.nocase()
+	cols := []struct{ name, def string }{
+		{"dxcc", "INTEGER NOT NULL DEFAULT 0"},
+		{"cqz", "INTEGER NOT NULL DEFAULT 0"},
+		{"ituz", "INTEGER NOT NULL DEFAULT 0"},
+		{"cont", "VARCHAR(2) NOT NULL DEFAULT ''" + nc},
+		{"state", "VARCHAR(50) NOT NULL DEFAULT ''" + nc},
+		{"cnty", "VARCHAR(50) NOT NULL DEFAULT ''" + nc},
+		{"gridsquare", "VARCHAR(10) NOT NULL DEFAULT ''" + nc},
+	}
+	for _, c := range cols {
+		err := m.addColumn("stationlogs", c.name, c.def)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// great circle distance in km from the station grid to the grid of the QSO
+func distanceColumn(m *migrator) error {
+	return m.addColumn("stationlogs", "distance", "INTEGER NOT NULL DEFAULT 0")
+}
+
+// the QSOs TQSL signs and uploads to LoTW together, with the outcome of
+// the last try.  A batch that failed stays queued to be sent again.
+func lotwBatches(m *migrator) error {
+	err := m.createTable("lotwbatches", `CREATE TABLE lotwbatches (
+	id `+m.id()+`,
+	created DATETIME NOT NULL,
+	tried DATETIME NOT NULL,
+	status VARCHAR(10) NOT NULL,
+	attempts INTEGER NOT NULL,
+	result INTEGER NOT NULL,
+	message TEXT NOT NULL
+	)`)
+	if err != nil {
+		return err
+	}
+	return m.createTable("lotwbatchqsos", `CREATE TABLE lotwbatchqsos (
+	id `+m.id()+`,
+	batch INTEGER NOT NULL,
+	logid INTEGER NOT NULL
+	)`,
+		`CREATE INDEX idx_lotwbatchqsos_batch ON lotwbatchqsos(batch)`,
+		`CREATE INDEX idx_lotwbatchqsos_logid ON lotwbatchqsos(logid)`)
+}
+
+// the LoTW confirmations that matched no QSO or more than one, waiting to
+// be reviewed
+func lotwUnmatched(m *migrator) error {
+	return m.createTable("lotwunmatched", `CREATE TABLE lotwunmatched (
+	id `+m.id()+`,
+	received DATETIME NOT NULL,
+	reason VARCHAR(10) NOT NULL,
+	callsign VARCHAR(20) NOT NULL,
+	band VARCHAR(10) NOT NULL,
+	mode VARCHAR(20) NOT NULL,
+	qsotime DATETIME NOT NULL,
+	rxqso DATETIME NOT NULL,
+	rxqsl DATETIME NOT NULL
+	)`)
+}
+
+// where an unmatched confirmation came from, LoTW for the ones before,
+// and whether an eQSL is Authenticity Guaranteed
+func unmatchedSource(m *migrator) error {
+	err := m.addColumn("lotwunmatched", "source", "VARCHAR(10) NOT NULL DEFAULT 'lotw'")
+	if err != nil {
+		return err
+	}
+	return m.addColumn("lotwunmatched", "ag", "INTEGER NOT NULL DEFAULT 0")
+}
+
+// eQSL sent and received like the LoTW columns, and whether the eQSL is
+// Authenticity Guaranteed
+func eqslColumns(m *migrator) error {
+	nc := m.nocase()
+	cols := []struct{ name, def string }{
+		{"eqsl_sent", "VARCHAR(20) NOT NULL DEFAULT ''" + nc},
+		{"eqsl_rcvd", "VARCHAR(20) NOT NULL DEFAULT ''" + nc},
+		{"eqsl_qslrdate", "DATETIME NOT NULL DEFAULT '1970-01-02 00:00:00'"},
+		{"eqsl_ag", "INTEGER NOT NULL DEFAULT 0"},
+	}
+	for _, c := range cols {
+		err := m.addColumn("stationlogs", c.name, c.def)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// the QRZ Logbook confirmation of each QSO and the QSOs waiting to be
+// pushed to the QRZ Logbook
+func qrzLogQSOs(m *migrator) error {
+	nc := m.nocase()
+	err := m.addColumn("stationlogs", "qrz_rcvd", "VARCHAR(20) NOT NULL DEFAULT ''"+nc)
+	if err != nil {
+		return err
+	}
+	err = m.addColumn("stationlogs", "qrz_qslrdate",
+		"DATETIME NOT NULL DEFAULT '1970-01-02 00:00:00'")
+	if err != nil {
+		return err
+	}
+	return m.createTable("qrzlogqsos", `CREATE TABLE qrzlogqsos (
+	id `+m.id()+`,
+	logid INTEGER NOT NULL,
+	status VARCHAR(10) NOT NULL,
+	qrzlogid VARCHAR(20) NOT NULL DEFAULT '',
+	attempts INTEGER NOT NULL,
+	tried DATETIME NOT NULL,
+	message TEXT NOT NULL
+	)`,
+		`CREATE UNIQUE INDEX idx_qrzlogqsos_logid ON qrzlogqsos(logid)`,
+		`CREATE INDEX idx_qrzlogqsos_qrzlogid ON qrzlogqsos(qrzlogid)`)
+}
+
+func clublogColumns(m *migrator) error {
+	err := m.addColumn("stationlogs", "clublog_sent", "VARCHAR(20) NOT NULL DEFAULT ''"+m.nocase())
+	if err != nil {
+		return err
+	}
+	return m.addColumn("stationlogs", "clublog_date",
+		"DATETIME NOT NULL DEFAULT '1970-01-02 00:00:00'")
+}
+
+func paperQSLColumns(m *migrator) error {
+	nc := m.nocase()
+	cols := []struct{ name, def string }{
+		{"qsl_sent", "VARCHAR(20) NOT NULL DEFAULT ''" + nc},
+		{"qsl_rcvd", "VARCHAR(20) NOT NULL DEFAULT ''" + nc},
+		{"qsl_sent_via", "VARCHAR(10) NOT NULL DEFAULT ''"},
+		{"qsl_rcvd_via", "VARCHAR(10) NOT NULL DEFAULT ''"},
+		{"qsl_sdate", "DATETIME NOT NULL DEFAULT '1970-01-02 00:00:00'"},
+		{"qsl_rdate", "DATETIME NOT NULL DEFAULT '1970-01-02 00:00:00'"},
+	}
+	for _, c := range cols {
+		err := m.addColumn("stationlogs", c.name, c.def)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleted QSOs keep their id, as JSON, until the trash is emptied
+func trashLogs(m *migrator) error {
+	return m.createTable("trashlogs", `CREATE TABLE trashlogs (
+	id INTEGER NOT NULL PRIMARY KEY,
+	deleted DATETIME NOT NULL,
+	qso TEXT NOT NULL
+	)`)
+}
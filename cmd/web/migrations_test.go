@@ -0,0 +1,162 @@
+package main
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMigrate(t *testing.T) {
+	db, err := openSQLite(filepath.Join(t.TempDir(), "stationmaster.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	for i := 0; i < 2; i++ {
+		v, err := migrate(db, sqliteDriver)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v != latestVersion() {
+			t.Errorf("want version %d, got %d", latestVersion(), v)
+		}
+	}
+	var n int
+	err = db.QueryRow(`SELECT COUNT(*) FROM schema_version`).Scan(&n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != len(migrations) {
+		t.Errorf("want %d schema_version rows, got %d", len(migrations), n)
+	}
+	err = db.QueryRow(`SELECT COUNT(*) FROM stashtable`).Scan(&n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Errorf("want 1 stashtable row, got %d", n)
+	}
+}
+
+// an install built by hand from the old dbscripts, before contestname
+// and the contest fields were added
+func TestMigrateOldInstall(t *testing.T) {
+	db, err := openSQLite(filepath.Join(t.TempDir(), "stationmaster.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	_, err = db.Exec(`CREATE TABLE stationlogs (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	time DATETIME NOT NULL,
+	callsign VARCHAR(20) NOT NULL,
+	mode VARCHAR(20) NOT NULL,
+	sent VARCHAR(10) NOT NULL,
+	rcvd VARCHAR(10) NOT NULL,
+	band VARCHAR(10) NOT NULL,
+	name VARCHAR(100) NOT NULL,
+	country VARCHAR(100) NOT NULL,
+	comment VARCHAR(100) NOT NULL,
+	lotwsent VARCHAR(20) NOT NULL,
+	lotwrcvd VARCHAR(20) NOT NULL,
+	lotwqsodate DATETIME NOT NULL DEFAULT '1970-01-02 00:00:00',
+	lotwqsldate DATETIME NOT NULL DEFAULT '1970-01-02 00:00:00'
+	)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Exec(`INSERT INTO stationlogs (time, callsign, mode, sent, rcvd,
+	band, name, country, comment, lotwsent, lotwrcvd)
+	VALUES (?, '5T2AI', 'USB', '599', '599', '12m', 'Al Graham', 'Mauritania', '', '', '')`,
+		time.Now().UTC())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = migrate(db, sqliteDriver)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &logsModel{DB: db}
+	_, err = m.insertLog(&LogsRow{Call: "J5UAP", Mode: "CW", Band: "80m",
+		ContestName: "CWOps", Field1Sent: "Saied"}, sourceWeb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logs, err := m.getLatestLogs(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(logs) != 2 {
+		t.Errorf("want 2 logs after migration, got %d", len(logs))
+	}
+}
+
+func TestMigrateNewerSchema(t *testing.T) {
+	db, err := openSQLite(filepath.Join(t.TempDir(), "stationmaster.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	_, err = migrate(db, sqliteDriver)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Exec(`INSERT INTO schema_version (version, name, applied) VALUES (?, ?, ?)`,
+		latestVersion()+1, "from the future", time.Now().UTC())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = migrate(db, sqliteDriver)
+	if !errors.Is(err, errSchemaTooNew) {
+		t.Errorf("want errSchemaTooNew, got %v", err)
+	}
+}
+
+// a failed migration leaves neither its tables nor its version behind, and
+// a database error is not taken for a missing table
+func TestMigrateFailed(t *testing.T) {
+	db, err := openSQLite(filepath.Join(t.TempDir(), "stationmaster.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	released := migrations
+	defer func() { migrations = released }()
+	migrations = append(migrations[:len(migrations):len(migrations)], migration{
+		latestVersion() + 1, "fails half way", func(m *migrator) error {
+			err := m.createTable("halfway", `CREATE TABLE halfway (id INTEGER)`)
+			if err != nil {
+				return err
+			}
+			return m.addColumn("halfway", "name", "NOT A TYPE (")
+		},
+	})
+	v, err := migrate(db, sqliteDriver)
+	if err == nil {
+		t.Fatal("want the last migration to fail")
+	}
+	if v != latestVersion()-1 {
+		t.Errorf("want version %d, got %d", latestVersion()-1, v)
+	}
+	m := &migrator{db: db, driver: sqliteDriver}
+	ok, err := m.tableExists("halfway")
+	if err != nil || ok {
+		t.Errorf("want the halfway table rolled back, got %v %v", ok, err)
+	}
+	ok, err = m.columnExists("stationlogs", "contestname")
+	if err != nil || !ok {
+		t.Errorf("want the contestname column, got %v %v", ok, err)
+	}
+
+	db.Close()
+	_, err = m.tableExists("stationlogs")
+	if err == nil {
+		t.Errorf("want the error of a closed database")
+	}
+}
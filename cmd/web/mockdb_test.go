@@ -2,6 +2,7 @@ package main
 
 import (
 	"errors"
+	"time"
 )
 
 var errTest = errors.New("error for use in testing")
@@ -38,7 +39,7 @@ func (f *mockLogsModel) getCabrilloData(cd *contestData) ([]LogsRow, error) {
 	return []LogsRow{}, nil
 }
 
-func (f *mockLogsModel) insertLog(l *LogsRow) (int, error) {
+func (f *mockLogsModel) insertLog(l *LogsRow, source string) (int, error) {
 	return 0, nil
 }
 
@@ -68,7 +69,11 @@ func (f *mockLogsModel) getLogsByCall(call string) ([]*LogsRow, error) {
 	return r, nil
 }
 
-func (f *mockLogsModel) updateLog(l *LogsRow, id int) error {
+func (f *mockLogsModel) updateLog(l *LogsRow, id int, source string) error {
+	return nil
+}
+
+func (f *mockLogsModel) replaceLog(l *LogsRow, source string) error {
 	return nil
 }
 
@@ -76,7 +81,31 @@ func (f *mockLogsModel) getADIFData() ([]LogsRow, error) {
 	return []LogsRow{}, nil
 }
 
-func (f *mockLogsModel) updateQSO(map[itemType]string) error {
+func (f *mockLogsModel) confirmQSO(int, time.Time, time.Time) error {
+	return nil
+}
+
+func (f *mockLogsModel) confirmEQSL(int, time.Time, bool) error {
+	return nil
+}
+
+func (f *mockLogsModel) confirmQRZ(int, time.Time) error {
+	return nil
+}
+
+func (f *mockLogsModel) getCardQueue() ([]CardQSO, error) {
+	return []CardQSO{}, nil
+}
+
+func (f *mockLogsModel) queueCards(string) (int, error) {
+	return 0, nil
+}
+
+func (f *mockLogsModel) cardSent(int, string, time.Time) error {
+	return nil
+}
+
+func (f *mockLogsModel) cardReceived(int, string, time.Time) error {
 	return nil
 }
 
@@ -88,7 +117,7 @@ func (f *mockLogsModel) getLogsByCounty(county string) ([]LogsRow, error) {
 	return []LogsRow{}, nil
 }
 
-func (f *mockLogsModel) getConfirmedCounties() ([]LogsRow, error) {
+func (f *mockLogsModel) getConfirmedCounties(paper bool) ([]LogsRow, error) {
 	return []LogsRow{}, nil
 }
 
@@ -100,7 +129,7 @@ func (f *mockLogsModel) getConfirmedContacts() ([]LogsRow, error) {
 	return []LogsRow{}, nil
 }
 
-func (f *mockLogsModel) getConfirmedStates() ([]LogsRow, error) {
+func (f *mockLogsModel) getConfirmedStates(paper bool) ([]LogsRow, error) {
 	return []LogsRow{}, nil
 }
 
@@ -194,13 +223,120 @@ func (m *mockLogsModel) updateLOTWSent(id int) error {
 	return nil
 }
 
-func (m *mockLogsModel) getSimpleLogs(m, l, c string) ([]LogsRow, error) {
+func (m *mockLogsModel) getEQSLData() ([]LogsRow, error) {
+	return []LogsRow{}, nil
+}
+
+func (m *mockLogsModel) updateEQSLSent(id int) error {
 	return nil
 }
 
-func (m *mockLogsModel) getUniqueCountry(m, l string) ([]LogsRow, error) {
+func (m *mockLogsModel) getClublogData() ([]LogsRow, error) {
+	return []LogsRow{}, nil
+}
+
+func (m *mockLogsModel) updateClublogSent(id int, sent time.Time) error {
 	return nil
 }
-func (m *mockLogsModel) getUniqueState(m, l string) ([]LogsRow, error) {
+
+func (m *mockLogsModel) getSimpleLogs(mode, confirmed, country string) ([]LogsRow, error) {
+	return []LogsRow{}, nil
+}
+
+func (m *mockLogsModel) getUniqueCountry(mode, confirmed string) ([]LogsRow, error) {
+	return []LogsRow{}, nil
+}
+
+func (m *mockLogsModel) getUniqueState(mode, confirmed string) ([]LogsRow, error) {
+	return []LogsRow{}, nil
+}
+
+func (m *mockLogsModel) getNewCabrilloData(cd *contestData) ([]LogsRow, error) {
+	return []LogsRow{}, nil
+}
+
+func (m *mockLogsModel) checkDupe(t time.Time, contestname, callsign, band, mode string) (bool, error) {
+	return false, nil
+}
+
+func (m *mockLogsModel) importLog(l *LogsRow, source string) (int, error) {
+	return 0, nil
+}
+
+func (m *mockLogsModel) getHistory(id int) ([]AuditRow, error) {
+	return []AuditRow{}, nil
+}
+
+func (m *mockLogsModel) revertChange(id int) error {
 	return nil
 }
+
+func (m *mockLogsModel) deleteLogs(ids []int, source string) (int, error) {
+	return len(ids), nil
+}
+
+func (m *mockLogsModel) restoreLogs(ids []int, source string) (int, error) {
+	return len(ids), nil
+}
+
+func (m *mockLogsModel) purgeLogs(ids []int) (int, error) {
+	return len(ids), nil
+}
+
+func (m *mockLogsModel) getTrash() ([]TrashRow, error) {
+	return []TrashRow{}, nil
+}
+
+func (m *mockLogsModel) bulkEditLogs(ids []int, be *bulkEdit, source string) (int, error) {
+	return len(ids), nil
+}
+
+func (m *mockLogsModel) findDupe(l *LogsRow, window time.Duration) (bool, error) {
+	return false, nil
+}
+
+func (m *mockLogsModel) getExportData(lf *logFilter) ([]LogsRow, error) {
+	return m.rows, nil
+}
+
+func (m *mockLogsModel) searchLogs(lf *logFilter, offset, limit int) ([]LogsRow, int, error) {
+	if offset >= len(m.rows) {
+		return []LogsRow{}, len(m.rows), nil
+	}
+	end := offset + limit
+	if end > len(m.rows) {
+		end = len(m.rows)
+	}
+	return m.rows[offset:end], len(m.rows), nil
+}
+
+type mockProfileModel struct {
+	profiles []ProfileRow
+}
+
+func (m *mockProfileModel) insertProfile(p *ProfileRow) (int, error) {
+	p.Id = len(m.profiles) + 1
+	m.profiles = append(m.profiles, *p)
+	return p.Id, nil
+}
+
+func (m *mockProfileModel) updateProfile(p *ProfileRow) error {
+	return nil
+}
+
+func (m *mockProfileModel) deleteProfile(id int) error {
+	return nil
+}
+
+func (m *mockProfileModel) getProfile(id int) (*ProfileRow, error) {
+	for _, p := range m.profiles {
+		if p.Id == id {
+			return &p, nil
+		}
+	}
+	return nil, errNoRecord
+}
+
+func (m *mockProfileModel) getProfiles() ([]ProfileRow, error) {
+	return m.profiles, nil
+}
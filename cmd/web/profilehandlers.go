@@ -0,0 +1,273 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+//<<===================== Station profile handlers =====================>>
+
+// lists the station profiles, with ?id=n the form is filled in with
+// profile n for editing
+func (app *application) profiles(w http.ResponseWriter, r *http.Request) {
+	td := initTemplateData()
+	if v := r.URL.Query().Get("id"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			app.clientError(w, http.StatusBadRequest)
+			return
+		}
+		p, err := app.profileModel.getProfile(id)
+		if err != nil {
+			if errors.Is(err, errNoRecord) {
+				app.clientError(w, http.StatusNotFound)
+				return
+			}
+			app.serverError(w, err)
+			return
+		}
+		td.FormData.setProfile(p)
+	}
+	app.renderProfiles(w, r, td)
+}
+
+// adds a new profile (blank id) or updates an existing one
+func (app *application) saveProfile(w http.ResponseWriter, r *http.Request) {
+	td := initTemplateData()
+	err := r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	f := newForm(r.PostForm)
+	f.checkProfile()
+	td.FormData = f
+	if !f.valid() {
+		app.renderProfiles(w, r, td)
+		return
+	}
+	p := f.profile()
+	if p.Id == 0 {
+		p.Id, err = app.profileModel.insertProfile(p)
+	} else {
+		err = app.profileModel.updateProfile(p)
+	}
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	err = app.switchProfile()
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	td.FormData = newForm(url.Values{})
+	td.Message = fmt.Sprintf("Saved the %s profile for %s", p.Name, p.Call)
+	app.renderProfiles(w, r, td)
+}
+
+// makes the profile the one the logs, the generators and the spider use
+func (app *application) useProfile(w http.ResponseWriter, r *http.Request) {
+	td := initTemplateData()
+	p, ok := app.postedProfile(w, r)
+	if !ok {
+		return
+	}
+	err := app.otherModel.updateDefault("profile", strconv.Itoa(p.Id))
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	err = app.switchProfile()
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	td.Message = fmt.Sprintf("Now operating as %s (%s)", p.Call, p.Name)
+	app.renderProfiles(w, r, td)
+}
+
+func (app *application) deleteProfile(w http.ResponseWriter, r *http.Request) {
+	td := initTemplateData()
+	p, ok := app.postedProfile(w, r)
+	if !ok {
+		return
+	}
+	profiles, err := app.profileModel.getProfiles()
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	if len(profiles) < 2 {
+		td.Message = "The last profile can not be deleted"
+		app.renderProfiles(w, r, td)
+		return
+	}
+	err = app.profileModel.deleteProfile(p.Id)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	err = app.switchProfile()
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	td.Message = fmt.Sprintf("Deleted the %s profile for %s", p.Name, p.Call)
+	app.renderProfiles(w, r, td)
+}
+
+// reads the profile whose id is posted, on failure the response has
+// been written
+func (app *application) postedProfile(w http.ResponseWriter, r *http.Request) (*ProfileRow, bool) {
+	err := r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return nil, false
+	}
+	id, err := strconv.Atoi(r.PostForm.Get("id"))
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return nil, false
+	}
+	p, err := app.profileModel.getProfile(id)
+	if err != nil {
+		if errors.Is(err, errNoRecord) {
+			app.clientError(w, http.StatusNotFound)
+			return nil, false
+		}
+		app.serverError(w, err)
+		return nil, false
+	}
+	return p, true
+}
+
+// the call sign of the active profile.  The WSJT-X, spider and QRZ Logbook
+// goroutines read it while a profile change sets it.
+func (app *application) stationCall() string {
+	app.callLock.RLock()
+	defer app.callLock.RUnlock()
+	return app.call
+}
+
+func (app *application) setStationCall(call string) {
+	app.callLock.Lock()
+	defer app.callLock.Unlock()
+	app.call = call
+}
+
+// picks up the call sign of the active profile after a profile change and
+// logs in to the DX spider with it
+func (app *application) switchProfile() error {
+	p, err := app.activeProfile()
+	if err != nil {
+		return err
+	}
+	if p.Call == app.stationCall() {
+		return nil
+	}
+	//a spot fetch waits for the spider to echo the call, so the call changes
+	//with the spider held
+	app.spLock.Lock()
+	defer app.spLock.Unlock()
+	app.setStationCall(p.Call)
+	if app.sp.w != nil {
+		err = app.sp.bye()
+		if err != nil {
+			app.errorLog.Printf("failed spider logout: %v", err)
+		}
+	}
+	sp, err := app.initSpider()
+	if err != nil {
+		app.errorLog.Printf("failed spider login as %s: %v", p.Call, err)
+		return nil
+	}
+	app.sp = sp
+	return nil
+}
+
+func (app *application) renderProfiles(w http.ResponseWriter, r *http.Request, td *templateData) {
+	var err error
+	td.Profiles, err = app.profileModel.getProfiles()
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	p, err := app.activeProfile()
+	if err != nil && !errors.Is(err, errNoProfile) {
+		app.serverError(w, err)
+		return
+	}
+	if p != nil {
+		td.ActiveProfile = p.Id
+	}
+	app.render(w, r, "profiles.page.html", td)
+}
+
+var profileFields = []struct {
+	name string
+	max  int
+}{
+	{"name", 50}, {"callsign", 20}, {"operator", 100}, {"address", 150},
+	{"grid", 10}, {"location", 20}, {"club", 100}, {"power", 10},
+	{"rig", 100}, {"antenna", 100},
+}
+
+func (f *formData) checkProfile() {
+	f.required("name", "callsign")
+	for _, pf := range profileFields {
+		f.maxLength(pf.name, pf.max)
+	}
+	call := strings.ToUpper(strings.TrimSpace(f.Get("callsign")))
+	if call != "" && !validCall(call) {
+		f.Errors.add("callsign", "this is not a valid call sign")
+	}
+	grid := strings.TrimSpace(f.Get("grid"))
+	if grid != "" && !validGrid(grid) {
+		f.Errors.add("grid", "must be a 4 or 6 character grid square, e.g. FN20 or FN20qh")
+	}
+	power := strings.ToUpper(strings.TrimSpace(f.Get("power")))
+	switch power {
+	case "", "HIGH", "LOW", "QRP":
+	default:
+		watts, err := strconv.Atoi(power)
+		if err != nil || watts <= 0 {
+			f.Errors.add("power", "must be the power in watts or HIGH, LOW or QRP")
+		}
+	}
+}
+
+func (f *formData) profile() *ProfileRow {
+	id, _ := strconv.Atoi(f.Get("id"))
+	return &ProfileRow{
+		Id:       id,
+		Name:     strings.TrimSpace(f.Get("name")),
+		Call:     strings.ToUpper(strings.TrimSpace(f.Get("callsign"))),
+		Operator: strings.TrimSpace(f.Get("operator")),
+		Address:  strings.TrimSpace(f.Get("address")),
+		Grid:     strings.TrimSpace(f.Get("grid")),
+		Location: strings.ToUpper(strings.TrimSpace(f.Get("location"))),
+		Club:     strings.TrimSpace(f.Get("club")),
+		Power:    strings.ToUpper(strings.TrimSpace(f.Get("power"))),
+		Rig:      strings.TrimSpace(f.Get("rig")),
+		Antenna:  strings.TrimSpace(f.Get("antenna")),
+	}
+}
+
+func (f *formData) setProfile(p *ProfileRow) {
+	f.Set("id", strconv.Itoa(p.Id))
+	f.Set("name", p.Name)
+	f.Set("callsign", p.Call)
+	f.Set("operator", p.Operator)
+	f.Set("address", p.Address)
+	f.Set("grid", p.Grid)
+	f.Set("location", p.Location)
+	f.Set("club", p.Club)
+	f.Set("power", p.Power)
+	f.Set("rig", p.Rig)
+	f.Set("antenna", p.Antenna)
+}
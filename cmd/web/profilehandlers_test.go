@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestSaveProfile(t *testing.T) {
+	tests := []struct {
+		name     string
+		form     url.Values
+		wantBody string
+		profiles int
+	}{
+		{"new profile", url.Values{"name": {"Field Day"}, "callsign": {"w2zq"},
+			"grid": {"FN20"}, "power": {"qrp"}}, "Saved the Field Day profile for W2ZQ", 2},
+		{"bad call", url.Values{"name": {"Field Day"}, "callsign": {"W2"}},
+			"this is not a valid call sign", 1},
+		{"bad grid and power", url.Values{"name": {"Portable"}, "callsign": {"N2VY/P"},
+			"grid": {"FN2"}, "power": {"lots"}}, "must be the power in watts", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestSQLiteApp(t)
+			app.setStationCall("N2VY")
+			rr := httptest.NewRecorder()
+			r, err := http.NewRequest(http.MethodPost, "/save-profile",
+				strings.NewReader(tt.form.Encode()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			app.saveProfile(rr, r)
+			rs := rr.Result()
+			defer rs.Body.Close()
+			bod, err := io.ReadAll(rs.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if rs.StatusCode != http.StatusOK {
+				t.Errorf("expected %d got %d", http.StatusOK, rs.StatusCode)
+			}
+			if !bytes.Contains(bod, []byte(tt.wantBody)) {
+				t.Errorf("expected %q in the body, did not get it", tt.wantBody)
+			}
+			profiles, err := app.profileModel.getProfiles()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(profiles) != tt.profiles {
+				t.Errorf("want %d profiles, got %d", tt.profiles, len(profiles))
+			}
+		})
+	}
+}
+
+func TestUseProfile(t *testing.T) {
+	app := newTestSQLiteApp(t)
+	app.setStationCall("N2VY")
+	id, err := app.profileModel.insertProfile(&ProfileRow{Name: "Special Event", Call: "N2V"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	form := url.Values{"id": {"2"}}
+	r, err := http.NewRequest(http.MethodPost, "/use-profile", strings.NewReader(form.Encode()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	//the spider and the QRZ Logbook push read the call while it changes,
+	//go test -race catches an unguarded read
+	done := make(chan bool)
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			app.stationCall()
+		}
+	}()
+	app.useProfile(rr, r)
+	<-done
+	if rr.Code != http.StatusOK {
+		t.Errorf("expected %d got %d", http.StatusOK, rr.Code)
+	}
+	p, err := app.activeProfile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Id != id || app.stationCall() != "N2V" {
+		t.Errorf("want profile %d and call N2V, got %d and %s", id, p.Id, app.stationCall())
+	}
+	if !strings.Contains(rr.Body.String(), "N2V Station Master") {
+		t.Errorf("the page did not show the new call")
+	}
+}
+
+// fakeSpider answers the DX spider login and show/dx commands with the call
+// each connection logged in as
+func fakeSpider(t *testing.T) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func(c net.Conn) {
+				defer c.Close()
+				s := bufio.NewScanner(c)
+				fmt.Fprint(c, "login: ")
+				if !s.Scan() {
+					return
+				}
+				call := s.Text()
+				fmt.Fprintf(c, "Hello %s\n", call)
+				for s.Scan() {
+					if s.Text() == "bye" {
+						return
+					}
+					for i := 0; i < dxLines; i++ {
+						fmt.Fprintf(c, "%-*s\n", lineLength,
+							"14025.0  DL1ABC  1-May-2023 1200Z  CQ  <G4XYZ>")
+					}
+					fmt.Fprint(c, call)
+				}
+			}(c)
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func TestSwitchProfileSpots(t *testing.T) {
+	app := newTestSQLiteApp(t)
+	app.dxspider = fakeSpider(t)
+	app.setStationCall("N2VY")
+	sp, err := app.initSpider()
+	if err != nil {
+		t.Fatal(err)
+	}
+	app.setSpider(sp)
+	_, err = app.profileModel.insertProfile(&ProfileRow{Name: "Special Event", Call: "N2V"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k, v := range map[string]string{"profile": "2", "band": "20m"} {
+		err = app.otherModel.updateDefault(k, v)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	//go test -race catches a spot fetch that reads the spider while the
+	//profile switch replaces it
+	done := make(chan int)
+	go func() {
+		defer close(done)
+		for i := 0; i < 10; i++ {
+			rr := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/api/v1/spots", nil)
+			app.apiSpots(rr, r)
+			if rr.Code != http.StatusOK {
+				done <- rr.Code
+				return
+			}
+		}
+	}()
+	err = app.switchProfile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code, ok := <-done; ok {
+		t.Errorf("expected %d got %d", http.StatusOK, code)
+	}
+	if app.stationCall() != "N2V" || !app.spiderUp() {
+		t.Errorf("want the spider logged in as N2V, got %s", app.stationCall())
+	}
+	dx, err := app.getSpider("20m", dxLines)
+	if err != nil || len(dx) != dxLines {
+		t.Errorf("want %d spots, got %d: %v", dxLines, len(dx), err)
+	}
+}
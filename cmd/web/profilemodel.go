@@ -0,0 +1,166 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"strconv"
+)
+
+//A station profile is everything about the station that goes out with the
+//log: the call sign, the operator, where the station is and what it is.
+//One install can keep the home station, a club station, a portable call
+//or a special event call and switch between them.
+
+type profileType interface {
+	insertProfile(*ProfileRow) (int, error)
+	updateProfile(*ProfileRow) error
+	deleteProfile(int) error
+	getProfile(int) (*ProfileRow, error)
+	getProfiles() ([]ProfileRow, error)
+}
+
+// ProfileRow is the data for the stationprofiles table rows
+type ProfileRow struct {
+	Id       int
+	Name     string //what the profile is for, e.g. Home or Field Day
+	Call     string
+	Operator string //operator name for the Cabrillo header
+	Address  string
+	Grid     string
+	Location string //ARRL section or state for the Cabrillo header
+	Club     string
+	Power    string //watts, or HIGH, LOW or QRP
+	Rig      string
+	Antenna  string
+}
+
+type profileModel struct {
+	DB *sql.DB
+}
+
+var errNoProfile = errors.New("there is no station profile")
+
+func (m *profileModel) insertProfile(p *ProfileRow) (int, error) {
+	stmt := `INSERT INTO stationprofiles (name, callsign, operator, address,
+	grid, location, club, power, rig, antenna)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	result, err := m.DB.Exec(stmt, p.Name, p.Call, p.Operator, p.Address,
+		p.Grid, p.Location, p.Club, p.Power, p.Rig, p.Antenna)
+	if err != nil {
+		return 0, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	return int(id), nil
+}
+
+func (m *profileModel) updateProfile(p *ProfileRow) error {
+	stmt := `UPDATE stationprofiles SET name = ?, callsign = ?, operator = ?,
+	address = ?, grid = ?, location = ?, club = ?, power = ?, rig = ?,
+	antenna = ? WHERE id = ?`
+
+	_, err := m.DB.Exec(stmt, p.Name, p.Call, p.Operator, p.Address,
+		p.Grid, p.Location, p.Club, p.Power, p.Rig, p.Antenna, p.Id)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (m *profileModel) deleteProfile(id int) error {
+	_, err := m.DB.Exec(`DELETE FROM stationprofiles WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (m *profileModel) getProfile(id int) (*ProfileRow, error) {
+	stmt := `SELECT id, name, callsign, operator, address, grid, location,
+	club, power, rig, antenna FROM stationprofiles WHERE id = ?`
+
+	row := m.DB.QueryRow(stmt, id)
+	p := &ProfileRow{}
+	err := row.Scan(&p.Id, &p.Name, &p.Call, &p.Operator, &p.Address,
+		&p.Grid, &p.Location, &p.Club, &p.Power, &p.Rig, &p.Antenna)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errNoRecord
+		}
+		return nil, err
+	}
+	return p, nil
+}
+
+func (m *profileModel) getProfiles() ([]ProfileRow, error) {
+	stmt := `SELECT id, name, callsign, operator, address, grid, location,
+	club, power, rig, antenna FROM stationprofiles ORDER BY id`
+
+	rows, err := m.DB.Query(stmt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tr := []ProfileRow{}
+	for rows.Next() {
+		p := ProfileRow{}
+		err := rows.Scan(&p.Id, &p.Name, &p.Call, &p.Operator, &p.Address,
+			&p.Grid, &p.Location, &p.Club, &p.Power, &p.Rig, &p.Antenna)
+		if err != nil {
+			return nil, err
+		}
+		tr = append(tr, p)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return tr, nil
+}
+
+// returns the profile picked on the profiles page, the first one if none
+// has been picked (or the picked one was deleted)
+func (app *application) activeProfile() (*ProfileRow, error) {
+	v, err := app.otherModel.getDefault("profile")
+	if err != nil && !errors.Is(err, errNoRecord) {
+		return nil, err
+	}
+	if id, err := strconv.Atoi(v); err == nil {
+		p, err := app.profileModel.getProfile(id)
+		if err == nil {
+			return p, nil
+		}
+		if !errors.Is(err, errNoRecord) {
+			return nil, err
+		}
+	}
+	profiles, err := app.profileModel.getProfiles()
+	if err != nil {
+		return nil, err
+	}
+	if len(profiles) == 0 {
+		return nil, errNoProfile
+	}
+	return &profiles[0], nil
+}
+
+// the Cabrillo CATEGORY-POWER of the profile power
+func (p *ProfileRow) powerCategory() string {
+	if p.Power == "" {
+		return "LOW"
+	}
+	watts, err := strconv.Atoi(p.Power)
+	if err != nil {
+		return p.Power
+	}
+	switch {
+	case watts > 100:
+		return "HIGH"
+	case watts > 5:
+		return "LOW"
+	}
+	return "QRP"
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/xml"
 	"errors"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"testing"
@@ -99,6 +100,10 @@ func (m *mockClient) Get(url string) (*http.Response, error) {
 	return m.mockGet(url)
 }
 
+func (m *mockClient) Post(url, contentType string, body io.Reader) (*http.Response, error) {
+	return m.mockGet(url)
+}
+
 func TestGetXML(t *testing.T) {
 
 	r := ioutil.NopCloser(bytes.NewReader([]byte(xmlTestData)))
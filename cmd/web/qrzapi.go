@@ -19,7 +19,7 @@ type Qtype struct {
 
 // Ctype is the main payload of the QRZ API
 type Ctype struct {
-	XMLName     xml.Name `xml:"Callsign"`
+	XMLName     xml.Name `xml:"Callsign" json:"-"`
 	Id          int32
 	Time        time.Time
 	Call        string `xml:"call"`
@@ -71,7 +71,7 @@ type Ctype struct {
 // Stype is the validation part of the QRZ API
 type Stype struct {
 	Key   string `xml:"Key"`
-	Error string `xml: "Error"`
+	Error string `xml:"Error"`
 	Count string `xml:"Count"`
 	Time  string `xml:"GMTime"`
 }
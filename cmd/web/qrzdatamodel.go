@@ -24,6 +24,30 @@ func (m *qrzModel) insertQRZ(c *Ctype) error {
 	if c.Call == "" {
 		return nil
 	}
+	trimQRZ(c)
+
+	stmt := `INSERT INTO qrztable (time, callsign, aliases, dxcc, first_name,
+		last_name, nickname, born, addr1, addr2, state, zip, country, country_code,
+		lat, lon, grid, county, fips, land, cqzone, ituzone, geolocation, effdate,
+		expdate, prevcall, class, codes, qslmgr, email, url, views, bio, image,
+		moddate, msa, areacode, timezone, gmtoffset, dst, eqsl, mqsl, attn, qso_count)
+	VALUES (UTC_TIMESTAMP(), ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?,
+	?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	_, err := m.DB.Exec(stmt, c.Call, c.Aliases, c.Dxcc, c.Fname, c.Lname,
+		c.NickName, c.Born, c.Addr1, c.Addr2, c.State, c.Zip, c.Country, c.CountryCode,
+		c.Lat, c.Long, c.Grid, c.County, c.FIPS, c.Land, c.CQzone, c.ITUzone,
+		c.GeoLocation, c.EffDate, c.ExpDate, c.PrevCall, c.Class, c.Codes, c.QSLMgr,
+		c.Email, c.URL, c.Views, c.Bio, c.Image, c.ModDate, c.MSA, c.AreaCode, c.TimeZone,
+		c.GMTOffset, c.DST, c.EQSL, c.MQSL, c.Attn, c.QSOCount)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// trims the QRZ fields to the column widths of qrztable
+func trimQRZ(c *Ctype) {
 	if len(c.Call) > 20 {
 		c.Call = c.Call[0:20]
 	}
@@ -150,25 +174,6 @@ func (m *qrzModel) insertQRZ(c *Ctype) error {
 	if len(c.Attn) > 100 {
 		c.Attn = c.Attn[0:100]
 	}
-
-	stmt := `INSERT INTO qrztable (time, callsign, aliases, dxcc, first_name,
-		last_name, nickname, born, addr1, addr2, state, zip, country, country_code,
-		lat, lon, grid, county, fips, land, cqzone, ituzone, geolocation, effdate,
-		expdate, prevcall, class, codes, qslmgr, email, url, views, bio, image,
-		moddate, msa, areacode, timezone, gmtoffset, dst, eqsl, mqsl, attn, qso_count)
-	VALUES (UTC_TIMESTAMP(), ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?,
-	?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
-
-	_, err := m.DB.Exec(stmt, c.Call, c.Aliases, c.Dxcc, c.Fname, c.Lname,
-		c.NickName, c.Born, c.Addr1, c.Addr2, c.State, c.Zip, c.Country, c.CountryCode,
-		c.Lat, c.Long, c.Grid, c.County, c.FIPS, c.Land, c.CQzone, c.ITUzone,
-		c.GeoLocation, c.EffDate, c.ExpDate, c.PrevCall, c.Class, c.Codes, c.QSLMgr,
-		c.Email, c.URL, c.Views, c.Bio, c.Image, c.ModDate, c.MSA, c.AreaCode, c.TimeZone,
-		c.GMTOffset, c.DST, c.EQSL, c.MQSL, c.Attn, c.QSOCount)
-	if err != nil {
-		return err
-	}
-	return nil
 }
 
 func (m *qrzModel) getQRZ(call string) (*Ctype, error) {
@@ -0,0 +1,358 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//<<========================== QRZ Logbook sync ===========================>>
+
+//A QSO is queued for the QRZ Logbook as it is logged (by hand, in a contest,
+//from WSJT-X or through the API) and pushed with the INSERT action of the
+//QRZ Logbook API.  A QSO the QRZ Logbook did not take stays queued and is
+//tried again with the next sync.  The sync also FETCHes the QSOs the QRZ
+//Logbook has confirmed since the last one, a page at a time, and marks them
+//confirmed in the log, found by the QRZ Logbook id they were sent with or
+//else the way a LoTW QSL is matched.  Those that match no QSO or more than
+//one wait for review with the unmatched LoTW QSLs.  The API key of the
+//logbook is given on the command line (-qrzlogkey), qrzlogevery in
+//config.yaml syncs on a schedule.
+
+const qrzLogURL = "https://logbook.qrz.com/api"
+
+// the defaults key of the date of the last fetch
+const qrzLogSinceKey = "qrzlogmodsince"
+
+// how many records a FETCH asks for at a time
+var qrzLogPage = 250
+
+var (
+	errQRZLogKey = errors.New("no QRZ Logbook API key, start with -qrzlogkey")
+	errQRZLog    = errors.New("QRZ Logbook sync failed")
+)
+
+// qrzSync is the outcome of one sync
+type qrzSync struct {
+	Sent      int    //QSOs the QRZ Logbook took
+	Failed    int    //QSOs left queued
+	Since     string //the confirmations since this date were fetched, blank for all
+	QSLs      int    //confirmed QSOs the QRZ Logbook sent back
+	Confirmed int
+	Unmatched int
+	Ambiguous int
+}
+
+func (s *qrzSync) String() string {
+	msg := fmt.Sprintf("Sent %d QSOs to the QRZ Logbook", s.Sent)
+	if s.Failed > 0 {
+		msg += fmt.Sprintf(", %d failed and stay queued", s.Failed)
+	}
+	msg += fmt.Sprintf(", fetched %d confirmations", s.QSLs)
+	if s.Since != "" {
+		msg += " since " + s.Since
+	}
+	msg += fmt.Sprintf(" and confirmed %d QSOs", s.Confirmed)
+	if s.Unmatched > 0 || s.Ambiguous > 0 {
+		msg += fmt.Sprintf(", %d matched no QSO and %d more than one and wait for review",
+			s.Unmatched, s.Ambiguous)
+	}
+	return msg
+}
+
+func (app *application) qrzLogSite() string {
+	if app.qrzlogURL != "" {
+		return app.qrzlogURL
+	}
+	return qrzLogURL
+}
+
+// the key/value pairs of a QRZ Logbook answer.  The ADIF of a FETCH comes
+// last, with its brackets as &lt; and &gt;, and is taken as it is.
+func qrzLogResponse(body string) map[string]string {
+	res := map[string]string{}
+	if n := strings.Index(body, "ADIF="); n != -1 {
+		res["ADIF"] = html.UnescapeString(body[n+len("ADIF="):])
+		body = strings.TrimSuffix(body[:n], "&")
+	}
+	for _, pair := range strings.Split(strings.TrimSpace(body), "&") {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		v, err := url.QueryUnescape(kv[1])
+		if err != nil {
+			v = kv[1]
+		}
+		res[strings.ToUpper(kv[0])] = v
+	}
+	return res
+}
+
+// posts the action to the QRZ Logbook API.  A refused key is an error,
+// the rest of the answers are for the caller to read.
+func (app *application) qrzLogAPI(v url.Values) (map[string]string, error) {
+	v.Set("KEY", app.qrzlogKey)
+	resp, err := client.Post(app.qrzLogSite(), "application/x-www-form-urlencoded",
+		strings.NewReader(v.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errQRZLog, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: status %d", errQRZLog, resp.StatusCode)
+	}
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errQRZLog, err)
+	}
+	res := qrzLogResponse(string(data))
+	if res["RESULT"] == "AUTH" {
+		return nil, fmt.Errorf("%w: the API key was refused %s", errQRZLog, res["REASON"])
+	}
+	return res, nil
+}
+
+// queues a newly logged QSO and pushes it in the background
+func (app *application) queueQRZLog(id int) {
+	if app.qrzlogKey == "" {
+		return
+	}
+	err := app.qrzLogModel.queueQRZ(id)
+	if err != nil {
+		app.errorLog.Printf("failed to queue QSO %d for the QRZ Logbook: %v", id, err)
+		return
+	}
+	go func() {
+		_, err := app.sendQRZLog(&qrzSync{})
+		if err != nil {
+			app.errorLog.Printf("QRZ Logbook push failed: %v", err)
+		}
+	}()
+}
+
+// sends the queued QSOs.  Duplicates the QRZ Logbook already has count as
+// sent.  A QSO deleted since it was queued waits in case it is restored.
+func (app *application) sendQRZLog(s *qrzSync) (*qrzSync, error) {
+	if app.qrzlogKey == "" {
+		return nil, errQRZLogKey
+	}
+	app.qrzlogLock.Lock()
+	defer app.qrzlogLock.Unlock()
+	queued, err := app.qrzLogModel.queuedQRZ()
+	if err != nil {
+		return nil, err
+	}
+	for i := range queued {
+		q := &queued[i]
+		l, err := app.logsModel.getLogByID(q.LogId)
+		if errors.Is(err, errNoRecord) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		var b bytes.Buffer
+		if call := app.stationCall(); call != "" {
+			adifField(&b, "station_callsign", call)
+		}
+		b = writeQSOs(b, []LogsRow{*l})
+		res, err := app.qrzLogAPI(url.Values{"ACTION": {"INSERT"}, "ADIF": {b.String()}})
+		if err != nil {
+			return nil, err
+		}
+		q.Attempts++
+		q.Tried = time.Now().UTC().Truncate(time.Second)
+		switch {
+		case res["RESULT"] == "OK":
+			q.Status, q.QRZLogId, q.Message = qrzSent, res["LOGID"], ""
+			s.Sent++
+		case strings.Contains(strings.ToLower(res["REASON"]), "duplicate"):
+			q.Status, q.Message = qrzSent, res["REASON"]
+			s.Sent++
+		default:
+			q.Message = res["REASON"]
+			if q.Message == "" {
+				q.Message = "RESULT=" + res["RESULT"]
+			}
+			s.Failed++
+		}
+		err = app.qrzLogModel.saveQRZ(q)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// the QSO in the log a confirmed QRZ Logbook record is for, 0 with
+// qslUnmatched or qslAmbiguous if none
+func (app *application) qrzLogQSO(r adifRecord) (int, string, error) {
+	if qrzlogid := strings.TrimSpace(r["APP_QRZLOG_LOGID"]); qrzlogid != "" {
+		id, err := app.qrzLogModel.findQRZ(qrzlogid)
+		if err == nil {
+			return id, "", nil
+		}
+		if !errors.Is(err, errNoRecord) {
+			return 0, "", err
+		}
+	}
+	call := strings.ToUpper(strings.TrimSpace(r["CALL"]))
+	t, err := adifTime(r["QSO_DATE"], r["TIME_ON"])
+	if call == "" || err != nil {
+		return 0, qslUnmatched, nil
+	}
+	matches, err := app.matchQSOs(call, strings.ToLower(strings.TrimSpace(r["BAND"])),
+		strings.TrimSpace(r["MODE"]), t)
+	switch {
+	case err != nil:
+		return 0, "", err
+	case len(matches) > 1:
+		return 0, qslAmbiguous, nil
+	case len(matches) == 0:
+		return 0, qslUnmatched, nil
+	}
+	return matches[0].Id, "", nil
+}
+
+// keeps a confirmation that matched no QSO or more than one for review, the
+// QRZ Logbook does not give it again.  One without a call or a time cannot
+// be matched by hand either and is only counted.
+func (app *application) keepQRZLog(s *qrzSync, r adifRecord, reason string, rcvd, start time.Time) error {
+	if reason == qslAmbiguous {
+		s.Ambiguous++
+	} else {
+		s.Unmatched++
+	}
+	call := strings.ToUpper(strings.TrimSpace(r["CALL"]))
+	t, err := adifTime(r["QSO_DATE"], r["TIME_ON"])
+	if call == "" || err != nil {
+		return nil
+	}
+	return app.qslModel.insertUnmatched(&UnmatchedQSL{Received: start.Truncate(time.Second),
+		Reason: reason, Source: sourceQRZ, LoTWQSL: LoTWQSL{Call: call,
+			Band: strings.ToLower(strings.TrimSpace(r["BAND"])), Mode: strings.TrimSpace(r["MODE"]),
+			Time: t, RxQSO: rcvd, RxQSL: rcvd}})
+}
+
+// fetches the QSOs the QRZ Logbook confirmed since the last fetch, a page
+// at a time, and confirms them in the log.  The date of the fetch is kept
+// only once the last page is in.
+func (app *application) fetchQRZLog(s *qrzSync) (*qrzSync, error) {
+	if app.qrzlogKey == "" {
+		return nil, errQRZLogKey
+	}
+	since, err := app.otherModel.getDefault(qrzLogSinceKey)
+	if err != nil && !errors.Is(err, errNoRecord) {
+		return nil, err
+	}
+	s.Since = since
+	start := time.Now().UTC()
+	options := "TYPE:ADIF,STATUS:CONFIRMED,MAX:" + strconv.Itoa(qrzLogPage)
+	if since != "" {
+		options += ",MODSINCE:" + since
+	}
+	after := 0
+	for {
+		option := options
+		if after > 0 {
+			option += ",AFTERLOGID:" + strconv.Itoa(after)
+		}
+		res, err := app.qrzLogAPI(url.Values{"ACTION": {"FETCH"}, "OPTION": {option}})
+		if err != nil {
+			return nil, err
+		}
+		if res["COUNT"] == "0" {
+			break
+		}
+		if res["RESULT"] != "OK" {
+			return nil, fmt.Errorf("%w: %s", errQRZLog, res["REASON"])
+		}
+		records := []adifRecord{}
+		if strings.TrimSpace(res["ADIF"]) != "" {
+			records, err = parseADIF(res["ADIF"])
+			if err != nil {
+				return nil, fmt.Errorf("%w: %v", errQRZLog, err)
+			}
+		}
+		next := after
+		for _, r := range records {
+			logid, err := strconv.Atoi(strings.TrimSpace(r["APP_QRZLOG_LOGID"]))
+			if err == nil && logid >= next {
+				next = logid + 1
+			}
+			err = app.confirmQRZLog(s, r, start)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if next == after {
+			//no QRZ Logbook id to go on from
+			break
+		}
+		after = next
+	}
+	err = app.otherModel.updateDefault(qrzLogSinceKey, start.Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// confirms the QSO of a fetched record or keeps it for review
+func (app *application) confirmQRZLog(s *qrzSync, r adifRecord, start time.Time) error {
+	if !strings.EqualFold(strings.TrimSpace(r["APP_QRZLOG_STATUS"]), "C") {
+		return nil
+	}
+	s.QSLs++
+	rcvd, err := time.Parse("20060102", strings.TrimSpace(r["APP_QRZLOG_QSLDATE"]))
+	if err != nil {
+		rcvd = start.Truncate(time.Second)
+	}
+	id, reason, err := app.qrzLogQSO(r)
+	if err != nil {
+		return err
+	}
+	if id == 0 {
+		return app.keepQRZLog(s, r, reason, rcvd, start)
+	}
+	err = app.logsModel.confirmQRZ(id, rcvd)
+	if errors.Is(err, errNoRecord) {
+		return app.keepQRZLog(s, r, qslUnmatched, rcvd, start)
+	}
+	if err != nil {
+		return err
+	}
+	s.Confirmed++
+	return nil
+}
+
+// sends the queued QSOs and fetches the new confirmations
+func (app *application) syncQRZLog() (*qrzSync, error) {
+	s, err := app.sendQRZLog(&qrzSync{})
+	if err != nil {
+		return nil, err
+	}
+	return app.fetchQRZLog(s)
+}
+
+// syncs with the QRZ Logbook every so often for as long as the program runs
+func (app *application) qrzLogLoop(every time.Duration) {
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+	for range ticker.C {
+		s, err := app.syncQRZLog()
+		if err != nil {
+			app.errorLog.Printf("scheduled QRZ Logbook sync failed: %v", err)
+			continue
+		}
+		app.infoLog.Printf("QRZ Logbook sync: %s", s)
+	}
+}
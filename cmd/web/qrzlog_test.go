@@ -0,0 +1,274 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// a QRZ Logbook API stand-in.  It takes the INSERTs of the calls it does
+// not refuse and answers a FETCH with the records it is given, paged by
+// their QRZ Logbook ids as MAX and AFTERLOGID ask.
+type qrzLogServer struct {
+	refuse  map[string]string //call to the reason it is refused
+	fetch   []string          //the ADIF records of a FETCH by QRZ Logbook id
+	logids  map[string]string //call to the id it was given
+	options []string
+}
+
+func newQRZLogServer(t *testing.T, app *application) *qrzLogServer {
+	s := &qrzLogServer{refuse: map[string]string{}, logids: map[string]string{}}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.PostFormValue("KEY") != "ABCD-1234" {
+			w.Write([]byte("RESULT=AUTH&REASON=invalid+api+key&EXTENDED="))
+			return
+		}
+		switch r.PostFormValue("ACTION") {
+		case "INSERT":
+			records, err := parseADIF(r.PostFormValue("ADIF"))
+			if err != nil || len(records) != 1 {
+				w.Write([]byte("RESULT=FAIL&REASON=bad+adif"))
+				return
+			}
+			call := records[0]["CALL"]
+			if reason, ok := s.refuse[call]; ok {
+				w.Write([]byte("RESULT=FAIL&REASON=" + reason))
+				return
+			}
+			s.logids[call] = fmt.Sprintf("%d", 1000+len(s.logids))
+			w.Write([]byte("RESULT=OK&LOGID=" + s.logids[call] + "&COUNT=1"))
+		case "FETCH":
+			s.options = append(s.options, r.PostFormValue("OPTION"))
+			max, after := len(s.fetch), 0
+			for _, o := range strings.Split(r.PostFormValue("OPTION"), ",") {
+				kv := strings.SplitN(o, ":", 2)
+				switch kv[0] {
+				case "MAX":
+					max, _ = strconv.Atoi(kv[1])
+				case "AFTERLOGID":
+					after, _ = strconv.Atoi(kv[1])
+				}
+			}
+			page := []string{}
+			for _, rec := range s.fetch {
+				records, _ := parseADIF(rec)
+				logid, _ := strconv.Atoi(records[0]["APP_QRZLOG_LOGID"])
+				if logid >= after && len(page) < max {
+					page = append(page, rec)
+				}
+			}
+			if len(page) == 0 {
+				w.Write([]byte("RESULT=FAIL&REASON=no+log+entries+found&COUNT=0"))
+				return
+			}
+			w.Write([]byte(fmt.Sprintf("RESULT=OK&COUNT=%d&ADIF=%s", len(page),
+				html.EscapeString(strings.Join(page, "\n")))))
+		}
+	}))
+	t.Cleanup(srv.Close)
+	old := client
+	client = srv.Client()
+	t.Cleanup(func() { client = old })
+	app.qrzlogURL = srv.URL
+	app.qrzlogKey = "ABCD-1234"
+	return s
+}
+
+func TestQRZLogResponse(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want map[string]string
+	}{
+		{"insert", "RESULT=OK&LOGID=130877825&COUNT=1",
+			map[string]string{"RESULT": "OK", "LOGID": "130877825", "COUNT": "1"}},
+		{"fail", "RESULT=FAIL&REASON=Unable+to+add+QSO%3A+duplicate",
+			map[string]string{"RESULT": "FAIL", "REASON": "Unable to add QSO: duplicate"}},
+		{"fetch", "RESULT=OK&COUNT=1&ADIF=&lt;call:4&gt;G3AB&lt;eor&gt;\n",
+			map[string]string{"RESULT": "OK", "COUNT": "1", "ADIF": "<call:4>G3AB<eor>\n"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := qrzLogResponse(tt.body)
+			if len(got) != len(tt.want) {
+				t.Fatalf("want %v, got %v", tt.want, got)
+			}
+			for k, v := range tt.want {
+				if got[k] != v {
+					t.Errorf("want %s %q, got %q", k, v, got[k])
+				}
+			}
+		})
+	}
+}
+
+func TestSyncQRZLog(t *testing.T) {
+	app, _ := newTestUploadApp(t, "DL1ABC", "G3AB", "K1ABC")
+	s := newQRZLogServer(t, app)
+	s.refuse["K1ABC"] = "Unable+to+add+QSO+to+database%3A+invalid+band"
+	for _, id := range []int{1, 2, 3, 1} {
+		err := app.qrzLogModel.queueQRZ(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := app.sendQRZLog(&qrzSync{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Sent != 2 || got.Failed != 1 {
+		t.Errorf("want 2 sent and 1 failed, got %v", got)
+	}
+	queued, err := app.qrzLogModel.queuedQRZ()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(queued) != 1 || queued[0].LogId != 3 || queued[0].Call != "K1ABC" || queued[0].Attempts != 1 ||
+		queued[0].Message != "Unable to add QSO to database: invalid band" {
+		t.Fatalf("want K1ABC left queued with the reason, got %v", queued)
+	}
+
+	//DL1ABC is found by its QRZ Logbook id though the time is off, G3AB by
+	//its call, band, mode and time.  Two to a page takes three pages.
+	old := qrzLogPage
+	qrzLogPage = 2
+	t.Cleanup(func() { qrzLogPage = old })
+	s.fetch = []string{
+		`<call:4>G3AB<band:3>20m<mode:2>CW<qso_date:8>20230415<time_on:4>1416<app_qrzlog_logid:3>999<app_qrzlog_status:1>C<eor>`,
+		`<call:6>DL1ABC<band:3>20m<mode:2>CW<qso_date:8>20230415<time_on:4>1800` +
+			`<app_qrzlog_logid:4>` + s.logids["DL1ABC"] + `<app_qrzlog_status:1>C<app_qrzlog_qsldate:8>20230501<eor>`,
+		`<call:6>JA1XYZ<band:3>15m<mode:3>FT8<qso_date:8>20230415<time_on:4>1416<app_qrzlog_logid:4>1500<app_qrzlog_status:1>C<app_qrzlog_qsldate:8>20230502<eor>`,
+		`<call:5>K1ABC<band:3>20m<mode:2>CW<qso_date:8>20230415<time_on:4>1417<app_qrzlog_logid:4>1600<app_qrzlog_status:1>N<eor>`,
+	}
+	delete(s.refuse, "K1ABC")
+	got, err = app.syncQRZLog()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := qrzSync{Sent: 1, QSLs: 3, Confirmed: 2, Unmatched: 1}
+	if *got != want {
+		t.Errorf("want %v, got %v", want, *got)
+	}
+	if len(s.options) != 3 || s.options[0] != "TYPE:ADIF,STATUS:CONFIRMED,MAX:2" ||
+		s.options[1] != "TYPE:ADIF,STATUS:CONFIRMED,MAX:2,AFTERLOGID:1001" ||
+		s.options[2] != "TYPE:ADIF,STATUS:CONFIRMED,MAX:2,AFTERLOGID:1601" {
+		t.Errorf("want all the confirmations fetched two at a time, got %q", s.options)
+	}
+	for _, tt := range []struct {
+		id   int
+		want string
+	}{{1, "YES"}, {2, "YES"}, {3, ""}} {
+		l, err := app.logsModel.getLogByID(tt.id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if l.Qrzrcvd != tt.want {
+			t.Errorf("want QSO %d confirmed %q, got %q", tt.id, tt.want, l.Qrzrcvd)
+		}
+		if tt.id == 1 && !l.QrzQSLdate.Equal(time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC)) {
+			t.Errorf("want the QRZ Logbook QSL date, got %v", l.QrzQSLdate)
+		}
+	}
+	u, err := app.qslModel.getUnmatchedQSLs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(u) != 1 || u[0].Source != sourceQRZ || u[0].Call != "JA1XYZ" ||
+		!u[0].RxQSL.Equal(time.Date(2023, 5, 2, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("want the JA1XYZ confirmation kept for review, got %v", u)
+	}
+	l, err := app.addQSL(u[0].Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err = app.logsModel.getLogByID(l.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.Qrzrcvd != "YES" || l.Lotwrcvd == "YES" {
+		t.Errorf("want the QSO added from the confirmation confirmed by QRZ, got %v", l)
+	}
+	history, err := app.logsModel.getHistory(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if history[0].Source != sourceQRZ {
+		t.Errorf("want the confirmation audited as %s, got %v", sourceQRZ, history[0])
+	}
+
+	s.fetch = nil
+	got, err = app.syncQRZLog()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Sent != 0 || got.QSLs != 0 || got.Since == "" ||
+		s.options[3] != "TYPE:ADIF,STATUS:CONFIRMED,MAX:2,MODSINCE:"+got.Since {
+		t.Errorf("want nothing new since the last fetch, got %v %q", got, s.options[3])
+	}
+}
+
+func TestSyncQRZLogErrors(t *testing.T) {
+	app, _ := newTestUploadApp(t, "DL1ABC")
+	newQRZLogServer(t, app)
+	err := app.qrzLogModel.queueQRZ(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	app.qrzlogKey = "WRONG"
+	_, err = app.syncQRZLog()
+	if !errors.Is(err, errQRZLog) || !strings.Contains(err.Error(), "refused") {
+		t.Errorf("want the refused key, got %v", err)
+	}
+	queued, err := app.qrzLogModel.queuedQRZ()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(queued) != 1 || queued[0].Attempts != 0 {
+		t.Errorf("want the QSO still queued, got %v", queued)
+	}
+	app.qrzlogKey = ""
+	if _, err = app.syncQRZLog(); !errors.Is(err, errQRZLogKey) {
+		t.Errorf("want %v, got %v", errQRZLogKey, err)
+	}
+	since, err := app.otherModel.getDefault(qrzLogSinceKey)
+	if !errors.Is(err, errNoRecord) {
+		t.Errorf("a failed sync kept the date %q", since)
+	}
+}
+
+func TestQRZLogSyncHandler(t *testing.T) {
+	app, _ := newTestUploadApp(t, "DL1ABC")
+	s := newQRZLogServer(t, app)
+	s.refuse["DL1ABC"] = "Unable+to+add+QSO+to+database%3A+invalid+band"
+	err := app.qrzLogModel.queueQRZ(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	app.routes().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/qrzlog-sync", nil))
+	if rr.Code != http.StatusMethodNotAllowed {
+		t.Errorf("want 405 for a GET, got %d", rr.Code)
+	}
+	rr = httptest.NewRecorder()
+	app.routes().ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/qrzlog-sync", nil))
+	body := rr.Body.String()
+	if rr.Code != http.StatusOK ||
+		!strings.Contains(body, "Sent 0 QSOs to the QRZ Logbook, 1 failed and stay queued") ||
+		!strings.Contains(body, "invalid band") {
+		t.Errorf("want the failed QSO listed, got %d %q", rr.Code, body)
+	}
+	app.qrzlogKey = ""
+	rr = httptest.NewRecorder()
+	app.routes().ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/qrzlog-sync", nil))
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), "-qrzlogkey") {
+		t.Errorf("want the missing key message, got %d", rr.Code)
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+//Each QSO pushed to the QRZ Logbook has a row in qrzlogqsos with its
+//sync state.  It is queued when it is logged and sent once the QRZ Logbook
+//took it, with the id the QRZ Logbook gave it.  A QSO the QRZ Logbook did
+//not take stays queued with the reason and is tried again with the next
+//sync.
+
+const (
+	qrzQueued = "queued"
+	qrzSent   = "sent"
+)
+
+type qrzLogType interface {
+	queueQRZ(int) error
+	saveQRZ(*QRZLogQSO) error
+	queuedQRZ() ([]QRZLogQSO, error)
+	findQRZ(string) (int, error)
+}
+
+// QRZLogQSO is a row of qrzlogqsos with the call of its QSO
+type QRZLogQSO struct {
+	Id       int
+	LogId    int //stationlogs id
+	Status   string
+	QRZLogId string //the QRZ Logbook id, blank until sent
+	Attempts int
+	Tried    time.Time
+	Message  string //why the last try failed
+	Call     string
+}
+
+type qrzLogModel struct {
+	DB *sql.DB
+}
+
+// queues the QSO unless it is already queued or sent
+func (m *qrzLogModel) queueQRZ(logid int) error {
+	var id int
+	err := m.DB.QueryRow(`SELECT id FROM qrzlogqsos WHERE logid = ?`, logid).Scan(&id)
+	switch {
+	case err == nil:
+		return nil
+	case !errors.Is(err, sql.ErrNoRows):
+		return err
+	}
+	stmt := `INSERT INTO qrzlogqsos (logid, status, qrzlogid, attempts, tried,
+	message) VALUES (?, ?, '', 0, ?, '')`
+
+	_, err = m.DB.Exec(stmt, logid, qrzQueued, time.Now().UTC().Truncate(time.Second))
+	return err
+}
+
+// records the outcome of a try
+func (m *qrzLogModel) saveQRZ(q *QRZLogQSO) error {
+	stmt := `UPDATE qrzlogqsos SET status = ?, qrzlogid = ?, attempts = ?,
+	tried = ?, message = ? WHERE id = ?`
+
+	_, err := m.DB.Exec(stmt, q.Status, q.QRZLogId, q.Attempts, q.Tried, q.Message, q.Id)
+	return err
+}
+
+// the QSOs waiting to be sent, the oldest first.  The call is blank for a
+// QSO deleted since it was queued.
+func (m *qrzLogModel) queuedQRZ() ([]QRZLogQSO, error) {
+	stmt := `SELECT qrzlogqsos.id, qrzlogqsos.logid, qrzlogqsos.status,
+	qrzlogqsos.qrzlogid, qrzlogqsos.attempts, qrzlogqsos.tried,
+	qrzlogqsos.message, COALESCE(stationlogs.callsign, '')
+	FROM qrzlogqsos LEFT JOIN stationlogs ON stationlogs.id = qrzlogqsos.logid
+	WHERE qrzlogqsos.status = ? ORDER BY qrzlogqsos.id`
+
+	rows, err := m.DB.Query(stmt, qrzQueued)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	qsos := []QRZLogQSO{}
+	for rows.Next() {
+		q := QRZLogQSO{}
+		err = rows.Scan(&q.Id, &q.LogId, &q.Status, &q.QRZLogId, &q.Attempts,
+			&q.Tried, &q.Message, &q.Call)
+		if err != nil {
+			return nil, err
+		}
+		qsos = append(qsos, q)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return qsos, nil
+}
+
+// the stationlogs id of the QSO the QRZ Logbook knows by the id
+func (m *qrzLogModel) findQRZ(qrzlogid string) (int, error) {
+	var logid int
+	err := m.DB.QueryRow(`SELECT logid FROM qrzlogqsos WHERE qrzlogid = ?`,
+		qrzlogid).Scan(&logid)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, errNoRecord
+		}
+		return 0, err
+	}
+	return logid, nil
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Saied74/stationmaster/pkg/labels"
+)
+
+//<<========================= Paper QSL card pages ==========================>>
+
+func (app *application) qslCards(w http.ResponseWriter, r *http.Request) {
+	td := initTemplateData()
+	td.Layout = r.URL.Query().Get("layout")
+	app.renderCards(w, r, td)
+}
+
+// renders the cards to send laid out for the label layout picked
+func (app *application) renderCards(w http.ResponseWriter, r *http.Request, td *templateData) {
+	l, err := labels.Find(td.Layout)
+	if err != nil {
+		td.Layout = defaultLabels
+		l, err = labels.Find(td.Layout)
+		if err != nil {
+			app.serverError(w, err)
+			return
+		}
+	}
+	td.Layouts = labels.Numbers()
+	td.Cards, err = app.cardsToSend(l, nil)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	app.render(w, r, "qslcards.page.html", td)
+}
+
+// parses the posted form, false when the response has been written
+func (app *application) postedCards(w http.ResponseWriter, r *http.Request) bool {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		app.clientError(w, http.StatusMethodNotAllowed)
+		return false
+	}
+	err := r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// the QSO ids in a list like "12, 15 18", nil if one is not a number
+func cardIDs(s string) []int {
+	ids := []int{}
+	for _, f := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ' ' }) {
+		id, err := strconv.Atoi(f)
+		if err != nil {
+			return nil
+		}
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// the QSOs ticked on the cards to send
+func pickedCards(r *http.Request) map[int]bool {
+	ids := map[int]bool{}
+	for _, v := range r.PostForm["id"] {
+		id, err := strconv.Atoi(v)
+		if err == nil {
+			ids[id] = true
+		}
+	}
+	return ids
+}
+
+// queues a card for the QSOs with a call that have none yet
+func (app *application) qslQueue(w http.ResponseWriter, r *http.Request) {
+	if !app.postedCards(w, r) {
+		return
+	}
+	td := initTemplateData()
+	td.Layout = r.PostForm.Get("layout")
+	call := strings.ToUpper(strings.TrimSpace(r.PostForm.Get("call")))
+	if call == "" {
+		td.Message = "Please give the call to send a card to"
+		app.renderCards(w, r, td)
+		return
+	}
+	n, err := app.logsModel.queueCards(call)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	if n == 0 {
+		td.Message = fmt.Sprintf("There are no QSOs with %s without a card", call)
+	} else {
+		td.Message = fmt.Sprintf("Queued a card for %d QSOs with %s", n, call)
+	}
+	app.renderCards(w, r, td)
+}
+
+// records a card received for the QSOs, which queues one back for those
+// that have had none
+func (app *application) qslReceived(w http.ResponseWriter, r *http.Request) {
+	if !app.postedCards(w, r) {
+		return
+	}
+	td := initTemplateData()
+	td.Layout = r.PostForm.Get("layout")
+	ids := cardIDs(r.PostForm.Get("ids"))
+	route := r.PostForm.Get("route")
+	rcvd := time.Now().UTC().Truncate(24 * time.Hour)
+	var err error
+	if d := r.PostForm.Get("date"); d != "" {
+		rcvd, err = time.Parse("2006-01-02", d)
+	}
+	switch {
+	case len(ids) == 0:
+		td.Message = "Please give the ids of the QSOs the card confirms"
+	case !isRoute(route):
+		td.Message = "Please pick how the card came"
+	case err != nil:
+		td.Message = "Please give the date the card came as YYYY-MM-DD"
+	}
+	if td.Message != "" {
+		app.renderCards(w, r, td)
+		return
+	}
+	for _, id := range ids {
+		err = app.logsModel.cardReceived(id, route, rcvd)
+		if errors.Is(err, errNoRecord) {
+			td.Message = fmt.Sprintf("There is no QSO %d", id)
+			app.renderCards(w, r, td)
+			return
+		}
+		if err != nil {
+			app.serverError(w, err)
+			return
+		}
+	}
+	td.Message = fmt.Sprintf("Card received for %d QSOs", len(ids))
+	app.renderCards(w, r, td)
+}
+
+// the ticked cards to send laid out for the posted layout, false when the
+// response has been written
+func (app *application) postedLabels(w http.ResponseWriter, r *http.Request) (labels.Layout, []QSLCard, bool) {
+	if !app.postedCards(w, r) {
+		return labels.Layout{}, nil, false
+	}
+	l, err := labels.Find(r.PostForm.Get("layout"))
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return l, nil, false
+	}
+	cards, err := app.cardsToSend(l, pickedCards(r))
+	if err != nil {
+		app.serverError(w, err)
+		return l, nil, false
+	}
+	if len(cards) == 0 {
+		td := initTemplateData()
+		td.Layout = r.PostForm.Get("layout")
+		td.Message = "Please tick the cards to send"
+		app.renderCards(w, r, td)
+		return l, nil, false
+	}
+	return l, cards, true
+}
+
+// the labels of the ticked cards as a PDF to print
+func (app *application) qslLabels(w http.ResponseWriter, r *http.Request) {
+	l, cards, ok := app.postedLabels(w, r)
+	if !ok {
+		return
+	}
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q",
+		"qsl-labels-"+time.Now().UTC().Format("20060102")+".pdf"))
+	err := writeLabels(w, l, cards)
+	if err != nil {
+		app.errorLog.Println(err)
+	}
+}
+
+// marks the ticked cards sent once they are in the mail
+func (app *application) qslSent(w http.ResponseWriter, r *http.Request) {
+	_, cards, ok := app.postedLabels(w, r)
+	if !ok {
+		return
+	}
+	n, err := app.markCardsSent(cards)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	td := initTemplateData()
+	td.Layout = r.PostForm.Get("layout")
+	td.Message = fmt.Sprintf("Marked %d cards sent for %d QSOs", len(cards), n)
+	app.renderCards(w, r, td)
+}
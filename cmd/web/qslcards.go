@@ -0,0 +1,199 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/Saied74/stationmaster/pkg/labels"
+)
+
+//<<=========================== Paper QSL cards ===========================>>
+
+//A card is queued for QSOs by hand (all the QSOs with a call that have no
+//card yet) and a card that comes in asks for one back for the QSOs it
+//confirms.  The cards to send are those QSOs grouped by call, as many QSOs
+//to a card as fit on a label.  A card goes to the QSL manager the QRZ
+//lookup of the call has, direct to a station that sent its card direct and
+//through the bureau otherwise.  The labels print as a PDF in one of the
+//Avery layouts and once the cards are in the mail they are marked sent.
+
+// qsl_sent of a QSO that a card is waiting to be sent for
+const cardQueued = "QUEUED"
+
+// how a card goes
+const (
+	routeBureau  = "bureau"
+	routeDirect  = "direct"
+	routeManager = "manager"
+)
+
+var cardRoutes = []string{routeBureau, routeDirect, routeManager}
+
+// the label layout when none is picked
+const defaultLabels = "5160"
+
+// CardQSO is a QSO a card is to be sent for
+type CardQSO struct {
+	Id         int
+	Time       time.Time
+	Call       string
+	Band       string
+	Mode       string
+	Sent       string //the report sent
+	QSLsent    string
+	QSLrcvd    string
+	QSLsentVia string
+	QSLrcvdVia string
+	Manager    string //qslmgr of the QRZ lookup of the call
+}
+
+// QSLCard is one card to send
+type QSLCard struct {
+	Call    string
+	Manager string //blank unless it goes to the manager
+	Route   string
+	QSOs    []CardQSO
+}
+
+// the ADIF QSL_SENT_VIA and QSL_RCVD_VIA letters of the routes
+var routeLetters = map[string]string{routeBureau: "B", routeDirect: "D", routeManager: "M"}
+
+func routeVia(route string) string {
+	return routeLetters[route]
+}
+
+// the route of an ADIF QSL_SENT_VIA or QSL_RCVD_VIA, blank for electronic
+// or none
+func viaRoute(via string) string {
+	via = strings.ToUpper(strings.TrimSpace(via))
+	for route, letter := range routeLetters {
+		if via == letter {
+			return route
+		}
+	}
+	return ""
+}
+
+func isRoute(route string) bool {
+	_, ok := routeLetters[route]
+	return ok
+}
+
+// the QSL manager in the qslmgr of a QRZ lookup, blank for none.  QRZ has
+// it as the call alone or as "via DJ9ZB", and NONE for none.
+func qslManager(qslmgr string) string {
+	m := strings.TrimSpace(qslmgr)
+	if len(m) > 4 && strings.EqualFold(m[:4], "via ") {
+		m = strings.TrimSpace(m[4:])
+	}
+	if strings.EqualFold(m, "NONE") {
+		return ""
+	}
+	return m
+}
+
+// how the card for the QSO goes and the manager it goes to
+func cardRoute(q CardQSO) (string, string) {
+	if m := qslManager(q.Manager); m != "" {
+		return routeManager, m
+	}
+	if q.QSLrcvdVia == routeDirect {
+		return routeDirect, ""
+	}
+	return routeBureau, ""
+}
+
+// the cards for the QSOs, perCard QSOs at most to a card.  The QSOs come
+// by call so those with one call go on the same cards.
+func groupCards(qsos []CardQSO, perCard int) []QSLCard {
+	if perCard < 1 {
+		perCard = 1
+	}
+	cards := []QSLCard{}
+	for _, q := range qsos {
+		route, manager := cardRoute(q)
+		n := len(cards) - 1
+		if n < 0 || cards[n].Call != q.Call || cards[n].Route != route ||
+			len(cards[n].QSOs) >= perCard {
+			cards = append(cards, QSLCard{Call: q.Call, Manager: manager, Route: route})
+			n++
+		}
+		cards[n].QSOs = append(cards[n].QSOs, q)
+	}
+	return cards
+}
+
+// the lines of the label of a card: who it is for, a line for each QSO
+// and whether it asks for a card back
+func (c QSLCard) label() []string {
+	to := "To Radio " + c.Call
+	if c.Manager != "" {
+		to = c.Call + " via " + c.Manager
+	}
+	lines := []string{to, "Date        UTC  Band Mode RST"}
+	tnx := true
+	for _, q := range c.QSOs {
+		lines = append(lines, fmt.Sprintf("%s %s %s %s %s", q.Time.UTC().Format("02 Jan 2006"),
+			q.Time.UTC().Format("1504"), strings.ToUpper(q.Band), q.Mode, q.Sent))
+		if !strings.EqualFold(q.QSLrcvd, "YES") {
+			tnx = false
+		}
+	}
+	if tnx {
+		return append(lines, "TNX QSL 73")
+	}
+	return append(lines, "PSE QSL TNX 73")
+}
+
+// how many QSOs go on a card with the label layout, the rest of the label
+// is the call, the heading and the closing line
+func cardQSOs(l labels.Layout) int {
+	return l.Lines() - 3
+}
+
+// the cards to send with the layout.  With ids only the QSOs with those
+// ids are on them.
+func (app *application) cardsToSend(l labels.Layout, ids map[int]bool) ([]QSLCard, error) {
+	qsos, err := app.logsModel.getCardQueue()
+	if err != nil {
+		return nil, err
+	}
+	if ids != nil {
+		picked := []CardQSO{}
+		for _, q := range qsos {
+			if ids[q.Id] {
+				picked = append(picked, q)
+			}
+		}
+		qsos = picked
+	}
+	return groupCards(qsos, cardQSOs(l)), nil
+}
+
+// writes the labels of the cards as a PDF
+func writeLabels(w io.Writer, l labels.Layout, cards []QSLCard) error {
+	sheet := [][]string{}
+	for _, c := range cards {
+		sheet = append(sheet, c.label())
+	}
+	return labels.Write(w, l, sheet)
+}
+
+// marks the QSOs on the cards sent today the way each card went and
+// returns how many QSOs that was
+func (app *application) markCardsSent(cards []QSLCard) (int, error) {
+	sent := time.Now().UTC().Truncate(24 * time.Hour)
+	n := 0
+	for _, c := range cards {
+		for _, q := range c.QSOs {
+			err := app.logsModel.cardSent(q.Id, c.Route, sent)
+			if err != nil {
+				return n, err
+			}
+			n++
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGroupCards(t *testing.T) {
+	at := time.Date(2023, 4, 15, 14, 15, 0, 0, time.UTC)
+	qso := func(id int, call, mgr, rcvd, via string) CardQSO {
+		return CardQSO{Id: id, Time: at.Add(time.Duration(id) * time.Minute), Call: call, Band: "20m",
+			Mode: "CW", Sent: "599", Manager: mgr, QSLrcvd: rcvd, QSLrcvdVia: via}
+	}
+	qsos := []CardQSO{qso(1, "DL1ABC", "NONE", "", ""), qso(2, "DL1ABC", "", "", ""),
+		qso(3, "DL1ABC", "", "", ""), qso(4, "G3AB", "via M0OXO", "", ""),
+		qso(5, "K1ABC", "", "YES", routeDirect)}
+	cards := groupCards(qsos, 2)
+	got := [][]int{}
+	for _, c := range cards {
+		ids := []int{}
+		for _, q := range c.QSOs {
+			ids = append(ids, q.Id)
+		}
+		got = append(got, ids)
+	}
+	if want := [][]int{{1, 2}, {3}, {4}, {5}}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("want the cards %v, got %v", want, got)
+	}
+	routes := []string{cards[0].Route, cards[2].Route, cards[3].Route}
+	if want := []string{routeBureau, routeManager, routeDirect}; !reflect.DeepEqual(routes, want) {
+		t.Errorf("want the routes %v, got %v", want, routes)
+	}
+
+	want := []string{"To Radio DL1ABC", "Date        UTC  Band Mode RST",
+		"15 Apr 2023 1416 20M CW 599", "15 Apr 2023 1417 20M CW 599", "PSE QSL TNX 73"}
+	if got := cards[0].label(); !reflect.DeepEqual(got, want) {
+		t.Errorf("want the label %q, got %q", want, got)
+	}
+	if got := cards[2].label(); got[0] != "G3AB via M0OXO" {
+		t.Errorf("want the card to the manager, got %q", got[0])
+	}
+	if got := cards[3].label(); got[len(got)-1] != "TNX QSL 73" {
+		t.Errorf("want thanks for the card received, got %q", got)
+	}
+}
+
+func TestRouteVia(t *testing.T) {
+	for _, route := range cardRoutes {
+		if got := viaRoute(routeVia(route)); got != route {
+			t.Errorf("want %s back, got %q", route, got)
+		}
+	}
+	if got := viaRoute("E"); got != "" {
+		t.Errorf("want no route for an electronic QSL, got %q", got)
+	}
+}
+
+// the log with QSOs with DL1ABC, G3AB who has a manager and K1ABC
+func newTestCardApp(t *testing.T) *application {
+	app, _ := newTestUploadApp(t, "DL1ABC", "DL1ABC", "G3AB", "K1ABC")
+	err := app.qrzModel.insertQRZ(&Ctype{Call: "G3AB", QSLMgr: "M0OXO"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return app
+}
+
+func TestCardQueue(t *testing.T) {
+	app := newTestCardApp(t)
+	for _, call := range []string{"DL1ABC", "G3AB"} {
+		if _, err := app.logsModel.queueCards(call); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n, err := app.logsModel.queueCards("DL1ABC"); err != nil || n != 0 {
+		t.Errorf("want no QSOs queued twice, got %d %v", n, err)
+	}
+	err := app.logsModel.cardReceived(4, routeDirect, time.Date(2023, 5, 2, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	qsos, err := app.logsModel.getCardQueue()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(qsos) != 4 || qsos[2].Manager != "M0OXO" || qsos[3].QSLrcvdVia != routeDirect {
+		t.Fatalf("want the 3 QSOs queued and the card received, got %v", qsos)
+	}
+
+	cards := groupCards(qsos, 4)
+	n, err := app.markCardsSent(cards[1:])
+	if err != nil || n != 2 {
+		t.Fatalf("want 2 QSOs sent, got %d %v", n, err)
+	}
+	l, err := app.logsModel.getLogByID(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.QSLsent != "YES" || l.QSLsentVia != routeManager || l.QSLsdate.IsZero() {
+		t.Errorf("want the card to the manager sent, got %q %q %v", l.QSLsent, l.QSLsentVia, l.QSLsdate)
+	}
+	if qsos, _ = app.logsModel.getCardQueue(); len(qsos) != 2 {
+		t.Errorf("want the DL1ABC card left, got %v", qsos)
+	}
+}
+
+func TestPaperConfirmations(t *testing.T) {
+	app := newTestSQLiteApp(t)
+	_, err := app.logsModel.importLog(&LogsRow{Call: "AA7BQ", Band: "20m", Mode: "CW",
+		Country: "United States", Time: time.Date(2023, 4, 15, 14, 15, 0, 0, time.UTC)}, sourceImport)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = app.qrzModel.insertQRZ(&Ctype{Call: "AA7BQ", State: "AZ", County: "Maricopa"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = app.logsModel.cardReceived(1, routeBureau, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, paper := range []bool{false, true} {
+		states, err := app.logsModel.getConfirmedStates(paper)
+		if err != nil {
+			t.Fatal(err)
+		}
+		counties, err := app.logsModel.getConfirmedCounties(paper)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := 0
+		if paper {
+			want = 1
+		}
+		if len(states) != want || len(counties) != want {
+			t.Errorf("paper %v: want %d confirmed, got %d states %d counties", paper,
+				want, len(states), len(counties))
+		}
+	}
+}
+
+func TestQSLCardHandlers(t *testing.T) {
+	app := newTestCardApp(t)
+	post := func(path string, v url.Values) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(v.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rr := httptest.NewRecorder()
+		app.routes().ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := httptest.NewRecorder()
+	app.routes().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/qsl-queue", nil))
+	if rr.Code != http.StatusMethodNotAllowed {
+		t.Errorf("want 405 for a GET, got %d", rr.Code)
+	}
+	rr = post("/qsl-queue", url.Values{"call": {"dl1abc"}})
+	if !strings.Contains(rr.Body.String(), "Queued a card for 2 QSOs with DL1ABC") {
+		t.Errorf("want the card queued, got %d %q", rr.Code, rr.Body.String())
+	}
+	for _, want := range []string{"Please give the ids", "Please pick how the card came", "There is no QSO 99"} {
+		v := url.Values{"ids": {"4"}, "route": {"direct"}}
+		switch want {
+		case "Please give the ids":
+			v.Set("ids", "four")
+		case "Please pick how the card came":
+			v.Set("route", "pigeon")
+		default:
+			v.Set("ids", "99")
+		}
+		if rr = post("/qsl-received", v); !strings.Contains(rr.Body.String(), want) {
+			t.Errorf("want %q, got %d", want, rr.Code)
+		}
+	}
+	rr = post("/qsl-received", url.Values{"ids": {"4"}, "route": {"direct"}, "date": {"2023-05-02"}})
+	if !strings.Contains(rr.Body.String(), "Card received for 1 QSOs") {
+		t.Errorf("want the card received, got %d", rr.Code)
+	}
+
+	rr = httptest.NewRecorder()
+	app.routes().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/qsl-cards", nil))
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), "DL1ABC") ||
+		!strings.Contains(rr.Body.String(), "K1ABC") {
+		t.Errorf("want the cards to send, got %d", rr.Code)
+	}
+
+	rr = post("/qsl-labels", url.Values{"layout": {"L7160"}, "id": {"1", "2", "4"}})
+	if rr.Code != http.StatusOK || rr.Header().Get("Content-Type") != "application/pdf" ||
+		!strings.Contains(rr.Body.String(), "(To Radio DL1ABC)") || !strings.Contains(rr.Body.String(), "/Count 1") {
+		t.Errorf("want the labels as a PDF, got %d %q", rr.Code, rr.Header().Get("Content-Type"))
+	}
+	if rr = post("/qsl-labels", url.Values{"layout": {"9999"}, "id": {"1"}}); rr.Code != http.StatusBadRequest {
+		t.Errorf("want 400 for an unknown layout, got %d", rr.Code)
+	}
+	rr = post("/qsl-sent", url.Values{"layout": {"5160"}, "id": {"1", "2", "4"}})
+	if !strings.Contains(rr.Body.String(), "Marked 2 cards sent for 3 QSOs") {
+		t.Errorf("want the cards sent, got %d", rr.Code)
+	}
+	if rr = post("/qsl-sent", url.Values{"layout": {"5160"}}); !strings.Contains(rr.Body.String(), "Please tick") {
+		t.Errorf("want a message with nothing ticked, got %d", rr.Code)
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+//<<=================== Review of unmatched confirmations ===================>>
+
+func (app *application) lotwQSLs(w http.ResponseWriter, r *http.Request) {
+	app.renderQSLs(w, r, initTemplateData())
+}
+
+func (app *application) renderQSLs(w http.ResponseWriter, r *http.Request, td *templateData) {
+	var err error
+	td.Unmatched, err = app.unmatchedQSLs()
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	app.render(w, r, "lotwqsls.page.html", td)
+}
+
+// the id of the waiting confirmation the form was posted for, false when
+// the response has been written
+func (app *application) postedQSL(w http.ResponseWriter, r *http.Request) (int, bool) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		app.clientError(w, http.StatusMethodNotAllowed)
+		return 0, false
+	}
+	err := r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return 0, false
+	}
+	id, err := strconv.Atoi(r.PostForm.Get("id"))
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return 0, false
+	}
+	return id, true
+}
+
+// confirms the QSO picked for a waiting confirmation
+func (app *application) lotwLink(w http.ResponseWriter, r *http.Request) {
+	id, ok := app.postedQSL(w, r)
+	if !ok {
+		return
+	}
+	td := initTemplateData()
+	logID, err := strconv.Atoi(r.PostForm.Get("logid"))
+	if err != nil {
+		td.Message = "Please give the id of the QSO to link"
+		app.renderQSLs(w, r, td)
+		return
+	}
+	_, err = app.logsModel.getLogByID(logID)
+	if errors.Is(err, errNoRecord) {
+		td.Message = fmt.Sprintf("There is no QSO %d", logID)
+		app.renderQSLs(w, r, td)
+		return
+	}
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	u, err := app.linkQSL(id, logID)
+	if errors.Is(err, errNoRecord) {
+		app.notFound(w)
+		return
+	}
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	td.Message = fmt.Sprintf("QSO %d confirmed by the %s QSL from %s", logID,
+		qslSourceName(u.Source), u.Call)
+	app.renderQSLs(w, r, td)
+}
+
+// logs a waiting confirmation as a new QSO
+func (app *application) lotwAdd(w http.ResponseWriter, r *http.Request) {
+	id, ok := app.postedQSL(w, r)
+	if !ok {
+		return
+	}
+	l, err := app.addQSL(id)
+	if errors.Is(err, errNoRecord) {
+		app.notFound(w)
+		return
+	}
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	td := initTemplateData()
+	td.Message = fmt.Sprintf("Logged %s on %s %s as QSO %d", l.Call, l.Band, l.Mode, l.Id)
+	app.renderQSLs(w, r, td)
+}
+
+// forgets a waiting confirmation
+func (app *application) lotwDismiss(w http.ResponseWriter, r *http.Request) {
+	id, ok := app.postedQSL(w, r)
+	if !ok {
+		return
+	}
+	u, err := app.qslModel.getUnmatched(id)
+	if errors.Is(err, errNoRecord) {
+		app.notFound(w)
+		return
+	}
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	err = app.qslModel.deleteUnmatched(id)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	td := initTemplateData()
+	td.Message = fmt.Sprintf("Dismissed the %s QSL from %s", qslSourceName(u.Source), u.Call)
+	app.renderQSLs(w, r, td)
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// a log with a QSO and three LoTW QSLs that match nothing in it
+func newTestQSLApp(t *testing.T) *application {
+	app := newTestSQLiteApp(t)
+	l := LogsRow{Call: "DL1ABC", Band: "20m", Mode: "FT8",
+		Time: time.Date(2023, 4, 15, 14, 15, 0, 0, time.UTC)}
+	_, err := app.logsModel.importLog(&l, sourceImport)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = app.matchQSLs([]map[itemType]string{
+		qslRecord("DL1ABC", "20M", "SSB", "2023-04-15T14:15:00Z"),
+		qslRecord("G3AB", "40M", "CW", "2023-04-15T15:00:00Z"),
+		qslRecord("K1ABC", "15M", "CW", "2023-04-15T16:00:00Z"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return app
+}
+
+func TestLoTWQSLHandlers(t *testing.T) {
+	app := newTestQSLApp(t)
+	post := func(path string, form url.Values) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, path, strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		app.routes().ServeHTTP(rr, r)
+		return rr
+	}
+
+	rr := httptest.NewRecorder()
+	app.routes().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/lotw-qsls", nil))
+	body := rr.Body.String()
+	if rr.Code != http.StatusOK || !strings.Contains(body, "<b>G3AB</b>") ||
+		!strings.Contains(body, `name="logid" value="1"`) || !strings.Contains(body, "No QSOs with K1ABC") {
+		t.Errorf("want the 3 QSLs with QSO 1 to link, got %d %q", rr.Code, body)
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		form    url.Values
+		code    int
+		message string
+	}{
+		{"get", "/lotw-link", nil, http.StatusMethodNotAllowed, ""},
+		{"bad id", "/lotw-link", url.Values{"id": {"x"}}, http.StatusBadRequest, ""},
+		{"no QSO id", "/lotw-link", url.Values{"id": {"1"}}, http.StatusOK, "Please give the id"},
+		{"no QSO", "/lotw-link", url.Values{"id": {"1"}, "logid": {"9"}}, http.StatusOK, "There is no QSO 9"},
+		{"link", "/lotw-link", url.Values{"id": {"1"}, "logid": {"1"}}, http.StatusOK,
+			"QSO 1 confirmed by the LoTW QSL from DL1ABC"},
+		{"linked", "/lotw-link", url.Values{"id": {"1"}, "logid": {"1"}}, http.StatusNotFound, ""},
+		{"add", "/lotw-add", url.Values{"id": {"2"}}, http.StatusOK, "Logged G3AB on 40m CW as QSO 2"},
+		{"dismiss", "/lotw-dismiss", url.Values{"id": {"3"}}, http.StatusOK, "Dismissed the LoTW QSL from K1ABC"},
+		{"dismissed", "/lotw-dismiss", url.Values{"id": {"3"}}, http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var rr *httptest.ResponseRecorder
+			if tt.form == nil {
+				rr = httptest.NewRecorder()
+				app.routes().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			} else {
+				rr = post(tt.path, tt.form)
+			}
+			if rr.Code != tt.code || !strings.Contains(rr.Body.String(), tt.message) {
+				t.Errorf("want %d %q, got %d %q", tt.code, tt.message, rr.Code, rr.Body.String())
+			}
+		})
+	}
+
+	for id := 1; id <= 2; id++ {
+		l, err := app.logsModel.getLogByID(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if l.Lotwrcvd != "YES" || !l.LotwQSLdate.Equal(time.Date(2023, 4, 16, 10, 5, 31, 0, time.UTC)) {
+			t.Errorf("want QSO %d confirmed, got %v", id, l)
+		}
+	}
+	l, err := app.logsModel.getLogByID(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.Call != "G3AB" || l.Lotwsent != "YES" {
+		t.Errorf("want G3AB logged as sent to LoTW, got %v", l)
+	}
+	qsls, err := app.qslModel.getUnmatchedQSLs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(qsls) != 0 {
+		t.Errorf("want no QSLs left, got %v", qsls)
+	}
+}
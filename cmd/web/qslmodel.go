@@ -0,0 +1,110 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+//The LoTW, eQSL and QRZ Logbook confirmations that matched no QSO in the
+//log, or more than one, wait in lotwunmatched until they are linked to a
+//QSO, logged as a new QSO or dismissed on the QSLs to review page.
+
+type qslType interface {
+	insertUnmatched(*UnmatchedQSL) error
+	getUnmatched(int) (*UnmatchedQSL, error)
+	getUnmatchedQSLs() ([]UnmatchedQSL, error)
+	deleteUnmatched(int) error
+}
+
+// UnmatchedQSL is a row of lotwunmatched
+type UnmatchedQSL struct {
+	Id       int
+	Received time.Time //when it was downloaded or read
+	Reason   string    //unmatched or ambiguous
+	Source   string    //sourceLoTW, sourceEQSL or sourceQRZ
+	AG       bool      //an eQSL from an Authenticity Guaranteed member
+	LoTWQSL
+	Candidates []LogsRow //QSOs it could be, for the review page
+}
+
+type qslModel struct {
+	DB *sql.DB
+}
+
+// keeps the confirmation unless the same one is already waiting
+func (m *qslModel) insertUnmatched(u *UnmatchedQSL) error {
+	if u.Source == "" {
+		u.Source = sourceLoTW
+	}
+	var id int
+	err := m.DB.QueryRow(`SELECT id FROM lotwunmatched WHERE source = ? AND
+	callsign = ? AND band = ? AND mode = ? AND qsotime = ?`, u.Source, u.Call,
+		u.Band, u.Mode, u.Time).Scan(&id)
+	switch {
+	case err == nil:
+		u.Id = id
+		return nil
+	case !errors.Is(err, sql.ErrNoRows):
+		return err
+	}
+	stmt := `INSERT INTO lotwunmatched (received, reason, source, ag, callsign,
+	band, mode, qsotime, rxqso, rxqsl) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	result, err := m.DB.Exec(stmt, u.Received, u.Reason, u.Source, u.AG, u.Call,
+		u.Band, u.Mode, u.Time, u.RxQSO, u.RxQSL)
+	if err != nil {
+		return err
+	}
+	newID, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	u.Id = int(newID)
+	return nil
+}
+
+const unmatchedSelect = `SELECT id, received, reason, source, ag, callsign,
+	band, mode, qsotime, rxqso, rxqsl FROM lotwunmatched`
+
+func (m *qslModel) getUnmatched(id int) (*UnmatchedQSL, error) {
+	u := &UnmatchedQSL{}
+	err := m.DB.QueryRow(unmatchedSelect+` WHERE id = ?`, id).Scan(&u.Id, &u.Received,
+		&u.Reason, &u.Source, &u.AG, &u.Call, &u.Band, &u.Mode, &u.Time, &u.RxQSO, &u.RxQSL)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errNoRecord
+		}
+		return nil, err
+	}
+	return u, nil
+}
+
+// the waiting confirmations by QSO time
+func (m *qslModel) getUnmatchedQSLs() ([]UnmatchedQSL, error) {
+	rows, err := m.DB.Query(unmatchedSelect + ` ORDER BY qsotime, id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tr := []UnmatchedQSL{}
+	for rows.Next() {
+		u := UnmatchedQSL{}
+		err = rows.Scan(&u.Id, &u.Received, &u.Reason, &u.Source, &u.AG, &u.Call,
+			&u.Band, &u.Mode, &u.Time, &u.RxQSO, &u.RxQSL)
+		if err != nil {
+			return nil, err
+		}
+		tr = append(tr, u)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return tr, nil
+}
+
+func (m *qslModel) deleteUnmatched(id int) error {
+	_, err := m.DB.Exec(`DELETE FROM lotwunmatched WHERE id = ?`, id)
+	return err
+}
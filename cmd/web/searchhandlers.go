@@ -0,0 +1,87 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// how many QSOs a page of search results shows
+const searchPageSize = 50
+
+// pager is the position of a page in the search results
+type pager struct {
+	Page  int
+	Pages int
+	Total int    //QSOs the search found
+	First int    //number of the first QSO on the page, from 1
+	Last  int    //number of the last QSO on the page
+	Prev  string //URLs of the neighbouring pages, blank at the ends
+	Next  string
+}
+
+// searches the log with the filters in the query string, a page at a time.
+// The filters are the logFilter fields.
+func (app *application) search(w http.ResponseWriter, r *http.Request) {
+	td := initTemplateData()
+	td.Logger = true
+	q := r.URL.Query()
+	f := newForm(q)
+	lf := f.logFilter()
+	page := 1
+	if v := q.Get("page"); v != "" {
+		var err error
+		page, err = strconv.Atoi(v)
+		if err != nil || page < 1 {
+			app.clientError(w, http.StatusBadRequest)
+			return
+		}
+	}
+	td.FormData = f
+	td.Columns = csvChoices(q["col"])
+	if !f.valid() {
+		app.render(w, r, "search.page.html", td)
+		return
+	}
+	rows, total, err := app.logsModel.searchLogs(lf, (page-1)*searchPageSize, searchPageSize)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	td.Table = rows
+	td.Pager = newPager(q, page, searchPageSize, total)
+	app.render(w, r, "search.page.html", td)
+}
+
+func newPager(q url.Values, page, size, total int) *pager {
+	p := &pager{
+		Page:  page,
+		Pages: (total + size - 1) / size,
+		Total: total,
+	}
+	if total > 0 && page <= p.Pages {
+		p.First = (page-1)*size + 1
+		p.Last = p.First + size - 1
+		if p.Last > total {
+			p.Last = total
+		}
+	}
+	link := func(n int) string {
+		v := url.Values{}
+		for k, vv := range q {
+			v[k] = vv
+		}
+		v.Set("page", strconv.Itoa(n))
+		return "/search?" + v.Encode()
+	}
+	if page > 1 {
+		p.Prev = link(page - 1)
+		if page > p.Pages && p.Pages > 0 {
+			p.Prev = link(p.Pages)
+		}
+	}
+	if page < p.Pages {
+		p.Next = link(page + 1)
+	}
+	return p
+}
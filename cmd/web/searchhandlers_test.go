@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestNewPager(t *testing.T) {
+	q := url.Values{"call": {"DL*"}}
+	tests := []struct {
+		name  string
+		page  int
+		total int
+		want  pager
+	}{
+		{"nothing found", 1, 0, pager{Page: 1}},
+		{"one page", 1, 7, pager{Page: 1, Pages: 1, Total: 7, First: 1, Last: 7}},
+		{"first of three", 1, 25, pager{Page: 1, Pages: 3, Total: 25, First: 1, Last: 10,
+			Next: "/search?call=DL%2A&page=2"}},
+		{"middle", 2, 25, pager{Page: 2, Pages: 3, Total: 25, First: 11, Last: 20,
+			Prev: "/search?call=DL%2A&page=1", Next: "/search?call=DL%2A&page=3"}},
+		{"last", 3, 25, pager{Page: 3, Pages: 3, Total: 25, First: 21, Last: 25,
+			Prev: "/search?call=DL%2A&page=2"}},
+		{"past the end", 5, 25, pager{Page: 5, Pages: 3, Total: 25,
+			Prev: "/search?call=DL%2A&page=3"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := newPager(q, tt.page, 10, tt.total)
+			if *got != tt.want {
+				t.Errorf("want %+v, got %+v", tt.want, *got)
+			}
+		})
+	}
+}
+
+func TestSearch(t *testing.T) {
+	app := newTestApp()
+	m := &mockLogsModel{}
+	for i := 1; i <= searchPageSize+5; i++ {
+		m.rows = append(m.rows, LogsRow{Id: i, Call: fmt.Sprintf("K%dAR", i)})
+	}
+	app.logsModel = m
+
+	tests := []struct {
+		name     string
+		query    string
+		wantCode int
+		wantBody []string
+	}{
+		{"first page", "", 200, []string{"QSOs 1 to 50 of 55", "K1AR", "page=2"}},
+		{"second page", "page=2&band=20m", 200, []string{"QSOs 51 to 55 of 55", "K55AR"}},
+		{"bad page", "page=0", 400, nil},
+		{"bad call", "call=K1%20AR", 200, []string{"only letters, digits"}},
+		{"bad date", "startdate=2023-13-01", 200, []string{"incorrect date format"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			r, err := http.NewRequest(http.MethodGet, "/search?"+tt.query, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			app.search(rr, r)
+			if rr.Code != tt.wantCode {
+				t.Errorf("expected %d got %d", tt.wantCode, rr.Code)
+			}
+			for _, w := range tt.wantBody {
+				if !strings.Contains(rr.Body.String(), w) {
+					t.Errorf("expected %q in the body, did not get it", w)
+				}
+			}
+		})
+	}
+}
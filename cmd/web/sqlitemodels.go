@@ -0,0 +1,65 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+//The SQLite models let stationmaster run out of a single database file with
+//no MySQL server, for example on a portable or field day laptop.  Most of the
+//SQL in the MySQL models runs unchanged on SQLite, so SQLite uses them too.
+//A model with a method that uses MySQL only syntax is embedded in a SQLite
+//model that overrides the method.
+
+const (
+	mysqlDriver  = "mysql"
+	sqliteDriver = "sqlite3"
+)
+
+type sqliteQRZModel struct {
+	qrzModel
+}
+
+// SQLite has no UTC_TIMESTAMP(), so the time is passed in as a parameter
+func (m *sqliteQRZModel) insertQRZ(c *Ctype) error {
+	if c.Call == "" {
+		return nil
+	}
+	trimQRZ(c)
+
+	stmt := `INSERT INTO qrztable (time, callsign, aliases, dxcc, first_name,
+		last_name, nickname, born, addr1, addr2, state, zip, country, country_code,
+		lat, lon, grid, county, fips, land, cqzone, ituzone, geolocation, effdate,
+		expdate, prevcall, class, codes, qslmgr, email, url, views, bio, image,
+		moddate, msa, areacode, timezone, gmtoffset, dst, eqsl, mqsl, attn, qso_count)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?,
+	?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	_, err := m.DB.Exec(stmt, time.Now().UTC(), c.Call, c.Aliases, c.Dxcc, c.Fname, c.Lname,
+		c.NickName, c.Born, c.Addr1, c.Addr2, c.State, c.Zip, c.Country, c.CountryCode,
+		c.Lat, c.Long, c.Grid, c.County, c.FIPS, c.Land, c.CQzone, c.ITUzone,
+		c.GeoLocation, c.EffDate, c.ExpDate, c.PrevCall, c.Class, c.Codes, c.QSLMgr,
+		c.Email, c.URL, c.Views, c.Bio, c.Image, c.ModDate, c.MSA, c.AreaCode, c.TimeZone,
+		c.GMTOffset, c.DST, c.EQSL, c.MQSL, c.Attn, c.QSOCount)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// opens (and creates if need be) the SQLite database file.  SQLite only
+// allows one writer at a time, so the pool is held to a single connection
+// to keep the WSJT-X listener and the web handlers from tripping over
+// each other.  The tables are built by the migrations.
+func openSQLite(fileName string) (*sql.DB, error) {
+	dsn := fmt.Sprintf("file:%s?_busy_timeout=5000", fileName)
+	db, err := openDB(sqliteDriver, dsn)
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(1)
+	return db, nil
+}
@@ -0,0 +1,354 @@
+package main
+
+import (
+	"errors"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func newTestSQLiteApp(t *testing.T) *application {
+	db, err := openSQLite(filepath.Join(t.TempDir(), "stationmaster.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	_, err = migrate(db, sqliteDriver)
+	if err != nil {
+		t.Fatal(err)
+	}
+	app := newTestApp()
+	app.setModels(sqliteDriver, db)
+	return app
+}
+
+func TestSQLiteLogs(t *testing.T) {
+	app := newTestSQLiteApp(t)
+
+	start := time.Now().UTC().Add(-time.Minute)
+	l := &LogsRow{
+		Call:        "AA7BQ",
+		Mode:        "CW",
+		Sent:        "599",
+		Rcvd:        "579",
+		Band:        "20m",
+		Name:        "Fred",
+		Country:     "United States",
+		Lotwsent:    "",
+		Lotwrcvd:    "",
+		Contest:     "Yes",
+		ContestName: "CWOps",
+		Field1Sent:  "Saied",
+		Field1Rcvd:  "Fred",
+		Freq:        "14.025500",
+		FreqRx:      "14.027000",
+	}
+	id, err := app.logsModel.insertLog(l, sourceWeb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != 1 {
+		t.Errorf("want id 1, got %d", id)
+	}
+
+	got, err := app.logsModel.getLogByID(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Call != l.Call || got.Band != l.Band || got.Mode != l.Mode {
+		t.Errorf("want %s %s %s, got %s %s %s", l.Call, l.Band, l.Mode,
+			got.Call, got.Band, got.Mode)
+	}
+	if got.Freq != l.Freq || got.FreqRx != l.FreqRx {
+		t.Errorf("want freq %s rx %s, got %s %s", l.Freq, l.FreqRx, got.Freq, got.FreqRx)
+	}
+	if got.Time.Before(start) {
+		t.Errorf("log time %v is before %v", got.Time, start)
+	}
+
+	logs, err := app.logsModel.getLatestLogs(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(logs) != 1 {
+		t.Errorf("want 1 latest log, got %d", len(logs))
+	}
+
+	dupe, err := app.logsModel.checkDupe(start, "CWOps", "AA7BQ", "20m", "CW")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !dupe {
+		t.Errorf("want a dupe for AA7BQ")
+	}
+	dupe, err = app.logsModel.checkDupe(start, "CWOps", "AA7BQ", "40m", "CW")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dupe {
+		t.Errorf("did not want a dupe for AA7BQ on 40m")
+	}
+
+	//the MySQL queries depend on case insensitive matching of the lotw flags
+	err = app.logsModel.updateLog(&LogsRow{Call: "AA7BQ", Mode: "CW", Band: "20m",
+		Sent: "599", Rcvd: "579", Name: "Fred", Country: "United States",
+		Lotwsent: "Yes", Lotwrcvd: "Yes"}, id, sourceWeb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logs, err = app.logsModel.getConfirmedContacts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(logs) != 1 {
+		t.Errorf("want 1 confirmed contact, got %d", len(logs))
+	}
+}
+
+func TestSQLiteQRZ(t *testing.T) {
+	app := newTestSQLiteApp(t)
+
+	_, err := app.qrzModel.getQRZ("AA7BQ")
+	if !errors.Is(err, errNoRecord) {
+		t.Errorf("want errNoRecord, got %v", err)
+	}
+	err = app.qrzModel.insertQRZ(&Ctype{Call: "AA7BQ", Fname: "Fred",
+		State: "AZ", County: "Maricopa", QSOCount: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := app.qrzModel.getQRZ("aa7bq")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Fname != "Fred" || c.QSOCount != 1 {
+		t.Errorf("want Fred with 1 QSO, got %s with %d", c.Fname, c.QSOCount)
+	}
+
+	err = app.qrzModel.stashQRZdata(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := app.qrzModel.unstashQRZdata()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Call != "AA7BQ" {
+		t.Errorf("want stashed AA7BQ, got %s", s.Call)
+	}
+}
+
+func TestSQLiteDefaults(t *testing.T) {
+	app := newTestSQLiteApp(t)
+
+	_, err := app.otherModel.getDefault("band")
+	if !errors.Is(err, errNoRecord) {
+		t.Errorf("want errNoRecord, got %v", err)
+	}
+	for _, v := range []string{"20m", "40m"} {
+		err = app.otherModel.updateDefault("band", v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := app.otherModel.getDefault("band")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != v {
+			t.Errorf("want band %s, got %s", v, got)
+		}
+	}
+}
+
+func TestSQLiteContest(t *testing.T) {
+	app := newTestSQLiteApp(t)
+
+	c := &ContestRow{
+		Time:        time.Now().UTC(),
+		ContestName: "CWOps",
+		FieldCount:  2,
+		Field1Name:  "Name",
+		Field2Name:  "Number",
+	}
+	err := app.contestModel.insertContest(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Field2Name = "State"
+	err = app.contestModel.insertContest(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := app.contestModel.getContest("CWOps")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Field2Name != "State" {
+		t.Errorf("want State, got %s", got.Field2Name)
+	}
+}
+
+func TestSQLiteExportData(t *testing.T) {
+	app := newTestSQLiteApp(t)
+
+	_, err := app.importADIF("test.adi", adifImportData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = app.qrzModel.insertQRZ(&Ctype{Call: "AA7BQ", State: "AZ",
+		County: "Maricopa", Grid: "DM32af"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		lf   *logFilter
+		want int
+	}{
+		{"everything", &logFilter{}, 3},
+		{"band", &logFilter{Band: "40m"}, 1},
+		{"ssb", &logFilter{Mode: "SSB"}, 2},
+		{"contest", &logFilter{ContestName: "arrl-dx"}, 1},
+		{"confirmed", &logFilter{Confirmed: confirmedYes}, 1},
+		{"not confirmed", &logFilter{Confirmed: confirmedNo}, 2},
+		{"one day", &logFilter{Start: time.Date(2023, 4, 15, 0, 0, 0, 0, time.UTC),
+			End: time.Date(2023, 4, 15, 0, 0, 0, 0, time.UTC)}, 3},
+		{"before", &logFilter{End: time.Date(2023, 4, 14, 0, 0, 0, 0, time.UTC)}, 0},
+		{"call", &logFilter{Call: "AA7BQ"}, 1},
+		{"prefix", &logFilter{Call: "D*"}, 1},
+		{"one character wildcards", &logFilter{Call: "??1*"}, 1},
+		{"country", &logFilter{Country: "United States"}, 1},
+		{"state", &logFilter{State: "AZ"}, 1},
+		{"comment", &logFilter{Comment: "dB"}, 1},
+		{"comment percent", &logFilter{Comment: "12%r"}, 0},
+		{"comment underscore", &logFilter{Comment: "12_dB"}, 0},
+		{"combined", &logFilter{Call: "*A*", Band: "20m", Confirmed: confirmedNo}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, err := app.logsModel.getExportData(tt.lf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(rows) != tt.want {
+				t.Errorf("want %d rows, got %d", tt.want, len(rows))
+			}
+		})
+	}
+	rows, err := app.logsModel.getExportData(&logFilter{Confirmed: confirmedYes})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) == 1 && (rows[0].State != "AZ" || rows[0].Grid != "DM32af") {
+		t.Errorf("want the QRZ state and grid, got %s %s", rows[0].State, rows[0].Grid)
+	}
+
+	_, err = app.logsModel.insertLog(&LogsRow{Call: "G4XYZ", Mode: "CW", Band: "20m",
+		Comment: `QSB 50% a_b c\d`}, sourceWeb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []string{"50%", "a_b", `c\d`} {
+		rows, err = app.logsModel.getExportData(&logFilter{Comment: c})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(rows) != 1 || rows[0].Call != "G4XYZ" {
+			t.Errorf("want G4XYZ for the comment %s, got %d rows", c, len(rows))
+		}
+	}
+}
+
+func TestSQLiteSearchLogs(t *testing.T) {
+	app := newTestSQLiteApp(t)
+
+	_, err := app.importADIF("test.adi", adifImportData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		offset int
+		want   []string
+	}{
+		{"first page", 0, []string{"K1XX", "DL1AB"}},
+		{"last page", 2, []string{"AA7BQ"}},
+		{"past the end", 4, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, total, err := app.logsModel.searchLogs(&logFilter{}, tt.offset, 2)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if total != 3 {
+				t.Errorf("want 3 in all, got %d", total)
+			}
+			if len(rows) != len(tt.want) {
+				t.Fatalf("want %v, got %d rows", tt.want, len(rows))
+			}
+			for i, r := range rows {
+				if r.Call != tt.want[i] {
+					t.Errorf("want %s in row %d, got %s", tt.want[i], i, r.Call)
+				}
+			}
+		})
+	}
+}
+
+func TestSQLiteProfiles(t *testing.T) {
+	app := newTestSQLiteApp(t)
+
+	//the migration carries over the old compiled in call
+	p, err := app.activeProfile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Call != "N2VY" {
+		t.Errorf("want N2VY, got %s", p.Call)
+	}
+
+	id, err := app.profileModel.insertProfile(&ProfileRow{Name: "Club", Call: "W2ZQ",
+		Grid: "FN20", Location: "NJ", Club: "DVRA", Power: "1500"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = app.otherModel.updateDefault("profile", strconv.Itoa(id))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err = app.activeProfile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Call != "W2ZQ" || p.powerCategory() != "HIGH" {
+		t.Errorf("want W2ZQ at HIGH power, got %s at %s", p.Call, p.powerCategory())
+	}
+
+	p.Call = "K2Z"
+	err = app.profileModel.updateProfile(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	profiles, err := app.profileModel.getProfiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(profiles) != 2 || profiles[1].Call != "K2Z" {
+		t.Errorf("want 2 profiles, the second K2Z, got %v", profiles)
+	}
+
+	//deleting the active profile falls back to the first one
+	err = app.profileModel.deleteProfile(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err = app.activeProfile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Call != "N2VY" {
+		t.Errorf("want N2VY, got %s", p.Call)
+	}
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+//<<======================== LoTW upload with TQSL ========================>>
+
+//The QSOs not yet sent to LoTW go up in batches.  A batch is written to
+//lotw-<batch id>.adi in the QSL directory and handed to the TQSL command
+//line (tqsl in config.yaml), which signs it with the station location set
+//up in TQSL (tqslstation) and uploads it.  The QSOs are marked sent only
+//when TQSL says the upload went through.  A batch that failed stays queued
+//with what TQSL said and is tried again with the next upload.
+
+// TQSL command line exit codes
+const (
+	tqslOK        = 0
+	tqslAllDupes  = 8  //nothing new, the QSOs are already at LoTW
+	tqslSomeDupes = 9  //the rest went up
+	tqslNotRun    = -1 //tqsl did not start or was stopped
+)
+
+var tqslResults = map[int]string{
+	tqslOK:        "signed and uploaded",
+	1:             "cancelled",
+	2:             "rejected by LoTW",
+	3:             "unexpected response from the LoTW server",
+	4:             "TQSL error",
+	5:             "TQSLlib error",
+	6:             "TQSL could not open the input file",
+	7:             "TQSL could not open the output file",
+	tqslAllDupes:  "all the QSOs were duplicates or out of the date range",
+	tqslSomeDupes: "some QSOs were duplicates or out of the date range, the rest were uploaded",
+	10:            "TQSL command syntax error",
+	11:            "could not connect to LoTW",
+	tqslNotRun:    "TQSL did not run",
+}
+
+// how long TQSL gets to sign and upload a batch
+const tqslTimeout = 10 * time.Minute
+
+var errNoTQSL = errors.New("there is no tqsl in config.yaml")
+
+// the upload went through, duplicates LoTW already has count as sent
+func tqslSent(result int) bool {
+	return result == tqslOK || result == tqslAllDupes || result == tqslSomeDupes
+}
+
+// what a TQSL result means with the last line TQSL wrote
+func tqslMessage(result int, output string) string {
+	msg, ok := tqslResults[result]
+	if !ok {
+		msg = fmt.Sprintf("TQSL exit code %d", result)
+	}
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	if last := strings.TrimSpace(lines[len(lines)-1]); last != "" {
+		msg += ": " + last
+	}
+	return msg
+}
+
+// signs and uploads the file with TQSL and returns its exit code and
+// what it wrote
+func (app *application) runTQSL(fileName string) (int, string) {
+	args := []string{"-x", "-d", "-u", "-a", "compliant"}
+	if app.tqslStation != "" {
+		args = append(args, "-l", app.tqslStation)
+	}
+	args = append(args, fileName)
+	ctx, cancel := context.WithTimeout(context.Background(), tqslTimeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, app.tqsl, args...).CombinedOutput()
+	if err == nil {
+		return tqslOK, string(out)
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() >= 0 {
+		return exitErr.ExitCode(), string(out)
+	}
+	return tqslNotRun, strings.TrimSpace(string(out) + "\n" + err.Error())
+}
+
+// the file a batch is signed from
+func (app *application) batchFile(id int) string {
+	return filepath.Join(app.qslDir, fmt.Sprintf("lotw-%d.adi", id))
+}
+
+// tries a batch once.  QSOs deleted or sent some other way since the
+// batch was queued are left out.
+func (app *application) sendBatch(b *UploadBatch) error {
+	rows := []LogsRow{}
+	for _, id := range b.QSOs {
+		l, err := app.logsModel.getLogByID(id)
+		if errors.Is(err, errNoRecord) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if strings.EqualFold(l.Lotwsent, "YES") {
+			continue
+		}
+		rows = append(rows, *l)
+	}
+	b.Attempts++
+	b.Tried = time.Now().UTC().Truncate(time.Second)
+	if len(rows) == 0 {
+		b.Status, b.Result, b.Message = batchSent, tqslOK, "nothing left to upload"
+		return app.uploadModel.saveBatch(b)
+	}
+	fileName := app.batchFile(b.Id)
+	err := app.writeLoTWFile(fileName, rows)
+	if err != nil {
+		return err
+	}
+	result, output := app.runTQSL(fileName)
+	b.Result, b.Message = result, tqslMessage(result, output)
+	if !tqslSent(result) {
+		b.Status = batchQueued
+		return app.uploadModel.saveBatch(b)
+	}
+	for _, row := range rows {
+		err = app.logsModel.updateLOTWSent(row.Id)
+		if err != nil {
+			return err
+		}
+	}
+	b.Status = batchSent
+	return app.uploadModel.saveBatch(b)
+}
+
+// tries the queued batches again and sends the QSOs that are in none of
+// them as a new batch.  It returns the batches it tried.
+func (app *application) uploadLoTW() ([]UploadBatch, error) {
+	if app.tqsl == "" {
+		return nil, errNoTQSL
+	}
+	app.tqslLock.Lock()
+	defer app.tqslLock.Unlock()
+	batches, err := app.uploadModel.queuedBatches()
+	if err != nil {
+		return nil, err
+	}
+	queued := map[int]bool{}
+	for _, b := range batches {
+		for _, id := range b.QSOs {
+			queued[id] = true
+		}
+	}
+	rows, err := app.logsModel.getADIFData()
+	if err != nil {
+		return nil, err
+	}
+	ids := []int{}
+	for _, row := range rows {
+		if !queued[row.Id] {
+			ids = append(ids, row.Id)
+		}
+	}
+	if len(ids) > 0 {
+		b, err := app.uploadModel.newBatch(ids)
+		if err != nil {
+			return nil, err
+		}
+		batches = append(batches, *b)
+	}
+	for i := range batches {
+		err = app.sendBatch(&batches[i])
+		if err != nil {
+			return nil, err
+		}
+	}
+	return batches, nil
+}
+
+// tries one queued batch again
+func (app *application) retryLoTW(id int) (*UploadBatch, error) {
+	if app.tqsl == "" {
+		return nil, errNoTQSL
+	}
+	app.tqslLock.Lock()
+	defer app.tqslLock.Unlock()
+	b, err := app.uploadModel.getBatch(id)
+	if err != nil {
+		return nil, err
+	}
+	if b.Status != batchQueued {
+		return b, nil
+	}
+	return b, app.sendBatch(b)
+}
+
+// a line about each batch tried
+func uploadMessage(batches []UploadBatch) string {
+	if len(batches) == 0 {
+		return "There is nothing to upload to LoTW"
+	}
+	msgs := []string{}
+	for _, b := range batches {
+		if b.Status == batchSent {
+			msgs = append(msgs, fmt.Sprintf("Batch %d of %d QSOs: %s", b.Id, len(b.QSOs), b.Message))
+			continue
+		}
+		msgs = append(msgs, fmt.Sprintf("Batch %d of %d QSOs failed, queued to retry: %s",
+			b.Id, len(b.QSOs), b.Message))
+	}
+	return strings.Join(msgs, "; ")
+}
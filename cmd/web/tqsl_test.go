@@ -0,0 +1,208 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// a stand-in for tqsl that keeps its arguments, prints a line and exits
+// with the code
+func newTQSLStub(t *testing.T, dir string, code int) string {
+	stub := filepath.Join(dir, fmt.Sprintf("tqsl-%d", code))
+	script := fmt.Sprintf("#!/bin/sh\necho \"$@\" >> %s\necho \"Final Status: code %d\"\nexit %d\n",
+		filepath.Join(dir, "args"), code, code)
+	err := os.WriteFile(stub, []byte(script), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return stub
+}
+
+// a log with QSOs to upload and a directory for the tqsl stand-ins
+func newTestUploadApp(t *testing.T, calls ...string) (*application, string) {
+	old := writeControl
+	writeControl = &fileWrite{}
+	t.Cleanup(func() { writeControl = old })
+	app := newTestSQLiteApp(t)
+	app.qslDir = t.TempDir()
+	app.tqslStation = "Home"
+	qso := time.Date(2023, 4, 15, 14, 15, 0, 0, time.UTC)
+	for i, call := range calls {
+		l := LogsRow{Call: call, Band: "20m", Mode: "CW", Sent: "599", Rcvd: "599",
+			Time: qso.Add(time.Duration(i) * time.Minute)}
+		_, err := app.logsModel.importLog(&l, sourceImport)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return app, t.TempDir()
+}
+
+func isLoTWSent(t *testing.T, app *application, id int) bool {
+	l, err := app.logsModel.getLogByID(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return l.Lotwsent == "YES"
+}
+
+func TestUploadLoTW(t *testing.T) {
+	app, dir := newTestUploadApp(t, "DL1ABC", "G3AB")
+
+	app.tqsl = newTQSLStub(t, dir, 11)
+	batches, err := app.uploadLoTW()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(batches) != 1 || batches[0].Status != batchQueued || len(batches[0].QSOs) != 2 ||
+		batches[0].Result != 11 || batches[0].Message != "could not connect to LoTW: Final Status: code 11" {
+		t.Fatalf("want batch 1 queued after a connection error, got %v", batches)
+	}
+	if isLoTWSent(t, app, 1) || isLoTWSent(t, app, 2) {
+		t.Error("QSOs marked sent after a failed upload")
+	}
+	data, err := os.ReadFile(filepath.Join(app.qslDir, "lotw-1.adi"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "<call:6>DL1ABC") || !strings.Contains(string(data), "<call:4>G3AB") {
+		t.Errorf("want both QSOs in the batch file, got %q", data)
+	}
+
+	//a QSO logged after the failure goes in a batch of its own
+	l := LogsRow{Call: "K1ABC", Band: "40m", Mode: "CW", Time: time.Now().UTC()}
+	_, err = app.logsModel.importLog(&l, sourceImport)
+	if err != nil {
+		t.Fatal(err)
+	}
+	app.tqsl = newTQSLStub(t, dir, tqslOK)
+	batches, err = app.uploadLoTW()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(batches) != 2 {
+		t.Fatalf("want the queued batch and a new one, got %v", batches)
+	}
+	for i, want := range []struct{ id, qsos, attempts int }{{1, 2, 2}, {2, 1, 1}} {
+		b := batches[i]
+		if b.Id != want.id || len(b.QSOs) != want.qsos || b.Attempts != want.attempts || b.Status != batchSent {
+			t.Errorf("want batch %d of %d QSOs sent on try %d, got %v", want.id, want.qsos, want.attempts, b)
+		}
+	}
+	for id := 1; id <= 3; id++ {
+		if !isLoTWSent(t, app, id) {
+			t.Errorf("QSO %d is not marked sent", id)
+		}
+	}
+	args, err := os.ReadFile(filepath.Join(dir, "args"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(args)), "\n")
+	want := "-x -d -u -a compliant -l Home " + filepath.Join(app.qslDir, "lotw-1.adi")
+	if len(lines) != 3 || lines[0] != want || lines[1] != want {
+		t.Errorf("want batch 1 signed twice with %q, got %q", want, lines)
+	}
+
+	batches, err = app.uploadLoTW()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(batches) != 0 || uploadMessage(batches) != "There is nothing to upload to LoTW" {
+		t.Errorf("want nothing to upload, got %v", batches)
+	}
+	b, err := app.uploadModel.getBatch(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Status != batchSent || b.Attempts != 2 || b.Tried.Before(b.Created) {
+		t.Errorf("want batch 1 stored as sent on the second try, got %v", b)
+	}
+}
+
+func TestTQSLResults(t *testing.T) {
+	tests := []struct {
+		name    string
+		code    int
+		missing bool
+		status  string
+		message string
+	}{
+		{"uploaded", tqslOK, false, batchSent, "signed and uploaded"},
+		{"all dupes", tqslAllDupes, false, batchSent, "all the QSOs were duplicates"},
+		{"some dupes", tqslSomeDupes, false, batchSent, "the rest were uploaded"},
+		{"rejected", 2, false, batchQueued, "rejected by LoTW"},
+		{"unknown", 42, false, batchQueued, "TQSL exit code 42"},
+		{"no tqsl", 0, true, batchQueued, "TQSL did not run"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app, dir := newTestUploadApp(t, "DL1ABC")
+			app.tqsl = newTQSLStub(t, dir, tt.code)
+			if tt.missing {
+				app.tqsl = filepath.Join(dir, "no-tqsl")
+			}
+			batches, err := app.uploadLoTW()
+			if err != nil {
+				t.Fatal(err)
+			}
+			b := batches[0]
+			if b.Status != tt.status || !strings.Contains(b.Message, tt.message) {
+				t.Errorf("want %s with %q, got %s with %q", tt.status, tt.message, b.Status, b.Message)
+			}
+			if isLoTWSent(t, app, 1) != (tt.status == batchSent) {
+				t.Errorf("want the QSO sent only when the batch is")
+			}
+		})
+	}
+}
+
+func TestLoTWUploadHandlers(t *testing.T) {
+	app, dir := newTestUploadApp(t, "DL1ABC")
+	for _, path := range []string{"/lotw-upload", "/lotw-retry"} {
+		rr := httptest.NewRecorder()
+		app.routes().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+		if rr.Code != http.StatusMethodNotAllowed {
+			t.Errorf("%s: want 405 for a GET, got %d", path, rr.Code)
+		}
+	}
+	post := func(path string, form url.Values) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, path, strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		app.routes().ServeHTTP(rr, r)
+		return rr
+	}
+
+	rr := post("/lotw-upload", nil)
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), "there is no tqsl in config.yaml") {
+		t.Errorf("want the missing tqsl message, got %d", rr.Code)
+	}
+	app.tqsl = newTQSLStub(t, dir, 2)
+	rr = post("/lotw-upload", nil)
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), "Batch 1 of 1 QSOs failed, queued to retry") ||
+		!strings.Contains(rr.Body.String(), `name="batch" value="1"`) {
+		t.Errorf("want batch 1 queued with a retry button, got %d %q", rr.Code, rr.Body.String())
+	}
+	app.tqsl = newTQSLStub(t, dir, tqslOK)
+	rr = post("/lotw-retry", url.Values{"batch": {"1"}})
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), "Batch 1 of 1 QSOs: signed and uploaded") {
+		t.Errorf("want batch 1 uploaded, got %d %q", rr.Code, rr.Body.String())
+	}
+	if !isLoTWSent(t, app, 1) {
+		t.Error("the retried QSO is not marked sent")
+	}
+	if rr = post("/lotw-retry", url.Values{"batch": {"9"}}); rr.Code != http.StatusNotFound {
+		t.Errorf("want 404 for a batch that is not there, got %d", rr.Code)
+	}
+	if rr = post("/lotw-retry", url.Values{"batch": {"one"}}); rr.Code != http.StatusBadRequest {
+		t.Errorf("want 400 for a bad batch, got %d", rr.Code)
+	}
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+//<<================ Delete, trash and bulk edit handlers ================>>
+
+// moves the selected QSOs to the trash
+func (app *application) deleteLogs(w http.ResponseWriter, r *http.Request) {
+	td := initTemplateData()
+	f, ids, ok := app.postedIds(w, r)
+	if !ok {
+		return
+	}
+	if !f.valid() {
+		td.FormData = f
+		app.renderLog(w, r, td)
+		return
+	}
+	n, err := app.logsModel.deleteLogs(ids, sourceWeb)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	td.Message = fmt.Sprintf("Moved %d QSOs to the trash", n)
+	app.renderLog(w, r, td)
+}
+
+// changes the band, mode, contest name or LOTW sent flag of the selected
+// QSOs
+func (app *application) bulkEditLogs(w http.ResponseWriter, r *http.Request) {
+	td := initTemplateData()
+	f, ids, ok := app.postedIds(w, r)
+	if !ok {
+		return
+	}
+	be := f.bulkEdit()
+	if !f.valid() {
+		td.FormData = f
+		app.renderLog(w, r, td)
+		return
+	}
+	n, err := app.logsModel.bulkEditLogs(ids, be, sourceWeb)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	td.Message = fmt.Sprintf("Changed %d of %d QSOs", n, len(ids))
+	app.renderLog(w, r, td)
+}
+
+func (app *application) trash(w http.ResponseWriter, r *http.Request) {
+	app.renderTrash(w, r, initTemplateData())
+}
+
+// puts the selected QSOs back in the log
+func (app *application) restoreLogs(w http.ResponseWriter, r *http.Request) {
+	td := initTemplateData()
+	f, ids, ok := app.postedIds(w, r)
+	if !ok {
+		return
+	}
+	if !f.valid() {
+		td.FormData = f
+		app.renderTrash(w, r, td)
+		return
+	}
+	n, err := app.logsModel.restoreLogs(ids, sourceWeb)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	td.Message = fmt.Sprintf("Restored %d QSOs to the log", n)
+	app.renderTrash(w, r, td)
+}
+
+// removes the selected QSOs for good, or all of them when all=yes is posted
+func (app *application) purgeLogs(w http.ResponseWriter, r *http.Request) {
+	td := initTemplateData()
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		app.clientError(w, http.StatusMethodNotAllowed)
+		return
+	}
+	err := r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	var ids []int
+	if r.PostForm.Get("all") == "yes" {
+		trash, err := app.logsModel.getTrash()
+		if err != nil {
+			app.serverError(w, err)
+			return
+		}
+		for _, t := range trash {
+			ids = append(ids, t.Id)
+		}
+	} else {
+		f, posted, ok := app.postedIds(w, r)
+		if !ok {
+			return
+		}
+		if !f.valid() {
+			td.FormData = f
+			app.renderTrash(w, r, td)
+			return
+		}
+		ids = posted
+	}
+	n, err := app.logsModel.purgeLogs(ids)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	td.Message = fmt.Sprintf("Permanently deleted %d QSOs", n)
+	app.renderTrash(w, r, td)
+}
+
+func (app *application) renderTrash(w http.ResponseWriter, r *http.Request, td *templateData) {
+	var err error
+	td.Logger = true
+	td.Trash, err = app.logsModel.getTrash()
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	app.render(w, r, "trash.page.html", td)
+}
+
+// parses the form and the ids of the selected QSOs.  No selection is a
+// form error, a bad id is a client error and the response has been written.
+func (app *application) postedIds(w http.ResponseWriter, r *http.Request) (*formData, []int, bool) {
+	err := r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return nil, nil, false
+	}
+	f := newForm(r.PostForm)
+	ids := []int{}
+	for _, v := range r.PostForm["id"] {
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			app.clientError(w, http.StatusBadRequest)
+			return nil, nil, false
+		}
+		ids = append(ids, id)
+	}
+	if len(ids) == 0 {
+		f.Errors.add("id", "select one or more QSOs first")
+	}
+	return f, ids, true
+}
+
+// reads the bulk edit fields, at least one of them has to be filled in
+func (f *formData) bulkEdit() *bulkEdit {
+	be := &bulkEdit{
+		Band:        strings.ToLower(strings.TrimSpace(f.Get("bulkband"))),
+		Mode:        strings.ToUpper(strings.TrimSpace(f.Get("bulkmode"))),
+		ContestName: strings.TrimSpace(f.Get("bulkcontest")),
+		LotwSent:    f.Get("bulklotw"),
+	}
+	f.maxLength("bulkmode", 20)
+	f.maxLength("bulkcontest", 50)
+	if be.Band != "" && !isBand(be.Band) {
+		f.Errors.add("bulkband", "this is not a band, e.g. 20m")
+	}
+	switch be.LotwSent {
+	case "", lotwSent, lotwUnsent:
+	default:
+		f.Errors.add("bulklotw", "must be sent, unsent or blank")
+	}
+	if *be == (bulkEdit{}) {
+		f.Errors.add("bulkband", "fill in the band, mode, contest or LOTW sent to change")
+	}
+	return be
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestBulkEditLogs(t *testing.T) {
+	app := newTestApp()
+	tests := []struct {
+		name     string
+		form     url.Values
+		wantCode int
+		wantBody string
+	}{
+		{"bad id", url.Values{"id": {"abc"}, "bulkband": {"20m"}}, 400, ""},
+		{"no selection", url.Values{"bulkband": {"20m"}}, 200, "select one or more QSOs first"},
+		{"nothing to change", url.Values{"id": {"1", "2"}}, 200,
+			"fill in the band, mode, contest or LOTW sent to change"},
+		{"bad band", url.Values{"id": {"1"}, "bulkband": {"21m"}}, 200, "this is not a band"},
+		{"wwv", url.Values{"id": {"1"}, "bulkband": {"WWV"}}, 200, "this is not a band"},
+		{"60m", url.Values{"id": {"1"}, "bulkband": {"60m"}}, 200, "Changed 1 of 1 QSOs"},
+		{"2m", url.Values{"id": {"1"}, "bulkband": {"2m"}}, 200, "Changed 1 of 1 QSOs"},
+		{"bad lotw", url.Values{"id": {"1"}, "bulklotw": {"maybe"}}, 200,
+			"must be sent, unsent or blank"},
+		{"good", url.Values{"id": {"1", "2"}, "bulkband": {"40M"}, "bulklotw": {"sent"}}, 200,
+			"Changed 2 of 2 QSOs"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			r, err := http.NewRequest(http.MethodPost, "/bulk-edit",
+				strings.NewReader(tt.form.Encode()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			app.bulkEditLogs(rr, r)
+			if rr.Code != tt.wantCode {
+				t.Errorf("expected %d got %d", tt.wantCode, rr.Code)
+			}
+			if !strings.Contains(rr.Body.String(), tt.wantBody) {
+				t.Errorf("expected %q in the body, did not get it", tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestTrashPages(t *testing.T) {
+	app := newTestSQLiteApp(t)
+	for k, v := range map[string]string{"contest": "No", "band": "15m", "mode": "USB"} {
+		err := app.otherModel.updateDefault(k, v)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, err := app.logsModel.insertLog(&LogsRow{Call: "VK2XYZ", Mode: "SSB", Band: "15m",
+		Sent: "59", Rcvd: "57"}, sourceWeb)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		handler  http.HandlerFunc
+		path     string
+		form     url.Values
+		wantBody string
+	}{
+		{"delete", app.deleteLogs, "/delete-logs", url.Values{"id": {"1"}}, "Moved 1 QSOs to the trash"},
+		{"trash", app.trash, "/trash", nil, "VK2XYZ"},
+		{"restore", app.restoreLogs, "/restore-logs", url.Values{"id": {"1"}}, "Restored 1 QSOs to the log"},
+		{"empty trash", app.purgeLogs, "/purge-logs", url.Values{"all": {"yes"}}, "Permanently deleted 0 QSOs"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			r, err := http.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.form.Encode()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			tt.handler(rr, r)
+			if rr.Code != http.StatusOK {
+				t.Errorf("expected %d got %d", http.StatusOK, rr.Code)
+			}
+			if !strings.Contains(rr.Body.String(), tt.wantBody) {
+				t.Errorf("expected %q in the body, did not get it", tt.wantBody)
+			}
+		})
+	}
+	l, err := app.logsModel.getLogByID(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.Call != "VK2XYZ" {
+		t.Errorf("want VK2XYZ back in the log, got %s", l.Call)
+	}
+
+	//a link cannot empty the trash
+	_, err = app.logsModel.deleteLogs([]int{1}, sourceWeb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	app.routes().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/purge-logs?all=yes", nil))
+	if rr.Code != http.StatusMethodNotAllowed {
+		t.Errorf("want 405 for a GET, got %d", rr.Code)
+	}
+	rr = httptest.NewRecorder()
+	r, err := http.NewRequest(http.MethodPost, "/purge-logs?all=yes", strings.NewReader(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	app.purgeLogs(rr, r)
+	if !strings.Contains(rr.Body.String(), "select one or more QSOs first") {
+		t.Errorf("want all=yes in the query ignored, got %d", rr.Code)
+	}
+	trash, err := app.logsModel.getTrash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trash) != 1 {
+		t.Errorf("want VK2XYZ still in the trash, got %v", trash)
+	}
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+//Deleting a QSO moves it to the trashlogs table with its id so it can be
+//put back.  Only emptying the trash removes it for good.  Deletes,
+//restores and bulk edits go through audited like every other change.
+
+// TrashRow is a deleted QSO
+type TrashRow struct {
+	Deleted time.Time
+	LogsRow
+}
+
+// bulkEdit is the change a bulk edit makes to each selected QSO, blank
+// fields are left as they are
+type bulkEdit struct {
+	Band        string
+	Mode        string
+	ContestName string //also marks the QSOs as contest QSOs
+	LotwSent    string //lotwSent, lotwUnsent or blank
+}
+
+const (
+	lotwSent   = "sent"
+	lotwUnsent = "unsent"
+)
+
+// moves the QSOs to the trash and returns how many were moved, QSOs that
+// are not in the log are skipped
+func (m *logsModel) deleteLogs(ids []int, source string) (int, error) {
+	n := 0
+	for _, id := range ids {
+		id := id
+		_, err := m.audited(id, auditDelete, source, func(tx *sql.Tx) (int, error) {
+			return id, trashLog(tx, id)
+		})
+		if err != nil {
+			if errors.Is(err, errNoRecord) {
+				continue
+			}
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+func trashLog(tx dbtx, id int) error {
+	l, err := getFullLog(tx, id)
+	if err != nil {
+		return err
+	}
+	qso, err := auditJSON(l)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`INSERT INTO trashlogs (id, deleted, qso) VALUES (?, ?, ?)`,
+		id, time.Now().UTC(), qso)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`DELETE FROM stationlogs WHERE id = ?`, id)
+	return err
+}
+
+// puts the QSOs back in the log with their old ids and returns how many
+// were restored
+func (m *logsModel) restoreLogs(ids []int, source string) (int, error) {
+	n := 0
+	for _, id := range ids {
+		var qso string
+		err := m.DB.QueryRow(`SELECT qso FROM trashlogs WHERE id = ?`, id).Scan(&qso)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				continue
+			}
+			return n, err
+		}
+		l, err := auditRow(qso)
+		if err != nil {
+			return n, err
+		}
+		_, err = m.audited(id, auditInsert, source, func(tx *sql.Tx) (int, error) {
+			return l.Id, untrashLog(tx, l)
+		})
+		if err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+func untrashLog(tx dbtx, l *LogsRow) error {
+	err := reinsertLog(tx, l)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`DELETE FROM trashlogs WHERE id = ?`, l.Id)
+	return err
+}
+
+// removes the QSOs from the trash for good
+func (m *logsModel) purgeLogs(ids []int) (int, error) {
+	n := 0
+	for _, id := range ids {
+		result, err := m.DB.Exec(`DELETE FROM trashlogs WHERE id = ?`, id)
+		if err != nil {
+			return n, err
+		}
+		c, err := result.RowsAffected()
+		if err != nil {
+			return n, err
+		}
+		n += int(c)
+	}
+	return n, nil
+}
+
+// returns the deleted QSOs, the last deleted first
+func (m *logsModel) getTrash() ([]TrashRow, error) {
+	rows, err := m.DB.Query(`SELECT deleted, qso FROM trashlogs ORDER BY deleted DESC, id DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tr := []TrashRow{}
+	for rows.Next() {
+		t := TrashRow{}
+		var qso string
+		err = rows.Scan(&t.Deleted, &qso)
+		if err != nil {
+			return nil, err
+		}
+		l, err := auditRow(qso)
+		if err != nil {
+			return nil, err
+		}
+		t.LogsRow = *l
+		tr = append(tr, t)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return tr, nil
+}
+
+// applies the edit to the QSOs and returns how many were changed
+func (m *logsModel) bulkEditLogs(ids []int, be *bulkEdit, source string) (int, error) {
+	n := 0
+	for _, id := range ids {
+		id := id
+		changed := false
+		_, err := m.audited(id, auditUpdate, source, func(tx *sql.Tx) (int, error) {
+			l, err := getFullLog(tx, id)
+			if err != nil {
+				return 0, err
+			}
+			changed = be.apply(l)
+			if !changed {
+				return id, nil
+			}
+			return id, restoreLog(tx, l)
+		})
+		if err != nil {
+			if errors.Is(err, errNoRecord) {
+				continue
+			}
+			return n, err
+		}
+		if changed {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// changes l and reports whether anything changed.  A frequency that is not
+// on the new band is cleared.
+func (be *bulkEdit) apply(l *LogsRow) bool {
+	before := *l
+	if be.Band != "" && be.Band != l.Band {
+		l.Band = be.Band
+		if mhzToBand(l.Freq) != be.Band {
+			l.Freq, l.FreqRx = "", ""
+		}
+	}
+	if be.Mode != "" {
+		l.Mode = be.Mode
+	}
+	if be.ContestName != "" {
+		l.Contest = "Yes"
+		l.ContestName = be.ContestName
+	}
+	switch be.LotwSent {
+	case lotwSent:
+		l.Lotwsent = "YES"
+	case lotwUnsent:
+		l.Lotwsent = ""
+	}
+	return *l != before
+}
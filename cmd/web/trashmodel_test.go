@@ -0,0 +1,167 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSQLiteTrash(t *testing.T) {
+	app := newTestSQLiteApp(t)
+
+	calls := []string{"K1AR", "K1AR", "W1AW"}
+	for _, c := range calls {
+		_, err := app.logsModel.insertLog(&LogsRow{Call: c, Mode: "FT8", Sent: "-10",
+			Rcvd: "-12", Band: "20m", Freq: "14.074000"}, sourceWSJTX)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	//the WSJT-X double log and a QSO that is not there
+	n, err := app.logsModel.deleteLogs([]int{2, 10}, sourceWeb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Errorf("want 1 deleted, got %d", n)
+	}
+	_, err = app.logsModel.getLogByID(2)
+	if !errors.Is(err, errNoRecord) {
+		t.Errorf("want errNoRecord for a deleted QSO, got %v", err)
+	}
+	trash, err := app.logsModel.getTrash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trash) != 1 || trash[0].Id != 2 || trash[0].Call != "K1AR" {
+		t.Fatalf("want QSO 2 with K1AR in the trash, got %v", trash)
+	}
+	logs, err := app.logsModel.getLatestLogs(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(logs) != 2 {
+		t.Errorf("want 2 QSOs in the log, got %d", len(logs))
+	}
+
+	n, err = app.logsModel.restoreLogs([]int{2}, sourceWeb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Errorf("want 1 restored, got %d", n)
+	}
+	got, err := app.logsModel.getLogByID(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Call != "K1AR" || got.Freq != "14.074000" {
+		t.Errorf("want K1AR on 14.074000 back, got %s %s", got.Call, got.Freq)
+	}
+	h, err := app.logsModel.getHistory(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(h) != 3 || h[1].Action != auditDelete || h[0].Action != auditInsert {
+		t.Errorf("want insert, delete and restore in the history, got %v", h)
+	}
+
+	n, err = app.logsModel.deleteLogs([]int{1, 2}, sourceWeb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err = app.logsModel.purgeLogs([]int{1, 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("want 2 purged, got %d", n)
+	}
+	trash, err = app.logsModel.getTrash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trash) != 0 {
+		t.Errorf("want an empty trash, got %d", len(trash))
+	}
+}
+
+func TestSQLiteBulkEdit(t *testing.T) {
+	app := newTestSQLiteApp(t)
+	db := app.logsModel.(*logsModel).DB
+	for _, l := range []LogsRow{
+		{Call: "DL1ABC", Mode: "CW", Band: "20m", Freq: "14.025000"},
+		{Call: "G4XYZ", Mode: "CW", Band: "20m", Freq: "14.030000", Lotwsent: "YES"},
+	} {
+		l := l
+		_, err := app.logsModel.insertLog(&l, sourceWeb)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		be      bulkEdit
+		want    int
+		check   func(l *LogsRow) bool
+		checkOf string
+	}{
+		{"band", bulkEdit{Band: "40m"}, 2,
+			func(l *LogsRow) bool { return l.Band == "40m" && l.Freq == "" },
+			"40m with no frequency"},
+		{"contest", bulkEdit{ContestName: "CQWW", Mode: "CW"}, 2,
+			func(l *LogsRow) bool { return l.Contest == "Yes" && l.ContestName == "CQWW" },
+			"a CQWW contest QSO"},
+		{"lotw unsent", bulkEdit{LotwSent: lotwUnsent}, 1,
+			func(l *LogsRow) bool { return l.Lotwsent == "" },
+			"not sent to LOTW"},
+		{"nothing to change", bulkEdit{Band: "40m"}, 0,
+			func(l *LogsRow) bool { return l.Band == "40m" }, "40m"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n, err := app.logsModel.bulkEditLogs([]int{1, 2, 5}, &tt.be, sourceWeb)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n != tt.want {
+				t.Errorf("want %d changed, got %d", tt.want, n)
+			}
+			for _, id := range []int{1, 2} {
+				l, err := getFullLog(db, id)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !tt.check(l) {
+					t.Errorf("want QSO %d to be %s, got %+v", id, tt.checkOf, l)
+				}
+			}
+		})
+	}
+}
+
+func TestBulkEditApply(t *testing.T) {
+	tests := []struct {
+		name     string
+		band     string
+		freq     string
+		wantFreq string
+	}{
+		{"on 60m", "60m", "5.357000", "5.357000"},
+		{"on 2m", "2m", "146.520000", "146.520000"},
+		{"off 2m", "2m", "14.025000", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := LogsRow{Band: "20m", Freq: tt.freq, FreqRx: tt.freq}
+			be := bulkEdit{Band: tt.band}
+			if !be.apply(&l) {
+				t.Fatal("want the QSO changed")
+			}
+			if l.Band != tt.band || l.Freq != tt.wantFreq || l.FreqRx != tt.wantFreq {
+				t.Errorf("want %s at %q, got %s at %q/%q", tt.band, tt.wantFreq, l.Band, l.Freq, l.FreqRx)
+			}
+		})
+	}
+}
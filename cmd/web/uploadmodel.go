@@ -0,0 +1,158 @@
+package main
+
+import (
+	"database/sql"
+	"time"
+)
+
+//An upload batch is the QSOs that went to TQSL together to be signed and
+//uploaded to LoTW.  The batch keeps the QSO ids, when it was last tried,
+//what TQSL said and whether it is sent or still queued to be tried again.
+
+const (
+	batchQueued = "queued"
+	batchSent   = "sent"
+)
+
+type uploadType interface {
+	newBatch([]int) (*UploadBatch, error)
+	saveBatch(*UploadBatch) error
+	getBatch(int) (*UploadBatch, error)
+	getBatches(int) ([]UploadBatch, error)
+	queuedBatches() ([]UploadBatch, error)
+}
+
+// UploadBatch is a row of lotwbatches with the ids of its QSOs
+type UploadBatch struct {
+	Id       int
+	Created  time.Time
+	Tried    time.Time //time of the last try, the upload date once sent
+	Status   string    //queued or sent
+	Attempts int
+	Result   int    //tqsl exit code of the last try
+	Message  string //what the last try came to
+	QSOs     []int  //stationlogs ids
+}
+
+type uploadModel struct {
+	DB *sql.DB
+}
+
+// queues a new batch of the QSOs
+func (m *uploadModel) newBatch(ids []int) (*UploadBatch, error) {
+	now := time.Now().UTC().Truncate(time.Second)
+	b := &UploadBatch{Created: now, Tried: now, Status: batchQueued, QSOs: ids}
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	result, err := tx.Exec(`INSERT INTO lotwbatches (created, tried, status,
+	attempts, result, message) VALUES (?, ?, ?, 0, 0, '')`, b.Created, b.Tried, b.Status)
+	if err != nil {
+		return nil, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	b.Id = int(id)
+	for _, logid := range ids {
+		_, err = tx.Exec(`INSERT INTO lotwbatchqsos (batch, logid) VALUES (?, ?)`, b.Id, logid)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return b, tx.Commit()
+}
+
+// records the outcome of a try
+func (m *uploadModel) saveBatch(b *UploadBatch) error {
+	stmt := `UPDATE lotwbatches SET tried = ?, status = ?, attempts = ?,
+	result = ?, message = ? WHERE id = ?`
+
+	_, err := m.DB.Exec(stmt, b.Tried, b.Status, b.Attempts, b.Result, b.Message, b.Id)
+	return err
+}
+
+func (m *uploadModel) getBatch(id int) (*UploadBatch, error) {
+	batches, err := m.selectBatches(`WHERE id = ?`, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(batches) == 0 {
+		return nil, errNoRecord
+	}
+	return &batches[0], nil
+}
+
+// the latest batches, the queued ones first
+func (m *uploadModel) getBatches(limit int) ([]UploadBatch, error) {
+	queued, err := m.queuedBatches()
+	if err != nil {
+		return nil, err
+	}
+	sent, err := m.selectBatches(`WHERE status = ? ORDER BY id DESC LIMIT ?`, batchSent, limit)
+	if err != nil {
+		return nil, err
+	}
+	return append(queued, sent...), nil
+}
+
+// the batches waiting to be tried again, the oldest first
+func (m *uploadModel) queuedBatches() ([]UploadBatch, error) {
+	return m.selectBatches(`WHERE status = ? ORDER BY id`, batchQueued)
+}
+
+func (m *uploadModel) selectBatches(where string, args ...interface{}) ([]UploadBatch, error) {
+	stmt := `SELECT id, created, tried, status, attempts, result, message
+	FROM lotwbatches ` + where
+
+	rows, err := m.DB.Query(stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	batches := []UploadBatch{}
+	for rows.Next() {
+		b := UploadBatch{}
+		err = rows.Scan(&b.Id, &b.Created, &b.Tried, &b.Status, &b.Attempts,
+			&b.Result, &b.Message)
+		if err != nil {
+			return nil, err
+		}
+		batches = append(batches, b)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	for i := range batches {
+		batches[i].QSOs, err = m.batchQSOs(batches[i].Id)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return batches, nil
+}
+
+func (m *uploadModel) batchQSOs(id int) ([]int, error) {
+	rows, err := m.DB.Query(`SELECT logid FROM lotwbatchqsos WHERE batch = ? ORDER BY id`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []int{}
+	for rows.Next() {
+		var logid int
+		if err = rows.Scan(&logid); err != nil {
+			return nil, err
+		}
+		ids = append(ids, logid)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
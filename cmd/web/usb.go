@@ -597,6 +597,8 @@ func (app *application) readYaesuInfo() (*yaesuInfo, error) {
 	mm := strings.Split(m, "-")
 	app.otherModel.updateDefault("band", b)
 	app.otherModel.updateDefault("mode", mm[0])
+	app.otherModel.updateDefault("freq", hzToMHz(f))
+	app.otherModel.updateDefault("freqrx", "")
 	y.Band = b
 	y.Mode = m
 	return &y, nil
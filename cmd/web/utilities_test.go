@@ -21,7 +21,9 @@ func newTestApp() *application {
 		logsModel:     &mockLogsModel{lastLogsErr: nil, defaultErr: nil},
 		qrzModel:      &mockQRZModel{},
 		otherModel:    &mockOtherModel{},
-		putCancel:     func(context.Context, context.CancelFunc, bool) {},
+		profileModel: &mockProfileModel{profiles: []ProfileRow{{Id: 1, Name: "Home",
+			Call: "N2VY", Location: "NJ", Power: "100"}}},
+		putCancel: func(context.Context, context.CancelFunc, bool) {},
 		getCancel: func() (context.Context, context.CancelFunc, bool) {
 			ctx, cancel := context.WithCancel(context.Background())
 			return ctx, cancel, false
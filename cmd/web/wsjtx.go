@@ -164,6 +164,12 @@ func (app *application) logQSO(message interface{}) error {
 		return err
 	}
 
+	//WSJT-X reports the dial frequency plus the audio offset
+	freq := ""
+	if m.TxFrequency > 0 {
+		freq = hzToMHz(int(m.TxFrequency))
+	}
+
 	call := m.DxCall
 
 	c, err := app.qrzModel.getQRZ(call)
@@ -187,15 +193,22 @@ func (app *application) logQSO(message interface{}) error {
 				Band:     band,
 				Mode:     mode,
 				Name:     fmt.Sprintf("%s %s", c.Fname, c.Lname),
-				Country:  c.Country,
+				Country:  app.qsoCountry(m.DxCall, c.Country),
 				Comment:  m.DxGrid,
+				Grid:     m.DxGrid,
 				ExchSent: m.ExchangeSent,
 				ExchRcvd: m.ExchangeReceived,
+				Freq:     freq,
+			}
+			err = app.fillLocation(&lr)
+			if err != nil {
+				return err
 			}
-			_, err = app.logsModel.insertLog(&lr)
+			id, err := app.logsModel.insertLog(&lr, sourceWSJTX)
 			if err != nil {
 				return err
 			}
+			app.logged(id)
 			return nil
 			//This is the case that this is the first contact
 		}
@@ -218,15 +231,22 @@ func (app *application) logQSO(message interface{}) error {
 		Band:     band,
 		Mode:     mode,
 		Name:     fmt.Sprintf("%s %s", c.Fname, c.Lname),
-		Country:  c.Country,
+		Country:  app.qsoCountry(m.DxCall, c.Country),
 		Comment:  m.DxGrid,
+		Grid:     m.DxGrid,
 		ExchSent: m.ExchangeSent,
 		ExchRcvd: m.ExchangeReceived,
+		Freq:     freq,
+	}
+	err = app.fillLocation(&lr)
+	if err != nil {
+		return err
 	}
-	_, err = app.logsModel.insertLog(&lr)
+	id, err := app.logsModel.insertLog(&lr, sourceWSJTX)
 	if err != nil {
 		return err
 	}
+	app.logged(id)
 	return nil
 }
 
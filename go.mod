@@ -6,6 +6,7 @@ require (
 	github.com/go-sql-driver/mysql v1.6.0
 	github.com/go-yaml/yaml v2.1.0+incompatible
 	github.com/k0swe/wsjtx-go/v4 v4.0.1
-	go.bug.st/serial v1.6.2 // indirect
+	github.com/mattn/go-sqlite3 v1.14.16
+	go.bug.st/serial v1.6.2
 	gobot.io/x/gobot v1.15.0
 )
@@ -0,0 +1,217 @@
+// Package cty resolves call signs to DXCC entities offline from the
+// country files of AD1C (cty.dat and cty.csv, https://www.country-files.com)
+// or of Club Log (cty.xml, https://clublog.org/cty.php).
+//
+// A call is looked up as a whole first, for the calls the files list one
+// by one (=K1ABC in cty.dat, the exceptions of cty.xml), and then by its
+// longest prefix.  Portable designators are handled the usual way:
+// EA8/DL1ABC and DL1ABC/EA8 are in the Canary Islands, K1ABC/4 is matched
+// as K4, /P, /M, /QRP and the like are dropped and /MM and /AM are in no
+// entity at all.
+//
+// The package also has the DXCC list by ADIF entity number, with the
+// deleted entities.
+package cty
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Entity is a DXCC entity, or a part of one with its own zones
+type Entity struct {
+	Name      string
+	DXCC      int    //ADIF entity number, by the primary prefix for cty.dat
+	Prefix    string //primary prefix
+	Continent string
+	CQZone    int
+	ITUZone   int     //0 from cty.xml which does not have them
+	Lat       float64 //degrees north
+	Lon       float64 //degrees east
+}
+
+// Database is a loaded country file
+type Database struct {
+	calls    map[string][]record //whole calls
+	prefixes map[string][]record
+	invalid  map[string][]period //operations that do not count (cty.xml)
+	zones    map[string][]zone   //CQ zone exceptions (cty.xml)
+	longest  int                 //length of the longest prefix
+}
+
+// record is an entity, valid for a period when the file says so
+type record struct {
+	Entity
+	period
+}
+
+// period is from start to end, a zero time is open
+type period struct {
+	start time.Time
+	end   time.Time
+}
+
+type zone struct {
+	period
+	cq int
+}
+
+var ErrFormat = errors.New("cty: unknown file type, expected .dat, .csv or .xml")
+
+func newDatabase() *Database {
+	return &Database{
+		calls:    map[string][]record{},
+		prefixes: map[string][]record{},
+		invalid:  map[string][]period{},
+		zones:    map[string][]zone{},
+	}
+}
+
+// Load reads a cty.dat, cty.csv or cty.xml file, told apart by the
+// extension
+func Load(fileName string) (*Database, error) {
+	parse := map[string]func(io.Reader) (*Database, error){
+		".dat": ParseDat,
+		".csv": ParseCSV,
+		".xml": ParseXML,
+	}[strings.ToLower(filepath.Ext(fileName))]
+	if parse == nil {
+		return nil, ErrFormat
+	}
+	f, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parse(f)
+}
+
+// Len is the number of prefixes and whole calls loaded
+func (d *Database) Len() int {
+	return len(d.prefixes) + len(d.calls)
+}
+
+func (p period) covers(t time.Time) bool {
+	return (p.start.IsZero() || !t.Before(p.start)) && (p.end.IsZero() || !t.After(p.end))
+}
+
+func (d *Database) addPrefix(prefix string, r record) {
+	d.prefixes[prefix] = append(d.prefixes[prefix], r)
+	if len(prefix) > d.longest {
+		d.longest = len(prefix)
+	}
+}
+
+func (d *Database) addCall(call string, r record) {
+	d.calls[call] = append(d.calls[call], r)
+}
+
+func find(records []record, t time.Time) (Entity, bool) {
+	for _, r := range records {
+		if r.covers(t) {
+			return r.Entity, true
+		}
+	}
+	return Entity{}, false
+}
+
+// Lookup is the entity of a call worked now
+func (d *Database) Lookup(call string) (Entity, bool) {
+	return d.LookupAt(call, time.Now().UTC())
+}
+
+// LookupAt is the entity of a call worked at t, false when the call is
+// in no entity (maritime mobile, not a call, an operation that does not
+// count)
+func (d *Database) LookupAt(call string, t time.Time) (Entity, bool) {
+	call = strings.ToUpper(strings.TrimSpace(call))
+	if call == "" {
+		return Entity{}, false
+	}
+	for _, p := range d.invalid[call] {
+		if p.covers(t) {
+			return Entity{}, false
+		}
+	}
+	e, ok := find(d.calls[call], t)
+	if !ok {
+		base, prefix, fine := splitCall(call)
+		if !fine {
+			return Entity{}, false
+		}
+		e, ok = find(d.calls[base], t)
+		if !ok {
+			e, ok = d.longestPrefix(prefix, t)
+		}
+	}
+	if !ok {
+		return Entity{}, false
+	}
+	for _, z := range d.zones[call] {
+		if z.covers(t) {
+			e.CQZone = z.cq
+		}
+	}
+	return e, true
+}
+
+func (d *Database) longestPrefix(prefix string, t time.Time) (Entity, bool) {
+	n := len(prefix)
+	if n > d.longest {
+		n = d.longest
+	}
+	for ; n > 0; n-- {
+		if e, ok := find(d.prefixes[prefix[:n]], t); ok {
+			return e, true
+		}
+	}
+	return Entity{}, false
+}
+
+// designators that do not change the entity
+var dropSuffix = map[string]bool{"P": true, "M": true, "QRP": true, "QRPP": true,
+	"A": true, "B": true, "LH": true, "J": true, "R": true}
+
+// designators with no entity
+var noEntity = map[string]bool{"MM": true, "AM": true}
+
+// splits a call into the call without the designators that do not change
+// the entity and the string its prefix is matched in.  False when the
+// call is in no entity.
+func splitCall(call string) (string, string, bool) {
+	parts := []string{}
+	for _, p := range strings.Split(call, "/") {
+		switch {
+		case p == "":
+		case noEntity[p]:
+			return "", "", false
+		case dropSuffix[p] && len(parts) > 0:
+		default:
+			parts = append(parts, p)
+		}
+	}
+	switch len(parts) {
+	case 0:
+		return "", "", false
+	case 1:
+		return parts[0], parts[0], true
+	}
+	a, b := parts[0], parts[1]
+	base := strings.Join(parts, "/")
+	//K1ABC/4 is in the 4 call area, matched as K4
+	if len(b) == 1 && b[0] >= '0' && b[0] <= '9' {
+		if i := strings.LastIndexAny(a, "0123456789"); i > 0 {
+			return base, a[:i] + b, true
+		}
+		return base, a, true
+	}
+	//the shorter part is the prefix, EA8/DL1ABC and DL1ABC/EA8
+	if len(b) < len(a) {
+		return base, b, true
+	}
+	return base, a, true
+}
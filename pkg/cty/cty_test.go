@@ -0,0 +1,241 @@
+package cty
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSplitCall(t *testing.T) {
+	tests := []struct {
+		call   string
+		base   string
+		prefix string
+		ok     bool
+	}{
+		{"DL1ABC", "DL1ABC", "DL1ABC", true},
+		{"EA8/DL1ABC", "EA8/DL1ABC", "EA8", true},
+		{"DL1ABC/EA8", "DL1ABC/EA8", "EA8", true},
+		{"K1ABC/4", "K1ABC/4", "K4", true},
+		{"W1AW/P", "W1AW", "W1AW", true},
+		{"EA8/DL1ABC/P", "EA8/DL1ABC", "EA8", true},
+		{"DL1ABC/QRP", "DL1ABC", "DL1ABC", true},
+		{"DL1ABC/MM", "", "", false},
+		{"K1ABC/AM", "", "", false},
+		{"/", "", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.call, func(t *testing.T) {
+			base, prefix, ok := splitCall(tt.call)
+			if base != tt.base || prefix != tt.prefix || ok != tt.ok {
+				t.Errorf("want %q %q %v, got %q %q %v", tt.base, tt.prefix, tt.ok, base, prefix, ok)
+			}
+		})
+	}
+}
+
+func TestLookupDat(t *testing.T) {
+	d, err := Load("testdata/cty.dat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		call string
+		ok   bool
+		name string
+		cq   int
+		itu  int
+		cont string
+	}{
+		{"DL1ABC", true, "Fed. Rep. of Germany", 14, 28, "EU"},
+		{"dl1abc", true, "Fed. Rep. of Germany", 14, 28, "EU"},
+		{"EA8/DL1ABC", true, "Canary Islands", 33, 36, "AF"},
+		{"DL1ABC/EA8", true, "Canary Islands", 33, 36, "AF"},
+		{"EA8ABC", true, "Canary Islands", 33, 36, "AF"},
+		{"EA1ABC", true, "Spain", 14, 37, "EU"},
+		{"DL1ABC/P", true, "Fed. Rep. of Germany", 14, 28, "EU"},
+		{"DL1ABC/MM", false, "", 0, 0, ""},
+		{"W6ABC", true, "United States", 3, 6, "NA"},
+		{"K1ABC/6", true, "United States", 3, 6, "NA"},
+		{"K6ABC/1", true, "United States", 5, 8, "NA"},
+		{"K1LZ", true, "United States", 4, 7, "SA"},
+		{"K1LZ/P", true, "United States", 4, 7, "SA"},
+		{"VE2IM", true, "Canada", 2, 4, "NA"},
+		{"VE3ABC", true, "Canada", 5, 9, "NA"},
+		{"KL7ABC", true, "Alaska", 1, 1, "NA"},
+		{"KH6ABC", true, "Hawaii", 31, 61, "OC"},
+		{"KG4AB", true, "Guantanamo Bay", 8, 11, "NA"},
+		{"TA1ABC", true, "Turkey", 20, 39, "AS"},
+		{"IT9ABC", true, "Italy", 15, 28, "EU"},
+		{"3D2CR", true, "Conway Reef", 32, 56, "OC"},
+		{"3D2AB", true, "Fiji", 32, 56, "OC"},
+		{"Q1ABC", false, "", 0, 0, ""},
+		{"", false, "", 0, 0, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.call, func(t *testing.T) {
+			e, ok := d.Lookup(tt.call)
+			if ok != tt.ok {
+				t.Fatalf("want %v, got %v", tt.ok, ok)
+			}
+			if e.Name != tt.name || e.CQZone != tt.cq || e.ITUZone != tt.itu || e.Continent != tt.cont {
+				t.Errorf("want %s %d %d %s, got %s %d %d %s", tt.name, tt.cq, tt.itu, tt.cont,
+					e.Name, e.CQZone, e.ITUZone, e.Continent)
+			}
+		})
+	}
+	e, _ := d.Lookup("DL1ABC")
+	if e.Lat != 51 || e.Lon != 10 || e.DXCC != 230 || e.Prefix != "DL" {
+		t.Errorf("want 51 10 230 DL, got %v %v %d %s", e.Lat, e.Lon, e.DXCC, e.Prefix)
+	}
+}
+
+func TestDatNumbers(t *testing.T) {
+	dat, err := Load("testdata/cty.dat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	csv, err := Load("testdata/cty.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, call := range []string{"DL1ABC", "EA8/DL1ABC", "TA1ABC", "3D2CR", "KG4AA", "KH6AA", "VE2IM"} {
+		d, _ := dat.Lookup(call)
+		c, _ := csv.Lookup(call)
+		if d.DXCC == 0 || d.DXCC != c.DXCC {
+			t.Errorf("%s: want the cty.csv number %d, got %d", call, c.DXCC, d.DXCC)
+		}
+	}
+	numbered := map[int]bool{}
+	for _, n := range datNumbers {
+		if _, ok := FindDXCC(n); !ok {
+			t.Errorf("want %d in the DXCC list", n)
+		}
+		numbered[n] = true
+	}
+	for _, e := range DXCCList() {
+		if !e.Deleted && !numbered[e.Number] {
+			t.Errorf("no primary prefix for %d %s", e.Number, e.Name)
+		}
+	}
+}
+
+func TestLookupCSV(t *testing.T) {
+	d, err := Load("testdata/cty.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		call string
+		name string
+		dxcc int
+		cq   int
+	}{
+		{"DL1ABC", "Fed. Rep. of Germany", 230, 14},
+		{"DL1ABC/EA8", "Canary Islands", 29, 33},
+		{"TA1ABC", "Turkey", 390, 20},
+		{"IT9ABC", "Italy", 248, 15},
+		{"3D2CR", "Conway Reef", 489, 32},
+		{"K1LZ", "United States", 291, 4},
+		{"VE2IM", "Canada", 1, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.call, func(t *testing.T) {
+			e, ok := d.Lookup(tt.call)
+			if !ok {
+				t.Fatal("not found")
+			}
+			if e.Name != tt.name || e.DXCC != tt.dxcc || e.CQZone != tt.cq {
+				t.Errorf("want %s %d %d, got %s %d %d", tt.name, tt.dxcc, tt.cq, e.Name, e.DXCC, e.CQZone)
+			}
+		})
+	}
+}
+
+func TestLookupXML(t *testing.T) {
+	d, err := Load("testdata/cty.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	date := func(y int) time.Time { return time.Date(y, 6, 1, 0, 0, 0, 0, time.UTC) }
+	tests := []struct {
+		name   string
+		call   string
+		t      time.Time
+		ok     bool
+		entity string
+		dxcc   int
+		cq     int
+	}{
+		{"prefix", "DL1ABC", date(2023), true, "FEDERAL REPUBLIC OF GERMANY", 230, 14},
+		{"portable", "EA8/DL1ABC", date(2023), true, "CANARY ISLANDS", 29, 33},
+		{"exception in its dates", "KC6ZZ", date(2003), true, "PALAU", 22, 27},
+		{"exception out of its dates", "KC6ZZ", date(2010), true, "UNITED STATES OF AMERICA", 291, 5},
+		{"invalid operation", "EA8XX", date(2020), false, "", 0, 0},
+		{"after the invalid operation", "EA8XX", date(2021), true, "CANARY ISLANDS", 29, 33},
+		{"zone exception", "VE8AA", date(2020), true, "CANADA", 1, 2},
+		{"before the zone exception", "VE8AA", date(2018), true, "CANADA", 1, 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, ok := d.LookupAt(tt.call, tt.t)
+			if ok != tt.ok {
+				t.Fatalf("want %v, got %v", tt.ok, ok)
+			}
+			if e.Name != tt.entity || e.DXCC != tt.dxcc || e.CQZone != tt.cq {
+				t.Errorf("want %s %d %d, got %s %d %d", tt.entity, tt.dxcc, tt.cq, e.Name, e.DXCC, e.CQZone)
+			}
+		})
+	}
+	e, _ := d.Lookup("DL1ABC")
+	if e.Lon != 10 || e.Prefix != "DL" {
+		t.Errorf("want 10 DL, got %v %s", e.Lon, e.Prefix)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	_, err := Load("testdata/cty.txt")
+	if !errors.Is(err, ErrFormat) {
+		t.Errorf("want %v, got %v", ErrFormat, err)
+	}
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"short record", "Canada: 05: 09: NA: VE;"},
+		{"bad zone", "Canada: x5: 09: NA: 44.35: 78.75: 5.0: VE: VE;"},
+		{"open override", "Canada: 05: 09: NA: 44.35: 78.75: 5.0: VE: =VE2IM(2;"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseDat(strings.NewReader(tt.data))
+			if err == nil {
+				t.Error("want an error")
+			}
+		})
+	}
+}
+
+func TestDXCCList(t *testing.T) {
+	current, deleted := 0, 0
+	for _, e := range DXCCList() {
+		if e.Deleted {
+			deleted++
+		} else {
+			current++
+		}
+	}
+	if current != 340 || deleted != 62 {
+		t.Errorf("want 340 current and 62 deleted entities, got %d and %d", current, deleted)
+	}
+	if e, ok := FindDXCC(230); !ok || e.Name != "FEDERAL REPUBLIC OF GERMANY" || e.Deleted {
+		t.Errorf("want 230 Germany, got %v %v", e, ok)
+	}
+	if e, ok := FindDXCC(81); !ok || !e.Deleted {
+		t.Errorf("want 81 deleted, got %v %v", e, ok)
+	}
+	if _, ok := FindDXCC(73); ok {
+		t.Error("want no entity 73")
+	}
+}
@@ -0,0 +1,179 @@
+package cty
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+//cty.dat has a record per entity, eight fields each ended by a colon
+//followed by its prefixes and calls, separated by commas and ended by a
+//semicolon:
+//
+//	Canada:  05:  09:  NA:  44.35:  78.75:  5.0:  VE:
+//	    CF,CG,VA,VE,VY,=VE2IM(2)[4],VY0(04)[04];
+//
+//The fields are the name, CQ zone, ITU zone, continent, latitude,
+//longitude (west positive), UTC offset and primary prefix.  The file has
+//no DXCC numbers, they come from the primary prefix (datNumbers).  A prefix
+//starting with * is a WAE entity (European Turkey, Sicily) which is not
+//a DXCC entity, those are left out so their calls fall back to the
+//parent entity.  A = marks a whole call and (CQ) [ITU] <lat/lon> {continent}
+//~offset~ override the fields of the entity for one prefix or call.
+//
+//cty.csv has the same data, one entity per line with the DXCC number:
+//
+//	VE,Canada,1,NA,5,9,44.35,78.75,5.0,CF CG VA VE VY =VE2IM(2)[4] VY0(04)[04];
+
+// ParseDat reads a cty.dat file
+func ParseDat(r io.Reader) (*Database, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	d := newDatabase()
+	for i, rec := range strings.Split(string(data), ";") {
+		if strings.TrimSpace(rec) == "" {
+			continue
+		}
+		fields := strings.SplitN(rec, ":", 9)
+		if len(fields) != 9 {
+			return nil, fmt.Errorf("cty: record %d has %d fields", i+1, len(fields)-1)
+		}
+		for j := range fields {
+			fields[j] = strings.TrimSpace(fields[j])
+		}
+		e := Entity{Name: fields[0], Continent: fields[3], Prefix: fields[7],
+			DXCC: datNumbers[fields[7]]}
+		e.CQZone, e.ITUZone, e.Lat, e.Lon, err = parseNumbers(fields[1], fields[2],
+			fields[4], fields[5])
+		if err != nil {
+			return nil, fmt.Errorf("cty: %s: %v", e.Name, err)
+		}
+		err = d.addAliases(e, strings.Split(fields[8], ","))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return d, nil
+}
+
+// ParseCSV reads a cty.csv file
+func ParseCSV(r io.Reader) (*Database, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = 10
+	d := newDatabase()
+	for {
+		fields, err := cr.Read()
+		if err == io.EOF {
+			return d, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("cty: %v", err)
+		}
+		e := Entity{Prefix: fields[0], Name: fields[1], Continent: fields[3]}
+		e.DXCC, err = strconv.Atoi(fields[2])
+		if err != nil {
+			return nil, fmt.Errorf("cty: %s: bad DXCC number %q", e.Name, fields[2])
+		}
+		e.CQZone, e.ITUZone, e.Lat, e.Lon, err = parseNumbers(fields[4], fields[5],
+			fields[6], fields[7])
+		if err != nil {
+			return nil, fmt.Errorf("cty: %s: %v", e.Name, err)
+		}
+		err = d.addAliases(e, strings.Fields(strings.TrimSuffix(strings.TrimSpace(fields[9]), ";")))
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// the zones and the position, the longitude is west positive in the files
+func parseNumbers(cq, itu, lat, lon string) (int, int, float64, float64, error) {
+	c, err := strconv.Atoi(cq)
+	if err != nil {
+		return 0, 0, 0, 0, fmt.Errorf("bad CQ zone %q", cq)
+	}
+	i, err := strconv.Atoi(itu)
+	if err != nil {
+		return 0, 0, 0, 0, fmt.Errorf("bad ITU zone %q", itu)
+	}
+	la, err := strconv.ParseFloat(lat, 64)
+	if err != nil {
+		return 0, 0, 0, 0, fmt.Errorf("bad latitude %q", lat)
+	}
+	lo, err := strconv.ParseFloat(lon, 64)
+	if err != nil {
+		return 0, 0, 0, 0, fmt.Errorf("bad longitude %q", lon)
+	}
+	return c, i, la, -lo, nil
+}
+
+// adds the prefixes and calls of an entity with their overrides
+func (d *Database) addAliases(e Entity, aliases []string) error {
+	if strings.HasPrefix(e.Prefix, "*") {
+		return nil
+	}
+	for _, a := range aliases {
+		a = strings.TrimSpace(a)
+		if a == "" {
+			continue
+		}
+		whole := strings.HasPrefix(a, "=")
+		a = strings.TrimPrefix(a, "=")
+		n := strings.IndexAny(a, "([<{~")
+		if n == -1 {
+			n = len(a)
+		}
+		name := strings.ToUpper(a[:n])
+		r := record{Entity: e}
+		err := override(&r.Entity, a[n:])
+		if err != nil {
+			return fmt.Errorf("cty: %s: %s: %v", e.Name, a, err)
+		}
+		if whole {
+			d.addCall(name, r)
+		} else {
+			d.addPrefix(name, r)
+		}
+	}
+	return nil
+}
+
+// applies the (CQ) [ITU] <lat/lon> {continent} ~offset~ overrides
+func override(e *Entity, s string) error {
+	closing := map[byte]byte{'(': ')', '[': ']', '<': '>', '{': '}', '~': '~'}
+	for s != "" {
+		c, ok := closing[s[0]]
+		if !ok {
+			return fmt.Errorf("unexpected %q", s)
+		}
+		n := strings.IndexByte(s[1:], c)
+		if n == -1 {
+			return fmt.Errorf("%c is not closed", s[0])
+		}
+		v := s[1 : n+1]
+		var err error
+		switch s[0] {
+		case '(':
+			e.CQZone, err = strconv.Atoi(v)
+		case '[':
+			e.ITUZone, err = strconv.Atoi(v)
+		case '<':
+			ll := strings.SplitN(v, "/", 2)
+			if len(ll) != 2 {
+				return fmt.Errorf("bad position %q", v)
+			}
+			_, _, e.Lat, e.Lon, err = parseNumbers("0", "0", ll[0], ll[1])
+		case '{':
+			e.Continent = v
+		}
+		if err != nil {
+			return err
+		}
+		s = s[n+2:]
+	}
+	return nil
+}
@@ -0,0 +1,490 @@
+package cty
+
+import "sort"
+
+//The DXCC entities by their ADIF numbers (the DXCC Entity Code enumeration
+//of the ADIF specification) with the ones ARRL has deleted.  The names are
+//the ADIF ones, the country files may spell them another way.
+
+// DXCC is an entity of the DXCC list
+type DXCC struct {
+	Number  int
+	Name    string
+	Deleted bool //no longer counts for the current DXCC totals
+}
+
+var dxccList = []DXCC{
+	{1, "CANADA", false},
+	{2, "ABU AIL IS.", true},
+	{3, "AFGHANISTAN", false},
+	{4, "AGALEGA & ST. BRANDON IS.", false},
+	{5, "ALAND IS.", false},
+	{6, "ALASKA", false},
+	{7, "ALBANIA", false},
+	{8, "ALDABRA", true},
+	{9, "AMERICAN SAMOA", false},
+	{10, "AMSTERDAM & ST. PAUL IS.", false},
+	{11, "ANDAMAN & NICOBAR IS.", false},
+	{12, "ANGUILLA", false},
+	{13, "ANTARCTICA", false},
+	{14, "ARMENIA", false},
+	{15, "ASIATIC RUSSIA", false},
+	{16, "NEW ZEALAND SUBANTARCTIC ISLANDS", false},
+	{17, "AVES I.", false},
+	{18, "AZERBAIJAN", false},
+	{19, "BAJO NUEVO", true},
+	{20, "BAKER & HOWLAND IS.", false},
+	{21, "BALEARIC IS.", false},
+	{22, "PALAU", false},
+	{23, "BLENHEIM REEF", true},
+	{24, "BOUVET", false},
+	{25, "BRITISH NORTH BORNEO", true},
+	{26, "BRITISH SOMALILAND", true},
+	{27, "BELARUS", false},
+	{28, "CANAL ZONE", true},
+	{29, "CANARY IS.", false},
+	{30, "CELEBE & MOLUCCA IS.", true},
+	{31, "C. KIRIBATI (BRITISH PHOENIX IS.)", false},
+	{32, "CEUTA & MELILLA", false},
+	{33, "CHAGOS IS.", false},
+	{34, "CHATHAM IS.", false},
+	{35, "CHRISTMAS I.", false},
+	{36, "CLIPPERTON I.", false},
+	{37, "COCOS I.", false},
+	{38, "COCOS (KEELING) IS.", false},
+	{39, "COMOROS", true},
+	{40, "CRETE", false},
+	{41, "CROZET I.", false},
+	{42, "DAMAO, DIU", true},
+	{43, "DESECHEO I.", false},
+	{44, "DESROCHES", true},
+	{45, "DODECANESE", false},
+	{46, "EAST MALAYSIA", false},
+	{47, "EASTER I.", false},
+	{48, "E. KIRIBATI (LINE IS.)", false},
+	{49, "EQUATORIAL GUINEA", false},
+	{50, "MEXICO", false},
+	{51, "ERITREA", false},
+	{52, "ESTONIA", false},
+	{53, "ETHIOPIA", false},
+	{54, "EUROPEAN RUSSIA", false},
+	{55, "FARQUHAR", true},
+	{56, "FERNANDO DE NORONHA", false},
+	{57, "FRENCH EQUATORIAL AFRICA", true},
+	{58, "FRENCH INDO-CHINA", true},
+	{59, "FRENCH WEST AFRICA", true},
+	{60, "BAHAMAS", false},
+	{61, "FRANZ JOSEF LAND", false},
+	{62, "BARBADOS", false},
+	{63, "FRENCH GUIANA", false},
+	{64, "BERMUDA", false},
+	{65, "BRITISH VIRGIN IS.", false},
+	{66, "BELIZE", false},
+	{67, "FRENCH INDIA", true},
+	{68, "KUWAIT/SAUDI ARABIA NEUTRAL ZONE", true},
+	{69, "CAYMAN IS.", false},
+	{70, "CUBA", false},
+	{71, "GALAPAGOS IS.", false},
+	{72, "DOMINICAN REPUBLIC", false},
+	{74, "EL SALVADOR", false},
+	{75, "GEORGIA", false},
+	{76, "GUATEMALA", false},
+	{77, "GRENADA", false},
+	{78, "HAITI", false},
+	{79, "GUADELOUPE", false},
+	{80, "HONDURAS", false},
+	{81, "GERMANY", true},
+	{82, "JAMAICA", false},
+	{84, "MARTINIQUE", false},
+	{85, "BONAIRE, CURACAO", true},
+	{86, "NICARAGUA", false},
+	{88, "PANAMA", false},
+	{89, "TURKS & CAICOS IS.", false},
+	{90, "TRINIDAD & TOBAGO", false},
+	{91, "ARUBA", false},
+	{93, "GEYSER REEF", true},
+	{94, "ANTIGUA & BARBUDA", false},
+	{95, "DOMINICA", false},
+	{96, "MONTSERRAT", false},
+	{97, "ST. LUCIA", false},
+	{98, "ST. VINCENT", false},
+	{99, "GLORIOSO IS.", false},
+	{100, "ARGENTINA", false},
+	{101, "GOA", true},
+	{102, "GOLD COAST, TOGOLAND", true},
+	{103, "GUAM", false},
+	{104, "BOLIVIA", false},
+	{105, "GUANTANAMO BAY", false},
+	{106, "GUERNSEY", false},
+	{107, "GUINEA", false},
+	{108, "BRAZIL", false},
+	{109, "GUINEA-BISSAU", false},
+	{110, "HAWAII", false},
+	{111, "HEARD I.", false},
+	{112, "CHILE", false},
+	{113, "IFNI", true},
+	{114, "ISLE OF MAN", false},
+	{115, "ITALIAN SOMALILAND", true},
+	{116, "COLOMBIA", false},
+	{117, "ITU HQ", false},
+	{118, "JAN MAYEN", false},
+	{119, "JAVA", true},
+	{120, "ECUADOR", false},
+	{122, "JERSEY", false},
+	{123, "JOHNSTON I.", false},
+	{124, "JUAN DE NOVA, EUROPA", false},
+	{125, "JUAN FERNANDEZ IS.", false},
+	{126, "KALININGRAD", false},
+	{127, "KAMARAN IS.", true},
+	{128, "KARELO-FINNISH REPUBLIC", true},
+	{129, "GUYANA", false},
+	{130, "KAZAKHSTAN", false},
+	{131, "KERGUELEN IS.", false},
+	{132, "PARAGUAY", false},
+	{133, "KERMADEC IS.", false},
+	{134, "KINGMAN REEF", true},
+	{135, "KYRGYZSTAN", false},
+	{136, "PERU", false},
+	{137, "REPUBLIC OF KOREA", false},
+	{138, "KURE I.", false},
+	{139, "KURIA MURIA I.", true},
+	{140, "SURINAME", false},
+	{141, "FALKLAND IS.", false},
+	{142, "LAKSHADWEEP IS.", false},
+	{143, "LAOS", false},
+	{144, "URUGUAY", false},
+	{145, "LATVIA", false},
+	{146, "LITHUANIA", false},
+	{147, "LORD HOWE I.", false},
+	{148, "VENEZUELA", false},
+	{149, "AZORES", false},
+	{150, "AUSTRALIA", false},
+	{151, "MALYJ VYSOTSKIJ I.", true},
+	{152, "MACAO", false},
+	{153, "MACQUARIE I.", false},
+	{154, "YEMEN ARAB REPUBLIC", true},
+	{155, "MALAYA", true},
+	{157, "NAURU", false},
+	{158, "VANUATU", false},
+	{159, "MALDIVES", false},
+	{160, "TONGA", false},
+	{161, "MALPELO I.", false},
+	{162, "NEW CALEDONIA", false},
+	{163, "PAPUA NEW GUINEA", false},
+	{164, "MANCHURIA", true},
+	{165, "MAURITIUS", false},
+	{166, "MARIANA IS.", false},
+	{167, "MARKET REEF", false},
+	{168, "MARSHALL IS.", false},
+	{169, "MAYOTTE", false},
+	{170, "NEW ZEALAND", false},
+	{171, "MELLISH REEF", false},
+	{172, "PITCAIRN I.", false},
+	{173, "MICRONESIA", false},
+	{174, "MIDWAY I.", false},
+	{175, "FRENCH POLYNESIA", false},
+	{176, "FIJI", false},
+	{177, "MINAMI TORISHIMA", false},
+	{178, "MINERVA REEF", true},
+	{179, "MOLDOVA", false},
+	{180, "MOUNT ATHOS", false},
+	{181, "MOZAMBIQUE", false},
+	{182, "NAVASSA I.", false},
+	{183, "NETHERLANDS BORNEO", true},
+	{184, "NETHERLANDS NEW GUINEA", true},
+	{185, "SOLOMON IS.", false},
+	{186, "NEWFOUNDLAND, LABRADOR", true},
+	{187, "NIGER", false},
+	{188, "NIUE", false},
+	{189, "NORFOLK I.", false},
+	{190, "SAMOA", false},
+	{191, "NORTH COOK IS.", false},
+	{192, "OGASAWARA", false},
+	{193, "OKINAWA (RYUKYU IS.)", true},
+	{194, "OKINO TORI-SHIMA", true},
+	{195, "ANNOBON I.", false},
+	{196, "PALESTINE", true},
+	{197, "PALMYRA & JARVIS IS.", false},
+	{198, "PAPUA TERRITORY", true},
+	{199, "PETER 1 I.", false},
+	{200, "PORTUGUESE TIMOR", true},
+	{201, "PRINCE EDWARD & MARION IS.", false},
+	{202, "PUERTO RICO", false},
+	{203, "ANDORRA", false},
+	{204, "REVILLAGIGEDO", false},
+	{205, "ASCENSION I.", false},
+	{206, "AUSTRIA", false},
+	{207, "RODRIGUEZ I.", false},
+	{208, "RUANDA-URUNDI", true},
+	{209, "BELGIUM", false},
+	{210, "SAAR", true},
+	{211, "SABLE I.", false},
+	{212, "BULGARIA", false},
+	{213, "SAINT MARTIN", false},
+	{214, "CORSICA", false},
+	{215, "CYPRUS", false},
+	{216, "SAN ANDRES & PROVIDENCIA", false},
+	{217, "SAN FELIX & SAN AMBROSIO", false},
+	{218, "CZECHOSLOVAKIA", true},
+	{219, "SAO TOME & PRINCIPE", false},
+	{220, "SARAWAK", true},
+	{221, "DENMARK", false},
+	{222, "FAROE IS.", false},
+	{223, "ENGLAND", false},
+	{224, "FINLAND", false},
+	{225, "SARDINIA", false},
+	{226, "SAUDI ARABIA/IRAQ NEUTRAL ZONE", true},
+	{227, "FRANCE", false},
+	{228, "SERRANA BANK & RONCADOR CAY", true},
+	{229, "GERMAN DEMOCRATIC REPUBLIC", true},
+	{230, "FEDERAL REPUBLIC OF GERMANY", false},
+	{231, "SIKKIM", true},
+	{232, "SOMALIA", false},
+	{233, "GIBRALTAR", false},
+	{234, "SOUTH COOK IS.", false},
+	{235, "SOUTH GEORGIA I.", false},
+	{236, "GREECE", false},
+	{237, "GREENLAND", false},
+	{238, "SOUTH ORKNEY IS.", false},
+	{239, "HUNGARY", false},
+	{240, "SOUTH SANDWICH IS.", false},
+	{241, "SOUTH SHETLAND IS.", false},
+	{242, "ICELAND", false},
+	{243, "PEOPLE'S DEMOCRATIC REP. OF YEMEN", true},
+	{244, "SOUTHERN SUDAN", true},
+	{245, "IRELAND", false},
+	{246, "SOVEREIGN MILITARY ORDER OF MALTA", false},
+	{247, "SPRATLY IS.", false},
+	{248, "ITALY", false},
+	{249, "ST. KITTS & NEVIS", false},
+	{250, "ST. HELENA", false},
+	{251, "LIECHTENSTEIN", false},
+	{252, "ST. PAUL I.", false},
+	{253, "ST. PETER & ST. PAUL ROCKS", false},
+	{254, "LUXEMBOURG", false},
+	{255, "ST. MAARTEN, SABA, ST. EUSTATIUS", true},
+	{256, "MADEIRA IS.", false},
+	{257, "MALTA", false},
+	{258, "SUMATRA", true},
+	{259, "SVALBARD", false},
+	{260, "MONACO", false},
+	{261, "SWAN IS.", true},
+	{262, "TAJIKISTAN", false},
+	{263, "NETHERLANDS", false},
+	{264, "TANGIER", true},
+	{265, "NORTHERN IRELAND", false},
+	{266, "NORWAY", false},
+	{267, "TERRITORY OF NEW GUINEA", true},
+	{268, "TIBET", true},
+	{269, "POLAND", false},
+	{270, "TOKELAU IS.", false},
+	{271, "TRIESTE", true},
+	{272, "PORTUGAL", false},
+	{273, "TRINDADE & MARTIM VAZ IS.", false},
+	{274, "TRISTAN DA CUNHA & GOUGH I.", false},
+	{275, "ROMANIA", false},
+	{276, "TROMELIN I.", false},
+	{277, "ST. PIERRE & MIQUELON", false},
+	{278, "SAN MARINO", false},
+	{279, "SCOTLAND", false},
+	{280, "TURKMENISTAN", false},
+	{281, "SPAIN", false},
+	{282, "TUVALU", false},
+	{283, "UK SOVEREIGN BASE AREAS ON CYPRUS", false},
+	{284, "SWEDEN", false},
+	{285, "VIRGIN IS.", false},
+	{286, "UGANDA", false},
+	{287, "SWITZERLAND", false},
+	{288, "UKRAINE", false},
+	{289, "UNITED NATIONS HQ", false},
+	{291, "UNITED STATES OF AMERICA", false},
+	{292, "UZBEKISTAN", false},
+	{293, "VIET NAM", false},
+	{294, "WALES", false},
+	{295, "VATICAN", false},
+	{296, "SERBIA", false},
+	{297, "WAKE I.", false},
+	{298, "WALLIS & FUTUNA IS.", false},
+	{299, "WEST MALAYSIA", false},
+	{301, "W. KIRIBATI (GILBERT IS.)", false},
+	{302, "WESTERN SAHARA", false},
+	{303, "WILLIS I.", false},
+	{304, "BAHRAIN", false},
+	{305, "BANGLADESH", false},
+	{306, "BHUTAN", false},
+	{307, "ZANZIBAR", true},
+	{308, "COSTA RICA", false},
+	{309, "MYANMAR", false},
+	{312, "CAMBODIA", false},
+	{315, "SRI LANKA", false},
+	{318, "CHINA", false},
+	{321, "HONG KONG", false},
+	{324, "INDIA", false},
+	{327, "INDONESIA", false},
+	{330, "IRAN", false},
+	{333, "IRAQ", false},
+	{336, "ISRAEL", false},
+	{339, "JAPAN", false},
+	{342, "JORDAN", false},
+	{344, "DEMOCRATIC PEOPLE'S REP. OF KOREA", false},
+	{345, "BRUNEI DARUSSALAM", false},
+	{348, "KUWAIT", false},
+	{354, "LEBANON", false},
+	{363, "MONGOLIA", false},
+	{369, "NEPAL", false},
+	{370, "OMAN", false},
+	{372, "PAKISTAN", false},
+	{375, "PHILIPPINES", false},
+	{376, "QATAR", false},
+	{378, "SAUDI ARABIA", false},
+	{379, "SEYCHELLES", false},
+	{381, "SINGAPORE", false},
+	{382, "DJIBOUTI", false},
+	{384, "SYRIA", false},
+	{386, "TAIWAN", false},
+	{387, "THAILAND", false},
+	{390, "TURKEY", false},
+	{391, "UNITED ARAB EMIRATES", false},
+	{400, "ALGERIA", false},
+	{401, "ANGOLA", false},
+	{402, "BOTSWANA", false},
+	{404, "BURUNDI", false},
+	{406, "CAMEROON", false},
+	{408, "CENTRAL AFRICA", false},
+	{409, "CAPE VERDE", false},
+	{410, "CHAD", false},
+	{411, "COMOROS", false},
+	{412, "REPUBLIC OF THE CONGO", false},
+	{414, "DEMOCRATIC REPUBLIC OF THE CONGO", false},
+	{416, "BENIN", false},
+	{420, "GABON", false},
+	{422, "THE GAMBIA", false},
+	{424, "GHANA", false},
+	{428, "COTE D'IVOIRE", false},
+	{430, "KENYA", false},
+	{432, "LESOTHO", false},
+	{434, "LIBERIA", false},
+	{436, "LIBYA", false},
+	{438, "MADAGASCAR", false},
+	{440, "MALAWI", false},
+	{442, "MALI", false},
+	{444, "MAURITANIA", false},
+	{446, "MOROCCO", false},
+	{450, "NIGERIA", false},
+	{452, "ZIMBABWE", false},
+	{453, "REUNION I.", false},
+	{454, "RWANDA", false},
+	{456, "SENEGAL", false},
+	{458, "SIERRA LEONE", false},
+	{460, "ROTUMA I.", false},
+	{462, "REPUBLIC OF SOUTH AFRICA", false},
+	{464, "NAMIBIA", false},
+	{466, "SUDAN", false},
+	{468, "KINGDOM OF ESWATINI", false},
+	{470, "TANZANIA", false},
+	{474, "TUNISIA", false},
+	{478, "EGYPT", false},
+	{480, "BURKINA FASO", false},
+	{482, "ZAMBIA", false},
+	{483, "TOGO", false},
+	{488, "WALVIS BAY", true},
+	{489, "CONWAY REEF", false},
+	{490, "BANABA I. (OCEAN I.)", false},
+	{492, "YEMEN", false},
+	{493, "PENGUIN IS.", true},
+	{497, "CROATIA", false},
+	{499, "SLOVENIA", false},
+	{501, "BOSNIA-HERZEGOVINA", false},
+	{502, "NORTH MACEDONIA", false},
+	{503, "CZECH REPUBLIC", false},
+	{504, "SLOVAK REPUBLIC", false},
+	{505, "PRATAS I.", false},
+	{506, "SCARBOROUGH REEF", false},
+	{507, "TEMOTU PROVINCE", false},
+	{508, "AUSTRAL I.", false},
+	{509, "MARQUESAS IS.", false},
+	{510, "PALESTINE", false},
+	{511, "TIMOR-LESTE", false},
+	{512, "CHESTERFIELD IS.", false},
+	{513, "DUCIE I.", false},
+	{514, "MONTENEGRO", false},
+	{515, "SWAINS I.", false},
+	{516, "SAINT BARTHELEMY", false},
+	{517, "CURACAO", false},
+	{518, "SINT MAARTEN", false},
+	{519, "SABA & ST. EUSTATIUS", false},
+	{520, "BONAIRE", false},
+	{521, "SOUTH SUDAN", false},
+	{522, "REPUBLIC OF KOSOVO", false},
+}
+
+var dxccByNumber = func() map[int]DXCC {
+	m := map[int]DXCC{}
+	for _, e := range dxccList {
+		m[e.Number] = e
+	}
+	return m
+}()
+
+// the ADIF numbers of the entities by their primary prefix in cty.dat, which
+// does not have the numbers.  Kingman Reef and Malyj Vysotskij are deleted
+// but older files still have them.
+var datNumbers = map[string]int{
+	"1A": 246, "1S": 247, "3A": 260, "3B6": 4, "3B8": 165, "3B9": 207, "3C": 49, "3C0": 195,
+	"3D2": 176, "3D2/c": 489, "3D2/r": 460, "3DA": 468, "3V": 474, "3W": 293, "3X": 107, "3Y/b": 24,
+	"3Y/p": 199, "4J": 18, "4L": 75, "4O": 514, "4S": 315, "4U1I": 117, "4U1U": 289, "4W": 511,
+	"4X": 336, "5A": 436, "5B": 215, "5H": 470, "5N": 450, "5R": 438, "5T": 444, "5U": 187,
+	"5V": 483, "5W": 190, "5X": 286, "5Z": 430, "6W": 456, "6Y": 82, "7O": 492, "7P": 432,
+	"7Q": 440, "7X": 400, "8P": 62, "8Q": 159, "8R": 129, "9A": 497, "9G": 424, "9H": 257,
+	"9J": 482, "9K": 348, "9L": 458, "9M2": 299, "9M6": 46, "9N": 369, "9Q": 414, "9U": 404,
+	"9V": 381, "9X": 454, "9Y": 90, "A2": 402, "A3": 160, "A4": 370, "A5": 306, "A6": 391,
+	"A7": 376, "A9": 304, "AP": 372, "BS7": 506, "BV": 386, "BV9P": 505, "BY": 318, "C2": 157,
+	"C3": 203, "C5": 422, "C6": 60, "C9": 181, "CE": 112, "CE0X": 217, "CE0Y": 47, "CE0Z": 125,
+	"CE9": 13, "CM": 70, "CN": 446, "CP": 104, "CT": 272, "CT3": 256, "CU": 149, "CX": 144,
+	"CY0": 211, "CY9": 252, "D2": 401, "D4": 409, "D6": 411, "DL": 230, "DU": 375, "E3": 51,
+	"E4": 510, "E5/n": 191, "E5/s": 234, "E6": 188, "E7": 501, "EA": 281, "EA6": 21, "EA8": 29,
+	"EA9": 32, "EI": 245, "EK": 14, "EL": 434, "EP": 330, "ER": 179, "ES": 52, "ET": 53,
+	"EU": 27, "EX": 135, "EY": 262, "EZ": 280, "F": 227, "FG": 79, "FH": 169, "FJ": 516,
+	"FK": 162, "FK/c": 512, "FM": 84, "FO": 175, "FO/a": 508, "FO/c": 36, "FO/m": 509, "FP": 277,
+	"FR": 453, "FR/g": 99, "FR/j": 124, "FR/t": 276, "FS": 213, "FT/w": 41, "FT/x": 131, "FT/z": 10,
+	"FW": 298, "FY": 63, "G": 223, "GD": 114, "GI": 265, "GJ": 122, "GM": 279, "GU": 106,
+	"GW": 294, "H4": 185, "H40": 507, "HA": 239, "HB": 287, "HB0": 251, "HC": 120, "HC8": 71,
+	"HH": 78, "HI": 72, "HK": 116, "HK0/a": 216, "HK0/m": 161, "HL": 137, "HP": 88, "HR": 80,
+	"HS": 387, "HV": 295, "HZ": 378, "I": 248, "IS": 225, "J2": 382, "J3": 77, "J5": 109,
+	"J6": 97, "J7": 95, "J8": 98, "JA": 339, "JD/m": 177, "JD/o": 192, "JT": 363, "JW": 259,
+	"JX": 118, "JY": 342, "K": 291, "KG4": 105, "KH0": 166, "KH1": 20, "KH2": 103, "KH3": 123,
+	"KH4": 174, "KH5": 197, "KH5K": 134, "KH6": 110, "KH7K": 138, "KH8": 9, "KH8/s": 515, "KH9": 297,
+	"KL": 6, "KP1": 182, "KP2": 285, "KP4": 202, "KP5": 43, "LA": 266, "LU": 100, "LX": 254,
+	"LY": 146, "LZ": 212, "OA": 136, "OD": 354, "OE": 206, "OH": 224, "OH0": 5, "OJ0": 167,
+	"OK": 503, "OM": 504, "ON": 209, "OX": 237, "OY": 222, "OZ": 221, "P2": 163, "P4": 91,
+	"P5": 344, "PA": 263, "PJ2": 517, "PJ4": 520, "PJ5": 519, "PJ7": 518, "PY": 108, "PY0F": 56,
+	"PY0S": 253, "PY0T": 273, "PZ": 140, "R1FJ": 61, "R1MV": 151, "S0": 302, "S2": 305, "S5": 499,
+	"S7": 379, "S9": 219, "SM": 284, "SP": 269, "ST": 466, "SU": 478, "SV": 236, "SV/a": 180,
+	"SV5": 45, "SV9": 40, "T2": 282, "T30": 301, "T31": 31, "T32": 48, "T33": 490, "T5": 232,
+	"T7": 278, "T8": 22, "TA": 390, "TF": 242, "TG": 76, "TI": 308, "TI9": 37, "TJ": 406,
+	"TK": 214, "TL": 408, "TN": 412, "TR": 420, "TT": 410, "TU": 428, "TY": 416, "TZ": 442,
+	"UA": 54, "UA2": 126, "UA9": 15, "UK": 292, "UN": 130, "UR": 288, "V2": 94, "V3": 66,
+	"V4": 249, "V5": 464, "V6": 173, "V7": 168, "V8": 345, "VE": 1, "VK": 150, "VK0H": 111,
+	"VK0M": 153, "VK9C": 38, "VK9L": 147, "VK9M": 171, "VK9N": 189, "VK9W": 303, "VK9X": 35, "VP2E": 12,
+	"VP2M": 96, "VP2V": 65, "VP5": 89, "VP6": 172, "VP6/d": 513, "VP8": 141, "VP8/g": 235, "VP8/h": 241,
+	"VP8/o": 238, "VP8/s": 240, "VP9": 64, "VQ9": 33, "VR": 321, "VU": 324, "VU4": 11, "VU7": 142,
+	"XE": 50, "XF4": 204, "XT": 480, "XU": 312, "XW": 143, "XX9": 152, "XZ": 309, "YA": 3,
+	"YB": 327, "YI": 333, "YJ": 158, "YK": 384, "YL": 145, "YN": 86, "YO": 275, "YS": 74,
+	"YU": 296, "YV": 148, "YV0": 17, "Z2": 452, "Z3": 502, "Z6": 522, "Z8": 521, "ZA": 7,
+	"ZB": 233, "ZC4": 283, "ZD7": 250, "ZD8": 205, "ZD9": 274, "ZF": 69, "ZK3": 270, "ZL": 170,
+	"ZL7": 34, "ZL8": 133, "ZL9": 16, "ZP": 132, "ZS": 462, "ZS8": 201,
+}
+
+// DXCCList returns the DXCC entities, the deleted ones too, by number
+func DXCCList() []DXCC {
+	l := make([]DXCC, len(dxccList))
+	copy(l, dxccList)
+	sort.Slice(l, func(i, j int) bool { return l[i].Number < l[j].Number })
+	return l
+}
+
+// FindDXCC returns the DXCC entity with the ADIF number
+func FindDXCC(number int) (DXCC, bool) {
+	e, ok := dxccByNumber[number]
+	return e, ok
+}
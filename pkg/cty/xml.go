@@ -0,0 +1,124 @@
+package cty
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+//cty.xml of Club Log lists the entities with their ADIF numbers, the
+//prefixes and the exceptions (whole calls), each with the entity, zones,
+//position and the dates it is valid for.  It also has the operations
+//that do not count for DXCC and the calls in another CQ zone than their
+//entity.  It has no ITU zones.
+
+type xmlFile struct {
+	Entities   []xmlEntity `xml:"entities>entity"`
+	Exceptions []xmlRecord `xml:"exceptions>exception"`
+	Prefixes   []xmlRecord `xml:"prefixes>prefix"`
+	Invalid    []xmlRecord `xml:"invalid_operations>invalid"`
+	Zones      []xmlRecord `xml:"zone_exceptions>zone_exception"`
+}
+
+type xmlEntity struct {
+	ADIF   int    `xml:"adif"`
+	Name   string `xml:"name"`
+	Prefix string `xml:"prefix"`
+}
+
+type xmlRecord struct {
+	Call   string  `xml:"call"`
+	Entity string  `xml:"entity"`
+	ADIF   int     `xml:"adif"`
+	CQZ    int     `xml:"cqz"`
+	Cont   string  `xml:"cont"`
+	Long   float64 `xml:"long"`
+	Lat    float64 `xml:"lat"`
+	Zone   int     `xml:"zone"`
+	Start  string  `xml:"start"`
+	End    string  `xml:"end"`
+}
+
+// ParseXML reads a cty.xml file
+func ParseXML(r io.Reader) (*Database, error) {
+	f := xmlFile{}
+	err := xml.NewDecoder(r).Decode(&f)
+	if err != nil {
+		return nil, fmt.Errorf("cty: %v", err)
+	}
+	primary := map[int]string{}
+	for _, e := range f.Entities {
+		primary[e.ADIF] = e.Prefix
+	}
+	d := newDatabase()
+	for _, x := range f.Prefixes {
+		r, err := x.record(primary)
+		if err != nil {
+			return nil, err
+		}
+		d.addPrefix(strings.ToUpper(x.Call), r)
+	}
+	for _, x := range f.Exceptions {
+		r, err := x.record(primary)
+		if err != nil {
+			return nil, err
+		}
+		d.addCall(strings.ToUpper(x.Call), r)
+	}
+	for _, x := range f.Invalid {
+		p, err := x.period()
+		if err != nil {
+			return nil, err
+		}
+		call := strings.ToUpper(x.Call)
+		d.invalid[call] = append(d.invalid[call], p)
+	}
+	for _, x := range f.Zones {
+		p, err := x.period()
+		if err != nil {
+			return nil, err
+		}
+		call := strings.ToUpper(x.Call)
+		d.zones[call] = append(d.zones[call], zone{period: p, cq: x.Zone})
+	}
+	return d, nil
+}
+
+func (x xmlRecord) record(primary map[int]string) (record, error) {
+	p, err := x.period()
+	if err != nil {
+		return record{}, err
+	}
+	return record{
+		Entity: Entity{
+			Name:      x.Entity,
+			DXCC:      x.ADIF,
+			Prefix:    primary[x.ADIF],
+			Continent: x.Cont,
+			CQZone:    x.CQZ,
+			Lat:       x.Lat,
+			Lon:       x.Long,
+		},
+		period: p,
+	}, nil
+}
+
+func (x xmlRecord) period() (period, error) {
+	p := period{}
+	var err error
+	if x.Start != "" {
+		p.start, err = time.Parse(time.RFC3339, x.Start)
+		if err != nil {
+			return p, fmt.Errorf("cty: %s: bad start %q", x.Call, x.Start)
+		}
+	}
+	if x.End != "" {
+		p.end, err = time.Parse(time.RFC3339, x.End)
+		if err != nil {
+			return p, fmt.Errorf("cty: %s: bad end %q", x.Call, x.End)
+		}
+	}
+	return p, nil
+}
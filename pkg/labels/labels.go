@@ -0,0 +1,181 @@
+// Package labels lays text out on sheets of address labels and writes the
+// sheets as a PDF.
+//
+// The common Avery layouts are built in, the US letter ones by their
+// number (5160, 5163) and the A4 ones by theirs (L7160, L7163).  A label is
+// a few lines of text set from its top left corner in Helvetica, the lines
+// that do not fit are dropped.  Only the Latin-1 characters are kept, the
+// rest print as ?.
+package labels
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+const (
+	inch = 72.0
+	mm   = inch / 25.4
+)
+
+// Layout is a sheet of labels, sizes are in points
+type Layout struct {
+	Name       string
+	PageWidth  float64
+	PageHeight float64
+	Columns    int
+	Rows       int
+	Left       float64 //page edge to the first column
+	Top        float64 //page edge to the first row
+	Width      float64 //of a label
+	Height     float64
+	HPitch     float64 //from one column to the next
+	VPitch     float64 //from one row to the next
+	FontSize   float64
+}
+
+// the space between the edge of a label and its text
+const padding = 6.0
+
+var layouts = map[string]Layout{
+	"5160": {Name: "Avery 5160 (30 per letter sheet)", PageWidth: 8.5 * inch, PageHeight: 11 * inch,
+		Columns: 3, Rows: 10, Left: 0.1875 * inch, Top: 0.5 * inch, Width: 2.625 * inch,
+		Height: inch, HPitch: 2.75 * inch, VPitch: inch, FontSize: 7},
+	"5163": {Name: "Avery 5163 (10 per letter sheet)", PageWidth: 8.5 * inch, PageHeight: 11 * inch,
+		Columns: 2, Rows: 5, Left: 0.15625 * inch, Top: 0.5 * inch, Width: 4 * inch,
+		Height: 2 * inch, HPitch: 4.1875 * inch, VPitch: 2 * inch, FontSize: 9},
+	"L7160": {Name: "Avery L7160 (21 per A4 sheet)", PageWidth: 210 * mm, PageHeight: 297 * mm,
+		Columns: 3, Rows: 7, Left: 7.25 * mm, Top: 15.15 * mm, Width: 63.5 * mm,
+		Height: 38.1 * mm, HPitch: 66.04 * mm, VPitch: 38.1 * mm, FontSize: 8},
+	"L7163": {Name: "Avery L7163 (14 per A4 sheet)", PageWidth: 210 * mm, PageHeight: 297 * mm,
+		Columns: 2, Rows: 7, Left: 4.65 * mm, Top: 15.15 * mm, Width: 99.1 * mm,
+		Height: 38.1 * mm, HPitch: 101.6 * mm, VPitch: 38.1 * mm, FontSize: 9},
+}
+
+// ErrLayout is returned for a layout that is not built in
+var ErrLayout = errors.New("no such label layout")
+
+// Find returns the layout by its Avery number
+func Find(number string) (Layout, error) {
+	l, ok := layouts[number]
+	if !ok {
+		return Layout{}, fmt.Errorf("%w: %s", ErrLayout, number)
+	}
+	return l, nil
+}
+
+// Numbers returns the Avery numbers of the built in layouts
+func Numbers() []string {
+	n := []string{}
+	for k := range layouts {
+		n = append(n, k)
+	}
+	sort.Strings(n)
+	return n
+}
+
+// the distance from one line of text to the next
+func (l Layout) leading() float64 {
+	return l.FontSize * 1.2
+}
+
+// Lines is how many lines of text fit on a label
+func (l Layout) Lines() int {
+	return int((l.Height - 2*padding) / l.leading())
+}
+
+// PerPage is how many labels there are on a sheet
+func (l Layout) PerPage() int {
+	return l.Columns * l.Rows
+}
+
+// Write writes the labels, each a few lines of text, as a PDF of as many
+// sheets as they take.  The labels fill the sheets a row at a time.
+func Write(w io.Writer, l Layout, labels [][]string) error {
+	pages := []string{}
+	for start := 0; start < len(labels) || start == 0; start += l.PerPage() {
+		end := start + l.PerPage()
+		if end > len(labels) {
+			end = len(labels)
+		}
+		pages = append(pages, l.page(labels[start:end]))
+	}
+	return writePDF(w, l, pages)
+}
+
+// the content stream of a sheet
+func (l Layout) page(labels [][]string) string {
+	var b strings.Builder
+	for i, lines := range labels {
+		x := l.Left + float64(i%l.Columns)*l.HPitch + padding
+		y := l.PageHeight - l.Top - float64(i/l.Columns)*l.VPitch - padding - l.FontSize
+		if len(lines) > l.Lines() {
+			lines = lines[:l.Lines()]
+		}
+		for _, line := range lines {
+			fmt.Fprintf(&b, "BT /F1 %.1f Tf %.2f %.2f Td (%s) Tj ET\n", l.FontSize, x, y, pdfString(line))
+			y -= l.leading()
+		}
+	}
+	return b.String()
+}
+
+// the text as a PDF string in WinAnsi
+func pdfString(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(byte(r))
+		case r < 32:
+			b.WriteByte(' ')
+		case r < 127:
+			b.WriteByte(byte(r))
+		case r >= 160 && r < 256:
+			fmt.Fprintf(&b, "\\%03o", r)
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
+
+// writes the objects of a PDF with one page for each content stream and
+// the cross reference table that finds them
+func writePDF(w io.Writer, l Layout, pages []string) error {
+	var b bytes.Buffer
+	offsets := []int{}
+	object := func(body string) {
+		offsets = append(offsets, b.Len())
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+	b.WriteString("%PDF-1.4\n")
+	//1 the catalog, 2 the page tree, 3 the font, then each page and its
+	//content
+	kids := []string{}
+	for i := range pages {
+		kids = append(kids, fmt.Sprintf("%d 0 R", 4+2*i))
+	}
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	for i, content := range pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] "+
+			"/Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>",
+			l.PageWidth, l.PageHeight, 5+2*i))
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", len(content), content))
+	}
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, o := range offsets {
+		fmt.Fprintf(&b, "%010d 00000 n \n", o)
+	}
+	fmt.Fprintf(&b, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+	_, err := w.Write(b.Bytes())
+	return err
+}
@@ -0,0 +1,78 @@
+package labels
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestFind(t *testing.T) {
+	for _, n := range Numbers() {
+		l, err := Find(n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		//the last label has to be on the sheet
+		right := l.Left + float64(l.Columns-1)*l.HPitch + l.Width
+		bottom := l.Top + float64(l.Rows-1)*l.VPitch + l.Height
+		if right > l.PageWidth+0.5 || bottom > l.PageHeight+0.5 {
+			t.Errorf("%s: the labels run off the sheet to %.1f, %.1f", n, right, bottom)
+		}
+		if l.Lines() < 4 {
+			t.Errorf("%s: want room for a call and a few QSOs, got %d lines", n, l.Lines())
+		}
+	}
+	if _, err := Find("9999"); !errors.Is(err, ErrLayout) {
+		t.Errorf("want %v, got %v", ErrLayout, err)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	l, err := Find("5160")
+	if err != nil {
+		t.Fatal(err)
+	}
+	labels := [][]string{}
+	for i := 0; i < 31; i++ {
+		labels = append(labels, []string{fmt.Sprintf("To Radio DL%dABC", i), "20m CW (599)"})
+	}
+	labels[0] = append(labels[0], "Müller \\ 73", "one", "two", "three", "four", "five")
+	var b bytes.Buffer
+	err = Write(&b, l, labels)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pdf := b.String()
+	if !strings.HasPrefix(pdf, "%PDF-1.4\n") || !strings.HasSuffix(pdf, "%%EOF\n") {
+		t.Fatalf("not a PDF: %q", pdf)
+	}
+	if !strings.Contains(pdf, "/Count 2") {
+		t.Error("want 31 labels on two sheets of 30")
+	}
+	for _, want := range []string{"(To Radio DL30ABC)", "(20m CW \\(599\\))", "(M\\374ller \\\\ 73)"} {
+		if !strings.Contains(pdf, want) {
+			t.Errorf("want %s in the PDF", want)
+		}
+	}
+	if strings.Contains(pdf, "(five)") {
+		t.Errorf("want the lines that do not fit on the label dropped, %d fit", l.Lines())
+	}
+
+	//every object is where the cross reference table says it is
+	n := strings.Index(pdf, "xref\n")
+	m := regexp.MustCompile(`startxref\n(\d+)`).FindStringSubmatch(pdf)
+	if m == nil || m[1] != strconv.Itoa(n) {
+		t.Fatalf("startxref does not point at the table at %d", n)
+	}
+	offsets := regexp.MustCompile(`(\d{10}) 00000 n`).FindAllStringSubmatch(pdf[n:], -1)
+	for i, o := range offsets {
+		at, _ := strconv.Atoi(o[1])
+		if !strings.HasPrefix(pdf[at:], fmt.Sprintf("%d 0 obj", i+1)) {
+			t.Errorf("object %d is not at %d", i+1, at)
+		}
+	}
+}
@@ -17,6 +17,8 @@ $(document).ready(function() {
     			$("#class").text(data["Class"])
    			$("#timezone").text(data["TimeZone"])
     			$("#qsocount").text(data["QSOCount"])
+    			$("#path").text(data["Path"])
+    			$("#localtime").text(data["LocalTime"])
    		});
   	});
 
@@ -26,6 +28,8 @@ $(document).ready(function() {
   			.then(function(data){
     			$("#name").val(data["Name"])
     			$("#country").val(data["Country"])
+    			$("#conn-path").text(data["Path"])
+    			$("#conn-localtime").text(data["LocalTime"])
     			// $("#band-select").val(data["Band"])
     			// $("#mode-select").val(data["Mode"])
     			// alert($("#master-mode").val());